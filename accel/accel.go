@@ -0,0 +1,110 @@
+// Package accel speeds up VFO tuning when a knob or wheel is turned quickly,
+// so that a band can be crossed without winding the knob for minutes while
+// slow turns still move one step at a time.
+package accel
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Preset    string  `dialsdesc:"VFO acceleration for knobs and the mouse wheel: off, gentle, normal or fast" dialsflag:"vfo-accel"`
+	Threshold float64 `dialsdesc:"Knob speed (steps per second) where acceleration starts, overriding the preset" dialsflag:"vfo-accel-threshold"`
+	Exponent  float64 `dialsdesc:"How steeply the acceleration rises with speed, overriding the preset" dialsflag:"vfo-accel-exponent"`
+	Max       float64 `dialsdesc:"The largest multiplier of the tuning step, overriding the preset" dialsflag:"vfo-accel-max"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Preset: "normal",
+	}
+}
+
+// Curve maps knob speed to a multiplier of the tuning step. Below Threshold
+// steps per second the multiplier is 1; above it, it grows as
+// (speed/Threshold)^Exponent, up to Max.
+type Curve struct {
+	Threshold float64
+	Exponent  float64
+	Max       float64
+}
+
+// Presets are the named curves
+var Presets = map[string]Curve{
+	"off":    {},
+	"gentle": {Threshold: 20, Exponent: 0.5, Max: 5},
+	"normal": {Threshold: 10, Exponent: 1, Max: 20},
+	"fast":   {Threshold: 5, Exponent: 1.5, Max: 100},
+}
+
+// Curve returns the configured preset with any overrides applied
+func (c *Config) Curve() (Curve, error) {
+	curve, ok := Presets[strings.ToLower(c.Preset)]
+	if !ok {
+		return Curve{}, fmt.Errorf("unknown VFO acceleration preset %q (want one of %s)", c.Preset, strings.Join(slices.Sorted(maps.Keys(Presets)), ", "))
+	}
+	if c.Threshold > 0 {
+		curve.Threshold = c.Threshold
+	}
+	if c.Exponent > 0 {
+		curve.Exponent = c.Exponent
+	}
+	if c.Max > 0 {
+		curve.Max = c.Max
+	}
+	return curve, nil
+}
+
+// Multiplier returns the step multiplier at speed steps per second
+func (c Curve) Multiplier(speed float64) float64 {
+	if c.Threshold <= 0 || c.Max <= 1 || speed <= c.Threshold {
+		return 1
+	}
+	return min(math.Pow(speed/c.Threshold, c.Exponent), c.Max)
+}
+
+const (
+	// The knob is taken to have stopped after a pause this long, so the
+	// next turn starts slow
+	idleReset = 250 * time.Millisecond
+	// How much each new reading moves the smoothed speed
+	smoothing = 0.5
+	// The shortest interval a speed is calculated over, so that a burst of
+	// events arriving together doesn't look infinitely fast
+	minInterval = 10 * time.Millisecond
+)
+
+// Accelerator applies a Curve to a stream of relative steps, measuring the
+// speed from the time between them. It isn't safe for concurrent use.
+type Accelerator struct {
+	curve Curve
+	speed float64 // smoothed, in steps per second
+	last  time.Time
+}
+
+// NewAccelerator creates an Accelerator following curve
+func NewAccelerator(curve Curve) *Accelerator {
+	return &Accelerator{curve: curve}
+}
+
+// Apply returns how many tuning steps the knob moving by steps at now should
+// tune. The result has the same sign as steps, and is never smaller.
+func (a *Accelerator) Apply(steps int, now time.Time) int {
+	if steps == 0 {
+		return 0
+	}
+	interval := now.Sub(a.last)
+	a.last = now
+	if interval > idleReset {
+		a.speed = 0
+		return steps
+	}
+	rate := math.Abs(float64(steps)) / max(interval, minInterval).Seconds()
+	a.speed += smoothing * (rate - a.speed)
+	return int(math.Round(float64(steps) * a.curve.Multiplier(a.speed)))
+}
@@ -0,0 +1,129 @@
+package accel
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+// turn applies n events of steps each, interval apart, starting at *now, and
+// returns the result of each
+func turn(a *Accelerator, now *time.Time, n, steps int, interval time.Duration) []int {
+	var results []int
+	for range n {
+		*now = now.Add(interval)
+		results = append(results, a.Apply(steps, *now))
+	}
+	return results
+}
+
+func TestPresets(t *testing.T) {
+	for name, curve := range Presets {
+		t.Run(name, func(t *testing.T) {
+			t.Run("slow turns stay at one step", func(t *testing.T) {
+				a := NewAccelerator(curve)
+				now := start
+				for i, got := range turn(a, &now, 50, 1, idleReset) {
+					if got != 1 {
+						t.Fatalf("event %d tuned %d steps, want 1", i, got)
+					}
+				}
+				for i, got := range turn(a, &now, 50, -1, idleReset) {
+					if got != -1 {
+						t.Fatalf("event %d backwards tuned %d steps, want -1", i, got)
+					}
+				}
+			})
+
+			t.Run("monotonic", func(t *testing.T) {
+				prev := 0.0
+				for speed := 0.0; speed <= 2000; speed += 0.5 {
+					m := curve.Multiplier(speed)
+					if m < 1 || m < prev {
+						t.Fatalf("Multiplier(%v) = %v after %v", speed, m, prev)
+					}
+					prev = m
+				}
+			})
+
+			t.Run("capped", func(t *testing.T) {
+				want := max(curve.Max, 1)
+				if got := curve.Multiplier(1e9); got != want {
+					t.Errorf("Multiplier(1e9) = %v, want %v", got, want)
+				}
+				a := NewAccelerator(curve)
+				now := start
+				results := turn(a, &now, 50, 10, minInterval)
+				if got, want := results[len(results)-1], int(10*want); got != want {
+					t.Errorf("fast turn tuned %d steps, want %d", got, want)
+				}
+				for i, got := range turn(a, &now, 50, 10, time.Millisecond) {
+					if got > int(10*want) {
+						t.Fatalf("burst event %d tuned %d steps, more than %d", i, got, int(10*want))
+					}
+				}
+			})
+
+			t.Run("idle resets the speed", func(t *testing.T) {
+				a := NewAccelerator(curve)
+				now := start
+				turn(a, &now, 50, 10, minInterval)
+				if got := turn(a, &now, 1, 1, idleReset+time.Millisecond); got[0] != 1 {
+					t.Errorf("first step after a pause tuned %d steps, want 1", got[0])
+				}
+				if got := turn(a, &now, 1, 1, idleReset); got[0] != 1 {
+					t.Errorf("slow step after a pause tuned %d steps, want 1", got[0])
+				}
+			})
+		})
+	}
+}
+
+func TestAccelerates(t *testing.T) {
+	a := NewAccelerator(Presets["normal"])
+	now := start
+	// 50 steps per second is 5 times the threshold
+	results := turn(a, &now, 20, 1, 20*time.Millisecond)
+	prev := 0
+	for i, got := range results {
+		if got < prev {
+			t.Fatalf("event %d tuned %d steps after %d at a steady speed", i, got, prev)
+		}
+		prev = got
+	}
+	if got := results[len(results)-1]; got != 5 {
+		t.Errorf("steady turn tuned %d steps, want 5", got)
+	}
+	if got := a.Apply(0, now.Add(time.Millisecond)); got != 0 {
+		t.Errorf("Apply(0) = %d", got)
+	}
+}
+
+func TestConfigCurve(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want Curve
+	}{
+		{"preset", Config{Preset: "gentle"}, Presets["gentle"]},
+		{"preset in capitals", Config{Preset: "Fast"}, Presets["fast"]},
+		{"overrides", Config{Preset: "normal", Threshold: 8, Exponent: 2, Max: 50}, Curve{Threshold: 8, Exponent: 2, Max: 50}},
+		{"partial override", Config{Preset: "normal", Max: 10}, Curve{Threshold: 10, Exponent: 1, Max: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.Curve()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Curve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (&Config{Preset: "ludicrous"}).Curve(); err == nil {
+		t.Error("unknown preset accepted")
+	}
+}
@@ -0,0 +1,192 @@
+// Package api serves an HTTP/JSON interface to the radio, with a WebSocket
+// stream of events, for dashboards and automation scripts.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+type Config struct {
+	Enabled bool   `dialsdesc:"Enable the HTTP/JSON API" dialsflag:"api"`
+	Listen  string `dialsdesc:"Listen address for the HTTP/JSON API" dialsflag:"api-listen"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Listen: "localhost:8073",
+	}
+}
+
+// eventClientBuffer is how many events may queue for a WebSocket client
+// before further events are dropped for it
+const eventClientBuffer = 100
+
+// Server is the HTTP API. It follows the event bus to report state that the
+// shim doesn't expose, and forwards every event to WebSocket clients.
+type Server struct {
+	cfg    *Config
+	shim   radioshim.Shim
+	events chan events.Event
+
+	mu           sync.RWMutex
+	connected    bool
+	lastError    string
+	transmitting bool
+	vox          bool
+	txParams     map[string]string
+	// clients maps each WebSocket client's channel to whether it wants
+	// waterfall rows
+	clients map[chan []byte]bool
+}
+
+// NewServer creates a server
+func NewServer(cfg *Config, shim radioshim.Shim, bus *events.Bus) *Server {
+	return &Server{
+		cfg:      cfg,
+		shim:     shim,
+		events:   bus.Subscribe(eventClientBuffer),
+		txParams: map[string]string{},
+		clients:  make(map[chan []byte]bool),
+	}
+}
+
+// Run serves the API until ctx is cancelled
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.cfg.Listen)
+	if err != nil {
+		return err
+	}
+	log.Println("API listening on", listener.Addr())
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves the API on listener until ctx is cancelled
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	go s.trackEvents(ctx)
+
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	err := server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// eventMessage is the WebSocket encoding of an event
+type eventMessage struct {
+	Type string       `json:"type"`
+	Data events.Event `json:"data"`
+}
+
+// eventType returns the event's type name, e.g. "SlicesUpdated"
+func eventType(event events.Event) string {
+	name := fmt.Sprintf("%T", event)
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+func (s *Server) trackEvents(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.events:
+			s.updateState(event)
+			_, waterfall := event.(events.WaterfallRowReceived)
+			if waterfall && !s.wantWaterfall() {
+				continue
+			}
+			msg, err := json.Marshal(eventMessage{Type: eventType(event), Data: event})
+			if err != nil {
+				log.Println("Failed to encode event:", err)
+				continue
+			}
+			s.broadcast(msg, waterfall)
+		}
+	}
+}
+
+func (s *Server) updateState(event events.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch e := event.(type) {
+	case events.RadioConnected:
+		s.connected = true
+		s.lastError = ""
+	case events.RadioDisconnected:
+		s.connected = false
+		s.lastError = e.Error
+		s.transmitting = false
+	case events.TransmitStateChanged:
+		s.transmitting = e.Transmitting
+	case events.VOXStateChanged:
+		s.vox = e.Enabled
+	case events.TransmitParamsChanged:
+		params := make(map[string]string, len(e.Params))
+		for k, v := range e.Params {
+			params[k] = v
+		}
+		s.txParams = params
+	}
+}
+
+// wantWaterfall reports whether any WebSocket client wants waterfall rows
+func (s *Server) wantWaterfall() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, waterfall := range s.clients {
+		if waterfall {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcast sends msg to the WebSocket clients, leaving out those that
+// didn't ask for waterfall rows if it is one
+func (s *Server) broadcast(msg []byte, waterfall bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for client, wantsWaterfall := range s.clients {
+		if waterfall && !wantsWaterfall {
+			continue
+		}
+		select {
+		case client <- msg:
+		default:
+		}
+	}
+}
+
+func (s *Server) addClient(waterfall bool) chan []byte {
+	ch := make(chan []byte, eventClientBuffer)
+	s.mu.Lock()
+	s.clients[ch] = waterfall
+	s.mu.Unlock()
+	return ch
+}
+
+func (s *Server) removeClient(ch chan []byte) {
+	s.mu.Lock()
+	delete(s.clients, ch)
+	s.mu.Unlock()
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+	"github.com/kc2g-flex-tools/minstrel/radioshim/shimtest"
+)
+
+func newTestServer(listen string) (*Server, *shimtest.Fake, *events.Bus) {
+	fake := shimtest.New(radioshim.SliceMap{
+		"A": {Index: 0, Freq: 14.074, Mode: "USB", Modes: []string{"USB", "LSB", "CW"}, Active: true, TX: true},
+	})
+	bus := events.NewBus()
+	return NewServer(&Config{Listen: listen}, fake, bus), fake, bus
+}
+
+func TestRequestChecks(t *testing.T) {
+	tests := []struct {
+		name        string
+		listen      string // default localhost:8073
+		method      string
+		path        string
+		host        string // default localhost:8073
+		origin      string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCalls   []string
+	}{
+		{
+			name:   "get without content type",
+			method: "GET", path: "/api/slices",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "create slice",
+			method: "POST", path: "/api/slices", contentType: "application/json",
+			wantStatus: http.StatusAccepted,
+			wantCalls:  []string{"CreateSlice"},
+		},
+		{
+			name:   "create slice without content type",
+			method: "POST", path: "/api/slices",
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "create slice as a form",
+			method: "POST", path: "/api/slices", contentType: "application/x-www-form-urlencoded",
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "activate slice as text",
+			method: "POST", path: "/api/slices/0/activate", contentType: "text/plain",
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "set TX slice from another site",
+			method: "POST", path: "/api/slices/0/tx", contentType: "application/json", origin: "https://evil.example",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "set TX slice from the same origin",
+			method: "POST", path: "/api/slices/0/tx", contentType: "application/json", origin: "http://localhost:8073",
+			wantStatus: http.StatusAccepted,
+			wantCalls:  []string{"SetSliceTX 0"},
+		},
+		{
+			name:   "delete without content type",
+			method: "DELETE", path: "/api/slices/0",
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "delete",
+			method: "DELETE", path: "/api/slices/0", contentType: "application/json",
+			wantStatus: http.StatusAccepted,
+			wantCalls:  []string{"RemoveSlice 0"},
+		},
+		{
+			name:   "PTT with charset",
+			method: "POST", path: "/api/transmit/ptt", contentType: "application/json; charset=utf-8", body: `{"enabled": true}`,
+			wantStatus: http.StatusAccepted,
+			wantCalls:  []string{"SetPTT true"},
+		},
+		{
+			name:   "PTT from a null origin",
+			method: "POST", path: "/api/transmit/ptt", contentType: "application/json", origin: "null", body: `{"enabled": true}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "rebound DNS name",
+			method: "GET", path: "/api/slices", host: "evil.example:8073",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "rebound DNS name posting",
+			method: "POST", path: "/api/transmit/ptt", host: "evil.example:8073", origin: "http://evil.example:8073",
+			contentType: "application/json", body: `{"enabled": true}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "loopback address",
+			method: "GET", path: "/api/slices", host: "127.0.0.1:8073",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "IPv6 loopback address",
+			method: "GET", path: "/api/slices", host: "[::1]:8073",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "any host when listening on the network",
+			listen: ":8073",
+			method: "POST", path: "/api/audio", host: "shack-pi.local:8073", origin: "http://shack-pi.local:8073",
+			contentType: "application/json", body: `{"enabled": true}`,
+			wantStatus: http.StatusAccepted,
+			wantCalls:  []string{"ToggleAudio true"},
+		},
+		{
+			name:   "other origin when listening on the network",
+			listen: ":8073",
+			method: "POST", path: "/api/audio", host: "shack-pi.local:8073", origin: "http://evil.example",
+			contentType: "application/json", body: `{"enabled": true}`,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listen := tt.listen
+			if listen == "" {
+				listen = "localhost:8073"
+			}
+			s, fake, _ := newTestServer(listen)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Host = "localhost:8073"
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if calls := fake.Calls(); !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %q, want %q", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWaterfallEventsOptIn(t *testing.T) {
+	s, _, bus := newTestServer("localhost:8073")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.trackEvents(ctx)
+
+	plain := s.addClient(false)
+	waterfall := s.addClient(true)
+
+	bus.Publish(events.WaterfallRowReceived{Bins: []uint16{1, 2, 3}})
+	bus.Publish(events.TransmitStateChanged{Transmitting: true})
+
+	receive := func(ch chan []byte) string {
+		t.Helper()
+		select {
+		case msg := <-ch:
+			var m struct{ Type string }
+			if err := json.Unmarshal(msg, &m); err != nil {
+				t.Fatal(err)
+			}
+			return m.Type
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event received")
+			return ""
+		}
+	}
+	if got := receive(plain); got != "TransmitStateChanged" {
+		t.Errorf("client without waterfall got %s first, want TransmitStateChanged", got)
+	}
+	if got := receive(waterfall); got != "WaterfallRowReceived" {
+		t.Errorf("waterfall client got %s first, want WaterfallRowReceived", got)
+	}
+	if got := receive(waterfall); got != "TransmitStateChanged" {
+		t.Errorf("waterfall client got %s second, want TransmitStateChanged", got)
+	}
+}
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+// Handler returns the API's HTTP handler
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/connection", s.getConnection)
+	mux.HandleFunc("GET /api/slices", s.getSlices)
+	mux.HandleFunc("POST /api/slices", s.createSlice)
+	mux.HandleFunc("DELETE /api/slices/{index}", s.removeSlice)
+	mux.HandleFunc("POST /api/slices/{index}/tune", s.tuneSlice)
+	mux.HandleFunc("POST /api/slices/{index}/mode", s.setSliceMode)
+	mux.HandleFunc("POST /api/slices/{index}/filter", s.setSliceFilter)
+	mux.HandleFunc("POST /api/slices/{index}/volume", s.setSliceVolume)
+	mux.HandleFunc("POST /api/slices/{index}/activate", s.activateSlice)
+	mux.HandleFunc("POST /api/slices/{index}/tx", s.setSliceTX)
+	mux.HandleFunc("GET /api/transmit", s.getTransmit)
+	mux.HandleFunc("POST /api/transmit/ptt", s.setPTT)
+	mux.HandleFunc("POST /api/transmit/vox", s.setVOX)
+	mux.HandleFunc("POST /api/transmit/params", s.setTransmitParam)
+	mux.HandleFunc("POST /api/audio", s.setAudio)
+	mux.HandleFunc("GET /api/events", s.streamEvents)
+	return s.checkRequest(mux)
+}
+
+// checkRequest protects the API from web pages. Requests that change
+// anything must come from the same origin and have a JSON content type, which
+// means browsers must preflight cross-origin requests, which we never allow.
+// When listening on loopback, the Host header must name loopback too, so that
+// a page can't reach the API through a DNS name rebound to 127.0.0.1.
+func (s *Server) checkRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.loopbackOnly() && !isLoopbackHost(r.Host) {
+			writeError(w, http.StatusForbidden, "Host must be a loopback address")
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if !sameOrigin(r) {
+				writeError(w, http.StatusForbidden, "cross-origin request")
+				return
+			}
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopbackOnly reports whether the API is listening only on loopback
+func (s *Server) loopbackOnly() bool {
+	host, _, err := net.SplitHostPort(s.cfg.Listen)
+	return err == nil && isLoopbackHost(host)
+}
+
+// isLoopbackHost reports whether host, which may include a port, is
+// localhost or a loopback address
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// readJSON decodes a request body; checkRequest has checked its content type
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+// slice looks up the slice named by the {index} path parameter
+func (s *Server) slice(w http.ResponseWriter, r *http.Request) (*radioshim.SliceData, bool) {
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid slice index")
+		return nil, false
+	}
+	for _, slice := range s.shim.GetSlices() {
+		if slice.Index == index {
+			return slice, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "no such slice")
+	return nil, false
+}
+
+func (s *Server) getConnection(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	writeJSON(w, map[string]any{
+		"connected": s.connected,
+		"error":     s.lastError,
+	})
+}
+
+func (s *Server) getSlices(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.shim.GetSlices())
+}
+
+func (s *Server) createSlice(w http.ResponseWriter, r *http.Request) {
+	s.shim.CreateSlice()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) removeSlice(w http.ResponseWriter, r *http.Request) {
+	if slice, ok := s.slice(w, r); ok {
+		s.shim.RemoveSlice(slice.Index)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (s *Server) tuneSlice(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Freq float64 `json:"freq"` // MHz
+		Snap bool    `json:"snap"`
+	}
+	slice, ok := s.slice(w, r)
+	if !ok || !readJSON(w, r, &req) {
+		return
+	}
+	if req.Freq <= 0 {
+		writeError(w, http.StatusBadRequest, "freq must be positive")
+		return
+	}
+	s.shim.TuneSlice(slice, req.Freq, req.Snap)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) setSliceMode(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Mode string `json:"mode"`
+	}
+	slice, ok := s.slice(w, r)
+	if !ok || !readJSON(w, r, &req) {
+		return
+	}
+	valid := false
+	for _, mode := range slice.Modes {
+		valid = valid || mode == req.Mode
+	}
+	if !valid {
+		writeError(w, http.StatusBadRequest, "mode not supported by slice")
+		return
+	}
+	s.shim.SetSliceMode(slice.Index, req.Mode)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) setSliceFilter(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Low  int `json:"low"`
+		High int `json:"high"`
+	}
+	slice, ok := s.slice(w, r)
+	if !ok || !readJSON(w, r, &req) {
+		return
+	}
+	if req.Low >= req.High {
+		writeError(w, http.StatusBadRequest, "low must be below high")
+		return
+	}
+	s.shim.SetSliceFilter(slice.Index, req.Low, req.High)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) setSliceVolume(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Volume int `json:"volume"`
+	}
+	slice, ok := s.slice(w, r)
+	if !ok || !readJSON(w, r, &req) {
+		return
+	}
+	if req.Volume < 0 || req.Volume > 100 {
+		writeError(w, http.StatusBadRequest, "volume must be 0-100")
+		return
+	}
+	s.shim.SetSliceVolume(slice.Index, req.Volume)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) activateSlice(w http.ResponseWriter, r *http.Request) {
+	if slice, ok := s.slice(w, r); ok {
+		s.shim.ActivateSlice(slice.Index)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (s *Server) setSliceTX(w http.ResponseWriter, r *http.Request) {
+	if slice, ok := s.slice(w, r); ok {
+		s.shim.SetSliceTX(slice.Index)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (s *Server) getTransmit(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	writeJSON(w, map[string]any{
+		"transmitting": s.transmitting,
+		"vox":          s.vox,
+		"params":       s.txParams,
+	})
+}
+
+type enableRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (s *Server) setPTT(w http.ResponseWriter, r *http.Request) {
+	var req enableRequest
+	if readJSON(w, r, &req) {
+		s.shim.SetPTT(req.Enabled)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (s *Server) setVOX(w http.ResponseWriter, r *http.Request) {
+	var req enableRequest
+	if readJSON(w, r, &req) {
+		s.shim.SetVOX(req.Enabled)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (s *Server) setAudio(w http.ResponseWriter, r *http.Request) {
+	var req enableRequest
+	if readJSON(w, r, &req) {
+		s.shim.ToggleAudio(req.Enabled)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// setTransmitParam sets a numeric transmit parameter such as rfpower
+func (s *Server) setTransmitParam(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Key   string `json:"key"`
+		Value int    `json:"value"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Key == "" {
+		writeError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+	s.shim.SetTransmitParam(req.Key, req.Value)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sameOrigin rejects requests from web pages on other sites. Non-browser
+// clients don't send an Origin header.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// streamEvents upgrades to a WebSocket and sends every bus event to it.
+// Waterfall rows arrive many times a second, so they're only sent with
+// ?waterfall=1.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	if !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, "cross-origin request")
+		return
+	}
+	conn, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	waterfall, _ := strconv.ParseBool(r.URL.Query().Get("waterfall"))
+	ch := s.addClient(waterfall)
+	defer s.removeClient(ch)
+
+	closed := make(chan struct{})
+	go func() {
+		conn.readLoop()
+		close(closed)
+	}()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-closed:
+			return
+		case msg := <-ch:
+			if err := conn.WriteText(msg); err != nil {
+				return
+			}
+		}
+	}
+}
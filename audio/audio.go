@@ -6,25 +6,17 @@ import (
 	"log"
 	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/hb9fxq/flexlib-go/vita"
-	"github.com/jfreymuth/pulse"
-	"github.com/jfreymuth/pulse/proto"
-	"github.com/kc2g-flex-tools/flexclient"
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
 	"github.com/kc2g-flex-tools/minstrel/opus"
 	"github.com/kc2g-flex-tools/minstrel/types"
+	"github.com/kc2g-flex-tools/minstrel/vita"
 	opuslib "gopkg.in/hraban/opus.v2"
 )
 
-// VitaOpusPacket represents a VITA packet for Opus audio transmission
-type VitaOpusPacket struct {
-	header   vita.VitaHeader
-	streamID types.StreamID
-	classID  vita.VitaClassID
-	payload  []byte
-}
-
-// TXAudioWriter implements pulse.Writer for TX audio processing
+// TXAudioWriter receives recorded frames for TX audio processing
 type TXAudioWriter struct {
 	audio *Audio
 }
@@ -33,17 +25,18 @@ func (w *TXAudioWriter) Write(data []byte) (int, error) {
 	return w.audio.processTXAudio(data)
 }
 
-func (w *TXAudioWriter) Format() byte {
-	return proto.FormatFloat32LE
+// PacketSender sends VITA-49 packets to the radio
+type PacketSender interface {
+	SendUDP(packet []byte) error
 }
 
 type Audio struct {
-	Context  *pulse.Client
+	backend  Backend
 	Opus     *opuslib.Decoder
-	OpusEnc  *opus.Encoder
-	player   *pulse.PlaybackStream
-	recorder *pulse.RecordStream
+	player   Stream
+	recorder Stream
 	s16Buf   [512]int16
+	rxBuf    [512]float32
 	f32buf   [4]byte
 	cbuf     *CircularBuf[[4]byte]
 	cbufSize int
@@ -52,11 +45,29 @@ type Audio struct {
 	// TX audio fields
 	txMutex    sync.Mutex
 	txRunning  bool
-	txClient   *flexclient.FlexClient
+	txSender   PacketSender
 	txStreamID *types.StreamID
-	txPacket   *VitaOpusPacket
-	txSeq      uint16
+	txSeq      uint8
 	txWriter   *TXAudioWriter
+	// txSampleCount is the number of samples per channel sent this transmission,
+	// used as the VITA fractional timestamp
+	txSampleCount uint64
+
+	// TX Opus encoder, replaced when the application changes
+	opusMutex    sync.Mutex
+	OpusEnc      *opus.Encoder
+	opusSettings audioshim.OpusSettings
+
+	// TX audio processing chain and its scratch buffers
+	txProcessor *TXProcessor
+	txSamples   []float32
+	txBytes     []byte
+
+	// Level metering and mic test loopback
+	rxMeter           LevelMeter
+	micTest           atomic.Bool
+	playing           bool
+	testStartedPlayer bool
 
 	// Device selection
 	sinkDevice   string
@@ -71,33 +82,32 @@ type Audio struct {
 	activeReaders map[*PlaybackReader]bool
 }
 
-func NewAudio() *Audio {
+func NewAudio(cfg *Config) *Audio {
 	audio := &Audio{
 		cbuf:          NewCircularBuf[[4]byte](2880),
 		cbufSize:      2880, // max cbuf latency: 240ms
 		wakeup:        make(chan struct{}),
 		activeReaders: make(map[*PlaybackReader]bool),
 	}
-	pc, err := pulse.NewClient(
-		pulse.ClientApplicationName("Minstrel"),
-	)
-	if err != nil {
-		panic(err)
+
+	// Fall back to the null backend rather than refusing to start
+	backend, err := NewBackend(cfg)
+	if err == nil {
+		audio.backend = backend
+		audio.player, err = audio.newPlayer("")
+		if err != nil {
+			backend.Close()
+		}
 	}
-	audio.Context = pc
-	reader := &PlaybackReader{audio: audio}
-	audio.readerMutex.Lock()
-	audio.activeReaders[reader] = true
-	audio.readerMutex.Unlock()
-	audio.player, err = pc.NewPlayback(
-		pulse.NewReader(reader, proto.FormatFloat32LE),
-		pulse.PlaybackChannels(proto.ChannelMap{proto.ChannelMono}),
-		pulse.PlaybackLatency(50.0/1000),
-		pulse.PlaybackSampleRate(24000),
-	)
 	if err != nil {
-		panic(err)
+		log.Printf("Audio backend %s failed: %v, using null audio backend", cfg.Backend, err)
+		audio.backend, _ = newFileBackend(&Config{})
+		audio.player, err = audio.newPlayer("")
+		if err != nil {
+			panic(err)
+		}
 	}
+	log.Println("Using audio backend", audio.backend.Name())
 
 	opusDecoder, err := opuslib.NewDecoder(24000, 1)
 	if err != nil {
@@ -106,29 +116,22 @@ func NewAudio() *Audio {
 	audio.Opus = opusDecoder
 
 	// Create Opus encoder for TX
-	opusEncoder, err := opus.NewEncoder(24000, 2, opus.ApplicationAudio)
+	audio.opusSettings = normalizeOpusSettings(cfg.OpusSettings())
+	opusEncoder, err := newOpusEncoder(audio.opusSettings)
 	if err != nil {
 		panic(err)
 	}
-	err = opusEncoder.SetBitrate(70000)
-	if err != nil {
-		log.Println("Failed to set opus bitrate:", err)
-	}
-	err = opusEncoder.SetComplexity(1)
-	if err != nil {
-		log.Println("Failed to set opus complexity:", err)
-	}
-
 	audio.OpusEnc = opusEncoder
 
 	// Create TX audio writer
 	audio.txWriter = &TXAudioWriter{audio: audio}
+	audio.txProcessor = NewTXProcessor(24000, 2)
 
 	return audio
 }
 
 // StartTX starts transmit audio recording and encoding
-func (a *Audio) StartTX(client *flexclient.FlexClient, streamID *types.StreamID) {
+func (a *Audio) StartTX(sender PacketSender, streamID *types.StreamID) {
 	a.txMutex.Lock()
 	defer a.txMutex.Unlock()
 
@@ -136,47 +139,51 @@ func (a *Audio) StartTX(client *flexclient.FlexClient, streamID *types.StreamID)
 		return
 	}
 
-	a.txClient = client
+	a.txSender = sender
 	a.txStreamID = streamID
 	a.txRunning = true
 	a.txSeq = 0
+	a.txSampleCount = 0
+
+	if err := a.startRecorder(); err != nil {
+		log.Println("Failed to create recorder:", err)
+		a.txRunning = false
+		return
+	}
+	log.Println("TX audio started")
+}
+
+// startRecorder starts recording from the selected source if it isn't
+// already running. The caller must hold txMutex.
+func (a *Audio) startRecorder() error {
+	if a.recorder != nil {
+		return nil
+	}
 
 	// Get selected source device
 	a.deviceMutex.RLock()
 	sourceDevice := a.sourceDevice
 	a.deviceMutex.RUnlock()
 
-	// Create PulseAudio recorder with optional device selection
-	var err error
-	opts := []pulse.RecordOption{
-		pulse.RecordStereo,
-		pulse.RecordSampleRate(24000),
-		pulse.RecordRawOption(func(rs *proto.CreateRecordStream) {
-			// Ask pulse to give us exactly 10ms frames (240 samples at 24kHz) * 2ch * 4bytes/sample = 1920 bytes
-			// Opus encoding will fail if the frame size isn't exactly a supported size.
-			rs.BufferFragSize = 3840
-		}),
-	}
-
-	// Add source device if specified
-	if sourceDevice != "" {
-		source, err := a.Context.SourceByID(sourceDevice)
-		if err != nil {
-			log.Printf("Failed to get source %s: %v, using default", sourceDevice, err)
-		} else {
-			opts = append(opts, pulse.RecordSource(source))
-		}
-	}
-
-	a.recorder, err = a.Context.NewRecord(a.txWriter, opts...)
+	recorder, err := a.backend.NewRecord(sourceDevice, a.txWriter)
 	if err != nil {
-		log.Println("Failed to create recorder:", err)
-		a.txRunning = false
-		return
+		return err
 	}
 
+	a.recorder = recorder
 	a.recorder.Start()
-	log.Println("TX audio started")
+	return nil
+}
+
+// stopRecorder stops recording and clears the mic meters. The caller must
+// hold txMutex.
+func (a *Audio) stopRecorder() {
+	if a.recorder != nil {
+		a.recorder.Stop()
+		a.recorder.Close()
+		a.recorder = nil
+	}
+	a.txProcessor.ResetLevels()
 }
 
 // StopTX stops transmit audio recording
@@ -189,97 +196,150 @@ func (a *Audio) StopTX() {
 	}
 
 	a.txRunning = false
-	if a.recorder != nil {
-		a.recorder.Stop()
-		a.recorder.Close()
-		a.recorder = nil
+	// Keep recording if the mic test is still using it
+	if !a.micTest.Load() {
+		a.stopRecorder()
 	}
 	log.Println("TX audio stopped")
 }
 
+// SetMicTest enables or disables the mic test, which records the microphone
+// and plays it back through the RX audio output instead of sending it to the
+// radio. RX audio from the radio is muted while the test is running.
+func (a *Audio) SetMicTest(enabled bool) {
+	a.txMutex.Lock()
+	if a.micTest.Load() == enabled {
+		a.txMutex.Unlock()
+		return
+	}
+	a.micTest.Store(enabled)
+	a.cbuf.Clear()
+	if enabled {
+		if err := a.startRecorder(); err != nil {
+			log.Println("Failed to create recorder for mic test:", err)
+			a.micTest.Store(false)
+			a.txMutex.Unlock()
+			return
+		}
+	} else if !a.txRunning {
+		a.stopRecorder()
+	}
+	a.txMutex.Unlock()
+
+	// Make the loopback audible even if RX audio is switched off
+	a.playerMutex.Lock()
+	defer a.playerMutex.Unlock()
+	if a.player == nil {
+		return
+	}
+	if enabled && !a.playing {
+		a.player.Start()
+		a.playing = true
+		a.testStartedPlayer = true
+	} else if !enabled && a.testStartedPlayer {
+		a.player.Stop()
+		a.playing = false
+		a.testStartedPlayer = false
+	}
+}
+
+// MicTest reports whether the mic test is running
+func (a *Audio) MicTest() bool {
+	return a.micTest.Load()
+}
+
 // processTXAudio processes incoming audio data for transmission
 func (a *Audio) processTXAudio(data []byte) (int, error) {
-	if !a.txRunning || a.txClient == nil || a.txStreamID == nil || !a.txStreamID.IsValid() {
+	testing := a.micTest.Load()
+	sending := a.txRunning && a.txSender != nil && a.txStreamID != nil && a.txStreamID.IsValid()
+	if !testing && !sending {
+		return len(data), nil
+	}
+
+	// Run the client-side processing chain, which also meters the mic
+	processed := a.processTXSamples(data)
+
+	if testing {
+		a.loopbackMic(a.txSamples[:len(processed)/4])
 		return len(data), nil
 	}
 
 	// Encode with Opus
-	opusData, err := a.OpusEnc.EncodeFloatRaw(data)
+	a.opusMutex.Lock()
+	opusData, err := a.OpusEnc.EncodeFloatRaw(processed)
+	a.opusMutex.Unlock()
 	if err != nil {
 		log.Println("Opus encoding error:", err)
 		return len(data), nil
 	}
 
 	// Send via VITA packet
-	a.sendVitaOpusPacket(opusData)
+	a.sendVitaOpusPacket(opusData, len(processed)/(4*recordChannels))
 
 	return len(data), nil
 }
 
-// sendVitaOpusPacket sends Opus data as a VITA packet
-func (a *Audio) sendVitaOpusPacket(opusData []byte) {
-	if a.txPacket == nil {
-		a.txPacket = &VitaOpusPacket{
-			header: vita.VitaHeader{
-				Pkt_type:     vita.ExtDataWithStream,
-				C:            true,
-				T:            false,
-				Tsi:          vita.Other,
-				Tsf:          vita.SampleCount,
-				Packet_count: 0,
-			},
-			streamID: *a.txStreamID,
-			classID: vita.VitaClassID{
-				OUI:                  0x001C2D,
-				InformationClassCode: 0x534C,
-				PacketClassCode:      0x8005,
-			},
-		}
+// processTXSamples runs the TX processing chain on raw float32LE data and
+// returns the processed bytes. The input buffer belongs to the recorder, so
+// the result is written to a scratch buffer instead.
+func (a *Audio) processTXSamples(data []byte) []byte {
+	n := len(data) / 4
+	if cap(a.txSamples) < n {
+		a.txSamples = make([]float32, n)
+		a.txBytes = make([]byte, 4*n)
+	}
+	samples := a.txSamples[:n]
+	for i := range samples {
+		samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
 	}
 
-	a.txPacket.payload = opusData
-	a.txPacket.header.Packet_size = uint16(math.Ceil(float64(len(opusData))/4.0) + 7.0) // 7*4=28 bytes VITA overhead
-	a.txPacket.header.Packet_count = a.txSeq
-	a.txSeq = (a.txSeq + 1) % 16
+	a.txProcessor.Process(samples)
 
-	// Convert to bytes and send
-	packetBytes := a.txPacket.ToBytes()
-	err := a.txClient.SendUdp(packetBytes)
-	if err != nil {
-		log.Println("Failed to send UDP packet:", err)
+	out := a.txBytes[:4*n]
+	for i, s := range samples {
+		binary.LittleEndian.PutUint32(out[4*i:], math.Float32bits(s))
 	}
+	return out
 }
 
-// ToBytes converts VitaOpusPacket to byte array for transmission
-func (p *VitaOpusPacket) ToBytes() []byte {
-	buf := make([]byte, 28+len(p.payload)) // 28 bytes header + payload
-
-	// VITA header (simplified)
-	headerWord := uint32(p.header.Pkt_type) << 28
-	if p.header.C {
-		headerWord |= 1 << 27
+// loopbackMic queues processed stereo mic samples for local playback
+func (a *Audio) loopbackMic(samples []float32) {
+	var frame [4]byte
+	for i := 0; i+1 < len(samples); i += 2 {
+		if a.cbuf.Size() > a.cbufSize-4 {
+			break
+		}
+		mono := (samples[i] + samples[i+1]) / 2
+		binary.LittleEndian.PutUint32(frame[:], math.Float32bits(mono))
+		a.cbuf.Insert(frame)
 	}
-	if p.header.T {
-		headerWord |= 1 << 26
+	select {
+	case a.wakeup <- struct{}{}:
+	default:
 	}
-	headerWord |= uint32(p.header.Tsi) << 22
-	headerWord |= uint32(p.header.Tsf) << 20
-	headerWord |= uint32(p.header.Packet_count) << 16
-	headerWord |= uint32(p.header.Packet_size)
-
-	binary.BigEndian.PutUint32(buf[0:4], headerWord)
-	binary.BigEndian.PutUint32(buf[4:8], uint32(p.streamID))
-	binary.BigEndian.PutUint32(buf[8:12], p.classID.OUI&0x00FFFFFF)
-	binary.BigEndian.PutUint32(buf[12:16], uint32(p.classID.InformationClassCode)<<16|uint32(p.classID.PacketClassCode))
+}
 
-	// Timestamps (set to 0 for now)
-	binary.BigEndian.PutUint32(buf[16:20], 0) // integer timestamp
-	binary.BigEndian.PutUint64(buf[20:28], 0) // fractional timestamp
+// sendVitaOpusPacket sends one Opus frame of frameSamples samples as a VITA packet
+func (a *Audio) sendVitaOpusPacket(opusData []byte, frameSamples int) {
+	frame, err := opus.PadToWords(opusData)
+	if err != nil {
+		log.Println("Failed to pad opus packet:", err)
+		return
+	}
 
-	// Payload
-	copy(buf[28:], p.payload)
+	pkt := vita.NewOpusPacket(uint32(*a.txStreamID), a.txSeq, uint32(time.Now().Unix()), a.txSampleCount, frame)
+	a.txSeq = (a.txSeq + 1) % 16
+	a.txSampleCount += uint64(frameSamples)
 
-	return buf
+	packetBytes, err := pkt.Encode()
+	if err != nil {
+		log.Println("Failed to encode VITA packet:", err)
+		return
+	}
+	err = a.txSender.SendUDP(packetBytes)
+	if err != nil {
+		log.Println("Failed to send UDP packet:", err)
+	}
 }
 
 func (a *Audio) Decode(data []byte) {
@@ -287,12 +347,21 @@ func (a *Audio) Decode(data []byte) {
 	if err != nil {
 		log.Println(err)
 	}
-	for i := range n {
+	samples := a.rxBuf[:max(n, 0)]
+	for i := range samples {
+		samples[i] = float32(a.s16Buf[i]) / 32768
+	}
+	a.rxMeter.Update(samples)
+
+	// The mic test owns the playback buffer while it runs
+	if a.micTest.Load() {
+		return
+	}
+	for _, f32 := range samples {
 		if a.cbuf.Size() > a.cbufSize-4 {
 			log.Println("audio cbuf overflow")
 			return
 		}
-		f32 := float32(a.s16Buf[i]) / 32768
 		binary.Append(a.f32buf[:0:4], binary.LittleEndian, f32)
 		a.cbuf.Insert(a.f32buf)
 	}
@@ -304,7 +373,7 @@ func (a *Audio) Decode(data []byte) {
 	}
 }
 
-// PlaybackReader reads audio data from the circular buffer for PulseAudio playback
+// PlaybackReader reads audio data from the circular buffer for playback
 type PlaybackReader struct {
 	audio  *Audio
 	closed bool
@@ -342,10 +411,6 @@ func (r *PlaybackReader) Read(dest []byte) (n int, err error) {
 	return
 }
 
-func (r *PlaybackReader) Format() byte {
-	return proto.FormatFloat32LE
-}
-
 func (r *PlaybackReader) Close() {
 	r.mu.Lock()
 	r.closed = true
@@ -386,42 +451,46 @@ func (a *Audio) Start() {
 		a.player.Stop()
 		a.player.Close()
 
-		// Get the sink by ID
-		sink, err := a.Context.SinkByID(sinkDevice)
-		if err != nil {
-			log.Printf("Failed to get sink %s: %v, using default", sinkDevice, err)
-			sink = nil
-		}
-
-		// Recreate player with selected sink
-		opts := []pulse.PlaybackOption{
-			pulse.PlaybackChannels(proto.ChannelMap{proto.ChannelMono}),
-			pulse.PlaybackLatency(50.0 / 1000),
-			pulse.PlaybackSampleRate(24000),
-		}
-		if sink != nil {
-			opts = append(opts, pulse.PlaybackSink(sink))
-		}
-
-		reader := &PlaybackReader{audio: a}
-		a.readerMutex.Lock()
-		a.activeReaders[reader] = true
-		a.readerMutex.Unlock()
-		a.player, err = a.Context.NewPlayback(pulse.NewReader(reader, proto.FormatFloat32LE), opts...)
+		player, err := a.newPlayer(sinkDevice)
 		if err != nil {
 			log.Printf("Failed to create playback stream: %v", err)
+			a.player = nil
 			return
 		}
+		a.player = player
+	}
+	if a.player == nil {
+		return
 	}
 
 	a.player.Start()
+	a.playing = true
+	a.testStartedPlayer = false
+}
+
+// newPlayer creates a playback stream reading from the RX audio buffer
+func (a *Audio) newPlayer(device string) (Stream, error) {
+	reader := &PlaybackReader{audio: a}
+	player, err := a.backend.NewPlayback(device, reader)
+	if err != nil {
+		return nil, err
+	}
+	a.readerMutex.Lock()
+	a.activeReaders[reader] = true
+	a.readerMutex.Unlock()
+	return player, nil
 }
 
 func (a *Audio) Pause() {
 	a.playerMutex.Lock()
 	defer a.playerMutex.Unlock()
 
-	a.player.Stop()
+	if a.player != nil {
+		a.player.Stop()
+	}
+	a.playing = false
+	a.testStartedPlayer = false
+	a.rxMeter.Reset()
 }
 
 // SetSinkDevice sets the output device for RX audio
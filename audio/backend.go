@@ -0,0 +1,113 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+)
+
+// Config selects the audio backend and configures the TX Opus encoder
+type Config struct {
+	Backend    string `dialsdesc:"Audio backend: auto, pulse, pipewire, alsa, file or null" dialsflag:"audio-backend"`
+	InputFile  string `dialsdesc:"WAV file to read TX audio from (file backend)" dialsflag:"audio-input"`
+	OutputFile string `dialsdesc:"WAV file to write RX audio to (file backend)" dialsflag:"audio-output"`
+
+	OpusBitrate     int    `dialsdesc:"TX Opus bitrate in bits per second" dialsflag:"opus-bitrate"`
+	OpusComplexity  int    `dialsdesc:"TX Opus encoder complexity (0-10)" dialsflag:"opus-complexity"`
+	OpusApplication string `dialsdesc:"TX Opus application: audio, voip or lowdelay" dialsflag:"opus-application"`
+	OpusFEC         bool   `dialsdesc:"Enable TX Opus in-band forward error correction" dialsflag:"opus-fec"`
+	OpusPacketLoss  int    `dialsdesc:"Expected packet loss percentage for TX Opus FEC" dialsflag:"opus-packet-loss"`
+	OpusDTX         bool   `dialsdesc:"Enable TX Opus discontinuous transmission" dialsflag:"opus-dtx"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Backend:         "auto",
+		OpusBitrate:     70000,
+		OpusComplexity:  1,
+		OpusApplication: "audio",
+	}
+}
+
+// OpusSettings returns the encoder settings from the config
+func (c *Config) OpusSettings() audioshim.OpusSettings {
+	return audioshim.OpusSettings{
+		Bitrate:        c.OpusBitrate,
+		Complexity:     c.OpusComplexity,
+		Application:    c.OpusApplication,
+		FEC:            c.OpusFEC,
+		PacketLossPerc: c.OpusPacketLoss,
+		DTX:            c.OpusDTX,
+	}
+}
+
+// All backends use the same stream format: 24 kHz float32LE, mono for
+// playback and stereo for recording.
+const (
+	sampleRate       = 24000
+	playbackChannels = 1
+	recordChannels   = 2
+	// recordFrameBytes is 20ms of stereo float32 audio. Opus encoding fails
+	// unless it is fed exactly a supported frame size.
+	recordFrameBytes = 480 * recordChannels * 4
+)
+
+// Backend is an audio system that can play RX audio and record TX audio.
+// Device IDs are backend-specific; an empty ID selects the system default.
+type Backend interface {
+	Name() string
+	ListSinks() ([]audioshim.AudioDevice, error)
+	ListSources() ([]audioshim.AudioDevice, error)
+	DefaultSink() (string, error)
+	DefaultSource() (string, error)
+	// NewPlayback creates a mono playback stream that pulls float32LE samples from r
+	NewPlayback(device string, r io.Reader) (Stream, error)
+	// NewRecord creates a stereo record stream that writes float32LE frames of
+	// recordFrameBytes to w
+	NewRecord(device string, w io.Writer) (Stream, error)
+	Close()
+}
+
+// Stream is a playback or record stream created by a Backend
+type Stream interface {
+	Start()
+	Stop()
+	Close()
+	// SetDevice moves the stream to another device, restarting it if needed
+	SetDevice(device string) error
+}
+
+// NewBackend creates the backend named in cfg. "auto" tries the sound
+// servers first, then ALSA, and finally falls back to the null backend so that
+// Minstrel can run on machines without audio.
+func NewBackend(cfg *Config) (Backend, error) {
+	if cfg.Backend != "" && cfg.Backend != "auto" {
+		return newNamedBackend(cfg.Backend, cfg)
+	}
+	for _, name := range []string{"pulse", "pipewire", "alsa"} {
+		backend, err := newNamedBackend(name, cfg)
+		if err == nil {
+			return backend, nil
+		}
+		log.Printf("Audio backend %s unavailable: %v", name, err)
+	}
+	log.Println("No audio system available, using null audio backend")
+	return newFileBackend(cfg)
+}
+
+func newNamedBackend(name string, cfg *Config) (Backend, error) {
+	switch name {
+	case "pulse":
+		return newPulseBackend()
+	case "pipewire":
+		return newPipeWireBackend()
+	case "alsa":
+		return newALSABackend()
+	case "file", "null":
+		return newFileBackend(cfg)
+	default:
+		return nil, fmt.Errorf("unknown audio backend %q", name)
+	}
+}
@@ -0,0 +1,304 @@
+package audio
+
+/*
+#cgo pkg-config: alsa
+#include <alsa/asoundlib.h>
+#include <stdlib.h>
+
+// Open a PCM as interleaved float32 with 50ms latency, resampling if needed
+static int minstrel_pcm_open(snd_pcm_t **pcm, const char *name, int capture, unsigned int rate, unsigned int channels) {
+	int err = snd_pcm_open(pcm, name, capture ? SND_PCM_STREAM_CAPTURE : SND_PCM_STREAM_PLAYBACK, 0);
+	if (err < 0) {
+		return err;
+	}
+	err = snd_pcm_set_params(*pcm, SND_PCM_FORMAT_FLOAT_LE, SND_PCM_ACCESS_RW_INTERLEAVED, channels, rate, 1, 50000);
+	if (err < 0) {
+		snd_pcm_close(*pcm);
+		*pcm = NULL;
+	}
+	return err;
+}
+
+static int minstrel_hint_valid(void **hints, int i) {
+	return hints[i] != NULL;
+}
+
+static char *minstrel_hint_get(void **hints, int i, const char *id) {
+	return snd_device_name_get_hint(hints[i], id);
+}
+*/
+import "C"
+import (
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+)
+
+// alsaBackend uses ALSA directly, for systems without a sound server
+type alsaBackend struct{}
+
+func newALSABackend() (Backend, error) {
+	// Make sure the default devices can actually be opened
+	for _, capture := range []bool{false, true} {
+		pcm, err := alsaOpen("default", capture)
+		if err != nil {
+			return nil, err
+		}
+		C.snd_pcm_close(pcm)
+	}
+	return &alsaBackend{}, nil
+}
+
+func alsaError(code C.int) error {
+	return errors.New("alsa: " + C.GoString(C.snd_strerror(code)))
+}
+
+func alsaOpen(device string, capture bool) (*C.snd_pcm_t, error) {
+	name := C.CString(device)
+	defer C.free(unsafe.Pointer(name))
+
+	channels, captureFlag := playbackChannels, 0
+	if capture {
+		channels, captureFlag = recordChannels, 1
+	}
+	var pcm *C.snd_pcm_t
+	if code := C.minstrel_pcm_open(&pcm, name, C.int(captureFlag), sampleRate, C.uint(channels)); code < 0 {
+		return nil, alsaError(code)
+	}
+	return pcm, nil
+}
+
+// alsaDevices lists PCM devices usable in the given direction ("Output" or "Input")
+func alsaDevices(direction string) ([]audioshim.AudioDevice, error) {
+	iface := C.CString("pcm")
+	defer C.free(unsafe.Pointer(iface))
+	nameID := C.CString("NAME")
+	defer C.free(unsafe.Pointer(nameID))
+	descID := C.CString("DESC")
+	defer C.free(unsafe.Pointer(descID))
+	ioID := C.CString("IOID")
+	defer C.free(unsafe.Pointer(ioID))
+
+	var hints *unsafe.Pointer
+	if code := C.snd_device_name_hint(-1, iface, &hints); code < 0 {
+		return nil, alsaError(code)
+	}
+	defer C.snd_device_name_free_hint(hints)
+
+	getHint := func(i int, id *C.char) string {
+		value := C.minstrel_hint_get(hints, C.int(i), id)
+		if value == nil {
+			return ""
+		}
+		defer C.free(unsafe.Pointer(value))
+		return C.GoString(value)
+	}
+
+	devices := []audioshim.AudioDevice{}
+	for i := 0; C.minstrel_hint_valid(hints, C.int(i)) != 0; i++ {
+		name := getHint(i, nameID)
+		// A missing IOID means the device supports both directions
+		if ioid := getHint(i, ioID); name == "" || name == "null" || (ioid != "" && ioid != direction) {
+			continue
+		}
+		desc := strings.ReplaceAll(getHint(i, descID), "\n", " - ")
+		if desc == "" {
+			desc = name
+		}
+		devices = append(devices, audioshim.AudioDevice{ID: name, Name: desc})
+	}
+	return devices, nil
+}
+
+func (b *alsaBackend) Name() string {
+	return "alsa"
+}
+
+func (b *alsaBackend) ListSinks() ([]audioshim.AudioDevice, error) {
+	return alsaDevices("Output")
+}
+
+func (b *alsaBackend) ListSources() ([]audioshim.AudioDevice, error) {
+	return alsaDevices("Input")
+}
+
+func (b *alsaBackend) DefaultSink() (string, error) {
+	return "default", nil
+}
+
+func (b *alsaBackend) DefaultSource() (string, error) {
+	return "default", nil
+}
+
+func (b *alsaBackend) NewPlayback(device string, r io.Reader) (Stream, error) {
+	s := &alsaStream{device: device, chunks: make(chan []byte), closed: make(chan struct{})}
+	go s.read(r)
+	return s, nil
+}
+
+func (b *alsaBackend) NewRecord(device string, w io.Writer) (Stream, error) {
+	return &alsaStream{capture: true, device: device, w: w, closed: make(chan struct{})}, nil
+}
+
+func (b *alsaBackend) Close() {}
+
+// alsaStream opens the PCM on Start and runs a goroutine that owns it until
+// Stop. Playback reads RX audio in a goroutine of its own, since reading
+// blocks until there is some, so that Stop doesn't have to wait for it.
+type alsaStream struct {
+	capture   bool
+	chunks    chan []byte
+	w         io.Writer
+	mu        sync.Mutex
+	device    string
+	stop      chan struct{}
+	done      chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *alsaStream) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+
+	device := s.device
+	if device == "" {
+		device = "default"
+	}
+	pcm, err := alsaOpen(device, s.capture)
+	if err != nil {
+		log.Printf("Failed to open ALSA device %s: %v", device, err)
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	if s.capture {
+		go s.runCapture(pcm, s.stop, s.done)
+	} else {
+		go s.runPlayback(pcm, s.stop, s.done)
+	}
+}
+
+func stopped(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// read delivers RX audio to whichever playback goroutine is running, until
+// the reader fails or the stream is closed
+func (s *alsaStream) read(r io.Reader) {
+	defer close(s.chunks)
+	for {
+		buf := make([]byte, 480*playbackChannels*4)
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		select {
+		case s.chunks <- buf[:n]:
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func (s *alsaStream) runPlayback(pcm *C.snd_pcm_t, stop, done chan struct{}) {
+	defer close(done)
+	defer C.snd_pcm_close(pcm)
+	defer C.snd_pcm_drop(pcm)
+
+	for {
+		var buf []byte
+		select {
+		case <-stop:
+			return
+		case chunk, ok := <-s.chunks:
+			if !ok {
+				return
+			}
+			buf = chunk
+		}
+		frames := len(buf) / (playbackChannels * 4)
+		for written := 0; written < frames && !stopped(stop); {
+			ret := C.snd_pcm_writei(pcm, unsafe.Pointer(&buf[written*playbackChannels*4]), C.snd_pcm_uframes_t(frames-written))
+			if ret < 0 {
+				// Recover from underruns and suspends
+				if code := C.snd_pcm_recover(pcm, C.int(ret), 1); code < 0 {
+					log.Println("ALSA playback error:", alsaError(code))
+					return
+				}
+				continue
+			}
+			written += int(ret)
+		}
+	}
+}
+
+func (s *alsaStream) runCapture(pcm *C.snd_pcm_t, stop, done chan struct{}) {
+	defer close(done)
+	defer C.snd_pcm_close(pcm)
+	defer C.snd_pcm_drop(pcm)
+
+	const frameSize = recordChannels * 4
+	frame := make([]byte, recordFrameBytes)
+	for !stopped(stop) {
+		for read := 0; read < len(frame)/frameSize; {
+			ret := C.snd_pcm_readi(pcm, unsafe.Pointer(&frame[read*frameSize]), C.snd_pcm_uframes_t(len(frame)/frameSize-read))
+			if ret < 0 {
+				// Recover from overruns and suspends
+				if code := C.snd_pcm_recover(pcm, C.int(ret), 1); code < 0 {
+					log.Println("ALSA capture error:", alsaError(code))
+					return
+				}
+				continue
+			}
+			read += int(ret)
+		}
+		s.w.Write(frame)
+	}
+}
+
+func (s *alsaStream) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	// Capture returns within a frame and playback within a write, so the
+	// device is free for Start once this returns
+	<-s.done
+	s.stop = nil
+	s.done = nil
+}
+
+func (s *alsaStream) Close() {
+	s.Stop()
+	s.closeOnce.Do(func() { close(s.closed) })
+}
+
+// SetDevice reopens the stream on the new device if it is running
+func (s *alsaStream) SetDevice(device string) error {
+	s.mu.Lock()
+	s.device = device
+	running := s.stop != nil
+	s.mu.Unlock()
+	if running {
+		s.Stop()
+		s.Start()
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+)
+
+// fileBackend writes RX audio to a WAV file and reads TX audio from one, for
+// automated tests. Without files it is a null backend that discards RX audio
+// and records silence, so Minstrel can run on machines without a sound card.
+type fileBackend struct {
+	inputFile  string
+	outputFile string
+	input      []float32
+}
+
+func newFileBackend(cfg *Config) (Backend, error) {
+	b := &fileBackend{
+		inputFile:  cfg.InputFile,
+		outputFile: cfg.OutputFile,
+	}
+	if b.inputFile != "" {
+		wav, err := ReadWAV(b.inputFile)
+		if err != nil {
+			return nil, err
+		}
+		b.input = wav.Convert(sampleRate, recordChannels)
+	}
+	return b, nil
+}
+
+func (b *fileBackend) Name() string {
+	if b.inputFile == "" && b.outputFile == "" {
+		return "null"
+	}
+	return "file"
+}
+
+func (b *fileBackend) ListSinks() ([]audioshim.AudioDevice, error) {
+	return []audioshim.AudioDevice{b.sink()}, nil
+}
+
+func (b *fileBackend) ListSources() ([]audioshim.AudioDevice, error) {
+	return []audioshim.AudioDevice{b.source()}, nil
+}
+
+func (b *fileBackend) DefaultSink() (string, error) {
+	return b.sink().ID, nil
+}
+
+func (b *fileBackend) DefaultSource() (string, error) {
+	return b.source().ID, nil
+}
+
+func (b *fileBackend) sink() audioshim.AudioDevice {
+	if b.outputFile == "" {
+		return audioshim.AudioDevice{ID: "null", Name: "Null output"}
+	}
+	return audioshim.AudioDevice{ID: "file", Name: "File: " + b.outputFile}
+}
+
+func (b *fileBackend) source() audioshim.AudioDevice {
+	if b.inputFile == "" {
+		return audioshim.AudioDevice{ID: "null", Name: "Silence"}
+	}
+	return audioshim.AudioDevice{ID: "file", Name: "File: " + b.inputFile}
+}
+
+func (b *fileBackend) NewPlayback(device string, r io.Reader) (Stream, error) {
+	p := &filePlayback{path: b.outputFile, r: r}
+	go p.run()
+	return p, nil
+}
+
+func (b *fileBackend) NewRecord(device string, w io.Writer) (Stream, error) {
+	return &fileRecord{samples: b.input, w: w}, nil
+}
+
+func (b *fileBackend) Close() {}
+
+// filePlayback drains the playback reader, appending to the output file while started
+type filePlayback struct {
+	path    string
+	r       io.Reader
+	mu      sync.Mutex
+	running bool
+	closed  bool
+	out     *WAVWriter
+}
+
+func (p *filePlayback) run() {
+	buf := make([]byte, 480*playbackChannels*4)
+	for {
+		n, err := p.r.Read(buf)
+		if err != nil {
+			return
+		}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		if p.running && p.out != nil {
+			if _, err := p.out.Write(buf[:n]); err != nil {
+				log.Println("Failed to write audio output file:", err)
+				p.out.Close()
+				p.out = nil
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *filePlayback) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = true
+	// The file is created on first start so that recreating the stream
+	// doesn't truncate a recording in progress
+	if p.out == nil && p.path != "" {
+		out, err := CreateWAV(p.path, sampleRate, playbackChannels)
+		if err != nil {
+			log.Println("Failed to create audio output file:", err)
+			return
+		}
+		p.out = out
+	}
+}
+
+func (p *filePlayback) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = false
+}
+
+func (p *filePlayback) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = false
+	p.closed = true
+	if p.out != nil {
+		if err := p.out.Close(); err != nil {
+			log.Println("Failed to close audio output file:", err)
+		}
+		p.out = nil
+	}
+}
+
+func (p *filePlayback) SetDevice(device string) error {
+	return nil
+}
+
+// fileRecord delivers the input file in real time, followed by silence
+type fileRecord struct {
+	samples []float32
+	w       io.Writer
+	mu      sync.Mutex
+	pos     int
+	stop    chan struct{}
+}
+
+func (r *fileRecord) run(stop chan struct{}) {
+	frame := make([]byte, recordFrameBytes)
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		r.mu.Lock()
+		for i := range recordFrameBytes / 4 {
+			var s float32
+			if r.pos < len(r.samples) {
+				s = r.samples[r.pos]
+				r.pos++
+			}
+			binary.LittleEndian.PutUint32(frame[4*i:], math.Float32bits(s))
+		}
+		r.mu.Unlock()
+		r.w.Write(frame)
+	}
+}
+
+func (r *fileRecord) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	go r.run(r.stop)
+}
+
+func (r *fileRecord) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+func (r *fileRecord) Close() {
+	r.Stop()
+}
+
+func (r *fileRecord) SetDevice(device string) error {
+	return nil
+}
@@ -0,0 +1,245 @@
+package audio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sync"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+)
+
+// pipeWireBackend talks to PipeWire directly using the pw-cat and pw-dump
+// tools, for systems that run PipeWire without its PulseAudio compatibility
+// server
+type pipeWireBackend struct{}
+
+// pwObject is the subset of pw-dump output we care about
+type pwObject struct {
+	Type string `json:"type"`
+	Info *struct {
+		Props map[string]any `json:"props"`
+	} `json:"info"`
+	Props    map[string]any `json:"props"`
+	Metadata []struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	} `json:"metadata"`
+}
+
+func newPipeWireBackend() (Backend, error) {
+	for _, tool := range []string{"pw-cat", "pw-dump"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return nil, err
+		}
+	}
+	// pw-dump fails if no PipeWire daemon is running
+	if _, err := pipeWireDump(); err != nil {
+		return nil, err
+	}
+	return &pipeWireBackend{}, nil
+}
+
+func pipeWireDump() ([]pwObject, error) {
+	out, err := exec.Command("pw-dump").Output()
+	if err != nil {
+		return nil, fmt.Errorf("pw-dump: %w", err)
+	}
+	var objects []pwObject
+	if err := json.Unmarshal(out, &objects); err != nil {
+		return nil, fmt.Errorf("failed to parse pw-dump output: %w", err)
+	}
+	return objects, nil
+}
+
+// pipeWireNodes lists audio nodes of the given media class (Audio/Sink or Audio/Source)
+func pipeWireNodes(class string) ([]audioshim.AudioDevice, error) {
+	objects, err := pipeWireDump()
+	if err != nil {
+		return nil, err
+	}
+	devices := []audioshim.AudioDevice{}
+	for _, obj := range objects {
+		if obj.Type != "PipeWire:Interface:Node" || obj.Info == nil {
+			continue
+		}
+		props := obj.Info.Props
+		if props["media.class"] != class {
+			continue
+		}
+		name, _ := props["node.name"].(string)
+		desc, _ := props["node.description"].(string)
+		if desc == "" {
+			desc = name
+		}
+		devices = append(devices, audioshim.AudioDevice{ID: name, Name: desc})
+	}
+	return devices, nil
+}
+
+// pipeWireDefault returns the node name stored under key in the default metadata
+func pipeWireDefault(key string) (string, error) {
+	objects, err := pipeWireDump()
+	if err != nil {
+		return "", err
+	}
+	for _, obj := range objects {
+		if obj.Type != "PipeWire:Interface:Metadata" || obj.Props["metadata.name"] != "default" {
+			continue
+		}
+		for _, entry := range obj.Metadata {
+			if entry.Key != key {
+				continue
+			}
+			var value struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(entry.Value, &value); err != nil {
+				return "", err
+			}
+			return value.Name, nil
+		}
+	}
+	return "", fmt.Errorf("no %s in PipeWire metadata", key)
+}
+
+func (b *pipeWireBackend) Name() string {
+	return "pipewire"
+}
+
+func (b *pipeWireBackend) ListSinks() ([]audioshim.AudioDevice, error) {
+	return pipeWireNodes("Audio/Sink")
+}
+
+func (b *pipeWireBackend) ListSources() ([]audioshim.AudioDevice, error) {
+	return pipeWireNodes("Audio/Source")
+}
+
+func (b *pipeWireBackend) DefaultSink() (string, error) {
+	return pipeWireDefault("default.audio.sink")
+}
+
+func (b *pipeWireBackend) DefaultSource() (string, error) {
+	return pipeWireDefault("default.audio.source")
+}
+
+func (b *pipeWireBackend) NewPlayback(device string, r io.Reader) (Stream, error) {
+	return &pipeWireStream{playback: true, device: device, r: r}, nil
+}
+
+func (b *pipeWireBackend) NewRecord(device string, w io.Writer) (Stream, error) {
+	return &pipeWireStream{device: device, w: w}, nil
+}
+
+func (b *pipeWireBackend) Close() {}
+
+// pipeWireStream runs pw-cat while started, piping raw samples through stdin
+// (playback) or stdout (record)
+type pipeWireStream struct {
+	playback bool
+	r        io.Reader
+	w        io.Writer
+	mu       sync.Mutex
+	device   string
+	cmd      *exec.Cmd
+}
+
+func (s *pipeWireStream) args() []string {
+	args := []string{"--rate", fmt.Sprint(sampleRate), "--format", "f32", "--latency", "50ms"}
+	if s.playback {
+		args = append(args, "--playback", "--channels", fmt.Sprint(playbackChannels))
+	} else {
+		args = append(args, "--record", "--channels", fmt.Sprint(recordChannels))
+	}
+	if s.device != "" {
+		args = append(args, "--target", s.device)
+	}
+	return append(args, "-")
+}
+
+func (s *pipeWireStream) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cmd != nil {
+		return
+	}
+
+	cmd := exec.Command("pw-cat", s.args()...)
+	var err error
+	if s.playback {
+		var stdin io.WriteCloser
+		if stdin, err = cmd.StdinPipe(); err == nil {
+			if err = cmd.Start(); err == nil {
+				go s.copyPlayback(stdin)
+			}
+		}
+	} else {
+		var stdout io.ReadCloser
+		if stdout, err = cmd.StdoutPipe(); err == nil {
+			if err = cmd.Start(); err == nil {
+				go s.copyRecord(stdout)
+			}
+		}
+	}
+	if err != nil {
+		log.Println("Failed to start pw-cat:", err)
+		return
+	}
+	s.cmd = cmd
+}
+
+// copyPlayback feeds samples to pw-cat until it exits
+func (s *pipeWireStream) copyPlayback(stdin io.WriteCloser) {
+	defer stdin.Close()
+	buf := make([]byte, 480*playbackChannels*4)
+	for {
+		n, err := s.r.Read(buf)
+		if err != nil {
+			return
+		}
+		if _, err := stdin.Write(buf[:n]); err != nil {
+			return
+		}
+	}
+}
+
+// copyRecord delivers whole frames from pw-cat until it exits
+func (s *pipeWireStream) copyRecord(stdout io.ReadCloser) {
+	frame := make([]byte, recordFrameBytes)
+	for {
+		if _, err := io.ReadFull(stdout, frame); err != nil {
+			return
+		}
+		s.w.Write(frame)
+	}
+}
+
+func (s *pipeWireStream) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cmd == nil {
+		return
+	}
+	s.cmd.Process.Kill()
+	s.cmd.Wait()
+	s.cmd = nil
+}
+
+func (s *pipeWireStream) Close() {
+	s.Stop()
+}
+
+// SetDevice restarts pw-cat with the new target if it is running
+func (s *pipeWireStream) SetDevice(device string) error {
+	s.mu.Lock()
+	s.device = device
+	running := s.cmd != nil
+	s.mu.Unlock()
+	if running {
+		s.Stop()
+		s.Start()
+	}
+	return nil
+}
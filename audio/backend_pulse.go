@@ -0,0 +1,195 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/jfreymuth/pulse"
+	"github.com/jfreymuth/pulse/proto"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+)
+
+// pulseBackend talks to PulseAudio (or pipewire-pulse) using the native protocol
+type pulseBackend struct {
+	client *pulse.Client
+}
+
+func newPulseBackend() (Backend, error) {
+	pc, err := pulse.NewClient(
+		pulse.ClientApplicationName("Minstrel"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &pulseBackend{client: pc}, nil
+}
+
+func (b *pulseBackend) Name() string {
+	return "pulse"
+}
+
+func (b *pulseBackend) ListSinks() ([]audioshim.AudioDevice, error) {
+	sinks, err := b.client.ListSinks()
+	if err != nil {
+		return nil, err
+	}
+	devices := []audioshim.AudioDevice{}
+	for _, sink := range sinks {
+		devices = append(devices, audioshim.AudioDevice{
+			ID:   sink.ID(),
+			Name: sink.Name(),
+		})
+	}
+	return devices, nil
+}
+
+func (b *pulseBackend) ListSources() ([]audioshim.AudioDevice, error) {
+	sources, err := b.client.ListSources()
+	if err != nil {
+		return nil, err
+	}
+	devices := []audioshim.AudioDevice{}
+	for _, source := range sources {
+		devices = append(devices, audioshim.AudioDevice{
+			ID:   source.ID(),
+			Name: source.Name(),
+		})
+	}
+	return devices, nil
+}
+
+func (b *pulseBackend) DefaultSink() (string, error) {
+	sink, err := b.client.DefaultSink()
+	if err != nil {
+		return "", err
+	}
+	return sink.ID(), nil
+}
+
+func (b *pulseBackend) DefaultSource() (string, error) {
+	source, err := b.client.DefaultSource()
+	if err != nil {
+		return "", err
+	}
+	return source.ID(), nil
+}
+
+func (b *pulseBackend) NewPlayback(device string, r io.Reader) (Stream, error) {
+	opts := []pulse.PlaybackOption{
+		pulse.PlaybackChannels(proto.ChannelMap{proto.ChannelMono}),
+		pulse.PlaybackLatency(50.0 / 1000),
+		pulse.PlaybackSampleRate(sampleRate),
+	}
+	if device != "" {
+		sink, err := b.client.SinkByID(device)
+		if err != nil {
+			log.Printf("Failed to get sink %s: %v, using default", device, err)
+		} else {
+			opts = append(opts, pulse.PlaybackSink(sink))
+		}
+	}
+
+	stream, err := b.client.NewPlayback(pulse.NewReader(r, proto.FormatFloat32LE), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &pulsePlayback{backend: b, stream: stream}, nil
+}
+
+func (b *pulseBackend) NewRecord(device string, w io.Writer) (Stream, error) {
+	opts := []pulse.RecordOption{
+		pulse.RecordStereo,
+		pulse.RecordSampleRate(sampleRate),
+		pulse.RecordRawOption(func(rs *proto.CreateRecordStream) {
+			// Ask pulse to give us exactly 20ms frames (480 samples at 24kHz) * 2ch * 4bytes/sample = 3840 bytes
+			// Opus encoding will fail if the frame size isn't exactly a supported size.
+			rs.BufferFragSize = recordFrameBytes
+		}),
+	}
+	if device != "" {
+		source, err := b.client.SourceByID(device)
+		if err != nil {
+			log.Printf("Failed to get source %s: %v, using default", device, err)
+		} else {
+			opts = append(opts, pulse.RecordSource(source))
+		}
+	}
+
+	stream, err := b.client.NewRecord(pulse.NewWriter(w, proto.FormatFloat32LE), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &pulseRecord{backend: b, stream: stream}, nil
+}
+
+func (b *pulseBackend) Close() {
+	b.client.Close()
+}
+
+type pulsePlayback struct {
+	backend *pulseBackend
+	stream  *pulse.PlaybackStream
+}
+
+func (p *pulsePlayback) Start() { p.stream.Start() }
+func (p *pulsePlayback) Stop()  { p.stream.Stop() }
+func (p *pulsePlayback) Close() { p.stream.Close() }
+
+// SetDevice moves the existing stream to the new device instead of recreating it
+func (p *pulsePlayback) SetDevice(device string) error {
+	return p.backend.client.RawRequest(&proto.MoveSinkInput{
+		SinkInputIndex: p.stream.StreamInputIndex(),
+		DeviceIndex:    proto.Undefined,
+		DeviceName:     device, // Empty string means default
+	}, nil)
+}
+
+type pulseRecord struct {
+	backend *pulseBackend
+	stream  *pulse.RecordStream
+}
+
+func (r *pulseRecord) Start() { r.stream.Start() }
+func (r *pulseRecord) Stop()  { r.stream.Stop() }
+func (r *pulseRecord) Close() { r.stream.Close() }
+
+// SetDevice moves the existing stream to the new device instead of recreating it
+func (r *pulseRecord) SetDevice(device string) error {
+	// RecordStream doesn't expose its source output index, so we query the server
+	sourceOutputIndex, err := r.backend.findOurSourceOutput()
+	if err != nil {
+		return err
+	}
+	return r.backend.client.RawRequest(&proto.MoveSourceOutput{
+		SourceOutputIndex: sourceOutputIndex,
+		DeviceIndex:       proto.Undefined,
+		DeviceName:        device, // Empty string means default
+	}, nil)
+}
+
+// findOurSourceOutput finds the source output index for our recording stream
+// by querying PulseAudio for all source outputs and finding the one with our PID
+func (b *pulseBackend) findOurSourceOutput() (uint32, error) {
+	var reply proto.GetSourceOutputInfoListReply
+	err := b.client.RawRequest(&proto.GetSourceOutputInfoList{}, &reply)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get source output list: %w", err)
+	}
+
+	// Get our process ID
+	myPID := fmt.Sprintf("%d", os.Getpid())
+
+	// Find the source output that belongs to our process
+	for _, info := range reply {
+		if info.Properties != nil {
+			if pid, ok := info.Properties["application.process.id"]; ok && pid.String() == myPID {
+				return info.SourceOutpuIndex, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("could not find source output for our process (PID %s)", myPID)
+}
@@ -0,0 +1,98 @@
+package audio
+
+import (
+	"log"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+	"github.com/kc2g-flex-tools/minstrel/opus"
+)
+
+// opusApplication maps an application name to its libopus constant
+func opusApplication(name string) (int, bool) {
+	switch name {
+	case "audio":
+		return opus.ApplicationAudio, true
+	case "voip":
+		return opus.ApplicationVoIP, true
+	case "lowdelay":
+		return opus.ApplicationRestrictedLowDelay, true
+	}
+	return 0, false
+}
+
+// normalizeOpusSettings clamps settings to the ranges libopus accepts and
+// replaces an unknown application with "audio"
+func normalizeOpusSettings(s audioshim.OpusSettings) audioshim.OpusSettings {
+	s.Bitrate = min(max(s.Bitrate, audioshim.OpusMinBitrate), audioshim.OpusMaxBitrate)
+	s.Complexity = min(max(s.Complexity, 0), audioshim.OpusMaxComplexity)
+	s.PacketLossPerc = min(max(s.PacketLossPerc, 0), audioshim.OpusMaxPacketLoss)
+	if _, ok := opusApplication(s.Application); !ok {
+		log.Printf("Unknown opus application %q, using audio", s.Application)
+		s.Application = "audio"
+	}
+	return s
+}
+
+// newOpusEncoder creates a stereo TX encoder with the given settings. Failures
+// to apply individual settings are logged, leaving the libopus default.
+func newOpusEncoder(s audioshim.OpusSettings) (*opus.Encoder, error) {
+	application, _ := opusApplication(s.Application)
+	enc, err := opus.NewEncoder(sampleRate, recordChannels, application)
+	if err != nil {
+		return nil, err
+	}
+	applyOpusSettings(enc, s)
+	return enc, nil
+}
+
+// applyOpusSettings applies everything except the application, which libopus
+// only accepts before the first frame
+func applyOpusSettings(enc *opus.Encoder, s audioshim.OpusSettings) {
+	if err := enc.SetBitrate(s.Bitrate); err != nil {
+		log.Println("Failed to set opus bitrate:", err)
+	}
+	if err := enc.SetComplexity(s.Complexity); err != nil {
+		log.Println("Failed to set opus complexity:", err)
+	}
+	if err := enc.SetInbandFEC(s.FEC); err != nil {
+		log.Println("Failed to set opus FEC:", err)
+	}
+	if err := enc.SetPacketLossPerc(s.PacketLossPerc); err != nil {
+		log.Println("Failed to set opus packet loss:", err)
+	}
+	if err := enc.SetDTX(s.DTX); err != nil {
+		log.Println("Failed to set opus DTX:", err)
+	}
+}
+
+// GetOpusSettings returns the current TX encoder settings
+func (a *Audio) GetOpusSettings() audioshim.OpusSettings {
+	a.opusMutex.Lock()
+	defer a.opusMutex.Unlock()
+	return a.opusSettings
+}
+
+// SetOpusSettings reconfigures the TX encoder. Changing the application
+// replaces the encoder, since libopus can't change it once encoding started.
+func (a *Audio) SetOpusSettings(s audioshim.OpusSettings) {
+	s = normalizeOpusSettings(s)
+
+	a.opusMutex.Lock()
+	defer a.opusMutex.Unlock()
+	if s == a.opusSettings {
+		return
+	}
+
+	if s.Application != a.opusSettings.Application {
+		enc, err := newOpusEncoder(s)
+		if err != nil {
+			log.Println("Failed to create opus encoder:", err)
+			return
+		}
+		a.OpusEnc.Destroy()
+		a.OpusEnc = enc
+	} else {
+		applyOpusSettings(a.OpusEnc, s)
+	}
+	a.opusSettings = s
+}
@@ -0,0 +1,80 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+)
+
+const (
+	// meterFloorDB is reported for silence instead of -Inf
+	meterFloorDB = -100.0
+	// clipLevel is the sample magnitude treated as clipping
+	clipLevel = 0.999
+	// clipHold is how long the clip indicator stays lit after clipping
+	clipHold = time.Second
+)
+
+// LevelMeter tracks peak and RMS levels of a stream of audio blocks.
+// The peak decays gradually so that short transients stay visible, and
+// clipping is latched for clipHold.
+type LevelMeter struct {
+	mu        sync.Mutex
+	peak      float64
+	ms        float64
+	clipUntil time.Time
+}
+
+// Update measures a block of samples (interleaved channels are fine)
+func (m *LevelMeter) Update(samples []float32) {
+	if len(samples) == 0 {
+		return
+	}
+	peak := 0.0
+	sum := 0.0
+	for _, s := range samples {
+		v := math.Abs(float64(s))
+		peak = max(peak, v)
+		sum += v * v
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.peak = max(peak, m.peak*0.95)
+	m.ms += 0.2 * (sum/float64(len(samples)) - m.ms)
+	if peak >= clipLevel {
+		m.clipUntil = time.Now().Add(clipHold)
+	}
+}
+
+// Levels returns the current readings in dBFS
+func (m *LevelMeter) Levels() audioshim.Levels {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return audioshim.Levels{
+		PeakDB:  toDB(m.peak),
+		RMSDB:   toDB(math.Sqrt(m.ms)),
+		Clipped: time.Now().Before(m.clipUntil),
+	}
+}
+
+// Reset clears the meter
+func (m *LevelMeter) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.peak = 0
+	m.ms = 0
+	m.clipUntil = time.Time{}
+}
+
+func toDB(v float64) float64 {
+	if v <= 0 {
+		return meterFloorDB
+	}
+	return max(20*math.Log10(v), meterFloorDB)
+}
+
+func fromDB(db float64) float64 {
+	return math.Pow(10, db/20)
+}
@@ -0,0 +1,234 @@
+package audio
+
+import (
+	"math"
+	"sync"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+)
+
+// biquad is a second-order IIR filter section (RBJ audio EQ cookbook)
+// with independent state for each interleaved channel.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     []float64
+}
+
+func newBiquad(channels int) *biquad {
+	return &biquad{
+		b0: 1,
+		x1: make([]float64, channels),
+		x2: make([]float64, channels),
+		y1: make([]float64, channels),
+		y2: make([]float64, channels),
+	}
+}
+
+func (f *biquad) setCoefficients(b0, b1, b2, a0, a1, a2 float64) {
+	f.b0, f.b1, f.b2 = b0/a0, b1/a0, b2/a0
+	f.a1, f.a2 = a1/a0, a2/a0
+}
+
+func (f *biquad) setHighPass(sampleRate, freq, q float64) {
+	w0 := 2 * math.Pi * freq / sampleRate
+	alpha := math.Sin(w0) / (2 * q)
+	cosw := math.Cos(w0)
+	f.setCoefficients((1+cosw)/2, -(1 + cosw), (1+cosw)/2, 1+alpha, -2*cosw, 1-alpha)
+}
+
+func (f *biquad) setPeaking(sampleRate, freq, q, gainDB float64) {
+	a := math.Pow(10, gainDB/40)
+	w0 := 2 * math.Pi * freq / sampleRate
+	alpha := math.Sin(w0) / (2 * q)
+	cosw := math.Cos(w0)
+	f.setCoefficients(1+alpha*a, -2*cosw, 1-alpha*a, 1+alpha/a, -2*cosw, 1-alpha/a)
+}
+
+func (f *biquad) process(ch int, x float64) float64 {
+	y := f.b0*x + f.b1*f.x1[ch] + f.b2*f.x2[ch] - f.a1*f.y1[ch] - f.a2*f.y2[ch]
+	f.x2[ch], f.x1[ch] = f.x1[ch], x
+	f.y2[ch], f.y1[ch] = f.y1[ch], y
+	return y
+}
+
+// timeCoefficient returns the one-pole smoothing coefficient for a time constant
+func timeCoefficient(sampleRate, ms float64) float64 {
+	if ms <= 0 {
+		return 0
+	}
+	return math.Exp(-1000 / (ms * sampleRate))
+}
+
+// TXProcessor runs the client-side TX audio chain on interleaved float32 frames.
+// The gate and compressor detect on the loudest channel and apply the same gain
+// to all channels so the stereo image is preserved.
+type TXProcessor struct {
+	mu         sync.Mutex
+	cfg        audioshim.TXProcessing
+	sampleRate float64
+	channels   int
+
+	highPass *biquad
+	eq       []*biquad
+	frame    []float64 // one frame of high-passed samples
+
+	gateEnv  float64
+	gateGain float64
+	gateHold int
+
+	compEnv       float64
+	compReduction float64
+
+	before LevelMeter
+	after  LevelMeter
+}
+
+// NewTXProcessor creates a processor for the given sample rate and channel count
+func NewTXProcessor(sampleRate, channels int) *TXProcessor {
+	p := &TXProcessor{
+		sampleRate: float64(sampleRate),
+		channels:   channels,
+		gateGain:   1,
+		frame:      make([]float64, channels),
+	}
+	p.configure(audioshim.TXProcessing{})
+	return p
+}
+
+// Config returns the current processing configuration
+func (p *TXProcessor) Config() audioshim.TXProcessing {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cfg := p.cfg
+	cfg.EQ.Bands = append([]audioshim.EQBand(nil), p.cfg.EQ.Bands...)
+	return cfg
+}
+
+// SetConfig replaces the processing configuration
+func (p *TXProcessor) SetConfig(cfg audioshim.TXProcessing) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.configure(cfg)
+}
+
+// configure recomputes filter coefficients. Filter state is kept where
+// possible so that adjusting a slider while talking doesn't click.
+func (p *TXProcessor) configure(cfg audioshim.TXProcessing) {
+	cfg.EQ.Bands = append([]audioshim.EQBand(nil), cfg.EQ.Bands...)
+	p.cfg = cfg
+
+	if p.highPass == nil {
+		p.highPass = newBiquad(p.channels)
+	}
+	nyquist := p.sampleRate / 2
+	p.highPass.setHighPass(p.sampleRate, math.Min(math.Max(cfg.HighPass.CutoffHz, 10), nyquist*0.9), math.Sqrt2/2)
+
+	if len(p.eq) != len(cfg.EQ.Bands) {
+		p.eq = make([]*biquad, len(cfg.EQ.Bands))
+		for i := range p.eq {
+			p.eq[i] = newBiquad(p.channels)
+		}
+	}
+	for i, band := range cfg.EQ.Bands {
+		q := band.Q
+		if q <= 0 {
+			q = 1
+		}
+		p.eq[i].setPeaking(p.sampleRate, math.Min(math.Max(band.FreqHz, 10), nyquist*0.9), q, band.GainDB)
+	}
+}
+
+// Process filters samples in place and updates the before/after level meters
+func (p *TXProcessor) Process(samples []float32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.before.Update(samples)
+	if !p.cfg.Enabled {
+		p.after.Update(samples)
+		return
+	}
+
+	cfg := &p.cfg
+	gateThreshold := fromDB(cfg.Gate.ThresholdDB)
+	gateAttack := timeCoefficient(p.sampleRate, cfg.Gate.AttackMs)
+	gateRelease := timeCoefficient(p.sampleRate, cfg.Gate.ReleaseMs)
+	gateHold := int(cfg.Gate.HoldMs * p.sampleRate / 1000)
+	compAttack := timeCoefficient(p.sampleRate, cfg.Compressor.AttackMs)
+	compRelease := timeCoefficient(p.sampleRate, cfg.Compressor.ReleaseMs)
+	envRelease := timeCoefficient(p.sampleRate, 50)
+	ratio := math.Max(cfg.Compressor.Ratio, 1)
+	makeup := fromDB(cfg.Compressor.MakeupDB)
+
+	frame := p.frame
+	for i := 0; i+p.channels <= len(samples); i += p.channels {
+		peak := 0.0
+		for ch := range p.channels {
+			x := float64(samples[i+ch])
+			if cfg.HighPass.Enabled {
+				x = p.highPass.process(ch, x)
+			}
+			frame[ch] = x
+			peak = math.Max(peak, math.Abs(x))
+		}
+
+		gain := 1.0
+		if cfg.Gate.Enabled {
+			// Peak envelope: instant attack, smooth release
+			p.gateEnv = math.Max(peak, p.gateEnv*envRelease)
+			target := p.gateGain
+			if p.gateEnv >= gateThreshold {
+				target = 1
+				p.gateHold = gateHold
+			} else if p.gateHold > 0 {
+				p.gateHold--
+			} else {
+				target = 0
+			}
+			coef := gateRelease
+			if target > p.gateGain {
+				coef = gateAttack
+			}
+			p.gateGain = target + coef*(p.gateGain-target)
+			gain *= p.gateGain
+		}
+
+		if cfg.Compressor.Enabled {
+			p.compEnv = math.Max(peak, p.compEnv*envRelease)
+			over := toDB(p.compEnv) - cfg.Compressor.ThresholdDB
+			reduction := 0.0
+			if over > 0 {
+				reduction = over * (1 - 1/ratio)
+			}
+			coef := compRelease
+			if reduction > p.compReduction {
+				coef = compAttack
+			}
+			p.compReduction = reduction + coef*(p.compReduction-reduction)
+			gain *= fromDB(-p.compReduction) * makeup
+		}
+
+		for ch := range p.channels {
+			x := frame[ch] * gain
+			if cfg.EQ.Enabled {
+				for _, band := range p.eq {
+					x = band.process(ch, x)
+				}
+			}
+			samples[i+ch] = float32(math.Max(-1, math.Min(1, x)))
+		}
+	}
+
+	p.after.Update(samples)
+}
+
+// Levels returns the meter readings before and after processing
+func (p *TXProcessor) Levels() (before, after audioshim.Levels) {
+	return p.before.Levels(), p.after.Levels()
+}
+
+// ResetLevels clears the meters, e.g. when the mic stops being recorded
+func (p *TXProcessor) ResetLevels() {
+	p.before.Reset()
+	p.after.Reset()
+}
@@ -0,0 +1,133 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+)
+
+const testRate = 48000
+
+// sine returns a second of a stereo tone at the given level in dBFS
+func sine(freq, db float64) []float32 {
+	amp := fromDB(db)
+	samples := make([]float32, 2*testRate)
+	for i := range testRate {
+		v := float32(amp * math.Sin(2*math.Pi*freq*float64(i)/testRate))
+		samples[2*i], samples[2*i+1] = v, v
+	}
+	return samples
+}
+
+// peakDB runs samples through p in 10 ms blocks and returns the peak level
+// of the last 100 ms on each channel, once the filters and envelopes have
+// settled
+func peakDB(t *testing.T, p *TXProcessor, samples []float32) float64 {
+	t.Helper()
+	block := 2 * testRate / 100
+	for i := 0; i < len(samples); i += block {
+		p.Process(samples[i:min(i+block, len(samples))])
+	}
+	var peak [2]float64
+	for i, s := range samples[len(samples)-2*testRate/10:] {
+		peak[i%2] = max(peak[i%2], math.Abs(float64(s)))
+	}
+	if math.Abs(peak[0]-peak[1]) > 1e-6 {
+		t.Errorf("channels differ: %v", peak)
+	}
+	return toDB(peak[0])
+}
+
+func processor(cfg audioshim.TXProcessing) *TXProcessor {
+	p := NewTXProcessor(testRate, 2)
+	cfg.Enabled = true
+	p.SetConfig(cfg)
+	return p
+}
+
+func TestProcessDisabled(t *testing.T) {
+	p := NewTXProcessor(testRate, 2)
+	p.SetConfig(audioshim.DefaultTXPresets()["Contest"])
+	cfg := p.Config()
+	cfg.Enabled = false
+	p.SetConfig(cfg)
+	in := sine(1000, -6)
+	out := append([]float32(nil), in...)
+	p.Process(out)
+	for i := range in {
+		if in[i] != out[i] {
+			t.Fatalf("sample %d changed from %v to %v", i, in[i], out[i])
+		}
+	}
+}
+
+func TestNoiseGate(t *testing.T) {
+	gate := audioshim.TXProcessing{Gate: audioshim.NoiseGate{
+		Enabled: true, ThresholdDB: -40, AttackMs: 1, HoldMs: 100, ReleaseMs: 50,
+	}}
+	if db := peakDB(t, processor(gate), sine(1000, -60)); db > -100 {
+		t.Errorf("-60 dB tone below the -40 dB gate came out at %.1f dB", db)
+	}
+	if db := peakDB(t, processor(gate), sine(1000, -20)); math.Abs(db+20) > 0.1 {
+		t.Errorf("-20 dB tone above the gate came out at %.1f dB", db)
+	}
+}
+
+func TestCompressor(t *testing.T) {
+	comp := audioshim.TXProcessing{Compressor: audioshim.Compressor{
+		Enabled: true, ThresholdDB: -20, Ratio: 4, AttackMs: 5, ReleaseMs: 100,
+	}}
+	// 14 dB over the threshold at 4:1 leaves 3.5 dB over
+	if db := peakDB(t, processor(comp), sine(1000, -6)); math.Abs(db+16.5) > 0.5 {
+		t.Errorf("-6 dB tone came out at %.1f dB, want -16.5", db)
+	}
+	if db := peakDB(t, processor(comp), sine(1000, -30)); math.Abs(db+30) > 0.1 {
+		t.Errorf("-30 dB tone below the threshold came out at %.1f dB", db)
+	}
+
+	comp.Compressor.MakeupDB = 6
+	if db := peakDB(t, processor(comp), sine(1000, -6)); math.Abs(db+10.5) > 0.5 {
+		t.Errorf("-6 dB tone with 6 dB makeup came out at %.1f dB, want -10.5", db)
+	}
+}
+
+func TestHighPass(t *testing.T) {
+	hpf := audioshim.TXProcessing{HighPass: audioshim.HighPass{Enabled: true, CutoffHz: 100}}
+	// Second order, so an octave below the cutoff is down about 12 dB
+	if db := peakDB(t, processor(hpf), sine(50, -6)); db > -6-11 {
+		t.Errorf("50 Hz hum came out at %.1f dB", db)
+	}
+	if db := peakDB(t, processor(hpf), sine(1000, -6)); math.Abs(db+6) > 0.1 {
+		t.Errorf("1 kHz tone came out at %.1f dB", db)
+	}
+}
+
+func TestEQ(t *testing.T) {
+	eq := audioshim.TXProcessing{EQ: audioshim.EQ{Enabled: true, Bands: []audioshim.EQBand{
+		{FreqHz: 400, GainDB: -3, Q: 1},
+		{FreqHz: 2000, GainDB: 6, Q: 1},
+	}}}
+	tests := []struct {
+		freq, want float64
+	}{
+		{2000, -14},
+		{400, -23},
+		{50, -20},
+	}
+	for _, tt := range tests {
+		if db := peakDB(t, processor(eq), sine(tt.freq, -20)); math.Abs(db-tt.want) > 0.5 {
+			t.Errorf("-20 dB tone at %v Hz came out at %.1f dB, want %v", tt.freq, db, tt.want)
+		}
+	}
+}
+
+func TestProcessAllocations(t *testing.T) {
+	// Enough channels that a frame wouldn't fit on the stack
+	p := NewTXProcessor(testRate, 8)
+	p.SetConfig(audioshim.DefaultTXPresets()["Contest"])
+	samples := sine(1000, -20)[:3840]
+	if n := testing.AllocsPerRun(10, func() { p.Process(samples) }); n != 0 {
+		t.Errorf("Process allocated %v times per call", n)
+	}
+}
@@ -1,29 +1,18 @@
 package audio
 
 import (
-	"fmt"
 	"log"
-	"os"
 
-	"github.com/jfreymuth/pulse/proto"
 	"github.com/kc2g-flex-tools/minstrel/audioshim"
 )
 
 // GetAudioSinks returns a list of available audio output devices
 func (a *Audio) GetAudioSinks(callback func([]audioshim.AudioDevice)) {
 	go func() {
-		sinks, err := a.Context.ListSinks()
-		devices := []audioshim.AudioDevice{}
+		devices, err := a.backend.ListSinks()
 		if err != nil {
 			log.Printf("Failed to list audio sinks: %v", err)
-			callback(devices)
-			return
-		}
-		for _, sink := range sinks {
-			devices = append(devices, audioshim.AudioDevice{
-				ID:   sink.ID(),
-				Name: sink.Name(),
-			})
+			devices = []audioshim.AudioDevice{}
 		}
 		callback(devices)
 	}()
@@ -31,29 +20,21 @@ func (a *Audio) GetAudioSinks(callback func([]audioshim.AudioDevice)) {
 
 // GetDefaultAudioSink returns the ID of the default audio output device
 func (a *Audio) GetDefaultAudioSink() string {
-	sink, err := a.Context.DefaultSink()
+	id, err := a.backend.DefaultSink()
 	if err != nil {
 		log.Printf("Failed to get default sink: %v", err)
 		return ""
 	}
-	return sink.ID()
+	return id
 }
 
 // GetAudioSources returns a list of available audio input devices
 func (a *Audio) GetAudioSources(callback func([]audioshim.AudioDevice)) {
 	go func() {
-		sources, err := a.Context.ListSources()
-		devices := []audioshim.AudioDevice{}
+		devices, err := a.backend.ListSources()
 		if err != nil {
 			log.Printf("Failed to list audio sources: %v", err)
-			callback(devices)
-			return
-		}
-		for _, source := range sources {
-			devices = append(devices, audioshim.AudioDevice{
-				ID:   source.ID(),
-				Name: source.Name(),
-			})
+			devices = []audioshim.AudioDevice{}
 		}
 		callback(devices)
 	}()
@@ -61,12 +42,17 @@ func (a *Audio) GetAudioSources(callback func([]audioshim.AudioDevice)) {
 
 // GetDefaultAudioSource returns the ID of the default audio input device
 func (a *Audio) GetDefaultAudioSource() string {
-	source, err := a.Context.DefaultSource()
+	id, err := a.backend.DefaultSource()
 	if err != nil {
 		log.Printf("Failed to get default source: %v", err)
 		return ""
 	}
-	return source.ID()
+	return id
+}
+
+// GetAudioBackend returns the name of the audio backend in use
+func (a *Audio) GetAudioBackend() string {
+	return a.backend.Name()
 }
 
 // SetAudioSink sets the output device and moves the stream if active
@@ -85,6 +71,9 @@ func (a *Audio) SetAudioSink(deviceID string) {
 	// Store the selected device (empty string means default)
 	a.SetSinkDevice(deviceID)
 
+	a.playerMutex.Lock()
+	defer a.playerMutex.Unlock()
+
 	// Check if player is active
 	if a.player == nil {
 		// No active player, setting will be used on next Start()
@@ -92,16 +81,7 @@ func (a *Audio) SetAudioSink(deviceID string) {
 	}
 
 	// Move the existing stream to the new device instead of recreating it
-	streamInputIndex := a.player.StreamInputIndex()
-
-	// Use RawRequest to send MoveSinkInput command
-	err := a.Context.RawRequest(&proto.MoveSinkInput{
-		SinkInputIndex: streamInputIndex,
-		DeviceIndex:    proto.Undefined,
-		DeviceName:     deviceID, // Empty string means default
-	}, nil)
-
-	if err != nil {
+	if err := a.player.SetDevice(deviceID); err != nil {
 		log.Printf("Failed to move sink input to device %s: %v", deviceID, err)
 	}
 }
@@ -121,55 +101,36 @@ func (a *Audio) SetAudioSource(deviceID string) {
 	// Store the selected device
 	a.SetSourceDevice(deviceID)
 
-	// Check if recording is active
+	// Move the recording stream (for TX or the mic test) if it is active
 	a.txMutex.Lock()
-	wasRunning := a.txRunning
-	a.txMutex.Unlock()
+	defer a.txMutex.Unlock()
 
-	if !wasRunning {
+	if a.recorder == nil {
 		return
 	}
 
-	// Find our source output by looking for our application
-	// RecordStream doesn't expose its index, so we query the server
-	sourceOutputIndex, err := a.findOurSourceOutput()
-	if err != nil {
-		log.Printf("Failed to find source output: %v", err)
-		return
-	}
-
-	// Move the existing stream to the new device
-	err = a.Context.RawRequest(&proto.MoveSourceOutput{
-		SourceOutputIndex: sourceOutputIndex,
-		DeviceIndex:       proto.Undefined,
-		DeviceName:        deviceID, // Empty string means default
-	}, nil)
-
-	if err != nil {
+	if err := a.recorder.SetDevice(deviceID); err != nil {
 		log.Printf("Failed to move source output to device %s: %v", deviceID, err)
 	}
 }
 
-// findOurSourceOutput finds the source output index for our recording stream
-// by querying PulseAudio for all source outputs and finding the one with our PID
-func (a *Audio) findOurSourceOutput() (uint32, error) {
-	var reply proto.GetSourceOutputInfoListReply
-	err := a.Context.RawRequest(&proto.GetSourceOutputInfoList{}, &reply)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get source output list: %w", err)
-	}
+// GetTXProcessing returns the current TX audio processing configuration
+func (a *Audio) GetTXProcessing() audioshim.TXProcessing {
+	return a.txProcessor.Config()
+}
 
-	// Get our process ID
-	myPID := fmt.Sprintf("%d", os.Getpid())
+// SetTXProcessing replaces the TX audio processing configuration
+func (a *Audio) SetTXProcessing(cfg audioshim.TXProcessing) {
+	a.txProcessor.SetConfig(cfg)
+}
 
-	// Find the source output that belongs to our process
-	for _, info := range reply {
-		if info.Properties != nil {
-			if pid, ok := info.Properties["application.process.id"]; ok && pid.String() == myPID {
-				return info.SourceOutpuIndex, nil
-			}
-		}
-	}
+// GetTXProcessingLevels returns the mic levels before and after processing
+func (a *Audio) GetTXProcessingLevels() (before, after audioshim.Levels) {
+	return a.txProcessor.Levels()
+}
 
-	return 0, fmt.Errorf("could not find source output for our process (PID %s)", myPID)
+// GetAudioLevels returns the current mic and RX audio levels
+func (a *Audio) GetAudioLevels() (mic, rx audioshim.Levels) {
+	mic, _ = a.txProcessor.Levels()
+	return mic, a.rxMeter.Levels()
 }
@@ -0,0 +1,195 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// WAV holds decoded audio from a WAV file as interleaved float32 samples
+type WAV struct {
+	SampleRate int
+	Channels   int
+	Samples    []float32
+}
+
+// ReadWAV decodes a 16-bit, 24-bit or 32-bit integer PCM, or 32-bit float WAV file
+func ReadWAV(path string) (*WAV, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errors.New("not a WAV file")
+	}
+
+	var format, bits int
+	wav := &WAV{}
+	pos := 12
+	for pos+8 <= len(data) {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		body := data[pos+8 : min(pos+8+size, len(data))]
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return nil, errors.New("short fmt chunk")
+			}
+			format = int(binary.LittleEndian.Uint16(body[0:]))
+			wav.Channels = int(binary.LittleEndian.Uint16(body[2:]))
+			wav.SampleRate = int(binary.LittleEndian.Uint32(body[4:]))
+			bits = int(binary.LittleEndian.Uint16(body[14:]))
+			if format == wavFormatExtensible && len(body) >= 26 {
+				// The real format is the first two bytes of the subformat GUID
+				format = int(binary.LittleEndian.Uint16(body[24:]))
+			}
+			if wav.Channels == 0 || wav.SampleRate == 0 {
+				return nil, errors.New("invalid fmt chunk")
+			}
+		case "data":
+			if wav.Channels == 0 {
+				return nil, errors.New("data chunk before fmt chunk")
+			}
+			samples, err := decodeWAVSamples(body, format, bits)
+			if err != nil {
+				return nil, err
+			}
+			wav.Samples = samples
+			return wav, nil
+		}
+		// Chunks are padded to an even size
+		pos += 8 + size + size%2
+	}
+	return nil, errors.New("no data chunk")
+}
+
+func decodeWAVSamples(body []byte, format, bits int) ([]float32, error) {
+	width := bits / 8
+	if width == 0 {
+		return nil, fmt.Errorf("unsupported sample size %d", bits)
+	}
+	samples := make([]float32, len(body)/width)
+	for i := range samples {
+		b := body[i*width:]
+		switch {
+		case format == wavFormatPCM && bits == 16:
+			samples[i] = float32(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+		case format == wavFormatPCM && bits == 24:
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			samples[i] = float32(v) / (1 << 23)
+		case format == wavFormatPCM && bits == 32:
+			samples[i] = float32(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+		case format == wavFormatFloat && bits == 32:
+			samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(b))
+		default:
+			return nil, fmt.Errorf("unsupported WAV format %d with %d bits", format, bits)
+		}
+	}
+	return samples, nil
+}
+
+// Convert returns the audio resampled to sampleRate with the given number of
+// channels. Mono is duplicated to stereo and extra channels are averaged down.
+// Resampling is linear, which is good enough for test signals.
+func (w *WAV) Convert(sampleRate, channels int) []float32 {
+	inFrames := len(w.Samples) / w.Channels
+	if inFrames == 0 {
+		return nil
+	}
+	outFrames := int(int64(inFrames) * int64(sampleRate) / int64(w.SampleRate))
+	out := make([]float32, outFrames*channels)
+	step := float64(w.SampleRate) / float64(sampleRate)
+	for i := range outFrames {
+		pos := float64(i) * step
+		idx := int(pos)
+		frac := float32(pos - float64(idx))
+		next := min(idx+1, inFrames-1)
+		for ch := range channels {
+			a := w.channelSample(idx, ch, channels)
+			b := w.channelSample(next, ch, channels)
+			out[i*channels+ch] = a + (b-a)*frac
+		}
+	}
+	return out
+}
+
+// channelSample returns frame's sample for output channel ch of outChannels
+func (w *WAV) channelSample(frame, ch, outChannels int) float32 {
+	in := w.Samples[frame*w.Channels : (frame+1)*w.Channels]
+	if w.Channels == outChannels {
+		return in[ch]
+	}
+	if w.Channels == 1 {
+		return in[0]
+	}
+	var sum float32
+	for _, s := range in {
+		sum += s
+	}
+	return sum / float32(len(in))
+}
+
+// WAVWriter writes 32-bit float WAV files
+type WAVWriter struct {
+	file       *os.File
+	sampleRate int
+	channels   int
+	bytes      uint32
+}
+
+// CreateWAV creates a WAV file, writing a header whose sizes are filled in by Close
+func CreateWAV(path string, sampleRate, channels int) (*WAVWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &WAVWriter{file: file, sampleRate: sampleRate, channels: channels}
+	if err := w.writeHeader(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *WAVWriter) writeHeader() error {
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], 36+w.bytes)
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], wavFormatFloat)
+	binary.LittleEndian.PutUint16(header[22:], uint16(w.channels))
+	binary.LittleEndian.PutUint32(header[24:], uint32(w.sampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(w.sampleRate*w.channels*4))
+	binary.LittleEndian.PutUint16(header[32:], uint16(w.channels*4))
+	binary.LittleEndian.PutUint16(header[34:], 32)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], w.bytes)
+	_, err := w.file.WriteAt(header, 0)
+	return err
+}
+
+// Write appends float32LE sample data, as produced by the playback path
+func (w *WAVWriter) Write(data []byte) (int, error) {
+	n, err := w.file.WriteAt(data, 44+int64(w.bytes))
+	w.bytes += uint32(n)
+	return n, err
+}
+
+// Close fills in the header sizes and closes the file
+func (w *WAVWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
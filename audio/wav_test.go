@@ -0,0 +1,194 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// tone returns n samples of a 1 kHz sine at rate, as float32LE bytes and as samples
+func tone(n, rate int) ([]byte, []float32) {
+	samples := make([]float32, n)
+	data := make([]byte, 0, 4*n)
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*1000*float64(i)/float64(rate)))
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(samples[i]))
+	}
+	return data, samples
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	w, err := CreateWAV(path, sampleRate, playbackChannels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, samples := tone(1000, sampleRate)
+	// In pieces, as playback writes it
+	for len(data) > 0 {
+		n := min(len(data), 480*4)
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wav, err := ReadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wav.SampleRate != sampleRate || wav.Channels != playbackChannels {
+		t.Errorf("read %d Hz, %d channels; want %d Hz, %d", wav.SampleRate, wav.Channels, sampleRate, playbackChannels)
+	}
+	if !reflect.DeepEqual(wav.Samples, samples) {
+		t.Errorf("read %d samples that differ from the %d written", len(wav.Samples), len(samples))
+	}
+}
+
+func TestReadWAV16Bit(t *testing.T) {
+	// A 16-bit stereo PCM file with a LIST chunk before the data, as audio
+	// editors write them
+	var b []byte
+	chunk := func(id string, body []byte) {
+		b = append(b, id...)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(body)))
+		b = append(b, body...)
+		if len(body)%2 == 1 {
+			b = append(b, 0)
+		}
+	}
+	var fmtChunk []byte
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, wavFormatPCM)
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, 2)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, 8000)
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, 8000*4)
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, 4)
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, 16)
+	var dataChunk []byte
+	for _, s := range []int16{16384, -16384, 32767, -32768} {
+		dataChunk = binary.LittleEndian.AppendUint16(dataChunk, uint16(s))
+	}
+	chunk("fmt ", fmtChunk)
+	chunk("LIST", []byte("INFOISFT\x05\x00\x00\x00sox!\x00"))
+	chunk("data", dataChunk)
+	b = append([]byte("RIFF\x00\x00\x00\x00WAVE"), b...)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)-8))
+
+	path := filepath.Join(t.TempDir(), "in.wav")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wav, err := ReadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &WAV{SampleRate: 8000, Channels: 2, Samples: []float32{0.5, -0.5, 32767.0 / 32768, -1}}
+	if !reflect.DeepEqual(wav, want) {
+		t.Errorf("ReadWAV = %+v, want %+v", wav, want)
+	}
+}
+
+func TestFileBackendPlayback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rx.wav")
+	b, err := newFileBackend(&Config{OutputFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, w := io.Pipe()
+	stream, err := b.NewPlayback("file", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := stream.(*filePlayback)
+	stream.Start()
+	data, samples := tone(2400, sampleRate)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	// The last read is written out after the pipe hands it over
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		p.mu.Lock()
+		written := p.out.bytes
+		p.mu.Unlock()
+		if int(written) == len(data) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("wrote %d bytes, want %d", written, len(data))
+		}
+	}
+	stream.Stop()
+	stream.Close()
+
+	wav, err := ReadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wav.SampleRate != sampleRate || wav.Channels != playbackChannels || !reflect.DeepEqual(wav.Samples, samples) {
+		t.Errorf("recorded %d Hz, %d channels, %d samples; want what was played", wav.SampleRate, wav.Channels, len(wav.Samples))
+	}
+}
+
+// frameWriter passes each frame written to it down a channel
+type frameWriter chan []byte
+
+func (w frameWriter) Write(frame []byte) (int, error) {
+	w <- append([]byte(nil), frame...)
+	return len(frame), nil
+}
+
+func TestFileBackendRecord(t *testing.T) {
+	// A 48 kHz mono file is sent as 24 kHz stereo
+	path := filepath.Join(t.TempDir(), "tx.wav")
+	in, err := CreateWAV(path, 48000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, samples := tone(48000/10, 48000)
+	if _, err := in.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := newFileBackend(&Config{InputFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := make(frameWriter, 10)
+	stream, err := b.NewRecord("file", frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Start()
+	defer stream.Close()
+
+	for f := range 2 {
+		var frame []byte
+		select {
+		case frame = <-frames:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no frame recorded")
+		}
+		if len(frame) != recordFrameBytes {
+			t.Fatalf("frame of %d bytes, want %d", len(frame), recordFrameBytes)
+		}
+		for i := range recordFrameBytes / (recordChannels * 4) {
+			want := samples[2*(f*480+i)]
+			left := math.Float32frombits(binary.LittleEndian.Uint32(frame[8*i:]))
+			right := math.Float32frombits(binary.LittleEndian.Uint32(frame[8*i+4:]))
+			if left != want || right != want {
+				t.Fatalf("frame %d sample %d = %v, %v; want %v in both channels", f, i, left, right, want)
+			}
+		}
+	}
+}
@@ -6,8 +6,20 @@ type Shim interface {
 	GetAudioSources(callback func([]AudioDevice))
 	GetDefaultAudioSink() string
 	GetDefaultAudioSource() string
+	GetAudioBackend() string
 	SetAudioSink(deviceID string)
 	SetAudioSource(deviceID string)
+	// The selected devices, empty for the system default
+	GetSinkDevice() string
+	GetSourceDevice() string
+	GetTXProcessing() TXProcessing
+	SetTXProcessing(cfg TXProcessing)
+	GetTXProcessingLevels() (before, after Levels)
+	GetAudioLevels() (mic, rx Levels)
+	SetMicTest(enabled bool)
+	MicTest() bool
+	GetOpusSettings() OpusSettings
+	SetOpusSettings(settings OpusSettings)
 }
 
 // AudioDevice represents an audio input or output device
@@ -0,0 +1,28 @@
+package audioshim
+
+// OpusSettings configures the Opus encoder used for TX audio
+type OpusSettings struct {
+	// Bitrate in bits per second
+	Bitrate int
+	// Complexity from 0 (fastest) to 10 (best quality)
+	Complexity int
+	// Application is one of OpusApplications
+	Application string
+	// FEC enables in-band forward error correction
+	FEC bool
+	// PacketLossPerc is the expected packet loss (0-100), used to size the FEC data
+	PacketLossPerc int
+	// DTX enables discontinuous transmission during silence
+	DTX bool
+}
+
+// OpusApplications are the valid values of OpusSettings.Application
+var OpusApplications = []string{"audio", "voip", "lowdelay"}
+
+// Limits for OpusSettings values
+const (
+	OpusMinBitrate    = 6000
+	OpusMaxBitrate    = 128000
+	OpusMaxComplexity = 10
+	OpusMaxPacketLoss = 100
+)
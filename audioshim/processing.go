@@ -0,0 +1,103 @@
+package audioshim
+
+// TXProcessing configures the client-side TX audio chain that runs on
+// microphone audio before it is Opus-encoded and sent to the radio.
+// Stages run in the order high-pass, noise gate, compressor, EQ.
+type TXProcessing struct {
+	Enabled    bool       `json:"enabled"`
+	HighPass   HighPass   `json:"high_pass"`
+	Gate       NoiseGate  `json:"gate"`
+	Compressor Compressor `json:"compressor"`
+	EQ         EQ         `json:"eq"`
+}
+
+// HighPass removes rumble and handling noise below CutoffHz
+type HighPass struct {
+	Enabled  bool    `json:"enabled"`
+	CutoffHz float64 `json:"cutoff_hz"`
+}
+
+// NoiseGate mutes the mic while its level stays below ThresholdDB
+type NoiseGate struct {
+	Enabled     bool    `json:"enabled"`
+	ThresholdDB float64 `json:"threshold_db"`
+	AttackMs    float64 `json:"attack_ms"`
+	HoldMs      float64 `json:"hold_ms"`
+	ReleaseMs   float64 `json:"release_ms"`
+}
+
+// Compressor is a downward compressor with makeup gain
+type Compressor struct {
+	Enabled     bool    `json:"enabled"`
+	ThresholdDB float64 `json:"threshold_db"`
+	Ratio       float64 `json:"ratio"`
+	AttackMs    float64 `json:"attack_ms"`
+	ReleaseMs   float64 `json:"release_ms"`
+	MakeupDB    float64 `json:"makeup_db"`
+}
+
+// EQ is a multi-band peaking equalizer
+type EQ struct {
+	Enabled bool     `json:"enabled"`
+	Bands   []EQBand `json:"bands"`
+}
+
+// EQBand is a single peaking filter of the EQ
+type EQBand struct {
+	FreqHz float64 `json:"freq_hz"`
+	GainDB float64 `json:"gain_db"`
+	Q      float64 `json:"q"`
+}
+
+// Levels is a level meter reading in dBFS. Clipped stays set for a short
+// time after the signal reached full scale.
+type Levels struct {
+	PeakDB  float64
+	RMSDB   float64
+	Clipped bool
+}
+
+// EQBandFrequencies are the center frequencies used by the built-in presets
+var EQBandFrequencies = []float64{150, 400, 1000, 2000, 3000}
+
+func makeEQ(gains ...float64) EQ {
+	eq := EQ{Enabled: true}
+	for i, freq := range EQBandFrequencies {
+		eq.Bands = append(eq.Bands, EQBand{FreqHz: freq, GainDB: gains[i], Q: 1.0})
+	}
+	return eq
+}
+
+// DefaultTXPresets returns the built-in TX processing presets, keyed by name
+func DefaultTXPresets() map[string]TXProcessing {
+	return map[string]TXProcessing{
+		"Flat": {
+			Enabled:    true,
+			HighPass:   HighPass{Enabled: true, CutoffHz: 60},
+			Gate:       NoiseGate{ThresholdDB: -60, AttackMs: 1, HoldMs: 150, ReleaseMs: 150},
+			Compressor: Compressor{ThresholdDB: -20, Ratio: 2, AttackMs: 5, ReleaseMs: 100},
+			EQ:         makeEQ(0, 0, 0, 0, 0),
+		},
+		"Ragchew": {
+			Enabled:    true,
+			HighPass:   HighPass{Enabled: true, CutoffHz: 80},
+			Gate:       NoiseGate{Enabled: true, ThresholdDB: -55, AttackMs: 1, HoldMs: 200, ReleaseMs: 200},
+			Compressor: Compressor{Enabled: true, ThresholdDB: -20, Ratio: 2, AttackMs: 10, ReleaseMs: 150, MakeupDB: 3},
+			EQ:         makeEQ(1, 0, 0, 1, 1),
+		},
+		"DX": {
+			Enabled:    true,
+			HighPass:   HighPass{Enabled: true, CutoffHz: 150},
+			Gate:       NoiseGate{Enabled: true, ThresholdDB: -50, AttackMs: 1, HoldMs: 150, ReleaseMs: 150},
+			Compressor: Compressor{Enabled: true, ThresholdDB: -24, Ratio: 4, AttackMs: 5, ReleaseMs: 100, MakeupDB: 6},
+			EQ:         makeEQ(-3, -1, 0, 3, 4),
+		},
+		"Contest": {
+			Enabled:    true,
+			HighPass:   HighPass{Enabled: true, CutoffHz: 200},
+			Gate:       NoiseGate{Enabled: true, ThresholdDB: -45, AttackMs: 1, HoldMs: 100, ReleaseMs: 100},
+			Compressor: Compressor{Enabled: true, ThresholdDB: -28, Ratio: 6, AttackMs: 3, ReleaseMs: 80, MakeupDB: 9},
+			EQ:         makeEQ(-6, -2, 0, 5, 6),
+		},
+	}
+}
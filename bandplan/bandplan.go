@@ -0,0 +1,47 @@
+// Package bandplan describes the amateur radio bands.
+package bandplan
+
+// Band is an amateur band
+type Band struct {
+	Name string  // ADIF band name, e.g. "20m"
+	Low  float64 // MHz
+	High float64 // MHz
+}
+
+// Bands are the amateur bands with the edges used by ADIF, which cover all
+// three IARU regions
+var Bands = []Band{
+	{"2190m", 0.1357, 0.1378},
+	{"630m", 0.472, 0.479},
+	{"160m", 1.8, 2.0},
+	{"80m", 3.5, 4.0},
+	{"60m", 5.06, 5.45},
+	{"40m", 7.0, 7.3},
+	{"30m", 10.1, 10.15},
+	{"20m", 14.0, 14.35},
+	{"17m", 18.068, 18.168},
+	{"15m", 21.0, 21.45},
+	{"12m", 24.89, 24.99},
+	{"10m", 28.0, 29.7},
+	{"6m", 50, 54},
+	{"4m", 70, 71},
+	{"2m", 144, 148},
+	{"1.25m", 222, 225},
+	{"70cm", 420, 450},
+}
+
+// Lookup returns the band containing freq (in MHz)
+func Lookup(freq float64) (Band, bool) {
+	for _, b := range Bands {
+		if freq >= b.Low && freq <= b.High {
+			return b, true
+		}
+	}
+	return Band{}, false
+}
+
+// BandName returns the name of the band containing freq (in MHz), or ""
+func BandName(freq float64) string {
+	b, _ := Lookup(freq)
+	return b.Name
+}
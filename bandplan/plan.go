@@ -0,0 +1,183 @@
+package bandplan
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/adrg/xdg"
+)
+
+// Segment modes
+const (
+	ModeCW     = "CW"
+	ModeDigi   = "DIGI"
+	ModePhone  = "PHONE"
+	ModeBeacon = "BEACON"
+)
+
+//go:embed bandplan.json
+var builtinPlan []byte
+
+type Config struct {
+	File    string `dialsdesc:"Band plan JSON file (empty for minstrel/bandplan.json in the config directory, or the built-in plan)" dialsflag:"bandplan-file"`
+	Region  int    `dialsdesc:"IARU region (1, 2 or 3) for the band plan" dialsflag:"region"`
+	License string `dialsdesc:"License class from the band plan whose privileges to check, e.g. us-general (empty for anywhere in the amateur bands)" dialsflag:"license"`
+	Inhibit bool   `dialsdesc:"Refuse to transmit outside the license privileges" dialsflag:"tx-inhibit"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Region:  2,
+		Inhibit: true,
+	}
+}
+
+// Segment is part of a band set aside for one mode. Frequencies are in MHz.
+type Segment struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+	Mode string  `json:"mode"`
+}
+
+// Privilege is a range a license may transmit in, with the modes allowed
+// there. No modes means any mode.
+type Privilege struct {
+	Low   float64  `json:"low"`
+	High  float64  `json:"high"`
+	Modes []string `json:"modes,omitempty"`
+}
+
+// License is a license class in the band plan file
+type License struct {
+	Name       string      `json:"name"`
+	Privileges []Privilege `json:"privileges"`
+}
+
+// planFile is the format of bandplan.json
+type planFile struct {
+	Regions  map[string][]Segment `json:"regions"`
+	Licenses map[string]License   `json:"licenses"`
+}
+
+// Plan is the band plan for one region and license
+type Plan struct {
+	Region     int
+	License    string
+	Segments   []Segment
+	Privileges []Privilege
+}
+
+// Load reads the band plan from cfg.File, minstrel/bandplan.json in the XDG
+// config directory, or the built-in copy, in that order, and selects the
+// configured region and license. Without a license, the privileges are the
+// amateur bands in any mode.
+func Load(cfg *Config) (*Plan, error) {
+	data := builtinPlan
+	path := cfg.File
+	if path == "" {
+		path, _ = xdg.SearchConfigFile("minstrel/bandplan.json")
+	}
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		path = "built-in band plan"
+	}
+
+	var file planFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	segments, ok := file.Regions[strconv.Itoa(cfg.Region)]
+	if !ok {
+		return nil, fmt.Errorf("%s has no region %d", path, cfg.Region)
+	}
+	plan := &Plan{
+		Region:   cfg.Region,
+		License:  cfg.License,
+		Segments: slices.Clone(segments),
+	}
+	slices.SortFunc(plan.Segments, func(a, b Segment) int {
+		return cmp.Compare(a.Low, b.Low)
+	})
+
+	if cfg.License == "" {
+		for _, b := range Bands {
+			plan.Privileges = append(plan.Privileges, Privilege{Low: b.Low, High: b.High})
+		}
+	} else {
+		license, ok := file.Licenses[cfg.License]
+		if !ok {
+			return nil, fmt.Errorf("%s has no license %q", path, cfg.License)
+		}
+		plan.Privileges = slices.Clone(license.Privileges)
+	}
+	slices.SortFunc(plan.Privileges, func(a, b Privilege) int {
+		return cmp.Compare(a.Low, b.Low)
+	})
+	return plan, nil
+}
+
+// SliceMode maps a Flex slice mode to the band plan mode it transmits in
+func SliceMode(flexMode string) string {
+	switch flexMode {
+	case "CW":
+		return ModeCW
+	case "DIGU", "DIGL", "RTTY":
+		return ModeDigi
+	}
+	return ModePhone
+}
+
+// CheckTransmit returns an error if a slice tuned to freq (MHz) in a Flex mode,
+// with filter edges filtLow and filtHigh (Hz), would transmit outside the
+// privileges. The whole filter is checked so that sidebands can't spill over
+// a band or segment edge.
+func (p *Plan) CheckTransmit(freq, filtLow, filtHigh float64, flexMode string) error {
+	low, high := freq+filtLow/1e6, freq+filtHigh/1e6
+	mode := SliceMode(flexMode)
+	if p.Allowed(low, high, mode) {
+		return nil
+	}
+	if p.License == "" {
+		return fmt.Errorf("%s at %.6f MHz (%.6f-%.6f) is outside the amateur bands", flexMode, freq, low, high)
+	}
+	return fmt.Errorf("%s at %.6f MHz (%.6f-%.6f) is outside %s privileges for %s", flexMode, freq, low, high, p.License, mode)
+}
+
+// AllowedRanges returns the ranges where mode may be transmitted, merging
+// adjacent privileges so a signal may straddle the boundary between them
+func (p *Plan) AllowedRanges(mode string) [][2]float64 {
+	var ranges [][2]float64
+	for _, priv := range p.Privileges {
+		if len(priv.Modes) > 0 && !slices.Contains(priv.Modes, mode) {
+			continue
+		}
+		if n := len(ranges); n > 0 && priv.Low <= ranges[n-1][1] {
+			ranges[n-1][1] = max(ranges[n-1][1], priv.High)
+			continue
+		}
+		ranges = append(ranges, [2]float64{priv.Low, priv.High})
+	}
+	return ranges
+}
+
+// Allowed reports whether a signal from low to high (in MHz) in mode is
+// entirely within the privileges
+func (p *Plan) Allowed(low, high float64, mode string) bool {
+	for _, r := range p.AllowedRanges(mode) {
+		if low >= r[0] && high <= r[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package bandplan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testPlan has adjacent, overlapping and separate privileges, some of them
+// restricted to particular modes
+var testPlan = &Plan{
+	Region:  2,
+	License: "test",
+	Privileges: []Privilege{
+		{Low: 3.5, High: 3.7},
+		{Low: 3.6, High: 3.8},
+		{Low: 7.0, High: 7.1, Modes: []string{ModeCW}},
+		{Low: 7.1, High: 7.2, Modes: []string{ModeDigi}},
+		{Low: 14.0, High: 14.15, Modes: []string{ModeCW, ModeDigi}},
+		{Low: 14.15, High: 14.35},
+		{Low: 21.0, High: 21.1},
+		{Low: 21.2, High: 21.3},
+	},
+}
+
+func TestAllowedRanges(t *testing.T) {
+	tests := []struct {
+		mode string
+		want [][2]float64
+	}{
+		{ModeCW, [][2]float64{{3.5, 3.8}, {7.0, 7.1}, {14.0, 14.35}, {21.0, 21.1}, {21.2, 21.3}}},
+		{ModeDigi, [][2]float64{{3.5, 3.8}, {7.1, 7.2}, {14.0, 14.35}, {21.0, 21.1}, {21.2, 21.3}}},
+		{ModePhone, [][2]float64{{3.5, 3.8}, {14.15, 14.35}, {21.0, 21.1}, {21.2, 21.3}}},
+	}
+	for _, tt := range tests {
+		if got := testPlan.AllowedRanges(tt.mode); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AllowedRanges(%s) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		low, high float64
+		mode      string
+		want      bool
+	}{
+		{"inside", 14.2, 14.203, ModePhone, true},
+		{"on the lower edge", 14.15, 14.153, ModePhone, true},
+		{"on the upper edge", 14.347, 14.35, ModePhone, true},
+		{"the whole range", 14.15, 14.35, ModePhone, true},
+		{"below the lower edge", 14.1499, 14.153, ModePhone, false},
+		{"above the upper edge", 14.348, 14.3501, ModePhone, false},
+		{"a point on the edge", 14.35, 14.35, ModePhone, true},
+		{"across adjacent privileges", 14.149, 14.152, ModeDigi, true},
+		{"across adjacent privileges for another mode", 14.149, 14.152, ModePhone, false},
+		{"across overlapping privileges", 3.65, 3.75, ModePhone, true},
+		{"CW across privileges for different modes", 7.099, 7.101, ModeCW, false},
+		{"DIGI across privileges for different modes", 7.099, 7.101, ModeDigi, false},
+		{"across a gap", 21.099, 21.201, ModePhone, false},
+		{"outside every privilege", 13.9, 13.903, ModeCW, false},
+		{"mode not allowed", 7.05, 7.0505, ModePhone, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testPlan.Allowed(tt.low, tt.high, tt.mode); got != tt.want {
+				t.Errorf("Allowed(%v, %v, %s) = %v, want %v", tt.low, tt.high, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTransmit(t *testing.T) {
+	tests := []struct {
+		name              string
+		freq              float64 // MHz
+		filtLow, filtHigh float64 // Hz
+		mode              string
+		want              bool
+	}{
+		{"USB inside", 14.2, 100, 2900, "USB", true},
+		{"USB with the carrier on the edge", 14.15, 0, 2800, "USB", true},
+		{"LSB with the carrier on the edge", 14.35, -2800, 0, "LSB", true},
+		{"USB filter past the upper edge", 14.35, 0, 100, "USB", false},
+		{"LSB carrier inside, passband spilling below", 14.1515, -2900, -100, "LSB", false},
+		{"USB carrier inside, passband spilling above", 14.3485, 100, 2900, "USB", false},
+		{"DIGL carrier on the band edge, passband below it", 14.0, -3000, 0, "DIGL", false},
+		{"DIGU carrier on the band edge, passband above it", 14.0, 0, 3000, "DIGU", true},
+		{"CW filter spilling below the band", 14.0, -250, 250, "CW", false},
+		{"CW filter just inside the band", 14.0003, -250, 250, "CW", true},
+		{"DIGU across adjacent privileges", 14.149, 0, 3000, "DIGU", true},
+		{"USB across adjacent privileges where it isn't allowed", 14.149, 100, 2900, "USB", false},
+		{"CW in a CW segment", 7.05, -250, 250, "CW", true},
+		{"DIGU in a CW segment", 7.05, 0, 3000, "DIGU", false},
+		{"RTTY in a digital segment", 7.15, -250, 250, "RTTY", true},
+		{"DIGU from the CW segment into the digital one", 7.099, 0, 3000, "DIGU", false},
+		{"AM across overlapping privileges", 3.7, -3000, 3000, "AM", true},
+		{"FM across a gap", 21.1, -5000, 5000, "FM", false},
+		{"outside the bands", 13.9, 100, 2900, "USB", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testPlan.CheckTransmit(tt.freq, tt.filtLow, tt.filtHigh, tt.mode)
+			if tt.want && err != nil {
+				t.Errorf("refused: %v", err)
+			} else if !tt.want && err == nil {
+				t.Error("allowed")
+			}
+			if err != nil && !strings.Contains(err.Error(), "test privileges for "+SliceMode(tt.mode)) {
+				t.Errorf("error %q doesn't name the license and mode", err)
+			}
+		})
+	}
+}
+
+// loadBuiltin loads the built-in plan, ignoring any in the config directory
+func loadBuiltin(t *testing.T, license string) *Plan {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bandplan.json")
+	if err := os.WriteFile(path, builtinPlan, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := Load(&Config{File: path, Region: 2, License: license})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+func TestCheckTransmitBuiltin(t *testing.T) {
+	general := loadBuiltin(t, "us-general")
+	extra := loadBuiltin(t, "us-extra")
+	anywhere := loadBuiltin(t, "")
+
+	tests := []struct {
+		name              string
+		plan              *Plan
+		freq              float64
+		filtLow, filtHigh float64
+		mode              string
+		want              bool
+	}{
+		{"General CW filter spilling below 14.025", general, 14.025, -250, 250, "CW", false},
+		{"General CW just above 14.025", general, 14.0255, -250, 250, "CW", true},
+		{"General USB below the phone segment", general, 14.2, 100, 2900, "USB", false},
+		{"General USB on the phone segment edge", general, 14.225, 0, 2800, "USB", true},
+		{"General FT8 on 17m", general, 18.1, 0, 3000, "DIGU", true},
+		{"General FT8 spilling into the 17m phone segment", general, 18.109, 0, 3000, "DIGU", false},
+		{"General FT8 in the 20m phone segment", general, 14.23, 0, 3000, "DIGU", false},
+		{"General CW in the 20m phone segment", general, 14.3, -250, 250, "CW", true},
+		{"General FT8 in the 10m phone segment", general, 28.5, 0, 3000, "DIGU", false},
+		{"General USB spilling into 17m CW/data", general, 18.1101, -200, 2900, "USB", false},
+		{"General USB on 17m", general, 18.13, 100, 2900, "USB", true},
+		{"General CW on 6m", general, 50.09, -250, 250, "CW", true},
+		{"General FT8 in the 6m CW segment", general, 50.09, 0, 3000, "DIGU", false},
+		{"Extra FT8 below 14.150", extra, 14.074, 0, 3000, "DIGU", true},
+		{"Extra DIGU in the 40m phone segment", extra, 7.2, 0, 3000, "DIGU", false},
+		{"Extra USB in the 80m phone segment", extra, 3.65, 100, 2900, "USB", true},
+		{"General USB in the first 60m channel", general, 5.3305, 200, 2800, "USB", true},
+		{"General CW in the middle of a 60m channel", general, 5.332, -250, 250, "CW", true},
+		{"General FT8 in a 60m channel", general, 5.357, 800, 2800, "DIGU", true},
+		{"General USB between 60m channels", general, 5.34, 200, 2800, "USB", false},
+		{"Extra CW between 60m channels", extra, 5.3405, -250, 250, "CW", false},
+		{"General USB spilling out of a 60m channel", general, 5.3305, 100, 3000, "USB", false},
+		{"No license, anywhere in 20m", anywhere, 14.01, 100, 2900, "USB", true},
+		{"No license, past the top of 20m", anywhere, 14.349, 0, 3000, "USB", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.plan.CheckTransmit(tt.freq, tt.filtLow, tt.filtHigh, tt.mode)
+			if tt.want && err != nil {
+				t.Errorf("refused: %v", err)
+			} else if !tt.want && err == nil {
+				t.Error("allowed")
+			}
+		})
+	}
+
+	err := anywhere.CheckTransmit(13.9, 100, 2900, "USB")
+	if err == nil || !strings.Contains(err.Error(), "outside the amateur bands") {
+		t.Errorf("CheckTransmit outside the bands without a license = %v", err)
+	}
+}
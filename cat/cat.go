@@ -0,0 +1,182 @@
+// Package cat emulates a Kenwood TS-2000 style CAT interface on a virtual
+// serial port, for programs that can only control a radio over serial.
+package cat
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+type Config struct {
+	Enabled bool   `dialsdesc:"Create a virtual serial port with Kenwood-style CAT control" dialsflag:"cat"`
+	Link    string `dialsdesc:"Symlink to create pointing at the CAT serial port, e.g. /tmp/minstrel-cat" dialsflag:"cat-link"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{}
+}
+
+// Server answers CAT commands on a pseudo-terminal. VFO A and VFO B are
+// slices A and B.
+type Server struct {
+	cfg    *Config
+	shim   radioshim.Shim
+	events chan events.Event
+
+	writeMu sync.Mutex
+	master  *os.File
+
+	mu           sync.Mutex
+	autoInfo     bool
+	transmitting bool
+	last         map[string]sliceState
+}
+
+// sliceState is what auto-information reports changes of
+type sliceState struct {
+	freq float64
+	mode string
+}
+
+// NewServer creates a server
+func NewServer(cfg *Config, shim radioshim.Shim, bus *events.Bus) *Server {
+	return &Server{
+		cfg:    cfg,
+		shim:   shim,
+		events: bus.Subscribe(10),
+		last:   make(map[string]sliceState),
+	}
+}
+
+// Run creates the serial port and answers commands until ctx is cancelled
+func (s *Server) Run(ctx context.Context) error {
+	master, slave, err := openPTY()
+	if err != nil {
+		return err
+	}
+	defer slave.Close()
+	s.master = master
+
+	log.Println("CAT serial port at", slave.Name())
+	if s.cfg.Link != "" {
+		if err := replaceLink(slave.Name(), s.cfg.Link); err != nil {
+			log.Printf("Failed to link %s to CAT port: %v", s.cfg.Link, err)
+		} else {
+			defer os.Remove(s.cfg.Link)
+		}
+	}
+
+	go s.trackEvents(ctx)
+	go func() {
+		<-ctx.Done()
+		master.Close()
+	}()
+
+	buf := make([]byte, 256)
+	var pending strings.Builder
+	for {
+		n, err := master.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for _, b := range buf[:n] {
+			switch {
+			case b == ';':
+				if reply := s.Execute(pending.String()); reply != "" {
+					s.write(reply)
+				}
+				pending.Reset()
+			case b > ' ' && pending.Len() < 64:
+				pending.WriteByte(b)
+			}
+		}
+	}
+}
+
+// replaceLink points link at target, replacing a stale symlink but never a
+// regular file
+func replaceLink(target, link string) error {
+	if info, err := os.Lstat(link); err == nil {
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return errors.New("not a symlink")
+		}
+		if err := os.Remove(link); err != nil {
+			return err
+		}
+	}
+	return os.Symlink(target, link)
+}
+
+func (s *Server) write(reply string) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := s.master.Write([]byte(reply)); err != nil {
+		log.Println("CAT write error:", err)
+	}
+}
+
+// trackEvents follows transmit state and sends auto-information when slices
+// A and B change
+func (s *Server) trackEvents(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.events:
+			var push []string
+			switch e := event.(type) {
+			case events.SlicesUpdated:
+				push = s.slicesChanged(e.Slices)
+			case events.TransmitStateChanged:
+				s.mu.Lock()
+				s.transmitting = e.Transmitting
+				autoInfo := s.autoInfo
+				s.mu.Unlock()
+				if autoInfo {
+					push = []string{s.info()}
+				}
+			}
+			for _, reply := range push {
+				s.write(reply)
+			}
+		}
+	}
+}
+
+// slicesChanged records the new slice state and returns the auto-information
+// replies for what changed
+func (s *Server) slicesChanged(slices radioshim.SliceMap) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var push []string
+	for _, letter := range []string{"A", "B"} {
+		var state sliceState
+		if slice, ok := slices[letter]; ok {
+			state = sliceState{freq: slice.Freq, mode: slice.Mode}
+		}
+		prev := s.last[letter]
+		s.last[letter] = state
+		if !s.autoInfo || state.mode == "" {
+			continue
+		}
+		if state.freq != prev.freq {
+			push = append(push, formatFreq("F"+letter, state.freq))
+		}
+		if state.mode != prev.mode && letter == s.rxLetter(slices) {
+			push = append(push, "MD"+modeCode(state.mode)+";")
+		}
+	}
+	return push
+}
@@ -0,0 +1,197 @@
+package cat
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+// errorReply is the Kenwood reply to a command that can't be executed
+const errorReply = "?;"
+
+// Kenwood mode codes, following SmartSDR CAT for the digital modes
+var modeCodes = map[string]string{
+	"LSB":  "1",
+	"USB":  "2",
+	"CW":   "3",
+	"FM":   "4",
+	"NFM":  "4",
+	"DFM":  "4",
+	"AM":   "5",
+	"SAM":  "5",
+	"DIGL": "6",
+	"RTTY": "6",
+	"DIGU": "9",
+}
+
+var codeModes = map[string]string{
+	"1": "LSB",
+	"2": "USB",
+	"3": "CW",
+	"4": "FM",
+	"5": "AM",
+	"6": "DIGL",
+	"7": "CW",
+	"9": "DIGU",
+}
+
+func modeCode(mode string) string {
+	if code, ok := modeCodes[mode]; ok {
+		return code
+	}
+	return "2"
+}
+
+// formatFreq formats an FA/FB reply; freq is in MHz
+func formatFreq(cmd string, freq float64) string {
+	return fmt.Sprintf("%s%011d;", cmd, int64(math.Round(freq*1e6)))
+}
+
+// Execute runs one command (without its terminating ';') and returns the
+// reply, which is empty for successful set commands
+func (s *Server) Execute(cmd string) string {
+	if len(cmd) < 2 {
+		return errorReply
+	}
+	name, arg := cmd[:2], cmd[2:]
+	slices := s.shim.GetSlices()
+
+	switch name {
+	case "FA", "FB":
+		slice, ok := slices[name[1:]]
+		if !ok {
+			return errorReply
+		}
+		if arg == "" {
+			return formatFreq(name, slice.Freq)
+		}
+		hz, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || hz <= 0 {
+			return errorReply
+		}
+		s.shim.TuneSlice(slice, float64(hz)/1e6, false)
+		return ""
+	case "MD":
+		slice, ok := slices[s.rxLetter(slices)]
+		if !ok {
+			return errorReply
+		}
+		if arg == "" {
+			return "MD" + modeCode(slice.Mode) + ";"
+		}
+		mode, ok := codeModes[arg]
+		if !ok {
+			return errorReply
+		}
+		s.shim.SetSliceMode(slice.Index, mode)
+		return ""
+	case "FR", "FT":
+		return s.vfoCommand(name, arg, slices)
+	case "IF":
+		return s.info()
+	case "TX":
+		s.shim.SetPTT(true)
+		return ""
+	case "RX":
+		s.shim.SetPTT(false)
+		return ""
+	case "AI":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch arg {
+		case "":
+			if s.autoInfo {
+				return "AI2;"
+			}
+			return "AI0;"
+		case "0":
+			s.autoInfo = false
+		case "1", "2", "3":
+			s.autoInfo = true
+		default:
+			return errorReply
+		}
+		return ""
+	case "ID":
+		return "ID019;" // TS-2000
+	case "PS":
+		return "PS1;"
+	}
+	return errorReply
+}
+
+// vfoCommand handles FR (receive VFO) and FT (transmit VFO), where 0 is VFO A
+// and 1 is VFO B
+func (s *Server) vfoCommand(name, arg string, slices radioshim.SliceMap) string {
+	if arg == "" {
+		letter := s.rxLetter(slices)
+		if name == "FT" {
+			letter = txLetter(slices)
+		}
+		if letter == "B" {
+			return name + "1;"
+		}
+		return name + "0;"
+	}
+
+	letter := map[string]string{"0": "A", "1": "B"}[arg]
+	slice, ok := slices[letter]
+	if !ok {
+		return errorReply
+	}
+	if name == "FR" {
+		s.shim.ActivateSlice(slice.Index)
+	} else {
+		s.shim.SetSliceTX(slice.Index)
+	}
+	return ""
+}
+
+// rxLetter returns the receive VFO: the active slice if it is A or B, else A
+func (s *Server) rxLetter(slices radioshim.SliceMap) string {
+	if slice, ok := slices["B"]; ok && slice.Active {
+		return "B"
+	}
+	return "A"
+}
+
+func txLetter(slices radioshim.SliceMap) string {
+	for letter, slice := range slices {
+		if slice.TX {
+			return letter
+		}
+	}
+	return "A"
+}
+
+// info builds the 38-character TS-2000 IF reply for the receive VFO
+func (s *Server) info() string {
+	slices := s.shim.GetSlices()
+	rx := s.rxLetter(slices)
+	var freq float64
+	mode := "USB"
+	if slice, ok := slices[rx]; ok {
+		freq, mode = slice.Freq, slice.Mode
+	}
+
+	s.mu.Lock()
+	transmitting := s.transmitting
+	s.mu.Unlock()
+
+	tx, vfo, split := 0, 0, 0
+	if transmitting {
+		tx = 1
+	}
+	if rx == "B" {
+		vfo = 1
+	}
+	if txLetter(slices) != rx {
+		split = 1
+	}
+	// frequency, step, RIT/XIT offset, RIT, XIT, memory bank and channel,
+	// TX, mode, VFO, scan, split, tone, tone number, shift
+	return fmt.Sprintf("IF%011d%5s%+05d%d%d%d%02d%d%s%d%d%d%d%02d%d;",
+		int64(math.Round(freq*1e6)), "", 0, 0, 0, 0, 0, tx, modeCode(mode), vfo, 0, split, 0, 0, 0)
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package cat
+
+import (
+	"errors"
+	"os"
+)
+
+func openPTY() (master, slave *os.File, err error) {
+	return nil, nil, errors.New("virtual serial ports are only supported on Linux")
+}
@@ -0,0 +1,92 @@
+// fakecluster is a local DX cluster for trying out Minstrel's DX cluster
+// client. It asks for a callsign like a real cluster, then sends canned spots
+// (with the current time) in a loop.
+//
+//	go run ./cmd/fakecluster -listen localhost:7300
+//	./minstrel --dxcluster-host localhost:7300 --dxcluster-call N0CALL
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Spots to send: spotter, frequency in kHz, callsign and comment
+var cannedSpots = [][4]string{
+	{"W3LPL", "14025.0", "JA1ABC", "CW 599"},
+	{"K1TTT-#", "7012.5", "DL1XYZ", "CW 18 dB 26 WPM CQ"},
+	{"N2IC", "14205.0", "VK2DEF", "SSB up 5"},
+	{"VE3EJ", "14074.0", "ZL1GHI", "FT8 -12 dB"},
+	{"G4ABC", "21295.0", "PY2JKL", "USB 59"},
+	{"KC2G", "7185.0", "KH6MNO", "LSB"},
+	{"AA4VV-#", "10112.3", "EA8PQR", "CW 22 dB 30 WPM"},
+	{"DK8NE", "18100.0", "5B4STU", "FT8"},
+	{"WA1VWX", "28450.0", "LU1YZA", "10m open"},
+	{"OH2BH", "3525.0", "UA9BCD", "CW"},
+}
+
+func spotLine(spot [4]string, now time.Time) string {
+	line := fmt.Sprintf("DX de %-10s%8s  %-13s%-30s", spot[0]+":", spot[1], spot[2], spot[3])
+	return fmt.Sprintf("%-70s%sZ", line, now.UTC().Format("1504"))
+}
+
+func serve(conn net.Conn, interval time.Duration, lines []string) {
+	defer conn.Close()
+	fmt.Fprint(conn, "Welcome to the Minstrel test cluster\r\n\r\nlogin: ")
+	call, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	call = strings.TrimSpace(call)
+	log.Printf("%s logged in as %s", conn.RemoteAddr(), call)
+	fmt.Fprintf(conn, "Hello %s\r\n%s de FAKECLUSTER >\r\n", call, call)
+
+	for i := 0; ; i++ {
+		var line string
+		if lines != nil {
+			line = lines[i%len(lines)]
+		} else {
+			line = spotLine(cannedSpots[i%len(cannedSpots)], time.Now())
+		}
+		if _, err := fmt.Fprint(conn, line+"\r\n"); err != nil {
+			log.Printf("%s disconnected", conn.RemoteAddr())
+			return
+		}
+		time.Sleep(interval)
+	}
+}
+
+func main() {
+	listen := flag.String("listen", "localhost:7300", "Address to listen on")
+	interval := flag.Duration("interval", 3*time.Second, "Time between spots")
+	file := flag.String("file", "", "File of lines to send instead of the built-in spots")
+	flag.Parse()
+
+	var lines []string
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("fake DX cluster listening on", listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Fatal(err)
+		}
+		go serve(conn, *interval, lines)
+	}
+}
@@ -0,0 +1,62 @@
+package contest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kc2g-flex-tools/minstrel/logbook"
+)
+
+// Cabrillo frequency field for QSOs above 30 MHz, which use the band
+// instead of the frequency in kHz
+var vhfBands = map[string]string{
+	"6m":    "50",
+	"4m":    "70",
+	"2m":    "144",
+	"1.25m": "222",
+	"70cm":  "432",
+}
+
+func cabrilloFreq(q logbook.QSO) string {
+	if band, ok := vhfBands[q.Band]; ok {
+		return band
+	}
+	return strconv.Itoa(int(q.Freq*1000 + 0.5))
+}
+
+// WriteCabrillo writes qsos as a Cabrillo 3 log for callsign. The category
+// headers are left for the operator to add.
+func WriteCabrillo(w io.Writer, contestID, callsign string, qsos []logbook.QSO) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "START-OF-LOG: 3.0\n")
+	fmt.Fprintf(bw, "CONTEST: %s\n", contestID)
+	fmt.Fprintf(bw, "CALLSIGN: %s\n", callsign)
+	fmt.Fprintf(bw, "CREATED-BY: Minstrel\n")
+	for _, q := range qsos {
+		t := q.Time.UTC()
+		fmt.Fprintf(bw, "QSO: %5s %s %s %-13s %-10s %-13s %s\n",
+			cabrilloFreq(q), modeGroup(q.Mode), t.Format("2006-01-02 1504"),
+			callsign, q.ExchSent, q.Call, strings.Join(strings.Fields(q.ExchRcvd), " "))
+	}
+	fmt.Fprintf(bw, "END-OF-LOG:\n")
+	return bw.Flush()
+}
+
+// ExportCabrillo writes the session's QSOs to a Cabrillo file at path,
+// returning the number of QSOs written
+func (s *Session) ExportCabrillo(path, callsign string) (int, error) {
+	qsos := s.QSOs()
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := WriteCabrillo(f, s.Template.ID, strings.ToUpper(callsign), qsos); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return len(qsos), f.Close()
+}
@@ -0,0 +1,66 @@
+package contest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/logbook"
+)
+
+func TestWriteCabrillo(t *testing.T) {
+	qsos := []logbook.QSO{
+		{Time: time.Date(2025, 5, 24, 0, 1, 30, 0, time.UTC), Call: "K1AB", Freq: 14.0255, Band: "20m", Mode: "CW", ExchSent: "599 001", ExchRcvd: "599 12"},
+		{Time: time.Date(2025, 5, 24, 0, 3, 0, 0, time.UTC), Call: "DL1ABC/P", Freq: 7.1874, Band: "40m", Mode: "SSB", ExchSent: "59 002", ExchRcvd: " 59   1234 "},
+		{Time: time.Date(2025, 5, 24, 13, 59, 59, 0, time.FixedZone("EDT", -4*3600)), Call: "N2FM", Freq: 50.125, Band: "6m", Mode: "FM", ExchSent: "59 003", ExchRcvd: "59 7"},
+		{Time: time.Date(2025, 5, 25, 23, 59, 0, 0, time.UTC), Call: "VE3XY", Freq: 3.58, Band: "80m", Mode: "RTTY", ExchSent: "599 004", ExchRcvd: "599 5"},
+		{Time: time.Date(2025, 5, 25, 23, 59, 0, 0, time.UTC), Call: "W9XYZ", Freq: 28.074, Band: "10m", Mode: "FT8", ExchSent: "59 005", ExchRcvd: "59 6"},
+	}
+	var b strings.Builder
+	if err := WriteCabrillo(&b, "CQ-WPX-CW", "KC2G", qsos); err != nil {
+		t.Fatal(err)
+	}
+	want := `START-OF-LOG: 3.0
+CONTEST: CQ-WPX-CW
+CALLSIGN: KC2G
+CREATED-BY: Minstrel
+QSO: 14026 CW 2025-05-24 0001 KC2G          599 001    K1AB          599 12
+QSO:  7187 PH 2025-05-24 0003 KC2G          59 002     DL1ABC/P      59 1234
+QSO:    50 FM 2025-05-24 1759 KC2G          59 003     N2FM          59 7
+QSO:  3580 RY 2025-05-25 2359 KC2G          599 004    VE3XY         599 5
+QSO: 28074 DG 2025-05-25 2359 KC2G          59 005     W9XYZ         59 6
+END-OF-LOG:
+`
+	if got := b.String(); got != want {
+		t.Errorf("WriteCabrillo =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportCabrillo(t *testing.T) {
+	clock := start.Add(time.Hour)
+	s := newSession(t, wpx, &clock)
+	add(t, s, logbook.QSO{Time: start.Add(time.Minute), Call: "W9XYZ", Freq: 14.03, Mode: "CW", ExchRcvd: "599 3"})
+	add(t, s, logbook.QSO{Time: start, Call: "K1AB", Freq: 14.025, Mode: "CW", ExchRcvd: "599 12"})
+	if _, err := s.Log.Add(logbook.QSO{Time: start, Call: "N0CALL", Freq: 14.2, Mode: "SSB"}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "wpx.log")
+	n, err := s.ExportCabrillo(path, "kc2g")
+	if err != nil || n != 2 {
+		t.Fatalf("ExportCabrillo = %d, %v; want 2", n, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Oldest first, only the contest's QSOs, under the callsign in capitals
+	want := "QSO: 14025 CW 2025-05-24 0000 KC2G          599 002    K1AB          599 12\n" +
+		"QSO: 14030 CW 2025-05-24 0001 KC2G          599 001    W9XYZ         599 3\n" +
+		"END-OF-LOG:\n"
+	if got := string(data); !strings.HasSuffix(got, want) || !strings.Contains(got, "CALLSIGN: KC2G\n") {
+		t.Errorf("exported\n%s\nwant it to end\n%s", got, want)
+	}
+}
@@ -0,0 +1,192 @@
+// Package contest adds contest operating on top of the logbook: exchange
+// templates, serial numbers, dupe checking, rates and Cabrillo export.
+package contest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"github.com/kc2g-flex-tools/minstrel/logbook"
+)
+
+// Template describes a contest's sent exchange. In Exchange, {rst} is
+// replaced by the usual report for the mode and {serial} by the serial number.
+type Template struct {
+	Name     string `json:"name"`
+	ID       string `json:"id"` // Cabrillo CONTEST name
+	Exchange string `json:"exchange"`
+}
+
+// Builtin templates. Exchanges with a zone, power or location are examples
+// to edit before the contest.
+var Builtin = []Template{
+	{Name: "Serial number", ID: "", Exchange: "{rst} {serial}"},
+	{Name: "CQ WPX SSB", ID: "CQ-WPX-SSB", Exchange: "{rst} {serial}"},
+	{Name: "CQ WPX CW", ID: "CQ-WPX-CW", Exchange: "{rst} {serial}"},
+	{Name: "CQ WW SSB", ID: "CQ-WW-SSB", Exchange: "{rst} 5"},
+	{Name: "CQ WW CW", ID: "CQ-WW-CW", Exchange: "{rst} 5"},
+	{Name: "IARU HF", ID: "IARU-HF", Exchange: "{rst} 8"},
+	{Name: "ARRL Field Day", ID: "ARRL-FD", Exchange: "1A EMA"},
+	{Name: "ARRL DX (W/VE)", ID: "ARRL-DX-SSB", Exchange: "{rst} MA"},
+}
+
+// LoadTemplates returns the builtin templates followed by any in
+// minstrel/contests.json in the XDG config directory, which is a JSON list of
+// templates. A user template with the same name as a builtin one replaces it.
+func LoadTemplates() ([]Template, error) {
+	templates := slices.Clone(Builtin)
+	path, err := xdg.SearchConfigFile("minstrel/contests.json")
+	if err != nil {
+		// No user templates
+		return templates, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return templates, err
+	}
+	var user []Template
+	if err := json.Unmarshal(data, &user); err != nil {
+		return templates, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	for _, t := range user {
+		idx := slices.IndexFunc(templates, func(b Template) bool { return b.Name == t.Name })
+		if idx >= 0 {
+			templates[idx] = t
+		} else {
+			templates = append(templates, t)
+		}
+	}
+	return templates, nil
+}
+
+// report is the signal report sent in contests, which is always 59 or 599
+// even in modes that don't normally use RST
+func report(mode string) string {
+	if rst := logbook.DefaultRST(mode); rst != "" {
+		return rst
+	}
+	return "59"
+}
+
+// Render fills in an exchange template for a QSO in the given ADIF mode
+func Render(exchange, mode string, serial int) string {
+	return strings.NewReplacer(
+		"{rst}", report(mode),
+		"{serial}", fmt.Sprintf("%03d", serial),
+	).Replace(exchange)
+}
+
+// modeGroup is the mode category used for dupe checking and Cabrillo
+func modeGroup(mode string) string {
+	switch mode {
+	case "CW":
+		return "CW"
+	case "SSB", "AM", "DIGITALVOICE":
+		return "PH"
+	case "FM":
+		return "FM"
+	case "RTTY":
+		return "RY"
+	}
+	return "DG"
+}
+
+// How far back QSOs with the same contest ID count as part of the current
+// contest; no contest runs for longer than 48 hours
+const contestLength = 48 * time.Hour
+
+// Session is a contest in progress. Its QSOs are kept in the logbook, tagged
+// with the template's ID, so serials and dupes survive restarts.
+type Session struct {
+	Template Template
+	// Exchange is the sent exchange template, which may have been edited
+	Exchange string
+	Log      *logbook.Logbook
+
+	now func() time.Time
+}
+
+// NewSession starts or resumes a contest
+func NewSession(lb *logbook.Logbook, t Template) *Session {
+	return &Session{
+		Template: t,
+		Exchange: t.Exchange,
+		Log:      lb,
+		now:      time.Now,
+	}
+}
+
+// QSOs returns the contest's QSOs, oldest first
+func (s *Session) QSOs() []logbook.QSO {
+	since := s.now().Add(-contestLength)
+	id := strings.ToUpper(s.Template.ID)
+	qsos := s.Log.All()
+	qsos = slices.DeleteFunc(qsos, func(q logbook.QSO) bool {
+		return q.Contest != id || q.Time.Before(since) || (id == "" && q.SerialSent == 0)
+	})
+	slices.SortFunc(qsos, func(a, b logbook.QSO) int {
+		if c := a.Time.Compare(b.Time); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	return qsos
+}
+
+// NextSerial is the serial number to send in the next QSO
+func (s *Session) NextSerial() int {
+	next := 1
+	for _, q := range s.QSOs() {
+		next = max(next, q.SerialSent+1)
+	}
+	return next
+}
+
+// Dupe returns an earlier QSO with call on the same band and in the same mode
+// category, if there is one
+func (s *Session) Dupe(call, band, mode string) (logbook.QSO, bool) {
+	call = strings.ToUpper(strings.TrimSpace(call))
+	for _, q := range s.QSOs() {
+		if q.Call == call && q.Band == band && modeGroup(q.Mode) == modeGroup(mode) {
+			return q, true
+		}
+	}
+	return logbook.QSO{}, false
+}
+
+// Add logs a contest QSO, filling in the serial number and sent exchange
+func (s *Session) Add(q logbook.QSO) (logbook.QSO, error) {
+	q.Contest = s.Template.ID
+	q.SerialSent = s.NextSerial()
+	q.ExchSent = Render(s.Exchange, q.Mode, q.SerialSent)
+	if strings.Contains(s.Exchange, "{rst}") {
+		q.RSTSent = report(q.Mode)
+		// The received report is the first part of the exchange if it
+		// looks like one
+		if fields := strings.Fields(q.ExchRcvd); len(fields) > 0 && len(fields[0]) == len(q.RSTSent) {
+			if _, err := strconv.Atoi(fields[0]); err == nil {
+				q.RSTRcvd = fields[0]
+			}
+		}
+	}
+	return s.Log.Add(q)
+}
+
+// Rate is the number of QSOs per hour over the period before now
+func Rate(qsos []logbook.QSO, now time.Time, period time.Duration) float64 {
+	since := now.Add(-period)
+	count := 0
+	for _, q := range qsos {
+		if q.Time.After(since) && !q.Time.After(now) {
+			count++
+		}
+	}
+	return float64(count) * float64(time.Hour) / float64(period)
+}
@@ -0,0 +1,234 @@
+package contest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/logbook"
+)
+
+var wpx = Template{Name: "CQ WPX CW", ID: "CQ-WPX-CW", Exchange: "{rst} {serial}"}
+
+// start is when the contests in these tests start
+var start = time.Date(2025, 5, 24, 0, 0, 0, 0, time.UTC)
+
+// newSession starts t in a new logbook, at a time that follows clock
+func newSession(t *testing.T, tmpl Template, clock *time.Time) *Session {
+	t.Helper()
+	lb, err := logbook.OpenFile(filepath.Join(t.TempDir(), "logbook.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resume(t, lb.Path(), tmpl, clock)
+}
+
+// resume reopens the logbook at path and resumes t, as after a restart
+func resume(t *testing.T, path string, tmpl Template, clock *time.Time) *Session {
+	t.Helper()
+	lb, err := logbook.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSession(lb, tmpl)
+	s.now = func() time.Time { return *clock }
+	return s
+}
+
+func add(t *testing.T, s *Session, q logbook.QSO) logbook.QSO {
+	t.Helper()
+	q, err := s.Add(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		exchange, mode string
+		serial         int
+		want           string
+	}{
+		{"{rst} {serial}", "CW", 7, "599 007"},
+		{"{rst} {serial}", "SSB", 42, "59 042"},
+		{"{rst} {serial}", "RTTY", 1234, "599 1234"},
+		// Modes without RST still send 59
+		{"{rst} {serial}", "FT8", 1, "59 001"},
+		{"{rst} {serial}", "", 1, "59 001"},
+		{"{rst} 5", "CW", 3, "599 5"},
+		{"1A EMA", "SSB", 3, "1A EMA"},
+		{"{serial} {serial}", "CW", 12, "012 012"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.exchange, tt.mode, tt.serial); got != tt.want {
+			t.Errorf("Render(%q, %s, %d) = %q, want %q", tt.exchange, tt.mode, tt.serial, got, tt.want)
+		}
+	}
+}
+
+func TestNextSerial(t *testing.T) {
+	clock := start
+	s := newSession(t, wpx, &clock)
+	if n := s.NextSerial(); n != 1 {
+		t.Fatalf("first serial = %d, want 1", n)
+	}
+	for i, call := range []string{"K1AB", "W9XYZ", "VE3XY"} {
+		clock = start.Add(time.Duration(i) * time.Minute)
+		q := add(t, s, logbook.QSO{Time: clock, Call: call, Freq: 14.025, Mode: "CW", ExchRcvd: "599 1"})
+		if q.SerialSent != i+1 {
+			t.Errorf("QSO %d sent serial %d", i+1, q.SerialSent)
+		}
+	}
+	// QSOs outside the contest don't take a serial
+	if _, err := s.Log.Add(logbook.QSO{Time: clock, Call: "N0CALL", Freq: 14.2, Mode: "SSB"}); err != nil {
+		t.Fatal(err)
+	}
+	other := NewSession(s.Log, Template{Name: "CQ WW CW", ID: "CQ-WW-CW", Exchange: "{rst} 5"})
+	other.now = s.now
+	if n := other.NextSerial(); n != 1 {
+		t.Errorf("another contest's next serial = %d, want 1", n)
+	}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  int
+	}{
+		{"after a restart", time.Hour, 4},
+		{"the next day", 30 * time.Hour, 4},
+		{"just inside 48 hours", 48*time.Hour + time.Minute, 4},
+		{"the next year", 365 * 24 * time.Hour, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := start.Add(tt.after)
+			if n := resume(t, s.Log.Path(), wpx, &clock).NextSerial(); n != tt.want {
+				t.Errorf("next serial = %d, want %d", n, tt.want)
+			}
+		})
+	}
+
+	// The window runs from the last QSO that's still inside it
+	clock = start.Add(48*time.Hour + 90*time.Second)
+	s = resume(t, s.Log.Path(), wpx, &clock)
+	if n := s.NextSerial(); n != 4 {
+		t.Errorf("next serial with only the last QSO inside 48 hours = %d, want 4", n)
+	}
+	if qsos := s.QSOs(); len(qsos) != 1 || qsos[0].Call != "VE3XY" {
+		t.Errorf("QSOs inside 48 hours = %+v, want VE3XY's", qsos)
+	}
+}
+
+func TestSerialWithoutContestID(t *testing.T) {
+	// The plain serial number template counts only QSOs that sent one
+	clock := start
+	s := newSession(t, Builtin[0], &clock)
+	if _, err := s.Log.Add(logbook.QSO{Time: start, Call: "N0CALL", Freq: 14.2, Mode: "SSB"}); err != nil {
+		t.Fatal(err)
+	}
+	q := add(t, s, logbook.QSO{Time: start, Call: "K1AB", Freq: 14.2, Mode: "SSB", ExchRcvd: "59 001"})
+	if q.SerialSent != 1 || q.ExchSent != "59 001" {
+		t.Errorf("first QSO sent %q with serial %d, want 59 001", q.ExchSent, q.SerialSent)
+	}
+	if n := s.NextSerial(); n != 2 {
+		t.Errorf("next serial = %d, want 2", n)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	clock := start
+	s := newSession(t, wpx, &clock)
+	tests := []struct {
+		mode, exchRcvd       string
+		exchSent, sent, rcvd string
+	}{
+		{"CW", "579 12", "599 001", "599", "579"},
+		{"CW", " 5NN 12", "599 002", "599", ""},
+		{"SSB", "57 3", "59 003", "59", "57"},
+		{"SSB", "599 3", "59 004", "59", ""},
+		{"CW", "12", "599 005", "599", ""},
+	}
+	for i, tt := range tests {
+		q := add(t, s, logbook.QSO{Time: start.Add(time.Duration(i) * time.Minute), Call: "K1AB", Freq: 14.025, Mode: tt.mode, ExchRcvd: tt.exchRcvd})
+		if q.ExchSent != tt.exchSent || q.RSTSent != tt.sent || q.RSTRcvd != tt.rcvd || q.Contest != "CQ-WPX-CW" {
+			t.Errorf("%s QSO receiving %q = sent %q (%q), received %q, contest %q; want %q (%q), %q",
+				tt.mode, tt.exchRcvd, q.ExchSent, q.RSTSent, q.RSTRcvd, q.Contest, tt.exchSent, tt.sent, tt.rcvd)
+		}
+	}
+
+	// Without {rst} the reports are left alone
+	fd := newSession(t, Template{Name: "ARRL Field Day", ID: "ARRL-FD", Exchange: "1A EMA"}, &clock)
+	q := add(t, fd, logbook.QSO{Time: start, Call: "K1AB", Freq: 14.2, Mode: "SSB", ExchRcvd: "59 2A WI"})
+	if q.ExchSent != "1A EMA" || q.RSTSent != "" || q.RSTRcvd != "" {
+		t.Errorf("Field Day QSO sent %q, reports %q/%q; want 1A EMA and no reports", q.ExchSent, q.RSTSent, q.RSTRcvd)
+	}
+}
+
+func TestDupe(t *testing.T) {
+	clock := start.Add(time.Hour)
+	s := newSession(t, wpx, &clock)
+	for _, q := range []logbook.QSO{
+		{Time: start, Call: "K1AB", Freq: 14.025, Mode: "CW"},
+		{Time: start, Call: "W9XYZ", Freq: 14.2, Mode: "SSB", Submode: "USB"},
+		{Time: start, Call: "VE3XY", Freq: 14.074, Mode: "FT8"},
+		{Time: start, Call: "DL1ABC", Freq: 14.08, Mode: "RTTY"},
+		{Time: start, Call: "N2FM", Freq: 29.6, Mode: "FM"},
+	} {
+		add(t, s, q)
+	}
+	// Not part of the contest
+	if _, err := s.Log.Add(logbook.QSO{Time: start, Call: "K2XX", Freq: 14.025, Mode: "CW"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		call, band, mode string
+		want             bool
+	}{
+		{"K1AB", "20m", "CW", true},
+		{" k1ab ", "20m", "CW", true},
+		{"K1AB", "40m", "CW", false},
+		{"K1AB", "20m", "SSB", false},
+		{"W9XYZ", "20m", "SSB", true},
+		{"W9XYZ", "20m", "AM", true},
+		{"W9XYZ", "20m", "DIGITALVOICE", true},
+		{"W9XYZ", "20m", "FM", false},
+		{"VE3XY", "20m", "MFSK", true},
+		{"VE3XY", "20m", "", true},
+		{"VE3XY", "20m", "RTTY", false},
+		{"DL1ABC", "20m", "RTTY", true},
+		{"DL1ABC", "20m", "FT8", false},
+		{"N2FM", "10m", "FM", true},
+		{"N2FM", "10m", "SSB", false},
+		{"K2XX", "20m", "CW", false},
+	}
+	for _, tt := range tests {
+		q, dupe := s.Dupe(tt.call, tt.band, tt.mode)
+		if dupe != tt.want {
+			t.Errorf("Dupe(%q, %s, %s) = %v, want %v", tt.call, tt.band, tt.mode, dupe, tt.want)
+		} else if dupe && q.SerialSent == 0 {
+			t.Errorf("Dupe(%q, %s, %s) returned %+v, not the contest QSO", tt.call, tt.band, tt.mode, q)
+		}
+	}
+
+	// Two days on, it's another running of the contest
+	clock = start.Add(49 * time.Hour)
+	if _, dupe := s.Dupe("K1AB", "20m", "CW"); dupe {
+		t.Error("QSO from two days ago is a dupe")
+	}
+}
+
+func TestRate(t *testing.T) {
+	now := start.Add(time.Hour)
+	var qsos []logbook.QSO
+	for _, ago := range []time.Duration{0, time.Minute, 9 * time.Minute, 10 * time.Minute, 30 * time.Minute, 2 * time.Hour, -time.Minute} {
+		qsos = append(qsos, logbook.QSO{Time: now.Add(-ago)})
+	}
+	if r := Rate(qsos, now, 10*time.Minute); r != 18 {
+		t.Errorf("10 minute rate = %v, want 18", r)
+	}
+	if r := Rate(qsos, now, time.Hour); r != 5 {
+		t.Errorf("hourly rate = %v, want 5", r)
+	}
+}
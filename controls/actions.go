@@ -0,0 +1,99 @@
+// Package controls carries out the actions that hardware controllers (MIDI
+// devices, jog wheels, foot switches) can be mapped to.
+package controls
+
+import (
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+// Actions that can be mapped
+const (
+	ActionTune        = "tune"
+	ActionVolume      = "volume"
+	ActionFilterWidth = "filter_width"
+	ActionRIT         = "rit"
+	ActionZoom        = "zoom"
+	ActionPTT         = "ptt"
+	ActionMOX         = "mox"
+	ActionTuneCarrier = "tune_carrier"
+	ActionVOX         = "vox"
+	ActionMute        = "mute"
+	ActionModeCycle   = "mode_cycle"
+	ActionSliceA      = "slice_a"
+	ActionSliceB      = "slice_b"
+	ActionZoomIn      = "zoom_in"
+	ActionZoomOut     = "zoom_out"
+	ActionScan        = "scan"
+)
+
+// ActionInfo describes a mappable action
+type ActionInfo struct {
+	Name        string
+	Description string
+	// Relative actions are driven by a knob or wheel reporting how far it
+	// turned. The others are buttons, which are pressed and released.
+	Relative bool
+}
+
+// Actions lists the mappable actions, in the order the UI shows them
+var Actions = []ActionInfo{
+	{ActionTune, "Tune", true},
+	{ActionVolume, "Volume", true},
+	{ActionFilterWidth, "Filter width", true},
+	{ActionRIT, "RIT", true},
+	{ActionZoom, "Zoom", true},
+	{ActionPTT, "PTT (hold)", false},
+	{ActionMOX, "MOX", false},
+	{ActionTuneCarrier, "TUNE", false},
+	{ActionVOX, "VOX", false},
+	{ActionMute, "Mute", false},
+	{ActionModeCycle, "Next mode", false},
+	{ActionSliceA, "Select slice A", false},
+	{ActionSliceB, "Select slice B", false},
+	{ActionZoomIn, "Zoom in", false},
+	{ActionZoomOut, "Zoom out", false},
+	{ActionScan, "Stop/resume scan", false},
+}
+
+// LookupAction returns the description of the named action
+func LookupAction(name string) (ActionInfo, bool) {
+	for _, action := range Actions {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return ActionInfo{}, false
+}
+
+// Adjustment sizes for the relative actions
+const (
+	filterWidthStep = 50 // Hz per encoder click
+	minFilterWidth  = 50 // Hz
+	ritStep         = 10 // Hz per encoder click
+)
+
+// activeSlice returns the active slice, or nil
+func activeSlice(sliceMap radioshim.SliceMap) *radioshim.SliceData {
+	for _, slice := range sliceMap {
+		if slice.Present && slice.Active {
+			return slice
+		}
+	}
+	return nil
+}
+
+// widenFilter widens (or with a negative by, narrows) a filter by by Hz. The
+// edge nearest the carrier stays put for sideband filters, and centered
+// filters widen on both sides.
+func widenFilter(lo, hi, by int) (int, int) {
+	switch {
+	case lo >= 0:
+		hi += by
+	case hi <= 0:
+		lo -= by
+	default:
+		lo -= by / 2
+		hi += by - by/2
+	}
+	return lo, hi
+}
@@ -0,0 +1,218 @@
+package controls
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/accel"
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+// How often accumulated knob movement is sent to the radio (max 20 per second)
+const rateLimit = 50 * time.Millisecond
+
+// adjustment is a relative action's movement
+type adjustment struct {
+	action string
+	delta  int
+}
+
+// Dispatcher carries out actions for one controller. Knob movements are
+// accumulated and sent to the radio at most every rateLimit while Run is
+// running.
+type Dispatcher struct {
+	shim        radioshim.Shim
+	bus         *events.Bus
+	curve       accel.Curve
+	adjustments chan adjustment
+	// Whether a PTT button is down, so PTT can be dropped if the controller
+	// goes away before it's released
+	pttHeld atomic.Bool
+}
+
+// NewDispatcher creates a dispatcher acting through shim, with tuning
+// accelerated by curve
+func NewDispatcher(shim radioshim.Shim, bus *events.Bus, curve accel.Curve) *Dispatcher {
+	return &Dispatcher{
+		shim:        shim,
+		bus:         bus,
+		curve:       curve,
+		adjustments: make(chan adjustment, 100), // Buffered channel for non-blocking sends
+	}
+}
+
+// Adjust moves a relative action by delta. It doesn't block.
+func (d *Dispatcher) Adjust(action string, delta int) {
+	select {
+	case d.adjustments <- adjustment{action, delta}:
+	default:
+		// Channel full - drop event (should be rare with 100 buffer)
+		log.Printf("Warning: control channel full, dropping %s event", action)
+	}
+}
+
+// Run applies adjustments, rate limited, until ctx is done. Movement still
+// waiting to be sent then is sent before it returns.
+func (d *Dispatcher) Run(ctx context.Context) {
+	// Accumulated deltas for each relative action
+	accumulators := make(map[string]int)
+	vfo := accel.NewAccelerator(d.curve)
+
+	timer := time.NewTimer(rateLimit)
+	timer.Stop() // Stop initially, will be started on first event
+	timerActive := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.drain(accumulators)
+			d.apply(accumulators, vfo)
+			return
+
+		case adj := <-d.adjustments:
+			accumulators[adj.action] += adj.delta
+
+			if !timerActive {
+				// No pending timer - send immediately and start timer
+				d.apply(accumulators, vfo)
+				timer.Reset(rateLimit)
+				timerActive = true
+			}
+			// If timer is active, just accumulate (will be sent when timer fires)
+
+		case <-timer.C:
+			// Timer expired - send any accumulated events
+			if len(accumulators) > 0 {
+				d.apply(accumulators, vfo)
+				// Restart timer in case more events come
+				timer.Reset(rateLimit)
+			} else {
+				// No accumulated events - mark timer inactive
+				timerActive = false
+			}
+		}
+	}
+}
+
+// drain adds the adjustments waiting to be read to accumulators
+func (d *Dispatcher) drain(accumulators map[string]int) {
+	for {
+		select {
+		case adj := <-d.adjustments:
+			accumulators[adj.action] += adj.delta
+		default:
+			return
+		}
+	}
+}
+
+// apply applies accumulated adjustments to the radio, and clears them
+func (d *Dispatcher) apply(accumulators map[string]int, vfo *accel.Accelerator) {
+	rs := d.shim
+	slice := activeSlice(rs.GetSlices())
+	for action, delta := range accumulators {
+		delete(accumulators, action)
+		if delta == 0 {
+			continue
+		}
+		switch action {
+		case ActionZoom:
+			if delta > 0 {
+				rs.ZoomIn()
+			} else {
+				rs.ZoomOut()
+			}
+			continue
+		}
+		if slice == nil {
+			continue
+		}
+		switch action {
+		case ActionTune:
+			rs.TuneSliceStep(slice, vfo.Apply(delta, time.Now()))
+		case ActionVolume:
+			// Volume uses the full accumulated delta (no multiplier),
+			// clamped to the valid range (0-100)
+			rs.SetSliceVolume(slice.Index, min(max(slice.Volume+delta, 0), 100))
+		case ActionFilterWidth:
+			lo, hi := widenFilter(int(slice.FiltLow), int(slice.FiltHigh), delta*filterWidthStep)
+			if hi-lo >= minFilterWidth {
+				rs.SetSliceFilter(slice.Index, lo, hi)
+			}
+		case ActionRIT:
+			// Turning RIT back to zero turns it off
+			offset := slice.RITOffset + delta*ritStep
+			rs.SetSliceRIT(slice.Index, offset != 0, offset)
+		}
+	}
+}
+
+// Press handles a button action being pressed or released
+func (d *Dispatcher) Press(action string, pressed bool) {
+	rs := d.shim
+	if action == ActionPTT {
+		if pressed {
+			d.pttHeld.Store(true)
+			rs.SetPTT(true)
+		} else if d.pttHeld.Swap(false) {
+			rs.SetPTT(false)
+		}
+		return
+	}
+	if !pressed {
+		return
+	}
+
+	// These wait for the radio, so don't hold up the controller
+	go func() {
+		switch action {
+		case ActionMOX:
+			transmitting, _, _ := rs.GetTransmitState()
+			rs.SetPTT(!transmitting)
+		case ActionTuneCarrier:
+			_, tuning, _ := rs.GetTransmitState()
+			rs.SetTune(!tuning)
+		case ActionVOX:
+			_, _, vox := rs.GetTransmitState()
+			rs.SetVOX(!vox)
+		case ActionMute:
+			if slice := activeSlice(rs.GetSlices()); slice != nil {
+				rs.SetSliceMute(slice.Index, !slice.Muted)
+			}
+		case ActionModeCycle:
+			if slice := activeSlice(rs.GetSlices()); slice != nil && len(slice.Modes) > 0 {
+				next := (slices.Index(slice.Modes, slice.Mode) + 1) % len(slice.Modes)
+				rs.SetSliceMode(slice.Index, slice.Modes[next])
+			}
+		case ActionSliceA, ActionSliceB:
+			letter := "A"
+			if action == ActionSliceB {
+				letter = "B"
+			}
+			if slice, ok := rs.GetSlices()[letter]; ok && slice.Present {
+				rs.ActivateSlice(slice.Index)
+			}
+		case ActionZoomIn:
+			rs.ZoomIn()
+		case ActionZoomOut:
+			rs.ZoomOut()
+		case ActionScan:
+			d.bus.Publish(events.ScanToggleRequested{})
+		}
+	}()
+}
+
+// ReleasePTT drops PTT if a PTT button is down, for when the controller goes
+// away before it's released. It reports whether it did.
+func (d *Dispatcher) ReleasePTT() bool {
+	if !d.pttHeld.Swap(false) {
+		return false
+	}
+	d.shim.SetPTT(false)
+	return true
+}
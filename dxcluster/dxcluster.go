@@ -0,0 +1,244 @@
+// Package dxcluster implements a telnet DX cluster client that collects spots
+// and publishes them on the event bus.
+package dxcluster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+)
+
+type Config struct {
+	Host     string        `dialsdesc:"DX cluster address, e.g. dxc.example.org:7300 (empty to disable)" dialsflag:"dxcluster-host"`
+	Callsign string        `dialsdesc:"Callsign to log in to the DX cluster with" dialsflag:"dxcluster-call"`
+	Bands    []string      `dialsdesc:"Only show DX cluster spots on these bands, e.g. 20m,40m (empty for all)" dialsflag:"dxcluster-bands"`
+	Modes    []string      `dialsdesc:"Only show DX cluster spots in these modes: CW, PHONE, DIGI (empty for all)" dialsflag:"dxcluster-modes"`
+	MaxAge   time.Duration `dialsdesc:"How long DX cluster spots are shown for" dialsflag:"dxcluster-max-age"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		MaxAge: 15 * time.Minute,
+	}
+}
+
+const (
+	// How long to wait for a login prompt before sending the callsign anyway
+	loginTimeout = 10 * time.Second
+	// How long to wait before reconnecting after losing the connection
+	reconnectDelay = 30 * time.Second
+	// How often to check for expired spots
+	expiryInterval = 30 * time.Second
+)
+
+// Client keeps a connection to a DX cluster open and tracks the spots it
+// sends. Spots for the same callsign on the same band replace each other.
+type Client struct {
+	cfg *Config
+	bus *events.Bus
+
+	mu    sync.Mutex
+	spots map[string]events.DXSpot
+}
+
+func NewClient(cfg *Config, bus *events.Bus) *Client {
+	return &Client{
+		cfg:   cfg,
+		bus:   bus,
+		spots: map[string]events.DXSpot{},
+	}
+}
+
+// Run connects to the cluster, reconnecting whenever the connection is lost,
+// until ctx is cancelled
+func (c *Client) Run(ctx context.Context) error {
+	if c.cfg.Callsign == "" {
+		return errors.New("a callsign (--dxcluster-call) is required to log in to the DX cluster")
+	}
+	go c.expireSpots(ctx)
+
+	for {
+		err := c.session(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("DX cluster connection lost: %v, reconnecting in %v", err, reconnectDelay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// session logs in and reads spots until the connection fails
+func (c *Client) session(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	log.Println("connected to DX cluster", c.cfg.Host)
+
+	var loggedIn atomic.Bool
+	login := func() {
+		if loggedIn.CompareAndSwap(false, true) {
+			conn.Write([]byte(c.cfg.Callsign + "\r\n"))
+		}
+	}
+	timer := time.AfterFunc(loginTimeout, login)
+	defer timer.Stop()
+
+	buf := make([]byte, 4096)
+	var pending []byte
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, stripTelnet(buf[:n])...)
+		for {
+			idx := bytes.IndexByte(pending, '\n')
+			if idx < 0 {
+				break
+			}
+			line := strings.TrimRight(string(pending[:idx]), "\r")
+			pending = pending[idx+1:]
+			if !loggedIn.Load() && isLoginPrompt(line) {
+				login()
+				continue
+			}
+			c.handleLine(line)
+		}
+		// Login prompts usually aren't followed by a newline
+		if !loggedIn.Load() && isLoginPrompt(string(pending)) {
+			login()
+			pending = nil
+		}
+	}
+}
+
+func isLoginPrompt(text string) bool {
+	text = strings.ToLower(strings.TrimSpace(text))
+	return strings.HasSuffix(text, "login:") || strings.HasSuffix(text, "call:") || strings.HasSuffix(text, "callsign:")
+}
+
+// stripTelnet removes telnet option negotiation from data. We don't answer
+// any options, which clusters accept. Commands split across reads aren't
+// handled.
+func stripTelnet(data []byte) []byte {
+	const (
+		iac  = 255
+		sb   = 250
+		se   = 240
+		will = 251
+		dont = 254
+	)
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] != iac {
+			out = append(out, data[i])
+			continue
+		}
+		if i+1 >= len(data) {
+			break
+		}
+		switch cmd := data[i+1]; {
+		case cmd == iac:
+			out = append(out, iac)
+			i++
+		case cmd >= will && cmd <= dont:
+			i += 2
+		case cmd == sb:
+			// Skip the subnegotiation up to IAC SE
+			end := bytes.Index(data[i:], []byte{iac, se})
+			if end < 0 {
+				return out
+			}
+			i += end + 1
+		default:
+			i++
+		}
+	}
+	return out
+}
+
+func (c *Client) handleLine(line string) {
+	spot, ok := ParseSpot(line, time.Now().UTC())
+	if !ok || !c.wanted(spot) {
+		return
+	}
+	c.mu.Lock()
+	c.spots[spot.Call+"/"+spot.Band] = spot
+	c.mu.Unlock()
+	c.publish()
+}
+
+// wanted applies the configured band and mode filters
+func (c *Client) wanted(spot events.DXSpot) bool {
+	if len(c.cfg.Bands) > 0 && !slices.ContainsFunc(c.cfg.Bands, func(b string) bool { return strings.EqualFold(b, spot.Band) }) {
+		return false
+	}
+	if len(c.cfg.Modes) > 0 && !slices.ContainsFunc(c.cfg.Modes, func(m string) bool { return strings.EqualFold(m, spot.Mode) }) {
+		return false
+	}
+	return time.Since(spot.Time) < c.cfg.MaxAge
+}
+
+func (c *Client) expireSpots(ctx context.Context) {
+	ticker := time.NewTicker(expiryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			expired := false
+			for key, spot := range c.spots {
+				if time.Since(spot.Time) >= c.cfg.MaxAge {
+					delete(c.spots, key)
+					expired = true
+				}
+			}
+			c.mu.Unlock()
+			if expired {
+				c.publish()
+			}
+		}
+	}
+}
+
+// publish sends the current spots, ordered by frequency
+func (c *Client) publish() {
+	c.mu.Lock()
+	spots := make([]events.DXSpot, 0, len(c.spots))
+	for _, spot := range c.spots {
+		spots = append(spots, spot)
+	}
+	c.mu.Unlock()
+	slices.SortFunc(spots, func(a, b events.DXSpot) int {
+		switch {
+		case a.Freq < b.Freq:
+			return -1
+		case a.Freq > b.Freq:
+			return 1
+		}
+		return strings.Compare(a.Call, b.Call)
+	})
+	c.bus.Publish(events.DXSpotsUpdated{Spots: spots})
+}
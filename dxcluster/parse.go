@@ -0,0 +1,158 @@
+package dxcluster
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/bandplan"
+	"github.com/kc2g-flex-tools/minstrel/events"
+)
+
+// Spot modes, used for filtering. They're the same as the band plan's.
+const (
+	ModeCW    = bandplan.ModeCW
+	ModePhone = bandplan.ModePhone
+	ModeDigi  = bandplan.ModeDigi
+)
+
+// Where the phone segment starts on each band, in kHz. Spots below it are
+// assumed to be CW unless the comment says otherwise. 60m starts at the
+// lowest US channel, although the band runs lower elsewhere.
+var phoneStart = map[string]float64{
+	"160m": 1840,
+	"80m":  3600,
+	"60m":  5330,
+	"40m":  7125,
+	"30m":  10150,
+	"20m":  14150,
+	"17m":  18110,
+	"15m":  21200,
+	"12m":  24930,
+	"10m":  28300,
+	"6m":   50100,
+	"2m":   144100,
+}
+
+// FT8 and FT4 dial frequencies in kHz. Spots within a few kHz above them are
+// assumed to be digital.
+var digiFrequencies = []float64{
+	1840, 3573, 3575, 5357, 7047.5, 7074, 10136, 10140, 14074, 14080,
+	18100, 18104, 21074, 21140, 24915, 24919, 28074, 28180, 50313, 50318,
+}
+
+// Comment keywords that identify the mode of a spot
+var modeKeywords = map[string]string{
+	"CW":     ModeCW,
+	"SSB":    ModePhone,
+	"USB":    ModePhone,
+	"LSB":    ModePhone,
+	"AM":     ModePhone,
+	"FM":     ModePhone,
+	"FT8":    ModeDigi,
+	"FT4":    ModeDigi,
+	"RTTY":   ModeDigi,
+	"PSK":    ModeDigi,
+	"PSK31":  ModeDigi,
+	"JT65":   ModeDigi,
+	"JS8":    ModeDigi,
+	"MSK144": ModeDigi,
+	"Q65":    ModeDigi,
+	"OLIVIA": ModeDigi,
+}
+
+// spotMode determines a spot's mode from its comment, falling back to a guess
+// based on the frequency
+func spotMode(freq float64, comment string) string {
+	for _, word := range strings.Fields(strings.ToUpper(comment)) {
+		if mode, ok := modeKeywords[word]; ok {
+			return mode
+		}
+	}
+	for _, digi := range digiFrequencies {
+		if freq >= digi && freq < digi+3 {
+			return ModeDigi
+		}
+	}
+	start, ok := phoneStart[bandplan.BandName(freq/1000)]
+	switch {
+	case !ok:
+		return ""
+	case freq < start:
+		return ModeCW
+	}
+	return ModePhone
+}
+
+// isSpotTime reports whether field is a spot time like "1234Z"
+func isSpotTime(field string) bool {
+	if len(field) != 5 || field[4] != 'Z' {
+		return false
+	}
+	for _, c := range field[:4] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return field[:2] < "24" && field[2:4] < "60"
+}
+
+// spotTime converts an HHMM time to the most recent such time at or before now
+// (allowing for a little clock skew)
+func spotTime(hhmm string, now time.Time) time.Time {
+	minutes, _ := strconv.Atoi(hhmm)
+	t := time.Date(now.Year(), now.Month(), now.Day(), minutes/100, minutes%100, 0, 0, time.UTC)
+	latest := now.Add(time.Minute)
+	if t.After(latest) {
+		t = t.AddDate(0, 0, -1)
+	} else if tomorrow := t.AddDate(0, 0, 1); !tomorrow.After(latest) {
+		// Just after midnight by a clock that's a little fast
+		t = tomorrow
+	}
+	return t
+}
+
+// ParseSpot parses a standard cluster spot line such as
+//
+//	DX de W3LPL:     14025.0  JA1ABC       CW 599 tnx QSO          1234Z
+//
+// now is used to resolve the spot's time to a date.
+func ParseSpot(line string, now time.Time) (events.DXSpot, bool) {
+	if !strings.HasPrefix(line, "DX de ") {
+		return events.DXSpot{}, false
+	}
+	spotter, rest, ok := strings.Cut(line[len("DX de "):], ":")
+	if !ok {
+		return events.DXSpot{}, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) < 2 {
+		return events.DXSpot{}, false
+	}
+	freq, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || !(freq > 0) || math.IsInf(freq, 0) {
+		return events.DXSpot{}, false
+	}
+
+	spot := events.DXSpot{
+		Spotter: strings.TrimSpace(spotter),
+		Call:    fields[1],
+		Freq:    freq / 1000,
+		Band:    bandplan.BandName(freq / 1000),
+		Time:    now,
+	}
+	// The comment runs from after the callsign to the time, which may be
+	// followed by a grid locator
+	comment := fields[2:]
+	for i := len(comment) - 1; i >= 0; i-- {
+		if isSpotTime(comment[i]) {
+			spot.Time = spotTime(comment[i][:4], now)
+			comment = comment[:i]
+			break
+		}
+	}
+	spot.Comment = strings.Join(comment, " ")
+	spot.Mode = spotMode(freq, spot.Comment)
+	return spot, true
+}
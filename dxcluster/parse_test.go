@@ -0,0 +1,311 @@
+package dxcluster
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+)
+
+func TestParseSpot(t *testing.T) {
+	now := time.Date(2025, 3, 10, 13, 0, 0, 0, time.UTC)
+	at := func(hour, min int) time.Time {
+		return time.Date(2025, 3, 10, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want events.DXSpot
+	}{
+		{
+			name: "CW",
+			line: "DX de W3LPL:     14025.0  JA1ABC       CW 599 tnx QSO          1234Z",
+			want: events.DXSpot{Spotter: "W3LPL", Call: "JA1ABC", Freq: 14.025, Band: "20m", Mode: ModeCW, Comment: "CW 599 tnx QSO", Time: at(12, 34)},
+		},
+		{
+			name: "grid after the time",
+			line: "DX de K1TTT-#:    7010.0  DL1ABC       24 dB 22 WPM CQ           1259Z FN42",
+			want: events.DXSpot{Spotter: "K1TTT-#", Call: "DL1ABC", Freq: 7.010, Band: "40m", Mode: ModeCW, Comment: "24 dB 22 WPM CQ", Time: at(12, 59)},
+		},
+		{
+			name: "phone by frequency",
+			line: "DX de G4ABC:     14205.0  VK2XYZ       59 in London             1130Z",
+			want: events.DXSpot{Spotter: "G4ABC", Call: "VK2XYZ", Freq: 14.205, Band: "20m", Mode: ModePhone, Comment: "59 in London", Time: at(11, 30)},
+		},
+		{
+			name: "FT8 dial frequency",
+			line: "DX de EA8BFK:    14075.5  PY2XYZ       -10 dB 1500 Hz           1230Z",
+			want: events.DXSpot{Spotter: "EA8BFK", Call: "PY2XYZ", Freq: 14.0755, Band: "20m", Mode: ModeDigi, Comment: "-10 dB 1500 Hz", Time: at(12, 30)},
+		},
+		{
+			name: "FT4 dial frequency on 40m",
+			line: "DX de N1MM:       7048.2  LU1ABC       -5 dB                    1201Z",
+			want: events.DXSpot{Spotter: "N1MM", Call: "LU1ABC", Freq: 7.0482, Band: "40m", Mode: ModeDigi, Comment: "-5 dB", Time: at(12, 1)},
+		},
+		{
+			name: "6m FT8 dial frequency",
+			line: "DX de W1AW:      50313.9  K5ABC        EM10 -15 dB              1145Z",
+			want: events.DXSpot{Spotter: "W1AW", Call: "K5ABC", Freq: 50.3139, Band: "6m", Mode: ModeDigi, Comment: "EM10 -15 dB", Time: at(11, 45)},
+		},
+		{
+			name: "just above a digital window",
+			line: "DX de W1AW:      14077.0  K5ABC                                 1145Z",
+			want: events.DXSpot{Spotter: "W1AW", Call: "K5ABC", Freq: 14.077, Band: "20m", Mode: ModeCW, Time: at(11, 45)},
+		},
+		{
+			name: "comment keyword beats the frequency",
+			line: "DX de W1AW:      14074.5  K5ABC        cw up 1                  1145Z",
+			want: events.DXSpot{Spotter: "W1AW", Call: "K5ABC", Freq: 14.0745, Band: "20m", Mode: ModeCW, Comment: "cw up 1", Time: at(11, 45)},
+		},
+		{
+			name: "RTTY keyword in the CW segment",
+			line: "DX de W1AW:      14085.0  K5ABC        RTTY                     1145Z",
+			want: events.DXSpot{Spotter: "W1AW", Call: "K5ABC", Freq: 14.085, Band: "20m", Mode: ModeDigi, Comment: "RTTY", Time: at(11, 45)},
+		},
+		{
+			name: "outside the amateur bands",
+			line: "DX de SWL:       11000.0  RADIO1       broadcast                1145Z",
+			want: events.DXSpot{Spotter: "SWL", Call: "RADIO1", Freq: 11.0, Comment: "broadcast", Time: at(11, 45)},
+		},
+		{
+			name: "no time",
+			line: "DX de W1AW:      21025.0  ZS6ABC",
+			want: events.DXSpot{Spotter: "W1AW", Call: "ZS6ABC", Freq: 21.025, Band: "15m", Mode: ModeCW, Time: now},
+		},
+		{
+			name: "invalid time is part of the comment",
+			line: "DX de W1AW:      21025.0  ZS6ABC       QRV 2575Z",
+			want: events.DXSpot{Spotter: "W1AW", Call: "ZS6ABC", Freq: 21.025, Band: "15m", Mode: ModeCW, Comment: "QRV 2575Z", Time: now},
+		},
+		{
+			name: "spot from yesterday",
+			line: "DX de W1AW:      21025.0  ZS6ABC                                2330Z",
+			want: events.DXSpot{Spotter: "W1AW", Call: "ZS6ABC", Freq: 21.025, Band: "15m", Mode: ModeCW, Time: time.Date(2025, 3, 9, 23, 30, 0, 0, time.UTC)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spot, ok := ParseSpot(tt.line, now)
+			if !ok {
+				t.Fatal("not parsed")
+			}
+			// The frequency is converted from kHz, so it may not be exact
+			if math.Abs(spot.Freq-tt.want.Freq) < 1e-9 {
+				spot.Freq = tt.want.Freq
+			}
+			if spot != tt.want {
+				t.Errorf("ParseSpot =\n%+v\nwant\n%+v", spot, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSpotMalformed(t *testing.T) {
+	now := time.Date(2025, 3, 10, 13, 0, 0, 0, time.UTC)
+	for _, line := range []string{
+		"",
+		"W1AW de K1TTT 10-Mar-2025 1300Z arrl >",
+		"WWV de VE7CC <18>:   SFI=150, A=5, K=1, No Storms -> No Storms",
+		"To ALL de W1AW: QRV on 20m",
+		"DX de W3LPL     14025.0  JA1ABC       CW                      1234Z",
+		"DX de W3LPL:",
+		"DX de W3LPL:     14025.0",
+		"DX de W3LPL:     14.025.0  JA1ABC",
+		"DX de W3LPL:     JA1ABC  14025.0",
+		"DX de W3LPL:     0  JA1ABC",
+		"DX de W3LPL:     -14025.0  JA1ABC",
+		"DX de W3LPL:     Inf  JA1ABC",
+		"DX de W3LPL:     NaN  JA1ABC",
+		"dx de W3LPL:     14025.0  JA1ABC",
+	} {
+		if spot, ok := ParseSpot(line, now); ok {
+			t.Errorf("ParseSpot(%q) = %+v, want failure", line, spot)
+		}
+	}
+}
+
+func TestSpotMode(t *testing.T) {
+	tests := []struct {
+		freq    float64 // kHz
+		comment string
+		want    string
+	}{
+		{1830, "", ModeCW},
+		{1840, "", ModeDigi},
+		{1850, "", ModePhone},
+		{3573, "", ModeDigi},
+		{3575.9, "", ModeDigi},
+		{3590, "", ModeCW},
+		{3700, "", ModePhone},
+		{5332, "", ModePhone},
+		{5357, "", ModeDigi},
+		{5403.5, "", ModePhone},
+		{7074, "", ModeDigi},
+		{7076.9, "", ModeDigi},
+		{7077, "", ModeCW},
+		{7200, "", ModePhone},
+		{10136, "", ModeDigi},
+		{10140, "", ModeDigi},
+		{10120, "", ModeCW},
+		{14150, "", ModePhone},
+		{14149.9, "", ModeCW},
+		{18100, "", ModeDigi},
+		{21074, "", ModeDigi},
+		{21140, "", ModeDigi},
+		{24915, "", ModeDigi},
+		{28074, "", ModeDigi},
+		{28180, "", ModeDigi},
+		{28500, "", ModePhone},
+		{50318, "", ModeDigi},
+		{144300, "", ModePhone},
+		{11000, "", ""},
+		{14200, "FT8 -12", ModeDigi},
+		{14074, "tnx CW", ModeCW},
+		{14020, "up 5 ssb", ModePhone},
+		{7030, "PSK31", ModeDigi},
+		{7030, "cwt", ModeCW},
+		{11000, "FM", ModePhone},
+	}
+	for _, tt := range tests {
+		if got := spotMode(tt.freq, tt.comment); got != tt.want {
+			t.Errorf("spotMode(%v, %q) = %q, want %q", tt.freq, tt.comment, got, tt.want)
+		}
+	}
+}
+
+func TestIsSpotTime(t *testing.T) {
+	tests := []struct {
+		field string
+		want  bool
+	}{
+		{"1234Z", true},
+		{"0000Z", true},
+		{"2359Z", true},
+		{"2400Z", false},
+		{"1260Z", false},
+		{"1234z", false},
+		{"1234", false},
+		{"123Z", false},
+		{"12345Z", false},
+		{"-123Z", false},
+		{"+123Z", false},
+		{"12a4Z", false},
+		{"FN42Z", false},
+	}
+	for _, tt := range tests {
+		if got := isSpotTime(tt.field); got != tt.want {
+			t.Errorf("isSpotTime(%q) = %v, want %v", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestSpotTime(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		hhmm string
+		want time.Time
+	}{
+		{
+			name: "earlier today",
+			now:  time.Date(2025, 3, 10, 13, 0, 0, 0, time.UTC),
+			hhmm: "1234",
+			want: time.Date(2025, 3, 10, 12, 34, 0, 0, time.UTC),
+		},
+		{
+			name: "now",
+			now:  time.Date(2025, 3, 10, 13, 0, 30, 0, time.UTC),
+			hhmm: "1300",
+			want: time.Date(2025, 3, 10, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "a minute ahead is clock skew",
+			now:  time.Date(2025, 3, 10, 13, 0, 0, 0, time.UTC),
+			hhmm: "1301",
+			want: time.Date(2025, 3, 10, 13, 1, 0, 0, time.UTC),
+		},
+		{
+			name: "more than a minute ahead is yesterday",
+			now:  time.Date(2025, 3, 10, 13, 0, 0, 0, time.UTC),
+			hhmm: "1302",
+			want: time.Date(2025, 3, 9, 13, 2, 0, 0, time.UTC),
+		},
+		{
+			name: "before midnight, just after it",
+			now:  time.Date(2025, 3, 10, 0, 5, 0, 0, time.UTC),
+			hhmm: "2358",
+			want: time.Date(2025, 3, 9, 23, 58, 0, 0, time.UTC),
+		},
+		{
+			name: "after midnight, just after it",
+			now:  time.Date(2025, 3, 10, 0, 5, 0, 0, time.UTC),
+			hhmm: "0001",
+			want: time.Date(2025, 3, 10, 0, 1, 0, 0, time.UTC),
+		},
+		{
+			name: "skew across midnight",
+			now:  time.Date(2025, 3, 9, 23, 59, 30, 0, time.UTC),
+			hhmm: "0000",
+			want: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "previous year",
+			now:  time.Date(2025, 1, 1, 0, 1, 0, 0, time.UTC),
+			hhmm: "2359",
+			want: time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC),
+		},
+		{
+			name: "previous month",
+			now:  time.Date(2025, 3, 1, 0, 10, 0, 0, time.UTC),
+			hhmm: "2345",
+			want: time.Date(2025, 2, 28, 23, 45, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spotTime(tt.hhmm, tt.now); !got.Equal(tt.want) {
+				t.Errorf("spotTime(%q, %v) = %v, want %v", tt.hhmm, tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripTelnet(t *testing.T) {
+	const (
+		iac  = 255
+		sb   = 250
+		se   = 240
+		nop  = 241
+		will = 251
+		do   = 253
+		dont = 254
+		echo = 1
+		ttyp = 24
+	)
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte
+	}{
+		{"plain text", []byte("login: "), []byte("login: ")},
+		{"empty", nil, []byte{}},
+		{"option negotiation", []byte{iac, will, echo, 'l', 'o', 'g', 'i', 'n', ':', iac, do, ttyp}, []byte("login:")},
+		{"don't", []byte{'a', iac, dont, echo, 'b'}, []byte("ab")},
+		{"escaped IAC", []byte{'a', iac, iac, 'b'}, []byte{'a', iac, 'b'}},
+		{"two-byte command", []byte{'a', iac, nop, 'b'}, []byte("ab")},
+		{"subnegotiation", []byte{'a', iac, sb, ttyp, 1, iac, se, 'b'}, []byte("ab")},
+		{"unterminated subnegotiation", []byte{'a', iac, sb, ttyp, 1, 'b'}, []byte("a")},
+		{"IAC at the end", []byte{'a', iac}, []byte("a")},
+		{"option cut off", []byte{'a', iac, will}, []byte("a")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripTelnet(tt.in); !bytes.Equal(got, tt.want) {
+				t.Errorf("stripTelnet(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
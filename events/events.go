@@ -1,6 +1,9 @@
 package events
 
 import (
+	"sync"
+	"time"
+
 	"github.com/kc2g-flex-tools/minstrel/radioshim"
 )
 
@@ -59,6 +62,22 @@ type VOXStateChanged struct {
 	Enabled bool
 }
 
+// TransmitInhibited is fired when PTT or VOX is refused, or turned off,
+// because the TX slice is outside our privileges or by the TX timeout or
+// watchdog
+type TransmitInhibited struct {
+	baseEvent
+	Action string // "PTT", "TUNE" or "VOX"
+	Reason string
+}
+
+// TXTimeoutWarning counts down to the TX timeout. It is fired every second
+// during the warning period, and with zero Remaining once transmitting stops.
+type TXTimeoutWarning struct {
+	baseEvent
+	Remaining time.Duration
+}
+
 // TransmitParamsChanged is fired when transmit parameters change
 type TransmitParamsChanged struct {
 	baseEvent
@@ -95,8 +114,104 @@ type RadioDisconnected struct {
 	Error string
 }
 
-// Bus provides simple event publish/subscribe
+// DXSpot is a spot received from a DX cluster
+type DXSpot struct {
+	Spotter string
+	Call    string
+	Freq    float64 // MHz
+	Band    string  // e.g. "20m", empty if outside the amateur bands
+	Mode    string  // "CW", "PHONE", "DIGI", or empty if unknown
+	Comment string
+	Time    time.Time
+}
+
+// DXSpotsUpdated is fired with the current (filtered, unexpired) DX cluster
+// spots whenever a spot arrives or expires
+type DXSpotsUpdated struct {
+	baseEvent
+	Spots []DXSpot
+}
+
+// RadioSpotsUpdated is fired with all of the radio's spots whenever one is
+// added, changed or removed
+type RadioSpotsUpdated struct {
+	baseEvent
+	Spots []radioshim.SpotData
+}
+
+// WSJTXDecode is a decode received from WSJT-X
+type WSJTXDecode struct {
+	Client        string        // WSJT-X instance that sent it
+	Time          time.Duration // since midnight UTC
+	SNR           int
+	DT            float64
+	DF            int     // audio offset in Hz
+	DialFreq      float64 // MHz
+	Mode          string  // WSJT-X's mode character, e.g. "~" for FT8
+	Message       string
+	LowConfidence bool
+	Call          string // the station transmitting
+	CQ            bool
+	ToMe          bool // addressed to the WSJT-X operator
+	Received      time.Time
+}
+
+// WSJTXDecodesUpdated is fired with the decodes from the last couple of
+// periods whenever one arrives or expires
+type WSJTXDecodesUpdated struct {
+	baseEvent
+	Decodes []WSJTXDecode
+}
+
+// WSJTXReplyRequested is fired to have WSJT-X answer a decode
+type WSJTXReplyRequested struct {
+	baseEvent
+	Decode WSJTXDecode
+}
+
+// ScanSettings describes a scan: either the frequencies from Low to High in
+// Step, or a list of frequencies (all in MHz)
+type ScanSettings struct {
+	Low   float64
+	High  float64
+	Step  float64
+	Freqs []float64 // used instead of Low, High and Step if not empty
+	Dwell time.Duration
+	Hang  time.Duration
+	// Waterfall brightness (0-255) above the black level that stops the scan
+	Threshold int
+}
+
+// ScanRequested is fired to start scanning with the active slice
+type ScanRequested struct {
+	baseEvent
+	Settings ScanSettings
+}
+
+// ScanStopRequested is fired to stop scanning
+type ScanStopRequested struct {
+	baseEvent
+}
+
+// ScanToggleRequested is fired to stop scanning, or resume the last scan
+type ScanToggleRequested struct {
+	baseEvent
+}
+
+// ScanStateChanged is fired when the scanner starts, stops, moves to another
+// frequency, or holds on or leaves a signal
+type ScanStateChanged struct {
+	baseEvent
+	Running bool
+	Holding bool // stopped on a signal
+	Freq    float64
+	Level   int
+}
+
+// Bus provides simple event publish/subscribe. Subscribers may be added
+// while events are being published.
 type Bus struct {
+	mu          sync.RWMutex
 	subscribers []chan Event
 }
 
@@ -108,12 +223,16 @@ func NewBus() *Bus {
 // Subscribe creates a new event channel for receiving events
 func (b *Bus) Subscribe(bufferSize int) chan Event {
 	ch := make(chan Event, bufferSize)
+	b.mu.Lock()
 	b.subscribers = append(b.subscribers, ch)
+	b.mu.Unlock()
 	return ch
 }
 
 // Publish sends an event to all subscribers (non-blocking)
 func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 	for _, ch := range b.subscribers {
 		select {
 		case ch <- event:
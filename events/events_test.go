@@ -0,0 +1,49 @@
+package events
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestBus(t *testing.T) {
+	b := NewBus()
+	first := b.Subscribe(1)
+	b.Publish(VOXStateChanged{Enabled: true})
+	second := b.Subscribe(1)
+	// A full subscriber is skipped rather than holding up the others
+	b.Publish(VOXStateChanged{Enabled: false})
+
+	if e := <-first; e != (VOXStateChanged{Enabled: true}) {
+		t.Errorf("first subscriber got %+v", e)
+	}
+	if e := <-second; e != (VOXStateChanged{Enabled: false}) {
+		t.Errorf("second subscriber got %+v", e)
+	}
+	select {
+	case e := <-first:
+		t.Errorf("full subscriber got %+v as well", e)
+	default:
+	}
+}
+
+func TestBusSubscribeWhilePublishing(t *testing.T) {
+	b := NewBus()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 1000 {
+			b.Publish(VOXStateChanged{})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			b.Subscribe(0)
+		}
+	}()
+	wg.Wait()
+	if n := len(b.subscribers); n != 100 {
+		t.Errorf("%d subscribers, want 100", n)
+	}
+}
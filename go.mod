@@ -6,13 +6,13 @@ require (
 	github.com/adrg/xdg v0.5.3
 	github.com/ebitenui/ebitenui v0.7.2
 	github.com/hajimehoshi/ebiten/v2 v2.8.8
-	github.com/hb9fxq/flexlib-go v0.0.0-20201128184122-8acafd8a4e14
 	github.com/jfreymuth/pulse v0.1.2-0.20250227192922-7a016f9e4952
 	github.com/kc2g-flex-tools/flexclient v0.9.2
 	github.com/tinne26/badcolor v0.0.0-20240606171339-6b0c9b4d5e38
 	github.com/vimeo/dials v0.17.1
 	gitlab.com/gomidi/midi/v2 v2.2.19
 	golang.org/x/image v0.31.0
+	golang.org/x/sys v0.36.0
 	gopkg.in/hraban/opus.v2 v2.0.0-20230925203106-0188a62cb302
 )
 
@@ -23,9 +23,9 @@ require (
 	github.com/fatih/structtag v1.2.0 // indirect
 	github.com/frustra/bbcode v0.0.0-20201127003707-6ef347fbe1c8 // indirect
 	github.com/go-text/typesetting v0.3.0 // indirect
+	github.com/hb9fxq/flexlib-go v0.0.0-20201128184122-8acafd8a4e14 // indirect
 	github.com/jezek/xgb v1.1.1 // indirect
 	golang.org/x/exp v0.0.0-20250911091902-df9299821621 // indirect
 	golang.org/x/sync v0.17.0 // indirect
-	golang.org/x/sys v0.36.0 // indirect
 	golang.org/x/text v0.29.0 // indirect
 )
@@ -0,0 +1,67 @@
+//go:build !headless
+
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/kc2g-flex-tools/minstrel/accel"
+	"github.com/kc2g-flex-tools/minstrel/audio"
+	"github.com/kc2g-flex-tools/minstrel/bandplan"
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/logbook"
+	"github.com/kc2g-flex-tools/minstrel/midi"
+	"github.com/kc2g-flex-tools/minstrel/radio"
+	"github.com/kc2g-flex-tools/minstrel/txguard"
+	"github.com/kc2g-flex-tools/minstrel/ui"
+)
+
+// guiAvailable is false in builds with the headless tag, which leave out
+// Ebiten entirely since it needs a display as soon as it is loaded
+const guiAvailable = true
+
+type uiConfig = ui.Config
+
+func defaultUIConfig() *uiConfig {
+	return ui.DefaultConfig()
+}
+
+// runGUI shows the radio list and runs the UI until the window is closed
+func runGUI(ctx context.Context, cfg *uiConfig, rs *radio.RadioState, audioCtx *audio.Audio, midiCtx *midi.MIDI, eventBus *events.Bus, lb *logbook.Logbook, plan *bandplan.Plan, guard *txguard.Guard, vfoCurve accel.Curve) {
+	// Create UI with event bus
+	u := ui.NewUI(cfg, eventBus)
+	u.RadioShim = rs
+	u.AudioShim = audioCtx
+	u.MIDIShim = midiCtx
+	u.Logbook = lb
+	u.BandPlan = plan
+	u.TXGuard = guard
+	u.VFOAccel = accel.NewAccelerator(vfoCurve)
+
+	// Start UI event handler
+	go u.HandleEvents(eventBus.Subscribe(100))
+
+	// Start RadioState event handler for connection requests
+	go func() {
+		eventChan := eventBus.Subscribe(10)
+		for event := range eventChan {
+			switch e := event.(type) {
+			case events.RadioSelected:
+				if err := rs.ConnectToRadio(ctx, e.Address); err != nil {
+					log.Println("Connection error:", err)
+					// TODO: show error in UI
+				}
+			}
+		}
+	}()
+
+	// Start radio discovery
+	rs.StartDiscovery(ctx)
+
+	if err := ebiten.RunGame(u); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build headless
+
+package main
+
+import (
+	"context"
+
+	"github.com/kc2g-flex-tools/minstrel/accel"
+	"github.com/kc2g-flex-tools/minstrel/audio"
+	"github.com/kc2g-flex-tools/minstrel/bandplan"
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/logbook"
+	"github.com/kc2g-flex-tools/minstrel/midi"
+	"github.com/kc2g-flex-tools/minstrel/radio"
+	"github.com/kc2g-flex-tools/minstrel/txguard"
+)
+
+const guiAvailable = false
+
+// uiConfig has no settings without the UI
+type uiConfig struct{}
+
+func defaultUIConfig() *uiConfig {
+	return &uiConfig{}
+}
+
+func runGUI(ctx context.Context, cfg *uiConfig, rs *radio.RadioState, audioCtx *audio.Audio, midiCtx *midi.MIDI, eventBus *events.Bus, lb *logbook.Logbook, plan *bandplan.Plan, guard *txguard.Guard, vfoCurve accel.Curve) {
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radio"
+)
+
+// How long to look for the configured radio before giving up
+const headlessDiscoveryTimeout = 30 * time.Second
+
+// runHeadless connects to the radio selected by spec and turns on remote
+// audio, then runs until SIGINT or SIGTERM, removing our streams before
+// exiting. If the connection to the radio is lost, it exits with status 1 so
+// that a service manager can restart it.
+func runHeadless(ctx context.Context, rs *radio.RadioState, eventBus *events.Bus, spec string) {
+	log.SetOutput(os.Stdout)
+	if spec == "" {
+		log.Fatal("--radio is required in headless mode")
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	eventChan := eventBus.Subscribe(10)
+	connected := false
+	audioStarted := false
+	connect := func(address string) {
+		log.Println("connecting to", address)
+		if err := rs.ConnectToRadio(ctx, address); err != nil {
+			log.Fatal("connection error: ", err)
+		}
+		connected = true
+	}
+
+	discoveryTimeout := time.After(headlessDiscoveryTimeout)
+	if address, ok := radio.DirectAddress(spec); ok {
+		connect(address)
+	} else {
+		log.Printf("looking for radio %q", spec)
+		rs.StartDiscovery(ctx)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("shutting down")
+			rs.Disconnect()
+			return
+		case <-discoveryTimeout:
+			if !connected {
+				log.Fatalf("radio %q not found", spec)
+			}
+		case event := <-eventChan:
+			switch e := event.(type) {
+			case events.RadiosDiscovered:
+				if address, ok := radio.FindRadio(e.Radios, spec); ok && !connected {
+					connect(address)
+				}
+			case events.RadioDisconnected:
+				log.Printf("lost connection to radio: %s", e.Error)
+				os.Exit(1)
+			case events.SlicesUpdated:
+				// The first update means the client is set up and can create streams
+				if connected && !audioStarted {
+					log.Println("starting remote audio")
+					rs.ToggleAudio(true)
+					audioStarted = true
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package input
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/adrg/xdg"
+
+	"github.com/kc2g-flex-tools/minstrel/controls"
+)
+
+type Config struct {
+	Enabled    bool   `dialsdesc:"Read jog wheels, foot switches and knobs from /dev/input (Linux)" dialsflag:"input"`
+	File       string `dialsdesc:"Input device mapping file (default: minstrel/input.json in the XDG config directory)" dialsflag:"input-file"`
+	Replay     string `dialsdesc:"Play back an event stream recorded from /dev/input/event* instead of reading devices" dialsflag:"input-replay"`
+	ReplayName string `dialsdesc:"Device name to map the replayed events as" dialsflag:"input-replay-name"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{}
+}
+
+// Binding maps a key or relative axis to an action
+type Binding struct {
+	Type   string `json:"type"`   // "key" or "rel"
+	Code   string `json:"code"`   // e.g. "BTN_4", "REL_DIAL", or a number
+	Action string `json:"action"` // one of controls.Actions
+	// Scale multiplies relative movements; negative reverses them
+	Scale int `json:"scale,omitempty"`
+	// Absolute is set for relative axes that report a position rather than a
+	// movement, like the ShuttleXpress jog dial and shuttle ring; they move
+	// by the change in position. Wrap is how many positions there are before
+	// the position wraps round to 0 (256 for the jog dial), or 0 if it
+	// doesn't.
+	Absolute bool `json:"absolute,omitempty"`
+	Wrap     int  `json:"wrap,omitempty"`
+
+	eventType uint16
+	code      uint16
+}
+
+// Device is the mapping for the devices whose names contain Name
+type Device struct {
+	Name string `json:"name"`
+	// Grab takes the device for ourselves, so that a foot switch posing as a
+	// keyboard doesn't also type into other programs
+	Grab     bool      `json:"grab,omitempty"`
+	Bindings []Binding `json:"bindings"`
+}
+
+type mappingFile struct {
+	Devices []Device `json:"devices"`
+}
+
+// loadDevices reads the device mappings from cfg.File, or minstrel/input.json
+// in the XDG config directory
+func loadDevices(cfg *Config) ([]Device, error) {
+	path := cfg.File
+	if path == "" {
+		var err error
+		path, err = xdg.SearchConfigFile("minstrel/input.json")
+		if err != nil {
+			return nil, fmt.Errorf("no input device mapping: create %s", defaultMappingPath())
+		}
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("input device mapping %s doesn't exist", path)
+	} else if err != nil {
+		return nil, err
+	}
+	var file mappingFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for i := range file.Devices {
+		device := &file.Devices[i]
+		if device.Name == "" {
+			return nil, fmt.Errorf("%s: device %d has no name", path, i+1)
+		}
+		for j := range device.Bindings {
+			if err := device.Bindings[j].parse(); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, device.Name, err)
+			}
+		}
+	}
+	return file.Devices, nil
+}
+
+func defaultMappingPath() string {
+	return filepath.Join(xdg.ConfigHome, "minstrel", "input.json")
+}
+
+// parse checks a binding and resolves its code
+func (b *Binding) parse() error {
+	action, ok := controls.LookupAction(b.Action)
+	if !ok {
+		return fmt.Errorf("unknown action %q", b.Action)
+	}
+	switch b.Type {
+	case "key":
+		if action.Relative {
+			return fmt.Errorf("%s needs a relative axis, not a key", b.Action)
+		}
+		b.eventType = evKey
+	case "rel":
+		if !action.Relative {
+			return fmt.Errorf("%s needs a key, not a relative axis", b.Action)
+		}
+		b.eventType = evRel
+	default:
+		return fmt.Errorf("unknown binding type %q (want key or rel)", b.Type)
+	}
+	if b.Wrap < 0 || b.Wrap > 0 && !b.Absolute {
+		return fmt.Errorf("%s: wrap needs a positive number of positions on an absolute axis", b.Code)
+	}
+	if b.Absolute && b.eventType != evRel {
+		return fmt.Errorf("%s: only relative axes can be absolute", b.Code)
+	}
+	code, err := parseCode(b.Code)
+	if err != nil {
+		return err
+	}
+	b.code = code
+	if b.Scale == 0 {
+		b.Scale = 1
+	}
+	return nil
+}
+
+// parseCode parses an event code, either a number or a name from
+// linux/input-event-codes.h
+func parseCode(s string) (uint16, error) {
+	if code, ok := codeNames[strings.ToUpper(s)]; ok {
+		return code, nil
+	}
+	code, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("unknown event code %q", s)
+	}
+	return uint16(code), nil
+}
+
+// matches reports whether the device named name uses this mapping
+func (d *Device) matches(name string) bool {
+	return strings.Contains(strings.ToLower(name), strings.ToLower(d.Name))
+}
+
+// binding finds the binding for an event
+func (d *Device) binding(e Event) (Binding, bool) {
+	for _, b := range d.Bindings {
+		if b.eventType == e.Type && b.code == e.Code {
+			return b, true
+		}
+	}
+	return Binding{}, false
+}
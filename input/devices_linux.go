@@ -0,0 +1,60 @@
+package input
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// EVIOCGRAB from linux/input.h
+const eviocgrab = 0x40044590
+
+// listDevices returns the event devices, keyed by path, with their names
+func listDevices() (map[string]string, error) {
+	paths, err := filepath.Glob("/dev/input/event*")
+	if err != nil {
+		return nil, err
+	}
+	devices := make(map[string]string, len(paths))
+	for _, path := range paths {
+		name, err := os.ReadFile(filepath.Join("/sys/class/input", filepath.Base(path), "device/name"))
+		if err != nil {
+			continue
+		}
+		devices[path] = strings.TrimSpace(string(name))
+	}
+	return devices, nil
+}
+
+// openDevice opens an event device, taking it away from other programs if
+// grab is set
+func openDevice(path string, grab bool) (*os.File, error) {
+	// Non-blocking, so that the runtime poller lets Close interrupt a Read
+	f, err := os.OpenFile(path, os.O_RDONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !grab {
+		return f, nil
+	}
+	conn, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	var grabErr error
+	err = conn.Control(func(fd uintptr) {
+		grabErr = unix.IoctlSetInt(int(fd), eviocgrab, 1)
+	})
+	if err == nil {
+		err = grabErr
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("grab %s: %w", path, err)
+	}
+	return f, nil
+}
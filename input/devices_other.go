@@ -0,0 +1,18 @@
+//go:build !linux
+
+package input
+
+import (
+	"errors"
+	"os"
+)
+
+var errUnsupported = errors.New("evdev input is only supported on Linux")
+
+func listDevices() (map[string]string, error) {
+	return nil, errUnsupported
+}
+
+func openDevice(path string, grab bool) (*os.File, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,106 @@
+package input
+
+import (
+	"encoding/binary"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Event is a Linux input event, as read from /dev/input/event*
+type Event struct {
+	Time  time.Time
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// Event types, from linux/input-event-codes.h
+const (
+	evSyn = 0x00
+	evKey = 0x01
+	evRel = 0x02
+)
+
+// Key event values
+const (
+	keyReleased = 0
+	keyPressed  = 1
+	keyRepeated = 2
+)
+
+// The size of struct input_event: a struct timeval of two longs, then the
+// type, code and value
+var eventSize = 2*strconv.IntSize/8 + 8
+
+// ReadEvents decodes events from r, which is a device or a recording of one
+// (e.g. made with cat /dev/input/eventN > recording), until it fails
+func ReadEvents(r io.Reader, fn func(Event)) error {
+	buf := make([]byte, eventSize)
+	long := strconv.IntSize / 8
+	for {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return err
+		}
+		var sec, usec int64
+		if long == 8 {
+			sec = int64(binary.NativeEndian.Uint64(buf[0:]))
+			usec = int64(binary.NativeEndian.Uint64(buf[8:]))
+		} else {
+			sec = int64(int32(binary.NativeEndian.Uint32(buf[0:])))
+			usec = int64(int32(binary.NativeEndian.Uint32(buf[4:])))
+		}
+		fields := buf[2*long:]
+		fn(Event{
+			Time:  time.Unix(sec, usec*1000),
+			Type:  binary.NativeEndian.Uint16(fields[0:]),
+			Code:  binary.NativeEndian.Uint16(fields[2:]),
+			Value: int32(binary.NativeEndian.Uint32(fields[4:])),
+		})
+	}
+}
+
+// codeNames are the event codes that can be given by name in bindings: the
+// relative axes, and the buttons and keys that controllers commonly send
+var codeNames = map[string]uint16{
+	"REL_X":      0x00,
+	"REL_Y":      0x01,
+	"REL_Z":      0x02,
+	"REL_RX":     0x03,
+	"REL_RY":     0x04,
+	"REL_RZ":     0x05,
+	"REL_HWHEEL": 0x06,
+	"REL_DIAL":   0x07,
+	"REL_WHEEL":  0x08,
+	"REL_MISC":   0x09,
+
+	"BTN_0":      0x100,
+	"BTN_1":      0x101,
+	"BTN_2":      0x102,
+	"BTN_3":      0x103,
+	"BTN_4":      0x104,
+	"BTN_5":      0x105,
+	"BTN_6":      0x106,
+	"BTN_7":      0x107,
+	"BTN_8":      0x108,
+	"BTN_9":      0x109,
+	"BTN_LEFT":   0x110,
+	"BTN_RIGHT":  0x111,
+	"BTN_MIDDLE": 0x112,
+	"BTN_SIDE":   0x113,
+	"BTN_EXTRA":  0x114,
+
+	"KEY_A":     30,
+	"KEY_B":     48,
+	"KEY_C":     46,
+	"KEY_ENTER": 28,
+	"KEY_SPACE": 57,
+	"KEY_LEFT":  105,
+	"KEY_RIGHT": 106,
+	"KEY_UP":    103,
+	"KEY_DOWN":  108,
+	"KEY_F13":   183,
+	"KEY_F14":   184,
+	"KEY_F15":   185,
+	"KEY_F16":   186,
+}
@@ -0,0 +1,290 @@
+// Package input reads jog wheels, foot switches and knobs through the Linux
+// evdev interface (/dev/input/event*), and maps their keys and relative axes
+// to controls actions.
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/accel"
+	"github.com/kc2g-flex-tools/minstrel/controls"
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+// How often to look for newly plugged in devices
+const scanInterval = 2 * time.Second
+
+// Manager opens the devices that have a mapping, and carries out their
+// actions
+type Manager struct {
+	cfg   *Config
+	shim  radioshim.Shim
+	bus   *events.Bus
+	curve accel.Curve
+
+	mu sync.Mutex
+	// Devices being read, by path
+	open map[string]bool
+	// Devices that couldn't be opened, so the error is only logged once
+	failed map[string]bool
+}
+
+// NewManager creates a manager acting through shim, with tuning accelerated by
+// curve
+func NewManager(cfg *Config, shim radioshim.Shim, bus *events.Bus, curve accel.Curve) *Manager {
+	return &Manager{
+		cfg:    cfg,
+		shim:   shim,
+		bus:    bus,
+		curve:  curve,
+		open:   make(map[string]bool),
+		failed: make(map[string]bool),
+	}
+}
+
+// Run reads devices as they're plugged in, or plays back cfg.Replay, until ctx
+// is cancelled
+func (m *Manager) Run(ctx context.Context) error {
+	devices, err := loadDevices(m.cfg)
+	if err != nil {
+		return err
+	}
+	if m.cfg.Replay != "" {
+		return m.replay(ctx, devices)
+	}
+
+	if err := m.scan(ctx, devices); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(scanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := m.scan(ctx, devices); err != nil {
+				log.Println("Failed to list input devices:", err)
+			}
+		}
+	}
+}
+
+// scan opens the devices with a mapping that aren't already open
+func (m *Manager) scan(ctx context.Context, devices []Device) error {
+	present, err := listDevices()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for path := range m.failed {
+		if _, ok := present[path]; !ok {
+			delete(m.failed, path)
+		}
+	}
+	for path, name := range present {
+		if m.open[path] || m.failed[path] {
+			continue
+		}
+		device := findDevice(devices, name)
+		if device == nil {
+			continue
+		}
+		f, err := openDevice(path, device.Grab)
+		if err != nil {
+			log.Printf("Failed to open input device %s (%s): %v", name, path, err)
+			m.failed[path] = true
+			continue
+		}
+		log.Printf("Input device %s (%s) connected", name, path)
+		m.open[path] = true
+		go m.read(ctx, path, name, f, device)
+	}
+	return nil
+}
+
+// read carries out a device's events until it goes away or ctx is cancelled
+func (m *Manager) read(ctx context.Context, path, name string, f *os.File, device *Device) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	d := controls.NewDispatcher(m.shim, m.bus, m.curve)
+	go d.Run(ctx)
+
+	mp := newMapper(d, device)
+	err := ReadEvents(f, mp.handle)
+	if ctx.Err() == nil {
+		log.Printf("Input device %s (%s) disconnected: %v", name, path, err)
+	}
+	// Don't leave the radio transmitting because a foot switch was unplugged
+	if d.ReleasePTT() {
+		log.Println("Released PTT held by", name)
+	}
+
+	m.mu.Lock()
+	delete(m.open, path)
+	m.mu.Unlock()
+}
+
+// replay plays back a recorded event stream, paced as it was recorded, as if
+// it came from a device named cfg.ReplayName
+func (m *Manager) replay(ctx context.Context, devices []Device) error {
+	device := findDevice(devices, m.cfg.ReplayName)
+	if device == nil {
+		return fmt.Errorf("no input device mapping matches %q", m.cfg.ReplayName)
+	}
+	f, err := os.Open(m.cfg.Replay)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Stop the dispatcher at the end, once it has sent the last movement
+	dctx, stop := context.WithCancel(ctx)
+	dispatched := make(chan struct{})
+	d := controls.NewDispatcher(m.shim, m.bus, m.curve)
+	go func() {
+		d.Run(dctx)
+		close(dispatched)
+	}()
+	defer func() {
+		stop()
+		<-dispatched
+	}()
+
+	mp := newMapper(d, device)
+	log.Printf("Replaying %s as %s", m.cfg.Replay, device.Name)
+	var start, first time.Time
+	err = ReadEvents(f, func(e Event) {
+		if ctx.Err() != nil {
+			return
+		}
+		if start.IsZero() {
+			start, first = time.Now(), e.Time
+		}
+		select {
+		case <-time.After(time.Until(start.Add(e.Time.Sub(first)))):
+			mp.handle(e)
+		case <-ctx.Done():
+		}
+	})
+	if d.ReleasePTT() {
+		log.Println("Released PTT held at the end of the replay")
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		log.Println("Replay finished")
+		return nil
+	}
+	return err
+}
+
+// findDevice returns the first mapping for the device named name, or nil
+func findDevice(devices []Device, name string) *Device {
+	for i := range devices {
+		if devices[i].matches(name) {
+			return &devices[i]
+		}
+	}
+	return nil
+}
+
+// mapper carries out the actions bound to one device's events
+type mapper struct {
+	d      *controls.Dispatcher
+	device *Device
+	// The last position of each absolute axis, by code
+	positions map[uint16]int32
+	// The absolute axes reported since the last EV_SYN
+	reported map[uint16]bool
+}
+
+func newMapper(d *controls.Dispatcher, device *Device) *mapper {
+	return &mapper{
+		d:         d,
+		device:    device,
+		positions: make(map[uint16]int32),
+		reported:  make(map[uint16]bool),
+	}
+}
+
+// handle carries out the action bound to an event
+func (mp *mapper) handle(e Event) {
+	if e.Type == evSyn {
+		mp.sync()
+		return
+	}
+	b, ok := mp.device.binding(e)
+	if !ok {
+		return
+	}
+	switch e.Type {
+	case evKey:
+		switch e.Value {
+		case keyPressed:
+			mp.d.Press(b.Action, true)
+		case keyReleased:
+			mp.d.Press(b.Action, false)
+		case keyRepeated:
+			// Auto-repeat of a held key; the press already did the job
+		}
+	case evRel:
+		delta := int(e.Value)
+		if b.Absolute {
+			mp.reported[e.Code] = true
+			delta = mp.move(b, e.Value)
+		}
+		if delta != 0 {
+			mp.d.Adjust(b.Action, delta*b.Scale)
+		}
+	}
+}
+
+// move records the new position of an absolute axis, and returns how far it
+// moved. Where a wrapping axis starts is unknown, so its first position only
+// sets the starting point; the others start at rest, at 0.
+func (mp *mapper) move(b Binding, position int32) int {
+	last, ok := mp.positions[b.code]
+	mp.positions[b.code] = position
+	if !ok && b.Wrap > 0 {
+		return 0
+	}
+	delta := int(position - last)
+	if b.Wrap > 0 {
+		// The shorter way round
+		delta = ((delta % b.Wrap) + b.Wrap) % b.Wrap
+		if delta > b.Wrap/2 {
+			delta -= b.Wrap
+		}
+	}
+	return delta
+}
+
+// sync ends a report from the device. The kernel doesn't pass on relative
+// events with a value of 0, so an absolute axis that doesn't wrap, like a
+// shuttle ring springing back to the middle, has returned to 0 if a report
+// doesn't include it.
+func (mp *mapper) sync() {
+	for _, b := range mp.device.Bindings {
+		if b.eventType != evRel || !b.Absolute || b.Wrap > 0 || mp.reported[b.code] {
+			continue
+		}
+		if delta := mp.move(b, 0); delta != 0 {
+			mp.d.Adjust(b.Action, delta*b.Scale)
+		}
+	}
+	clear(mp.reported)
+}
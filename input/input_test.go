@@ -0,0 +1,313 @@
+package input
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/accel"
+	"github.com/kc2g-flex-tools/minstrel/controls"
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+	"github.com/kc2g-flex-tools/minstrel/radioshim/shimtest"
+)
+
+// The event stream in testdata is laid out as a 64-bit little-endian machine
+// lays it out, so it only decodes on one
+func skipForeignRecording(t *testing.T) {
+	t.Helper()
+	if strconv.IntSize != 64 || binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("testdata event stream is for 64-bit little-endian machines")
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	skipForeignRecording(t)
+	f, err := os.Open("testdata/shuttlexpress.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var got []Event
+	syncs := 0
+	err = ReadEvents(f, func(e Event) {
+		if e.Type == evSyn {
+			syncs++
+		} else {
+			got = append(got, e)
+		}
+	})
+	if err == nil || !strings.Contains(err.Error(), "EOF") {
+		t.Errorf("ReadEvents = %v, want EOF", err)
+	}
+	if len(got) != 26 || syncs != 17 {
+		t.Fatalf("read %d events in %d reports, want 26 in 17", len(got), syncs)
+	}
+	first := Event{Time: time.Unix(1760348127, 519913000), Type: evRel, Code: 0x07, Value: 251}
+	if got[0] != first {
+		t.Errorf("first event = %+v, want %+v", got[0], first)
+	}
+	if e := got[1]; e.Value != 252 || e.Time.Sub(got[0].Time) != 37*time.Millisecond {
+		t.Errorf("second event = %+v, want 252 37ms after the first", e)
+	}
+}
+
+func TestBindings(t *testing.T) {
+	devices, err := loadDevices(&Config{File: "testdata/input.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	device := findDevice(devices, "Contour Design ShuttleXpress")
+	if device == nil {
+		t.Fatal("no mapping for the ShuttleXpress")
+	}
+	if findDevice(devices, "AT Translated Set 2 keyboard") != nil {
+		t.Error("keyboard matched the ShuttleXpress mapping")
+	}
+
+	tests := []struct {
+		event  Event
+		action string
+		scale  int
+	}{
+		{Event{Type: evRel, Code: 0x07}, controls.ActionTune, 1},
+		{Event{Type: evRel, Code: 0x08}, controls.ActionVolume, 5},
+		{Event{Type: evRel, Code: 0x06}, "", 0},
+		{Event{Type: evKey, Code: 0x104}, controls.ActionPTT, 1},
+		{Event{Type: evKey, Code: 0x100}, "", 0},
+		{Event{Type: evKey, Code: 0x07}, "", 0},
+	}
+	for _, tt := range tests {
+		b, ok := device.binding(tt.event)
+		if ok != (tt.action != "") || b.Action != tt.action || b.Scale != tt.scale {
+			t.Errorf("binding(%+v) = %+v, %v, want %s ×%d", tt.event, b, ok, tt.action, tt.scale)
+		}
+	}
+}
+
+func TestBadMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		binding Binding
+		want    string
+	}{
+		{"unknown action", Binding{Type: "key", Code: "BTN_0", Action: "launch"}, "unknown action"},
+		{"knob action on a key", Binding{Type: "key", Code: "BTN_0", Action: "tune"}, "needs a relative axis"},
+		{"button action on a knob", Binding{Type: "rel", Code: "REL_DIAL", Action: "ptt"}, "needs a key"},
+		{"unknown type", Binding{Type: "abs", Code: "REL_DIAL", Action: "tune"}, "unknown binding type"},
+		{"unknown code", Binding{Type: "key", Code: "BTN_FOO", Action: "ptt"}, "unknown event code"},
+		{"absolute key", Binding{Type: "key", Code: "BTN_0", Action: "ptt", Absolute: true}, "only relative axes"},
+		{"wrap without absolute", Binding{Type: "rel", Code: "REL_DIAL", Action: "tune", Wrap: 256}, "wrap needs"},
+		{"negative wrap", Binding{Type: "rel", Code: "REL_DIAL", Action: "tune", Absolute: true, Wrap: -1}, "wrap needs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.binding.parse(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parse() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAbsoluteAxes(t *testing.T) {
+	tests := []struct {
+		name      string
+		binding   Binding
+		positions []int32
+		want      []int
+	}{
+		{
+			name:      "jog dial",
+			binding:   Binding{Absolute: true, Wrap: 256},
+			positions: []int32{250, 251, 253, 253, 252},
+			want:      []int{0, 1, 2, 0, -1},
+		},
+		{
+			name:      "jog dial wrapping",
+			binding:   Binding{Absolute: true, Wrap: 256},
+			positions: []int32{254, 255, 1, 2, 1, 255, 254},
+			want:      []int{0, 1, 2, 1, -1, -2, -1},
+		},
+		{
+			name:      "shuttle ring",
+			binding:   Binding{Absolute: true},
+			positions: []int32{1, 2, 3, 3, 3, -2, 0},
+			want:      []int{1, 1, 1, 0, 0, -5, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp := newMapper(nil, &Device{Bindings: []Binding{tt.binding}})
+			var got []int
+			for _, p := range tt.positions {
+				got = append(got, mp.move(tt.binding, p))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("moves = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// mapEvents carries out events for device, and returns what they did to the
+// radio
+func mapEvents(t *testing.T, device *Device, evs []Event) (*shimtest.Fake, []string) {
+	t.Helper()
+	fake := shimtest.New(radioshim.SliceMap{
+		"A": {Index: 0, Freq: 14.074, TuneStep: 100, Volume: 50, Active: true, TX: true},
+	})
+	d := controls.NewDispatcher(fake, events.NewBus(), accel.Presets["off"])
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	mp := newMapper(d, device)
+	for _, e := range evs {
+		mp.handle(e)
+	}
+	cancel()
+	<-done
+	return fake, fake.Calls()
+}
+
+func TestMapper(t *testing.T) {
+	device := &Device{Name: "test", Bindings: []Binding{
+		{Type: "rel", Code: "REL_DIAL", Action: "tune", Absolute: true, Wrap: 256},
+		{Type: "rel", Code: "REL_WHEEL", Action: "volume", Absolute: true, Scale: 5},
+		{Type: "rel", Code: "REL_X", Action: "rit"},
+		{Type: "key", Code: "BTN_0", Action: "ptt"},
+	}}
+	for i := range device.Bindings {
+		if err := device.Bindings[i].parse(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rel := func(code uint16, value int32) Event { return Event{Type: evRel, Code: code, Value: value} }
+	key := func(value int32) Event { return Event{Type: evKey, Code: 0x100, Value: value} }
+	syn := Event{Type: evSyn}
+
+	tests := []struct {
+		name   string
+		evs    []Event
+		rit    int
+		volume int
+		ptt    []string
+	}{
+		{
+			name:   "relative axis",
+			evs:    []Event{rel(0x00, 3), syn, rel(0x00, 3), syn},
+			rit:    60,
+			volume: 50,
+		},
+		{
+			name:   "held key",
+			evs:    []Event{key(1), syn, key(2), syn, key(2), syn, key(0), syn},
+			volume: 50,
+			ptt:    []string{"SetPTT true", "SetPTT false"},
+		},
+		{
+			name:   "shuttle ring held",
+			evs:    []Event{rel(0x08, 2), rel(0x07, 9), syn, rel(0x08, 2), rel(0x07, 9), syn},
+			volume: 60,
+		},
+		{
+			name:   "shuttle ring turned further",
+			evs:    []Event{rel(0x08, 2), syn, rel(0x08, 5), syn},
+			volume: 75,
+		},
+		{
+			name:   "shuttle ring springing back",
+			evs:    []Event{rel(0x08, 2), rel(0x07, 9), syn, rel(0x07, 9), syn, rel(0x08, 1), syn},
+			volume: 55,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, calls := mapEvents(t, device, tt.evs)
+			slice := fake.Slice("A")
+			if slice.RITOffset != tt.rit || slice.Volume != tt.volume || slice.Freq != 14.074 {
+				t.Errorf("RIT %d, volume %d, frequency %v; want %d, %d, 14.074 (calls %q)",
+					slice.RITOffset, slice.Volume, slice.Freq, tt.rit, tt.volume, calls)
+			}
+			var ptt []string
+			for _, call := range calls {
+				if strings.HasPrefix(call, "SetPTT") {
+					ptt = append(ptt, call)
+				}
+			}
+			if !reflect.DeepEqual(ptt, tt.ptt) {
+				t.Errorf("PTT calls = %q, want %q", ptt, tt.ptt)
+			}
+		})
+	}
+
+	// A jog dial sitting still does nothing
+	evs := []Event{rel(0x07, 17), syn, rel(0x07, 17), syn, rel(0x07, 17), syn}
+	if _, calls := mapEvents(t, device, evs); len(calls) != 0 {
+		t.Errorf("jog dial sitting still made calls %q", calls)
+	}
+}
+
+// TestReplay plays the testdata event stream through the mapping as
+// --input-replay does, and checks what it did to the radio
+func TestReplay(t *testing.T) {
+	skipForeignRecording(t)
+	fake := shimtest.New(radioshim.SliceMap{
+		"A": {Index: 0, Freq: 14.074, TuneStep: 100, Volume: 50, Active: true, TX: true},
+	})
+	cfg := &Config{
+		File:       "testdata/input.json",
+		Replay:     "testdata/shuttlexpress.events",
+		ReplayName: "Contour Design ShuttleXpress",
+	}
+	m := NewManager(cfg, fake, events.NewBus(), accel.Presets["off"])
+	// Run returns once the replay has been carried out
+	if err := m.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := fake.Calls()
+	var steps int
+	var volumes, others []string
+	for _, call := range calls {
+		switch {
+		case strings.HasPrefix(call, "TuneSliceStep 0 "):
+			n, _ := strconv.Atoi(strings.TrimPrefix(call, "TuneSliceStep 0 "))
+			steps += n
+		case strings.HasPrefix(call, "SetSliceVolume "):
+			volumes = append(volumes, call)
+		default:
+			others = append(others, call)
+		}
+	}
+	// Seven clicks clockwise, the first only showing where the dial was,
+	// then two back
+	if steps != 4 {
+		t.Errorf("tuned %d steps, want 4 (calls %q)", steps, calls)
+	}
+	if got := fake.Slice("A").Freq; math.Abs(got-14.0744) > 1e-9 {
+		t.Errorf("tuned to %v, want 14.0744", got)
+	}
+	// The ring turned to 3 and held there, then let go
+	if !slices.Contains(volumes, "SetSliceVolume 0 65") || volumes[len(volumes)-1] != "SetSliceVolume 0 50" {
+		t.Errorf("volume calls = %q, want up to 65 and back to 50", volumes)
+	}
+	if want := []string{"SetPTT true", "SetPTT false"}; !reflect.DeepEqual(others, want) {
+		t.Errorf("other calls = %q, want %q", others, want)
+	}
+	// PTT was pressed after the jog dial and released before the ring turned
+	press, release := slices.Index(calls, "SetPTT true"), slices.Index(calls, "SetPTT false")
+	if press < 1 || release < 0 || release+1 >= len(calls) || !strings.HasPrefix(calls[press-1], "TuneSliceStep") || !strings.HasPrefix(calls[release+1], "SetSliceVolume") {
+		t.Errorf("calls out of order: %q", calls)
+	}
+}
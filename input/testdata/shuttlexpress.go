@@ -0,0 +1,100 @@
+//go:build ignore
+
+// Writes shuttlexpress.events: a Contour ShuttleXpress session in the form
+// the kernel's generic HID driver delivers it on a 64-bit little-endian
+// machine, as cat /dev/input/eventN would record it.
+//
+// Every report carries the shuttle ring (REL_WHEEL, -7 to 7, held where it's
+// turned to and springing back to 0) and the jog dial (REL_DIAL, an 8-bit
+// position that wraps), but the kernel drops relative events with a value
+// of 0. Buttons are BTN_4 to BTN_8, each change preceded by MSC_SCAN.
+//
+//	go run shuttlexpress.go
+package main
+
+import (
+	"encoding/binary"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	evSyn = 0x00
+	evKey = 0x01
+	evRel = 0x02
+	evMsc = 0x04
+
+	relWheel = 0x08
+	relDial  = 0x07
+	mscScan  = 0x04
+	btn4     = 0x104
+)
+
+var (
+	out   []byte
+	clock = time.Unix(1760348127, 482913000)
+	ring  int32
+	jog   int32 = 250
+)
+
+func event(typ, code uint16, value int32) {
+	b := make([]byte, 24)
+	binary.LittleEndian.PutUint64(b[0:], uint64(clock.Unix()))
+	binary.LittleEndian.PutUint64(b[8:], uint64(clock.Nanosecond()/1000))
+	binary.LittleEndian.PutUint16(b[16:], typ)
+	binary.LittleEndian.PutUint16(b[18:], code)
+	binary.LittleEndian.PutUint32(b[20:], uint32(value))
+	out = append(out, b...)
+}
+
+// report sends the ring and jog positions, any button change, and EV_SYN,
+// after ms milliseconds
+func report(ms int, button uint16, pressed int32) {
+	clock = clock.Add(time.Duration(ms) * time.Millisecond)
+	if ring != 0 {
+		event(evRel, relWheel, ring)
+	}
+	if jog != 0 {
+		event(evRel, relDial, jog)
+	}
+	if button != 0 {
+		event(evMsc, mscScan, 0x90000+int32(button-0x100)+1)
+		event(evKey, button, pressed)
+	}
+	event(evSyn, 0, 0)
+}
+
+func main() {
+	// Jog clockwise across the wrap (passing 0, which isn't reported), then
+	// back two clicks: 7 clicks, the first only showing where it started
+	for _, p := range []int32{251, 252, 253, 254, 255, 1, 2} {
+		jog = p
+		report(37, 0, 0)
+	}
+	for _, p := range []int32{1, 255} {
+		jog = p
+		report(61, 0, 0)
+	}
+
+	// Press and release BTN_4
+	report(412, btn4, 1)
+	report(238, btn4, 0)
+
+	// Turn the shuttle ring to 3, hold it there (the jog is nudged, so
+	// reports keep coming), and let it spring back
+	for _, p := range []int32{1, 2, 3} {
+		ring = p
+		report(83, 0, 0)
+	}
+	jog = 1
+	report(140, 0, 0)
+	jog = 255
+	report(133, 0, 0)
+	ring = 0
+	report(297, 0, 0)
+
+	if err := os.WriteFile("shuttlexpress.events", out, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
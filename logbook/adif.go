@@ -0,0 +1,194 @@
+package logbook
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	adifVersion   = "3.1.4"
+	adifProgramID = "Minstrel"
+	adifDate      = "20060102"
+	adifTime      = "150405"
+)
+
+func writeField(w *bufio.Writer, name, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(w, "<%s:%d>%s ", name, len(value), value)
+}
+
+// WriteADIF writes qsos as an ADIF 3 file
+func WriteADIF(w io.Writer, qsos []QSO) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Minstrel logbook export\n")
+	writeField(bw, "ADIF_VER", adifVersion)
+	writeField(bw, "PROGRAMID", adifProgramID)
+	writeField(bw, "CREATED_TIMESTAMP", time.Now().UTC().Format(adifDate+" "+adifTime))
+	fmt.Fprintf(bw, "<EOH>\n\n")
+
+	for _, q := range qsos {
+		t := q.Time.UTC()
+		writeField(bw, "CALL", q.Call)
+		writeField(bw, "QSO_DATE", t.Format(adifDate))
+		writeField(bw, "TIME_ON", t.Format(adifTime))
+		writeField(bw, "BAND", q.Band)
+		if q.Freq > 0 {
+			writeField(bw, "FREQ", strconv.FormatFloat(q.Freq, 'f', 6, 64))
+		}
+		writeField(bw, "MODE", q.Mode)
+		writeField(bw, "SUBMODE", q.Submode)
+		writeField(bw, "RST_SENT", q.RSTSent)
+		writeField(bw, "RST_RCVD", q.RSTRcvd)
+		writeField(bw, "NAME", q.Name)
+		writeField(bw, "GRIDSQUARE", q.Grid)
+		// COMMENT can't hold line breaks, NOTES can
+		if strings.ContainsAny(q.Notes, "\r\n") {
+			writeField(bw, "NOTES", q.Notes)
+		} else {
+			writeField(bw, "COMMENT", q.Notes)
+		}
+		writeField(bw, "CONTEST_ID", q.Contest)
+		if q.SerialSent > 0 {
+			writeField(bw, "STX", strconv.Itoa(q.SerialSent))
+		}
+		writeField(bw, "STX_STRING", q.ExchSent)
+		writeField(bw, "SRX_STRING", q.ExchRcvd)
+		fmt.Fprintf(bw, "<EOR>\n")
+	}
+	return bw.Flush()
+}
+
+// ReadADIF reads the QSOs from an ADIF file. Fields Minstrel doesn't use are
+// ignored, as are records without a callsign or a valid date and time.
+func ReadADIF(r io.Reader) ([]QSO, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+
+	// Anything before the first tag is a header only if the file doesn't
+	// start with a tag
+	inHeader := !strings.HasPrefix(strings.TrimSpace(text), "<")
+	var qsos []QSO
+	record := map[string]string{}
+	for {
+		start := strings.IndexByte(text, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '>')
+		if end < 0 {
+			break
+		}
+		spec := strings.Split(text[start+1:start+end], ":")
+		text = text[start+end+1:]
+		name := strings.ToUpper(strings.TrimSpace(spec[0]))
+
+		switch name {
+		case "EOH":
+			inHeader = false
+			record = map[string]string{}
+			continue
+		case "EOR":
+			if q, ok := qsoFromRecord(record); ok {
+				qsos = append(qsos, q)
+			}
+			record = map[string]string{}
+			continue
+		}
+		if len(spec) < 2 {
+			continue
+		}
+		length, err := strconv.Atoi(spec[1])
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid length in ADIF field %q", name)
+		}
+		if length > len(text) {
+			return nil, fmt.Errorf("ADIF field %s runs past the end of the file", name)
+		}
+		if !inHeader {
+			record[name] = text[:length]
+		}
+		text = text[length:]
+	}
+	return qsos, nil
+}
+
+func qsoFromRecord(record map[string]string) (QSO, bool) {
+	call := strings.TrimSpace(record["CALL"])
+	if call == "" {
+		return QSO{}, false
+	}
+	timeOn := strings.TrimSpace(record["TIME_ON"])
+	if len(timeOn) == 4 {
+		timeOn += "00"
+	}
+	t, err := time.Parse(adifDate+adifTime, strings.TrimSpace(record["QSO_DATE"])+timeOn)
+	if err != nil {
+		return QSO{}, false
+	}
+	freq, _ := strconv.ParseFloat(strings.TrimSpace(record["FREQ"]), 64)
+	serial, _ := strconv.Atoi(strings.TrimSpace(record["STX"]))
+	notes := record["COMMENT"]
+	if notes == "" {
+		notes = record["NOTES"]
+	}
+	return QSO{
+		Time:    t,
+		Call:    call,
+		Freq:    freq,
+		Band:    strings.ToLower(strings.TrimSpace(record["BAND"])),
+		Mode:    record["MODE"],
+		Submode: record["SUBMODE"],
+		RSTSent: record["RST_SENT"],
+		RSTRcvd: record["RST_RCVD"],
+		Name:    record["NAME"],
+		Grid:    record["GRIDSQUARE"],
+		Notes:   notes,
+
+		Contest:    record["CONTEST_ID"],
+		SerialSent: serial,
+		ExchSent:   record["STX_STRING"],
+		ExchRcvd:   record["SRX_STRING"],
+	}, true
+}
+
+// ExportADIF writes the whole log to an ADIF file at path, returning the
+// number of QSOs written
+func (lb *Logbook) ExportADIF(path string) (int, error) {
+	qsos := lb.All()
+	slices.Reverse(qsos)
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := WriteADIF(f, qsos); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return len(qsos), f.Close()
+}
+
+// ImportADIF adds the QSOs from the ADIF file at path, returning the number
+// that weren't already in the log
+func (lb *Logbook) ImportADIF(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	qsos, err := ReadADIF(f)
+	if err != nil {
+		return 0, err
+	}
+	return lb.Import(qsos)
+}
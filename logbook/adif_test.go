@@ -0,0 +1,157 @@
+package logbook
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadADIFFromWSJTX(t *testing.T) {
+	f, err := os.Open("testdata/wsjtx_log.adi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	qsos, err := ReadADIF(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []QSO{
+		{
+			Time: time.Date(2025, 10, 13, 12, 28, 0, 0, time.UTC), Call: "W9XYZ", Freq: 14.075234, Band: "20m",
+			Mode: "FT8", RSTSent: "-05", RSTRcvd: "+02", Grid: "EM73", Notes: "tnx fb",
+		},
+		{
+			Time: time.Date(2025, 10, 13, 13, 15, 15, 0, time.UTC), Call: "DL1ABC", Freq: 14.08145, Band: "20m",
+			Mode: "MFSK", Submode: "FT4", RSTSent: "-12", RSTRcvd: "-09", Grid: "JO62",
+		},
+		{
+			Time: time.Date(2025, 10, 14, 0, 45, 0, 0, time.UTC), Call: "VE3XY", Freq: 7.075812, Band: "40m",
+			Mode: "FT8", RSTSent: "+04", RSTRcvd: "-01", Name: "Ann", Grid: "FN03",
+		},
+	}
+	if !reflect.DeepEqual(qsos, want) {
+		t.Errorf("ReadADIF =\n%+v\nwant\n%+v", qsos, want)
+	}
+}
+
+func TestReadADIF(t *testing.T) {
+	tests := []struct {
+		name string
+		adif string
+		want []QSO
+	}{
+		{
+			name: "header",
+			adif: "Exported by hand\n<ADIF_VER:5>3.1.4 <CALL:4>NONE <EOH>\n" +
+				"<CALL:4>K1AB <QSO_DATE:8>20250102 <TIME_ON:6>030405 <EOR>",
+			want: []QSO{{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Call: "K1AB"}},
+		},
+		{
+			name: "no header",
+			adif: "<CALL:4>K1AB <QSO_DATE:8>20250102 <TIME_ON:6>030405 <EOR>",
+			want: []QSO{{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Call: "K1AB"}},
+		},
+		{
+			name: "typed fields",
+			adif: "<EOH><CALL:5:S>W9XYZ<QSO_DATE:8:D>20250102<TIME_ON:6:T>030405<FREQ:6:N>14.025<STX:3:N>042<EOR>",
+			want: []QSO{{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Call: "W9XYZ", Freq: 14.025, SerialSent: 42}},
+		},
+		{
+			name: "4-digit time",
+			adif: "<EOH><CALL:4>K1AB<QSO_DATE:8>20250102<TIME_ON:4>0304<EOR>",
+			want: []QSO{{Time: time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC), Call: "K1AB"}},
+		},
+		{
+			name: "notes",
+			adif: "<EOH><CALL:4>K1AB<QSO_DATE:8>20250102<TIME_ON:4>0304<NOTES:10>line 1\r\nl2<EOR>",
+			want: []QSO{{Time: time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC), Call: "K1AB", Notes: "line 1\r\nl2"}},
+		},
+		{
+			name: "comment over notes",
+			adif: "<EOH><CALL:4>K1AB<QSO_DATE:8>20250102<TIME_ON:4>0304<NOTES:5>notes<COMMENT:7>comment<EOR>",
+			want: []QSO{{Time: time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC), Call: "K1AB", Notes: "comment"}},
+		},
+		{
+			name: "values with brackets",
+			adif: "<EOH><CALL:4>K1AB<QSO_DATE:8>20250102<TIME_ON:4>0304<COMMENT:9><EOR> ok <EOR>",
+			want: []QSO{{Time: time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC), Call: "K1AB", Notes: "<EOR> ok "}},
+		},
+		{
+			name: "records without a call or time",
+			adif: "<EOH><QSO_DATE:8>20250102<TIME_ON:4>0304<EOR>" +
+				"<CALL:4>K1AB<TIME_ON:4>0304<EOR>" +
+				"<CALL:4>K1AB<QSO_DATE:8>20250102<TIME_ON:4>2561<EOR>",
+		},
+		{
+			name: "unterminated record",
+			adif: "<EOH><CALL:4>K1AB<QSO_DATE:8>20250102<TIME_ON:4>0304",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadADIF(strings.NewReader(tt.adif))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadADIF =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadADIFErrors(t *testing.T) {
+	for adif, want := range map[string]string{
+		"<EOH><CALL:x>K1AB<EOR>":    "invalid length",
+		"<EOH><CALL:-1>K1AB<EOR>":   "invalid length",
+		"<EOH><CALL:40>K1AB<EOR>":   "runs past the end",
+		"<EOH><CALL:4:S>K1A":        "runs past the end",
+		"header <CALL:10>K1AB<EOH>": "runs past the end",
+	} {
+		if _, err := ReadADIF(strings.NewReader(adif)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ReadADIF(%q) = %v, want an error containing %q", adif, err, want)
+		}
+	}
+}
+
+func TestADIFRoundTrip(t *testing.T) {
+	qsos := []QSO{
+		{
+			Time: time.Date(2025, 10, 13, 12, 28, 7, 0, time.UTC), Call: "W9XYZ", Freq: 14.2505, Band: "20m",
+			Mode: "SSB", Submode: "USB", RSTSent: "59", RSTRcvd: "57", Name: "Bob", Grid: "EM73", Notes: "tnx fb",
+		},
+		{
+			Time: time.Date(2025, 10, 13, 23, 59, 59, 0, time.UTC), Call: "K1AB", Band: "40m",
+			Mode: "CW", RSTSent: "599", RSTRcvd: "599", Notes: "first line\nsecond line",
+		},
+		{
+			Time: time.Date(2025, 11, 1, 0, 3, 0, 0, time.UTC), Call: "VE3XY", Freq: 7.0255, Band: "40m", Mode: "CW",
+			Contest: "CQ-WW-CW", SerialSent: 7, ExchSent: "599 007", ExchRcvd: "599 123",
+		},
+	}
+	var buf bytes.Buffer
+	if err := WriteADIF(&buf, qsos); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, field := range []string{"<ADIF_VER:5>3.1.4", "<PROGRAMID:8>Minstrel", "<COMMENT:6>tnx fb", "<NOTES:22>first line\nsecond line", "<STX:1>7"} {
+		if !strings.Contains(out, field) {
+			t.Errorf("export doesn't contain %q:\n%s", field, out)
+		}
+	}
+	if n := strings.Count(out, "<FREQ:"); n != 2 {
+		t.Errorf("export has %d frequencies, want 2 as one QSO has only a band", n)
+	}
+
+	got, err := ReadADIF(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, qsos) {
+		t.Errorf("read back\n%+v\nwant\n%+v", got, qsos)
+	}
+}
@@ -0,0 +1,233 @@
+// Package logbook stores QSOs in a local JSON file and converts them to and
+// from ADIF.
+package logbook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"github.com/kc2g-flex-tools/minstrel/bandplan"
+)
+
+// QSO is a single logged contact
+type QSO struct {
+	ID      int       `json:"id"`
+	Time    time.Time `json:"time"`
+	Call    string    `json:"call"`
+	Freq    float64   `json:"freq"` // MHz
+	Band    string    `json:"band"`
+	Mode    string    `json:"mode"`
+	Submode string    `json:"submode,omitempty"`
+	RSTSent string    `json:"rst_sent,omitempty"`
+	RSTRcvd string    `json:"rst_rcvd,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	Grid    string    `json:"grid,omitempty"`
+	Notes   string    `json:"notes,omitempty"`
+
+	// Contest QSOs only
+	Contest    string `json:"contest,omitempty"` // Cabrillo contest name
+	SerialSent int    `json:"serial_sent,omitempty"`
+	ExchSent   string `json:"exch_sent,omitempty"`
+	ExchRcvd   string `json:"exch_rcvd,omitempty"`
+}
+
+// Logbook is a list of QSOs saved to a file after every change
+type Logbook struct {
+	mu     sync.RWMutex
+	path   string
+	qsos   []QSO
+	nextID int
+}
+
+// Open opens the logbook in the XDG data directory
+func Open() (*Logbook, error) {
+	path, err := xdg.DataFile("minstrel/logbook.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logbook path: %w", err)
+	}
+	return OpenFile(path)
+}
+
+// OpenFile opens the logbook stored at path, which doesn't need to exist yet
+func OpenFile(path string) (*Logbook, error) {
+	lb := &Logbook{path: path, nextID: 1}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lb, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &lb.qsos); err != nil {
+		return nil, fmt.Errorf("failed to decode logbook: %w", err)
+	}
+	for _, q := range lb.qsos {
+		lb.nextID = max(lb.nextID, q.ID+1)
+	}
+	return lb, nil
+}
+
+// Path is the file the logbook is saved to
+func (lb *Logbook) Path() string {
+	return lb.path
+}
+
+// save writes the logbook to a temporary file and renames it into place, so
+// that a crash can't leave a truncated log behind. Called with mu held.
+func (lb *Logbook) save() error {
+	data, err := json.MarshalIndent(lb.qsos, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(lb.path), ".logbook-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), lb.path)
+}
+
+// All returns every QSO, newest first
+func (lb *Logbook) All() []QSO {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	qsos := slices.Clone(lb.qsos)
+	slices.SortStableFunc(qsos, func(a, b QSO) int {
+		return b.Time.Compare(a.Time)
+	})
+	return qsos
+}
+
+// Search returns the QSOs whose call, name or notes contain query (ignoring
+// case), newest first
+func (lb *Logbook) Search(query string) []QSO {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	qsos := lb.All()
+	if query == "" {
+		return qsos
+	}
+	return slices.DeleteFunc(qsos, func(q QSO) bool {
+		return !strings.Contains(strings.ToUpper(q.Call), query) &&
+			!strings.Contains(strings.ToUpper(q.Name), query) &&
+			!strings.Contains(strings.ToUpper(q.Notes), query)
+	})
+}
+
+// normalize fills in the band and tidies up fields typed by hand
+func normalize(q QSO) QSO {
+	q.Call = strings.ToUpper(strings.TrimSpace(q.Call))
+	q.Mode = strings.ToUpper(strings.TrimSpace(q.Mode))
+	q.Submode = strings.ToUpper(strings.TrimSpace(q.Submode))
+	q.Contest = strings.ToUpper(strings.TrimSpace(q.Contest))
+	q.Time = q.Time.UTC().Truncate(time.Second)
+	if q.Band == "" {
+		q.Band = bandplan.BandName(q.Freq)
+	}
+	return q
+}
+
+// Add logs a new QSO and returns it with its ID assigned
+func (lb *Logbook) Add(q QSO) (QSO, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	q = normalize(q)
+	q.ID = lb.nextID
+	lb.nextID++
+	lb.qsos = append(lb.qsos, q)
+	return q, lb.save()
+}
+
+// Update replaces the QSO with the same ID
+func (lb *Logbook) Update(q QSO) error {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	idx := slices.IndexFunc(lb.qsos, func(existing QSO) bool { return existing.ID == q.ID })
+	if idx < 0 {
+		return fmt.Errorf("no QSO with ID %d", q.ID)
+	}
+	lb.qsos[idx] = normalize(q)
+	return lb.save()
+}
+
+// Delete removes the QSO with the given ID
+func (lb *Logbook) Delete(id int) error {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	idx := slices.IndexFunc(lb.qsos, func(q QSO) bool { return q.ID == id })
+	if idx < 0 {
+		return fmt.Errorf("no QSO with ID %d", id)
+	}
+	lb.qsos = slices.Delete(lb.qsos, idx, idx+1)
+	return lb.save()
+}
+
+// Import adds QSOs, skipping any that are already in the log (same call, band
+// and mode within a minute). It returns how many were added.
+func (lb *Logbook) Import(qsos []QSO) (int, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	added := 0
+	for _, q := range qsos {
+		q = normalize(q)
+		dupe := slices.ContainsFunc(lb.qsos, func(existing QSO) bool {
+			diff := existing.Time.Sub(q.Time)
+			return existing.Call == q.Call && existing.Band == q.Band && existing.Mode == q.Mode &&
+				diff > -time.Minute && diff < time.Minute
+		})
+		if dupe {
+			continue
+		}
+		q.ID = lb.nextID
+		lb.nextID++
+		lb.qsos = append(lb.qsos, q)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	return added, lb.save()
+}
+
+// ModeFromSlice converts a FlexRadio slice mode to an ADIF mode and submode
+func ModeFromSlice(mode string) (string, string) {
+	switch mode {
+	case "USB", "LSB":
+		return "SSB", mode
+	case "SAM":
+		return "AM", ""
+	case "NFM", "DFM":
+		return "FM", ""
+	case "DIGU", "DIGL":
+		// Could be any data mode, so leave it to the operator
+		return "", ""
+	case "FDV":
+		return "DIGITALVOICE", "FREEDV"
+	}
+	return mode, ""
+}
+
+// DefaultRST is the usual signal report for an ADIF mode, or "" for modes
+// that don't use RST
+func DefaultRST(mode string) string {
+	switch mode {
+	case "SSB", "AM", "FM", "DIGITALVOICE":
+		return "59"
+	case "CW", "RTTY":
+		return "599"
+	}
+	return ""
+}
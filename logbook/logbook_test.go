@@ -0,0 +1,120 @@
+package logbook
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTemp(t *testing.T) *Logbook {
+	t.Helper()
+	lb, err := OpenFile(filepath.Join(t.TempDir(), "logbook.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lb
+}
+
+func TestImportDuplicates(t *testing.T) {
+	lb := openTemp(t)
+	at := time.Date(2025, 10, 13, 12, 28, 0, 0, time.UTC)
+	first := QSO{Time: at, Call: "W9XYZ", Freq: 14.074, Mode: "FT8"}
+	if n, err := lb.Import([]QSO{first}); err != nil || n != 1 {
+		t.Fatalf("Import = %d, %v; want 1", n, err)
+	}
+
+	tests := []struct {
+		name string
+		qso  QSO
+		dupe bool
+	}{
+		{"the same QSO", first, true},
+		{"59 seconds later", QSO{Time: at.Add(59 * time.Second), Call: "W9XYZ", Band: "20m", Mode: "FT8"}, true},
+		{"59 seconds earlier", QSO{Time: at.Add(-59 * time.Second), Call: "w9xyz ", Freq: 14.2, Mode: "ft8"}, true},
+		{"a minute later", QSO{Time: at.Add(time.Minute), Call: "W9XYZ", Freq: 14.074, Mode: "FT8"}, false},
+		{"another band", QSO{Time: at, Call: "W9XYZ", Freq: 7.074, Mode: "FT8"}, false},
+		{"another mode", QSO{Time: at, Call: "W9XYZ", Freq: 14.074, Mode: "MFSK", Submode: "FT4"}, false},
+		{"another call", QSO{Time: at, Call: "K1AB", Freq: 14.074, Mode: "FT8"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := openTemp(t)
+			if _, err := lb.Import([]QSO{first}); err != nil {
+				t.Fatal(err)
+			}
+			n, err := lb.Import([]QSO{tt.qso})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := map[bool]int{true: 0, false: 1}[tt.dupe]; n != want {
+				t.Errorf("Import added %d, want %d", n, want)
+			}
+		})
+	}
+
+	// Duplicates within one import are skipped too
+	n, err := lb.Import([]QSO{
+		{Time: at.Add(time.Hour), Call: "K1AB", Freq: 14.074, Mode: "FT8"},
+		{Time: at.Add(time.Hour + 30*time.Second), Call: "K1AB", Freq: 14.075, Mode: "FT8"},
+	})
+	if err != nil || n != 1 {
+		t.Errorf("Import of a QSO twice = %d, %v; want 1", n, err)
+	}
+}
+
+func TestImportSaves(t *testing.T) {
+	lb := openTemp(t)
+	qsos := []QSO{
+		{Time: time.Date(2025, 10, 13, 12, 28, 7, 500, time.UTC), Call: "w9xyz", Freq: 14.074, Mode: "ft8"},
+		{Time: time.Date(2025, 10, 13, 13, 0, 0, 0, time.UTC), Call: "K1AB", Band: "40m", Mode: "CW"},
+	}
+	if n, err := lb.Import(qsos); err != nil || n != 2 {
+		t.Fatalf("Import = %d, %v; want 2", n, err)
+	}
+	// Nothing new to save
+	if n, err := lb.Import(qsos); err != nil || n != 0 {
+		t.Fatalf("Import again = %d, %v; want 0", n, err)
+	}
+
+	reopened, err := OpenFile(lb.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reopened.All()
+	if len(got) != 2 {
+		t.Fatalf("reopened log has %d QSOs, want 2", len(got))
+	}
+	// Newest first, tidied up, with the band filled in
+	want := QSO{ID: 1, Time: time.Date(2025, 10, 13, 12, 28, 7, 0, time.UTC), Call: "W9XYZ", Freq: 14.074, Band: "20m", Mode: "FT8"}
+	if got[1] != want || got[0].Call != "K1AB" || got[0].ID != 2 {
+		t.Errorf("reopened log = %+v, want K1AB then %+v", got, want)
+	}
+	if q, err := reopened.Add(QSO{Time: time.Now(), Call: "VE3XY"}); err != nil || q.ID != 3 {
+		t.Errorf("Add after reopening = %+v, %v; want ID 3", q, err)
+	}
+}
+
+func TestModeFromSlice(t *testing.T) {
+	tests := []struct {
+		slice, mode, submode string
+	}{
+		{"USB", "SSB", "USB"},
+		{"LSB", "SSB", "LSB"},
+		{"CW", "CW", ""},
+		{"AM", "AM", ""},
+		{"SAM", "AM", ""},
+		{"FM", "FM", ""},
+		{"NFM", "FM", ""},
+		{"DFM", "FM", ""},
+		{"RTTY", "RTTY", ""},
+		{"FDV", "DIGITALVOICE", "FREEDV"},
+		// Could be any data mode
+		{"DIGU", "", ""},
+		{"DIGL", "", ""},
+	}
+	for _, tt := range tests {
+		if mode, submode := ModeFromSlice(tt.slice); mode != tt.mode || submode != tt.submode {
+			t.Errorf("ModeFromSlice(%s) = %q, %q; want %q, %q", tt.slice, mode, submode, tt.mode, tt.submode)
+		}
+	}
+}
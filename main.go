@@ -5,22 +5,42 @@ import (
 	"log"
 	"os"
 
-	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/vimeo/dials"
 	"github.com/vimeo/dials/sources/env"
 	"github.com/vimeo/dials/sources/flag"
 
+	"github.com/kc2g-flex-tools/minstrel/accel"
+	"github.com/kc2g-flex-tools/minstrel/api"
 	"github.com/kc2g-flex-tools/minstrel/audio"
+	"github.com/kc2g-flex-tools/minstrel/bandplan"
+	"github.com/kc2g-flex-tools/minstrel/cat"
+	"github.com/kc2g-flex-tools/minstrel/dxcluster"
 	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/input"
+	"github.com/kc2g-flex-tools/minstrel/logbook"
 	"github.com/kc2g-flex-tools/minstrel/midi"
 	"github.com/kc2g-flex-tools/minstrel/radio"
-	"github.com/kc2g-flex-tools/minstrel/ui"
+	"github.com/kc2g-flex-tools/minstrel/rigctl"
+	"github.com/kc2g-flex-tools/minstrel/txguard"
+	"github.com/kc2g-flex-tools/minstrel/wsjtx"
 )
 
 type Config struct {
-	Station string `dialsdesc:"Station name"`
-	Profile string `dialsdesc:"Global profile to load on startup"`
-	UI      *ui.Config
+	Station   string `dialsdesc:"Station name"`
+	Profile   string `dialsdesc:"Global profile to load on startup"`
+	Headless  bool   `dialsdesc:"Run without the UI, connecting to --radio and starting audio" dialsflag:"headless"`
+	Radio     string `dialsdesc:"Radio to connect to in headless mode (serial number, nickname or IP[:port])" dialsflag:"radio"`
+	UI        *uiConfig
+	Audio     *audio.Config
+	Rigctl    *rigctl.Config
+	CAT       *cat.Config
+	API       *api.Config
+	DXCluster *dxcluster.Config
+	WSJTX     *wsjtx.Config
+	BandPlan  *bandplan.Config
+	TX        *txguard.Config
+	VFO       *accel.Config
+	Input     *input.Config
 }
 
 var config *Config
@@ -32,9 +52,20 @@ func defaultConfig() *Config {
 	}
 
 	return &Config{
-		Station: stationName,
-		Profile: "",
-		UI:      ui.DefaultConfig(),
+		Station:   stationName,
+		Profile:   "",
+		Headless:  !guiAvailable,
+		UI:        defaultUIConfig(),
+		Audio:     audio.DefaultConfig(),
+		Rigctl:    rigctl.DefaultConfig(),
+		CAT:       cat.DefaultConfig(),
+		API:       api.DefaultConfig(),
+		DXCluster: dxcluster.DefaultConfig(),
+		WSJTX:     wsjtx.DefaultConfig(),
+		BandPlan:  bandplan.DefaultConfig(),
+		TX:        txguard.DefaultConfig(),
+		VFO:       accel.DefaultConfig(),
+		Input:     input.DefaultConfig(),
 	}
 }
 
@@ -56,42 +87,114 @@ func main() {
 	// Create event bus
 	eventBus := events.NewBus()
 
+	// Load the band plan and license privileges
+	plan, err := bandplan.Load(config.BandPlan)
+	if err != nil {
+		log.Fatal("Failed to load band plan: ", err)
+	}
+
 	// Create audio context
-	audioCtx := audio.NewAudio()
+	audioCtx := audio.NewAudio(config.Audio)
+
+	// Tuning acceleration for MIDI knobs and the mouse wheel
+	vfoCurve, err := config.VFO.Curve()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Create MIDI context with default config (will be configured via UI)
-	midiCtx := midi.NewMIDI(midi.DefaultConfig(), eventBus)
+	midiCfg := midi.DefaultConfig()
+	midiCfg.Acceleration = vfoCurve
+	midiCtx := midi.NewMIDI(midiCfg, eventBus)
+	go midiCtx.RunFeedback(mainCtx)
 
 	// Create RadioState before UI - it now owns discovery
-	rs := radio.NewRadioState(audioCtx, midiCtx, eventBus, config.Station, config.Profile)
-
-	// Create UI with event bus
-	u := ui.NewUI(config.UI, eventBus)
-	u.RadioShim = rs
-	u.AudioShim = audioCtx
-	u.MIDIShim = midiCtx
-
-	// Start UI event handler
-	go u.HandleEvents(eventBus.Subscribe(100))
-
-	// Start RadioState event handler for connection requests
-	go func() {
-		eventChan := eventBus.Subscribe(10)
-		for event := range eventChan {
-			switch e := event.(type) {
-			case events.RadioSelected:
-				if err := rs.ConnectToRadio(mainCtx, e.Address); err != nil {
-					log.Println("Connection error:", err)
-					// TODO: show error in UI
-				}
+	rs := radio.NewRadioState(audioCtx, midiCtx, eventBus, config.Station, config.Profile, config.Radio)
+	if config.BandPlan.Inhibit {
+		rs.SetBandPlan(plan)
+	}
+
+	// The scanner is controlled through the event bus
+	scanner := radio.NewScanner(rs, eventBus)
+	go scanner.Run(mainCtx)
+
+	// Stop transmitting after the TX timeout or if the UI hangs
+	guard := txguard.NewGuard(config.TX, rs, eventBus)
+	go guard.Run(mainCtx)
+
+	// Start the rigctld-compatible server if configured
+	if config.Rigctl.Listen != "" {
+		rigctlServer := rigctl.NewServer(config.Rigctl, rs, eventBus)
+		go func() {
+			if err := rigctlServer.Run(mainCtx); err != nil {
+				log.Println("rigctl server error:", err)
 			}
-		}
-	}()
+		}()
+	}
 
-	// Start radio discovery
-	rs.StartDiscovery(mainCtx)
+	// Start the CAT serial port if configured
+	if config.CAT.Enabled {
+		catServer := cat.NewServer(config.CAT, rs, eventBus)
+		go func() {
+			if err := catServer.Run(mainCtx); err != nil {
+				log.Println("CAT server error:", err)
+			}
+		}()
+	}
 
-	if err := ebiten.RunGame(u); err != nil {
-		log.Fatal(err)
+	// Start the HTTP API if enabled
+	if config.API.Enabled {
+		apiServer := api.NewServer(config.API, rs, eventBus)
+		go func() {
+			if err := apiServer.Run(mainCtx); err != nil {
+				log.Println("API server error:", err)
+			}
+		}()
 	}
+
+	// Read jog wheels, foot switches and knobs if enabled
+	if config.Input.Enabled {
+		inputManager := input.NewManager(config.Input, rs, eventBus, vfoCurve)
+		go func() {
+			if err := inputManager.Run(mainCtx); err != nil {
+				log.Println("Input device error:", err)
+			}
+		}()
+	}
+
+	// Connect to the DX cluster if configured
+	if config.DXCluster.Host != "" {
+		dxClient := dxcluster.NewClient(config.DXCluster, eventBus)
+		go func() {
+			if err := dxClient.Run(mainCtx); err != nil {
+				log.Println("DX cluster error:", err)
+			}
+		}()
+	}
+
+	// Open the logbook, which WSJT-X can add QSOs to as well as the UI
+	lb, err := logbook.Open()
+	if err != nil {
+		log.Println("Failed to open logbook:", err)
+	}
+
+	// Listen for WSJT-X if configured
+	if config.WSJTX.Listen != "" {
+		wsjtxListener := wsjtx.NewListener(config.WSJTX, eventBus, lb)
+		go func() {
+			if err := wsjtxListener.Run(mainCtx); err != nil {
+				log.Println("WSJT-X listener error:", err)
+			}
+		}()
+	}
+
+	if config.Headless {
+		runHeadless(mainCtx, rs, eventBus, config.Radio)
+		return
+	}
+	if !guiAvailable {
+		log.Fatal("built without the UI, so only --headless is supported")
+	}
+
+	runGUI(mainCtx, config.UI, rs, audioCtx, midiCtx, eventBus, lb, plan, guard, vfoCurve)
 }
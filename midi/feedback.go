@@ -0,0 +1,154 @@
+package midi
+
+import (
+	"context"
+	"log"
+
+	"gitlab.com/gomidi/midi/v2"
+
+	"github.com/kc2g-flex-tools/minstrel/controls"
+	"github.com/kc2g-flex-tools/minstrel/events"
+)
+
+// feedbackState is the radio state shown on the controller
+type feedbackState struct {
+	transmitting bool
+	tuning       bool
+	vox          bool
+	scanning     bool
+	activeSlice  string // letter
+	muted        bool
+	volume       int
+}
+
+// RunFeedback follows the radio state until ctx is done, sending it to the
+// controller's LEDs and faders while a device with an output port is
+// connected
+func (m *MIDI) RunFeedback(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-m.fbEvents:
+			m.fbMu.Lock()
+			switch e := event.(type) {
+			case events.TransmitStateChanged:
+				m.fb.transmitting = e.Transmitting
+			case events.VOXStateChanged:
+				m.fb.vox = e.Enabled
+			case events.TransmitParamsChanged:
+				if tune, ok := e.Params["tune"]; ok {
+					m.fb.tuning = tune == "1"
+				}
+			case events.ScanStateChanged:
+				m.fb.scanning = e.Running
+			case events.SlicesUpdated:
+				m.fb.activeSlice, m.fb.muted, m.fb.volume = "", false, 0
+				for letter, slice := range e.Slices {
+					if slice.Present && slice.Active {
+						m.fb.activeSlice, m.fb.muted, m.fb.volume = letter, slice.Muted, slice.Volume
+					}
+				}
+			}
+			m.fbMu.Unlock()
+			m.sendFeedback(false)
+		}
+	}
+}
+
+// openFeedback opens the output port matching the input port portName. Not
+// every controller has one, so failing isn't an error.
+func (m *MIDI) openFeedback(portName string) {
+	out, err := midi.FindOutPort(portName)
+	if err != nil {
+		log.Printf("No MIDI output port for %s, so no feedback: %v", portName, err)
+		return
+	}
+	send, err := midi.SendTo(out)
+	if err != nil {
+		log.Printf("Failed to open MIDI output port %s: %v", portName, err)
+		return
+	}
+	m.fbMu.Lock()
+	m.out, m.send = out, send
+	m.sent = make(map[Mapping]byte)
+	m.fbMu.Unlock()
+	m.sendFeedback(true)
+}
+
+// closeFeedback stops sending feedback and closes the output port
+func (m *MIDI) closeFeedback() {
+	m.fbMu.Lock()
+	defer m.fbMu.Unlock()
+	if m.out != nil {
+		m.out.Close()
+	}
+	m.out, m.send, m.sent = nil, nil, nil
+}
+
+// feedbackValue returns the note velocity or control value showing the state
+// of a mapping's action, and false if the action has no state to show
+func (fb *feedbackState) feedbackValue(mapping Mapping) (byte, bool) {
+	var on bool
+	switch mapping.Action {
+	case controls.ActionPTT, controls.ActionMOX:
+		on = fb.transmitting
+	case controls.ActionTuneCarrier:
+		on = fb.tuning
+	case controls.ActionVOX:
+		on = fb.vox
+	case controls.ActionScan:
+		on = fb.scanning
+	case controls.ActionMute:
+		on = fb.muted
+	case controls.ActionSliceA:
+		on = fb.activeSlice == "A"
+	case controls.ActionSliceB:
+		on = fb.activeSlice == "B"
+	case controls.ActionVolume:
+		return byte(min(max(fb.volume, 0), 100) * 127 / 100), true
+	default:
+		return 0, false
+	}
+	if on {
+		return 127, true
+	}
+	return 0, true
+}
+
+// sendFeedback sends the state of each mapped action that has changed since
+// it was last sent, or of all of them if all is set
+func (m *MIDI) sendFeedback(all bool) {
+	mappings := m.Mappings()
+
+	m.fbMu.Lock()
+	defer m.fbMu.Unlock()
+	if m.send == nil {
+		return
+	}
+	for _, mapping := range mappings {
+		value, ok := m.fb.feedbackValue(mapping)
+		if !ok {
+			continue
+		}
+		if last, sent := m.sent[mapping]; sent && last == value && !all {
+			continue
+		}
+		// Mappings for any channel answer on the first
+		ch := byte(max(mapping.Channel-1, 0))
+		var msg midi.Message
+		switch {
+		case mapping.Type == MessageCC:
+			msg = midi.ControlChange(ch, mapping.Number, value)
+		case value > 0:
+			msg = midi.NoteOn(ch, mapping.Number, value)
+		default:
+			msg = midi.NoteOff(ch, mapping.Number)
+		}
+		if err := m.send(msg); err != nil {
+			log.Printf("MIDI feedback error: %v", err)
+			return
+		}
+		m.sent[mapping] = value
+	}
+}
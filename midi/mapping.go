@@ -0,0 +1,120 @@
+package midi
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/kc2g-flex-tools/minstrel/controls"
+	"github.com/kc2g-flex-tools/minstrel/persistence"
+)
+
+// Mapping binds a MIDI note or control change to an action
+type Mapping = persistence.MIDIMapping
+
+// Message types a mapping can match
+const (
+	MessageNote = "note"
+	MessageCC   = "cc"
+)
+
+// DescribeMessage describes the message a mapping matches, e.g. "CC 100 ch 2"
+func DescribeMessage(m Mapping) string {
+	name := "Note"
+	if m.Type == MessageCC {
+		name = "CC"
+	}
+	if m.Channel == 0 {
+		return fmt.Sprintf("%s %d", name, m.Number)
+	}
+	return fmt.Sprintf("%s %d ch %d", name, m.Number, m.Channel)
+}
+
+// MappingsFromSettings returns the stored mappings, or if there are none the
+// defaults with any fixed controls from older settings applied
+func MappingsFromSettings(s persistence.MIDISettings) []Mapping {
+	if s.Mappings != nil {
+		return s.Mappings
+	}
+	mappings := DefaultConfig().Mappings
+	for i, m := range mappings {
+		switch {
+		case m.Action == controls.ActionTune && s.VFOControl != 0:
+			mappings[i].Number = s.VFOControl
+		case m.Action == controls.ActionVolume && s.VolControl != 0:
+			mappings[i].Number = s.VolControl
+		}
+	}
+	return mappings
+}
+
+// Mappings returns a copy of the current mappings
+func (m *MIDI) Mappings() []Mapping {
+	m.mapMu.RLock()
+	defer m.mapMu.RUnlock()
+	return slices.Clone(m.cfg.Mappings)
+}
+
+// SetMappings replaces the mappings
+func (m *MIDI) SetMappings(mappings []Mapping) {
+	m.mapMu.Lock()
+	m.cfg.Mappings = slices.Clone(mappings)
+	m.mapMu.Unlock()
+	// Light up any newly mapped controls
+	m.sendFeedback(false)
+}
+
+// Learn passes the next note on or control change to cb, as a mapping with no
+// action, instead of acting on it
+func (m *MIDI) Learn(cb func(Mapping)) {
+	m.mapMu.Lock()
+	defer m.mapMu.Unlock()
+	m.learn = cb
+}
+
+// CancelLearn stops waiting for a message to learn
+func (m *MIDI) CancelLearn() {
+	m.Learn(nil)
+}
+
+// learned hands the message to the learn callback if there is one, and
+// reports whether it did
+func (m *MIDI) learned(msgType string, ch, number byte) bool {
+	m.mapMu.Lock()
+	cb := m.learn
+	m.learn = nil
+	m.mapMu.Unlock()
+	if cb == nil {
+		return false
+	}
+	cb(Mapping{Type: msgType, Channel: int(ch) + 1, Number: number})
+	return true
+}
+
+// lookup finds the action mapped to a message on channel ch (0-15)
+func (m *MIDI) lookup(msgType string, ch, number byte) (controls.ActionInfo, bool) {
+	m.mapMu.RLock()
+	defer m.mapMu.RUnlock()
+	for _, mapping := range m.cfg.Mappings {
+		if mapping.Type == msgType && mapping.Number == number && (mapping.Channel == 0 || mapping.Channel == int(ch)+1) {
+			return controls.LookupAction(mapping.Action)
+		}
+	}
+	return controls.ActionInfo{}, false
+}
+
+// handleMessage acts on a note on (value 127), note off (value 0) or control
+// change. Relative actions take control changes centered on 64, and buttons
+// are pressed by a note on or a control change of 64 or more.
+func (m *MIDI) handleMessage(d *controls.Dispatcher, msgType string, ch, number, value byte) {
+	action, ok := m.lookup(msgType, ch, number)
+	if !ok {
+		return
+	}
+	if action.Relative {
+		if msgType == MessageCC {
+			d.Adjust(action.Name, int(value)-64)
+		}
+		return
+	}
+	d.Press(action.Name, value >= 64)
+}
@@ -5,34 +5,35 @@ import (
 	"fmt"
 	"log"
 	"sync"
-	"time"
 
 	"gitlab.com/gomidi/midi/v2"
+	"gitlab.com/gomidi/midi/v2/drivers"
 	_ "gitlab.com/gomidi/midi/v2/drivers/rtmididrv"
 
+	"github.com/kc2g-flex-tools/minstrel/accel"
+	"github.com/kc2g-flex-tools/minstrel/controls"
 	"github.com/kc2g-flex-tools/minstrel/events"
 	"github.com/kc2g-flex-tools/minstrel/radioshim"
 )
 
 type Config struct {
-	Enabled         bool
-	Port            string
-	VFOControl      byte
-	VolControl      byte
-	LeftPaddleNote  byte
-	RightPaddleNote byte
-	PTTNote         byte
+	Enabled  bool
+	Port     string
+	Mappings []Mapping
+	// Acceleration speeds up tuning when the VFO knob is turned quickly
+	Acceleration accel.Curve
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		Enabled:         false,
-		Port:            "",
-		VFOControl:      100,
-		VolControl:      102,
-		LeftPaddleNote:  20,
-		RightPaddleNote: 21,
-		PTTNote:         31,
+		Enabled: false,
+		Port:    "",
+		Mappings: []Mapping{
+			{Type: MessageCC, Number: 100, Action: controls.ActionTune},
+			{Type: MessageCC, Number: 102, Action: controls.ActionVolume},
+			{Type: MessageNote, Number: 31, Action: controls.ActionPTT},
+			{Type: MessageNote, Number: 32, Action: controls.ActionScan},
+		},
 	}
 }
 
@@ -41,20 +42,6 @@ type Device struct {
 	Name string
 }
 
-// ControlEventType identifies the type of control event
-type ControlEventType int
-
-const (
-	VFOControl ControlEventType = iota
-	VolumeControl
-)
-
-// ControlEvent represents a MIDI control change event
-type ControlEvent struct {
-	Type  ControlEventType
-	Delta int
-}
-
 type MIDI struct {
 	mu           sync.RWMutex
 	cfg          *Config
@@ -63,15 +50,28 @@ type MIDI struct {
 	connected    bool
 	lastError    string
 	currentPort  string
-	controlChan  chan ControlEvent
 	workerCancel context.CancelFunc
+
+	// mapMu guards cfg.Mappings and learn
+	mapMu sync.RWMutex
+	// learn receives the next message instead of it being acted on
+	learn func(Mapping)
+
+	// Feedback to the controller. fbMu guards the fields below it.
+	fbEvents chan events.Event
+	fbMu     sync.Mutex
+	fb       feedbackState
+	out      drivers.Out
+	send     func(midi.Message) error
+	// The last value sent for each mapping
+	sent map[Mapping]byte
 }
 
 func NewMIDI(cfg *Config, eventBus *events.Bus) *MIDI {
 	return &MIDI{
-		cfg:         cfg,
-		eventBus:    eventBus,
-		controlChan: make(chan ControlEvent, 100), // Buffered channel for non-blocking sends
+		cfg:      cfg,
+		eventBus: eventBus,
+		fbEvents: eventBus.Subscribe(100),
 	}
 }
 
@@ -85,92 +85,15 @@ func GetDevices() []Device {
 	return devices
 }
 
-// startControlWorker starts a goroutine that processes control events with rate limiting
-func (m *MIDI) startControlWorker(ctx context.Context, rs radioshim.Shim) {
+// startControlWorker starts a dispatcher carrying out the mapped actions,
+// with knob movements rate limited
+func (m *MIDI) startControlWorker(ctx context.Context, rs radioshim.Shim) *controls.Dispatcher {
 	workerCtx, cancel := context.WithCancel(ctx)
 	m.workerCancel = cancel
 
-	go func() {
-		const rateLimit = 50 * time.Millisecond // Max 20 events per second
-
-		// Accumulators for each control type
-		vfoAccumulator := 0
-		volumeAccumulator := 0
-
-		timer := time.NewTimer(rateLimit)
-		timer.Stop() // Stop initially, will be started on first event
-		timerActive := false
-
-		for {
-			select {
-			case <-workerCtx.Done():
-				timer.Stop()
-				return
-
-			case event := <-m.controlChan:
-				// Accumulate the delta based on event type
-				switch event.Type {
-				case VFOControl:
-					vfoAccumulator += event.Delta
-				case VolumeControl:
-					volumeAccumulator += event.Delta
-				}
-
-				if !timerActive {
-					// No pending timer - send immediately and start timer
-					m.applyControlEvents(rs, &vfoAccumulator, &volumeAccumulator)
-					timer.Reset(rateLimit)
-					timerActive = true
-				}
-				// If timer is active, just accumulate (will be sent when timer fires)
-
-			case <-timer.C:
-				// Timer expired - send any accumulated events
-				if vfoAccumulator != 0 || volumeAccumulator != 0 {
-					m.applyControlEvents(rs, &vfoAccumulator, &volumeAccumulator)
-					// Restart timer in case more events come
-					timer.Reset(rateLimit)
-				} else {
-					// No accumulated events - mark timer inactive
-					timerActive = false
-				}
-			}
-		}
-	}()
-}
-
-// applyControlEvents applies accumulated control changes to the radio
-func (m *MIDI) applyControlEvents(rs radioshim.Shim, vfoAccumulator, volumeAccumulator *int) {
-	slices := rs.GetSlices()
-
-	// Apply VFO changes
-	if *vfoAccumulator != 0 {
-		for _, slice := range slices {
-			if slice.Active {
-				rs.TuneSliceStep(slice, *vfoAccumulator)
-			}
-		}
-		*vfoAccumulator = 0
-	}
-
-	// Apply volume changes
-	if *volumeAccumulator != 0 {
-		for _, slice := range slices {
-			if slice.Active {
-				// Volume uses the full accumulated delta (no multiplier)
-				newVolume := slice.Volume + *volumeAccumulator
-				// Clamp to valid range (0-100)
-				if newVolume < 0 {
-					newVolume = 0
-				}
-				if newVolume > 100 {
-					newVolume = 100
-				}
-				rs.SetSliceVolume(slice.Index, newVolume)
-			}
-		}
-		*volumeAccumulator = 0
-	}
+	d := controls.NewDispatcher(rs, m.eventBus, m.cfg.Acceleration)
+	go d.Run(workerCtx)
+	return d
 }
 
 // Connect attempts to connect to the specified MIDI device
@@ -189,6 +112,7 @@ func (m *MIDI) Connect(ctx context.Context, portName string, rs radioshim.Shim)
 		m.cancel()
 		m.cancel = nil
 	}
+	m.closeFeedback()
 
 	if portName == "" {
 		m.connected = false
@@ -210,41 +134,20 @@ func (m *MIDI) Connect(ctx context.Context, portName string, rs radioshim.Shim)
 	m.cancel = cancel
 
 	// Start the control event worker
-	m.startControlWorker(ctx, rs)
+	d := m.startControlWorker(ctx, rs)
 
 	stopListen, err := midi.ListenTo(in, func(msg midi.Message, timestamp int32) {
 		var ch, id, val byte
 		switch {
 		case msg.GetNoteStart(&ch, &id, &val):
-			switch id {
-			case m.cfg.PTTNote:
-				rs.SetPTT(true)
+			if !m.learned(MessageNote, ch, id) {
+				m.handleMessage(d, MessageNote, ch, id, 127)
 			}
 		case msg.GetNoteEnd(&ch, &id):
-			switch id {
-			case m.cfg.PTTNote:
-				rs.SetPTT(false)
-			}
+			m.handleMessage(d, MessageNote, ch, id, 0)
 		case msg.GetControlChange(&ch, &id, &val):
-			switch id {
-			case m.cfg.VFOControl:
-				delta := int(val) - 64
-				// Send to worker channel (non-blocking due to buffer)
-				select {
-				case m.controlChan <- ControlEvent{Type: VFOControl, Delta: delta}:
-				default:
-					// Channel full - drop event (should be rare with 100 buffer)
-					log.Printf("Warning: MIDI control channel full, dropping VFO event")
-				}
-			case m.cfg.VolControl:
-				delta := int(val) - 64
-				// Send to worker channel (non-blocking due to buffer)
-				select {
-				case m.controlChan <- ControlEvent{Type: VolumeControl, Delta: delta}:
-				default:
-					// Channel full - drop event (should be rare with 100 buffer)
-					log.Printf("Warning: MIDI control channel full, dropping volume event")
-				}
+			if !m.learned(MessageCC, ch, id) {
+				m.handleMessage(d, MessageCC, ch, id, val)
 			}
 		default:
 			fmt.Printf("MIDI unknown message: %v\n", msg)
@@ -268,6 +171,9 @@ func (m *MIDI) Connect(ctx context.Context, portName string, rs radioshim.Shim)
 	m.currentPort = portName
 	log.Printf("MIDI connected to %s", portName)
 
+	// Light up the controller, if it can be
+	m.openFeedback(portName)
+
 	// Start goroutine to handle disconnection
 	go func() {
 		<-listenCtx.Done()
@@ -276,6 +182,9 @@ func (m *MIDI) Connect(ctx context.Context, portName string, rs radioshim.Shim)
 		m.connected = false
 		m.mu.Unlock()
 		log.Println("MIDI disconnected")
+		if d.ReleasePTT() {
+			log.Println("MIDI disconnected with the PTT note down, dropped PTT")
+		}
 	}()
 
 	return nil
@@ -295,6 +204,7 @@ func (m *MIDI) Disconnect() {
 		m.cancel()
 		m.cancel = nil
 	}
+	m.closeFeedback()
 	m.connected = false
 	m.currentPort = ""
 }
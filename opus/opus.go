@@ -17,6 +17,50 @@ int opus_encoder_set_vbr(OpusEncoder *st, int vbr) {
 	return opus_encoder_ctl(st, OPUS_SET_VBR_REQUEST, vbr);
 }
 
+int opus_encoder_set_application(OpusEncoder *st, int application) {
+	return opus_encoder_ctl(st, OPUS_SET_APPLICATION_REQUEST, application);
+}
+
+int opus_encoder_set_inband_fec(OpusEncoder *st, int fec) {
+	return opus_encoder_ctl(st, OPUS_SET_INBAND_FEC_REQUEST, fec);
+}
+
+int opus_encoder_set_packet_loss_perc(OpusEncoder *st, int perc) {
+	return opus_encoder_ctl(st, OPUS_SET_PACKET_LOSS_PERC_REQUEST, perc);
+}
+
+int opus_encoder_set_dtx(OpusEncoder *st, int dtx) {
+	return opus_encoder_ctl(st, OPUS_SET_DTX_REQUEST, dtx);
+}
+
+int opus_encoder_get_bitrate(OpusEncoder *st, opus_int32 *bitrate) {
+	return opus_encoder_ctl(st, OPUS_GET_BITRATE_REQUEST, bitrate);
+}
+
+int opus_encoder_get_complexity(OpusEncoder *st, opus_int32 *complexity) {
+	return opus_encoder_ctl(st, OPUS_GET_COMPLEXITY_REQUEST, complexity);
+}
+
+int opus_encoder_get_vbr(OpusEncoder *st, opus_int32 *vbr) {
+	return opus_encoder_ctl(st, OPUS_GET_VBR_REQUEST, vbr);
+}
+
+int opus_encoder_get_application(OpusEncoder *st, opus_int32 *application) {
+	return opus_encoder_ctl(st, OPUS_GET_APPLICATION_REQUEST, application);
+}
+
+int opus_encoder_get_inband_fec(OpusEncoder *st, opus_int32 *fec) {
+	return opus_encoder_ctl(st, OPUS_GET_INBAND_FEC_REQUEST, fec);
+}
+
+int opus_encoder_get_packet_loss_perc(OpusEncoder *st, opus_int32 *perc) {
+	return opus_encoder_ctl(st, OPUS_GET_PACKET_LOSS_PERC_REQUEST, perc);
+}
+
+int opus_encoder_get_dtx(OpusEncoder *st, opus_int32 *dtx) {
+	return opus_encoder_ctl(st, OPUS_GET_DTX_REQUEST, dtx);
+}
+
 */
 import "C"
 import (
@@ -128,20 +172,140 @@ func (e *Encoder) SetComplexity(complexity int) error {
 }
 
 func (e *Encoder) SetVBR(vbr bool) error {
-	vbrInt := 0
-	if vbr {
-		vbrInt = 1
-	}
-	ret := C.opus_encoder_set_vbr(e.encoder, C.int(vbrInt))
+	ret := C.opus_encoder_set_vbr(e.encoder, C.int(boolToInt(vbr)))
 	if ret != C.OPUS_OK {
 		return errors.New("failed to set vbr")
 	}
 	return nil
 }
 
+// SetApplication changes the intended application. libopus only allows this
+// before the first frame is encoded; afterwards a new encoder is needed.
+func (e *Encoder) SetApplication(application int) error {
+	ret := C.opus_encoder_set_application(e.encoder, C.int(application))
+	if ret != C.OPUS_OK {
+		return errors.New("failed to set application")
+	}
+	return nil
+}
+
+// SetInbandFEC enables in-band forward error correction, which lets the
+// decoder recover a lost packet from the one that follows it
+func (e *Encoder) SetInbandFEC(fec bool) error {
+	ret := C.opus_encoder_set_inband_fec(e.encoder, C.int(boolToInt(fec)))
+	if ret != C.OPUS_OK {
+		return errors.New("failed to set inband fec")
+	}
+	return nil
+}
+
+// SetPacketLossPerc sets the expected packet loss percentage (0-100), which
+// controls how much bitrate is spent on FEC
+func (e *Encoder) SetPacketLossPerc(perc int) error {
+	ret := C.opus_encoder_set_packet_loss_perc(e.encoder, C.int(perc))
+	if ret != C.OPUS_OK {
+		return errors.New("failed to set packet loss percentage")
+	}
+	return nil
+}
+
+// SetDTX enables discontinuous transmission, which sends only occasional
+// comfort noise packets during silence
+func (e *Encoder) SetDTX(dtx bool) error {
+	ret := C.opus_encoder_set_dtx(e.encoder, C.int(boolToInt(dtx)))
+	if ret != C.OPUS_OK {
+		return errors.New("failed to set dtx")
+	}
+	return nil
+}
+
+// getCTL reads an encoder setting with one of the opus_encoder_get_ helpers
+func (e *Encoder) getCTL(name string, get func(*C.OpusEncoder, *C.opus_int32) C.int) (int, error) {
+	var value C.opus_int32
+	if ret := get(e.encoder, &value); ret != C.OPUS_OK {
+		return 0, fmt.Errorf("failed to get %s", name)
+	}
+	return int(value), nil
+}
+
+// Bitrate returns the bitrate in bits per second
+func (e *Encoder) Bitrate() (int, error) {
+	return e.getCTL("bitrate", func(st *C.OpusEncoder, v *C.opus_int32) C.int {
+		return C.opus_encoder_get_bitrate(st, v)
+	})
+}
+
+// Complexity returns the computational complexity (0-10)
+func (e *Encoder) Complexity() (int, error) {
+	return e.getCTL("complexity", func(st *C.OpusEncoder, v *C.opus_int32) C.int {
+		return C.opus_encoder_get_complexity(st, v)
+	})
+}
+
+// VBR returns whether variable bitrate is enabled
+func (e *Encoder) VBR() (bool, error) {
+	vbr, err := e.getCTL("vbr", func(st *C.OpusEncoder, v *C.opus_int32) C.int {
+		return C.opus_encoder_get_vbr(st, v)
+	})
+	return vbr != 0, err
+}
+
+// Application returns the intended application
+func (e *Encoder) Application() (int, error) {
+	return e.getCTL("application", func(st *C.OpusEncoder, v *C.opus_int32) C.int {
+		return C.opus_encoder_get_application(st, v)
+	})
+}
+
+// InbandFEC returns whether in-band forward error correction is enabled
+func (e *Encoder) InbandFEC() (bool, error) {
+	fec, err := e.getCTL("inband fec", func(st *C.OpusEncoder, v *C.opus_int32) C.int {
+		return C.opus_encoder_get_inband_fec(st, v)
+	})
+	return fec != 0, err
+}
+
+// PacketLossPerc returns the expected packet loss percentage
+func (e *Encoder) PacketLossPerc() (int, error) {
+	return e.getCTL("packet loss percentage", func(st *C.OpusEncoder, v *C.opus_int32) C.int {
+		return C.opus_encoder_get_packet_loss_perc(st, v)
+	})
+}
+
+// DTX returns whether discontinuous transmission is enabled
+func (e *Encoder) DTX() (bool, error) {
+	dtx, err := e.getCTL("dtx", func(st *C.OpusEncoder, v *C.opus_int32) C.int {
+		return C.opus_encoder_get_dtx(st, v)
+	})
+	return dtx != 0, err
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func (e *Encoder) Destroy() {
 	if e.encoder != nil {
 		C.opus_encoder_destroy(e.encoder)
 		e.encoder = nil
 	}
 }
+
+// PadToWords pads an encoded packet to a multiple of 4 bytes using Opus
+// padding, so it still decodes to the same audio. VITA-49 payloads must be a
+// whole number of 32-bit words.
+func PadToWords(packet []byte) ([]byte, error) {
+	if len(packet)%4 == 0 {
+		return packet, nil
+	}
+	padded := make([]byte, (len(packet)+3)/4*4)
+	copy(padded, packet)
+	ret := C.opus_packet_pad((*C.uchar)(unsafe.Pointer(&padded[0])), C.opus_int32(len(packet)), C.opus_int32(len(padded)))
+	if ret != C.OPUS_OK {
+		return nil, errors.New("failed to pad packet")
+	}
+	return padded, nil
+}
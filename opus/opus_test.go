@@ -0,0 +1,182 @@
+package opus
+
+import (
+	"math"
+	"testing"
+
+	opuslib "gopkg.in/hraban/opus.v2"
+)
+
+const (
+	testRate     = 24000
+	testChannels = 2
+	testFrame    = testRate / 50 // 20 ms
+)
+
+func newTestEncoder(t *testing.T, application int) *Encoder {
+	t.Helper()
+	enc, err := NewEncoder(testRate, testChannels, application)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(enc.Destroy)
+	return enc
+}
+
+func TestIntCTLs(t *testing.T) {
+	tests := []struct {
+		name  string
+		set   func(*Encoder, int) error
+		get   func(*Encoder) (int, error)
+		value int
+	}{
+		{"bitrate", (*Encoder).SetBitrate, (*Encoder).Bitrate, 6000},
+		{"bitrate", (*Encoder).SetBitrate, (*Encoder).Bitrate, 64000},
+		{"complexity", (*Encoder).SetComplexity, (*Encoder).Complexity, 0},
+		{"complexity", (*Encoder).SetComplexity, (*Encoder).Complexity, 10},
+		{"packet loss", (*Encoder).SetPacketLossPerc, (*Encoder).PacketLossPerc, 0},
+		{"packet loss", (*Encoder).SetPacketLossPerc, (*Encoder).PacketLossPerc, 25},
+		{"packet loss", (*Encoder).SetPacketLossPerc, (*Encoder).PacketLossPerc, 100},
+		{"application", (*Encoder).SetApplication, (*Encoder).Application, ApplicationVoIP},
+		{"application", (*Encoder).SetApplication, (*Encoder).Application, ApplicationRestrictedLowDelay},
+	}
+	for _, tt := range tests {
+		enc := newTestEncoder(t, ApplicationAudio)
+		if err := tt.set(enc, tt.value); err != nil {
+			t.Errorf("set %s %d: %v", tt.name, tt.value, err)
+			continue
+		}
+		got, err := tt.get(enc)
+		if err != nil {
+			t.Errorf("get %s: %v", tt.name, err)
+		} else if got != tt.value {
+			t.Errorf("%s = %d, want %d", tt.name, got, tt.value)
+		}
+	}
+}
+
+func TestBoolCTLs(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(*Encoder, bool) error
+		get  func(*Encoder) (bool, error)
+	}{
+		{"vbr", (*Encoder).SetVBR, (*Encoder).VBR},
+		{"fec", (*Encoder).SetInbandFEC, (*Encoder).InbandFEC},
+		{"dtx", (*Encoder).SetDTX, (*Encoder).DTX},
+	}
+	for _, tt := range tests {
+		enc := newTestEncoder(t, ApplicationAudio)
+		for _, value := range []bool{true, false, true} {
+			if err := tt.set(enc, value); err != nil {
+				t.Errorf("set %s %v: %v", tt.name, value, err)
+				continue
+			}
+			got, err := tt.get(enc)
+			if err != nil {
+				t.Errorf("get %s: %v", tt.name, err)
+			} else if got != value {
+				t.Errorf("%s = %v, want %v", tt.name, got, value)
+			}
+		}
+	}
+}
+
+func TestCTLRanges(t *testing.T) {
+	enc := newTestEncoder(t, ApplicationAudio)
+	if err := enc.SetComplexity(11); err == nil {
+		t.Error("complexity 11 accepted")
+	}
+	if err := enc.SetPacketLossPerc(101); err == nil {
+		t.Error("packet loss 101% accepted")
+	}
+	if err := enc.SetPacketLossPerc(-1); err == nil {
+		t.Error("packet loss -1% accepted")
+	}
+	if err := enc.SetApplication(1234); err == nil {
+		t.Error("unknown application accepted")
+	}
+}
+
+// sineFrames returns n stereo frames of a 1 kHz sine at half scale
+func sineFrames(n int) [][]int16 {
+	frames := make([][]int16, n)
+	for f := range frames {
+		frame := make([]int16, testFrame*testChannels)
+		for i := 0; i < testFrame; i++ {
+			t := float64(f*testFrame+i) / testRate
+			s := int16(16384 * math.Sin(2*math.Pi*1000*t))
+			frame[2*i], frame[2*i+1] = s, s
+		}
+		frames[f] = frame
+	}
+	return frames
+}
+
+func rms(pcm []int16) float64 {
+	var sum float64
+	for _, s := range pcm {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(pcm)))
+}
+
+func TestSineRoundTrip(t *testing.T) {
+	for _, application := range []int{ApplicationAudio, ApplicationVoIP, ApplicationRestrictedLowDelay} {
+		enc := newTestEncoder(t, application)
+		for _, err := range []error{
+			enc.SetBitrate(32000),
+			enc.SetComplexity(5),
+			enc.SetInbandFEC(true),
+			enc.SetPacketLossPerc(10),
+			enc.SetDTX(false),
+		} {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		dec, err := opuslib.NewDecoder(testRate, testChannels)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		frames := sineFrames(10)
+		out := make([]int16, testFrame*testChannels)
+		for i, frame := range frames {
+			packet, err := enc.Encode(frame)
+			if err != nil {
+				t.Fatalf("application %d: encode frame %d: %v", application, i, err)
+			}
+			// Frames go out padded to whole VITA-49 words
+			packet, err = PadToWords(packet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(packet)%4 != 0 {
+				t.Fatalf("padded packet is %d bytes", len(packet))
+			}
+			n, err := dec.Decode(packet, out)
+			if err != nil {
+				t.Fatalf("application %d: decode frame %d: %v", application, i, err)
+			}
+			if n != testFrame {
+				t.Fatalf("decoded %d samples, want %d", n, testFrame)
+			}
+		}
+		// Past the codec delay, the sine comes back at about the same level
+		want := rms(frames[len(frames)-1])
+		if got := rms(out); got < want/2 || got > want*2 {
+			t.Errorf("application %d: decoded RMS %.0f, want about %.0f", application, got, want)
+		}
+	}
+}
+
+func TestApplicationFixedAfterEncoding(t *testing.T) {
+	enc := newTestEncoder(t, ApplicationAudio)
+	if _, err := enc.Encode(sineFrames(1)[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.SetApplication(ApplicationVoIP); err == nil {
+		t.Error("application changed after the first frame")
+	}
+}
@@ -5,23 +5,67 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
 
 	"github.com/adrg/xdg"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
 )
 
 // MIDISettings contains persistent MIDI configuration
 type MIDISettings struct {
-	Port            string `json:"port"`
-	VFOControl      byte   `json:"vfo_control,omitempty"`
-	VolControl      byte   `json:"vol_control,omitempty"`
-	LeftPaddleNote  byte   `json:"left_paddle_note,omitempty"`
-	RightPaddleNote byte   `json:"right_paddle_note,omitempty"`
+	Port string `json:"port"`
+	// Mappings is nil until the mapping has been changed, so that the
+	// defaults (and the older fixed controls below) still apply
+	Mappings []MIDIMapping `json:"mappings"`
+	// Fixed controls from before mappings, kept so that they can be migrated
+	VFOControl      byte `json:"vfo_control,omitempty"`
+	VolControl      byte `json:"vol_control,omitempty"`
+	LeftPaddleNote  byte `json:"left_paddle_note,omitempty"`
+	RightPaddleNote byte `json:"right_paddle_note,omitempty"`
+}
+
+// MIDIMapping binds a MIDI note or control change to an action
+type MIDIMapping struct {
+	Type    string `json:"type"`    // "note" or "cc"
+	Channel int    `json:"channel"` // 1-16, or 0 for any channel
+	Number  byte   `json:"number"`
+	Action  string `json:"action"`
+}
+
+// TXProcessingSettings contains the TX audio processing chain configuration
+// and any presets the user has saved
+type TXProcessingSettings struct {
+	Preset  string                            `json:"preset,omitempty"`
+	Current *audioshim.TXProcessing           `json:"current,omitempty"`
+	Presets map[string]audioshim.TXProcessing `json:"presets,omitempty"`
+}
+
+// UISettings contains the window state and the radio last connected to
+type UISettings struct {
+	WindowWidth  int    `json:"window_width,omitempty"`
+	WindowHeight int    `json:"window_height,omitempty"`
+	Fullscreen   bool   `json:"fullscreen,omitempty"`
+	LastRadio    string `json:"last_radio,omitempty"` // serial number
 }
 
 // Settings contains all persistent application settings
 type Settings struct {
-	ClientID string       `json:"client_id"`
-	MIDI     MIDISettings `json:"midi"`
+	// Radios holds the settings that belong to each radio, keyed by serial
+	// number
+	Radios map[string]*RadioSettings `json:"radios,omitempty"`
+	// The client ID and MIDI settings from before they were kept per radio.
+	// The client ID goes to the radio it was used with (see
+	// AdoptLegacyClientID), and the MIDI settings are the starting point for
+	// each new radio.
+	ClientID     string               `json:"client_id,omitempty"`
+	MIDI         MIDISettings         `json:"midi"`
+	TXProcessing TXProcessingSettings `json:"tx_processing"`
+	// Opus is nil until the encoder has been changed in the UI, so that the
+	// command line options apply until then
+	Opus *audioshim.OpusSettings `json:"opus,omitempty"`
+	UI   UISettings              `json:"ui"`
 }
 
 // SettingsStore handles persistent storage of application settings
@@ -29,6 +73,10 @@ type SettingsStore struct {
 	filepath string
 }
 
+// updateMutex serializes read-modify-write cycles across all SettingsStore
+// instances, since several components save their own part of the file.
+var updateMutex sync.Mutex
+
 // NewSettingsStore creates a new SettingsStore instance
 func NewSettingsStore() (*SettingsStore, error) {
 	filepath, err := xdg.DataFile("minstrel/settings.json")
@@ -120,12 +168,15 @@ func (ss *SettingsStore) migrateFromLegacy() (*Settings, bool, error) {
 	return settings, true, nil
 }
 
-// Save stores the settings to disk
+// Save stores the settings to disk. They're written to a temporary file that
+// replaces the old one, so that a crash part way through doesn't leave a
+// truncated file behind.
 func (ss *SettingsStore) Save(settings *Settings) error {
-	file, err := os.Create(ss.filepath)
+	file, err := os.CreateTemp(filepath.Dir(ss.filepath), ".settings-*.json")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(file.Name()) // Fails harmlessly once renamed
 	defer file.Close()
 
 	encoder := json.NewEncoder(file)
@@ -133,33 +184,27 @@ func (ss *SettingsStore) Save(settings *Settings) error {
 	if err := encoder.Encode(settings); err != nil {
 		return fmt.Errorf("failed to encode settings: %w", err)
 	}
-
-	return nil
-}
-
-// Compatibility functions for legacy client ID storage
-// These can be removed once all users have migrated
-
-// NewClientStore creates a new SettingsStore (legacy name)
-func NewClientStore() (*SettingsStore, error) {
-	return NewSettingsStore()
-}
-
-// LoadClientID retrieves just the client ID (legacy function)
-func (ss *SettingsStore) LoadClientID() (string, error) {
-	settings, err := ss.Load()
-	if err != nil {
-		return "", err
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
 	}
-	return settings.ClientID, nil
+	return os.Rename(file.Name(), ss.filepath)
 }
 
-// SaveClientID stores just the client ID (legacy function)
-func (ss *SettingsStore) SaveClientID(id string) error {
+// Update loads the settings, applies fn to them, and saves the result.
+// Concurrent updates from different components are serialized so that
+// they don't overwrite each other's changes. If the settings can't be read,
+// nothing is saved, so that they aren't replaced by empty ones.
+func (ss *SettingsStore) Update(fn func(*Settings)) error {
+	updateMutex.Lock()
+	defer updateMutex.Unlock()
+
 	settings, err := ss.Load()
 	if err != nil {
-		settings = &Settings{}
+		return err
 	}
-	settings.ClientID = id
+	fn(settings)
 	return ss.Save(settings)
 }
@@ -0,0 +1,79 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+)
+
+func newTestStore(t *testing.T) *SettingsStore {
+	t.Helper()
+	return &SettingsStore{filepath: filepath.Join(t.TempDir(), "settings.json")}
+}
+
+func TestUpdateSaves(t *testing.T) {
+	ss := newTestStore(t)
+	if err := ss.Save(&Settings{}); err != nil {
+		t.Fatal(err)
+	}
+	err := ss.Update(func(s *Settings) {
+		s.MIDI.Port = "Knob"
+		s.Opus = &audioshim.OpusSettings{Bitrate: 24000, Complexity: 5, Application: "voip", FEC: true, PacketLossPerc: 10}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := ss.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.MIDI.Port != "Knob" {
+		t.Errorf("MIDI port = %q, want %q", settings.MIDI.Port, "Knob")
+	}
+	if want := (audioshim.OpusSettings{Bitrate: 24000, Complexity: 5, Application: "voip", FEC: true, PacketLossPerc: 10}); settings.Opus == nil || *settings.Opus != want {
+		t.Errorf("Opus settings = %+v, want %+v", settings.Opus, want)
+	}
+
+	// Only the settings file is left behind
+	entries, err := os.ReadDir(filepath.Dir(ss.filepath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want 1", len(entries))
+	}
+}
+
+func TestUpdateKeepsUnreadableSettings(t *testing.T) {
+	for name, contents := range map[string]string{
+		"corrupt":   `{"client_id": "abc", "midi": {`,
+		"truncated": `{"client_id": "ab`,
+		"wrong":     `[1, 2, 3]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ss := newTestStore(t)
+			if err := os.WriteFile(ss.filepath, []byte(contents), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			called := false
+			err := ss.Update(func(s *Settings) {
+				called = true
+			})
+			if err == nil {
+				t.Error("Update succeeded")
+			}
+			if called {
+				t.Error("Update applied its function to unreadable settings")
+			}
+			data, err := os.ReadFile(ss.filepath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != contents {
+				t.Errorf("settings file changed to %q", data)
+			}
+		})
+	}
+}
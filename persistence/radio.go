@@ -0,0 +1,94 @@
+package persistence
+
+import (
+	"slices"
+)
+
+// RadioSettings contains the settings that belong to one radio
+type RadioSettings struct {
+	// ClientID is the GUI client UUID the radio knows us by
+	ClientID string       `json:"client_id,omitempty"`
+	MIDI     MIDISettings `json:"midi"`
+	// Station overrides the --station name for this radio
+	Station    string             `json:"station,omitempty"`
+	Panadapter PanadapterSettings `json:"panadapter"`
+	Audio      AudioSettings      `json:"audio"`
+}
+
+// AudioSettings are the remote audio choices
+type AudioSettings struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Device IDs, empty for the system default
+	RXDevice string `json:"rx_device,omitempty"`
+	TXDevice string `json:"tx_device,omitempty"`
+}
+
+// PanadapterSettings is where the panadapter was last left
+type PanadapterSettings struct {
+	Center    float64 `json:"center,omitempty"`    // MHz
+	Bandwidth float64 `json:"bandwidth,omitempty"` // MHz
+}
+
+// Radio returns the settings for the radio with the given serial number,
+// creating them if there aren't any yet. New settings start from the MIDI
+// settings from before they were kept per radio, but not the client ID,
+// which only AdoptLegacyClientID hands over.
+func (s *Settings) Radio(serial string) *RadioSettings {
+	if radio, ok := s.Radios[serial]; ok {
+		return radio
+	}
+	if s.Radios == nil {
+		s.Radios = make(map[string]*RadioSettings)
+	}
+	radio := &RadioSettings{
+		MIDI: s.MIDI,
+	}
+	radio.MIDI.Mappings = slices.Clone(s.MIDI.Mappings)
+	s.Radios[serial] = radio
+	return radio
+}
+
+// AdoptLegacyClientID gives the client ID from before settings were kept per
+// radio to the radio with the given serial number, if it has none of its own
+// and it's the radio the ID was used with: the last radio connected to, or
+// the one the configuration names (configured). Settings from before the last
+// radio was remembered don't say, so then it goes to the first radio
+// connected to. A client ID can only belong to one radio, so it's handed over
+// once. It reports whether it was.
+func (s *Settings) AdoptLegacyClientID(serial string, configured bool) bool {
+	if s.ClientID == "" || serial == "" {
+		return false
+	}
+	if !configured && s.UI.LastRadio != "" && serial != s.UI.LastRadio {
+		return false
+	}
+	radio := s.Radio(serial)
+	if radio.ClientID != "" {
+		return false
+	}
+	radio.ClientID = s.ClientID
+	s.ClientID = ""
+	return true
+}
+
+// MoveRadio files the settings kept under the serial number from a radio
+// under a different one, unless there are settings under that already
+func (s *Settings) MoveRadio(from, to string) {
+	radio, ok := s.Radios[from]
+	if !ok || from == to {
+		return
+	}
+	if _, ok := s.Radios[to]; ok {
+		return
+	}
+	s.Radios[to] = radio
+	delete(s.Radios, from)
+}
+
+// UpdateRadio loads the settings, applies fn to the settings for the radio
+// with the given serial number, and saves the result
+func (ss *SettingsStore) UpdateRadio(serial string, fn func(*RadioSettings)) error {
+	return ss.Update(func(settings *Settings) {
+		fn(settings.Radio(serial))
+	})
+}
@@ -0,0 +1,190 @@
+package persistence
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+const legacyClientID = "0F8C2B6A-3D41-4E5A-9B7C-2E1D4F6A8B90"
+
+// legacySettings loads settings saved by a version of Minstrel from before
+// settings were kept per radio
+func legacySettings(t *testing.T) *Settings {
+	t.Helper()
+	data, err := os.ReadFile("testdata/baseline-settings.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := newTestStore(t)
+	if err := os.WriteFile(ss.filepath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	settings, err := ss.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return settings
+}
+
+func TestRadioStartsFromLegacyMIDI(t *testing.T) {
+	s := legacySettings(t)
+	radio := s.Radio("5555-6666-7777-8888")
+	if radio.ClientID != "" {
+		t.Errorf("new radio got client ID %q", radio.ClientID)
+	}
+	if s.ClientID != legacyClientID {
+		t.Errorf("legacy client ID = %q, want it kept", s.ClientID)
+	}
+	if !reflect.DeepEqual(radio.MIDI, s.MIDI) {
+		t.Errorf("MIDI = %+v, want %+v", radio.MIDI, s.MIDI)
+	}
+	if radio.MIDI.Port != "Behringer X-TOUCH MINI" || radio.MIDI.VFOControl != 1 {
+		t.Errorf("MIDI = %+v, want the legacy port and controls", radio.MIDI)
+	}
+	// The mappings are a copy
+	s.MIDI.Mappings = []MIDIMapping{{Type: "cc", Number: 7, Action: "volume"}}
+	other := s.Radio("9999-0000-1111-2222")
+	other.MIDI.Mappings[0].Action = "tune"
+	if s.MIDI.Mappings[0].Action != "volume" {
+		t.Error("changing the radio's mapping changed the legacy one")
+	}
+	if s.Radio("5555-6666-7777-8888") != radio {
+		t.Error("second lookup made new settings")
+	}
+}
+
+func TestAdoptLegacyClientID(t *testing.T) {
+	const last, other = "1111-2222-3333-4444", "5555-6666-7777-8888"
+	tests := []struct {
+		name        string
+		lastRadio   string // as saved by a version that remembers it
+		serial      string
+		configured  bool
+		hasSettings bool
+		own         string // the radio's own client ID
+		want        bool
+	}{
+		{"first radio after upgrading", "", other, false, false, "", true},
+		{"first radio after upgrading, with settings", "", other, false, true, "", true},
+		{"first radio after upgrading, with its own ID", "", other, false, true, "own-id", false},
+		{"last radio", last, last, false, false, "", true},
+		{"configured radio", last, other, true, false, "", true},
+		{"another radio", last, other, false, false, "", false},
+		{"another radio with settings", last, other, false, true, "", false},
+		{"no serial number", "", "", true, false, "", false},
+		{"last radio with its own ID", last, last, false, true, "own-id", false},
+		{"last radio with settings but no ID", last, last, false, true, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := legacySettings(t)
+			s.UI.LastRadio = tt.lastRadio
+			if tt.hasSettings {
+				s.Radio(tt.serial).ClientID = tt.own
+			}
+
+			if got := s.AdoptLegacyClientID(tt.serial, tt.configured); got != tt.want {
+				t.Fatalf("AdoptLegacyClientID = %v, want %v", got, tt.want)
+			}
+			if tt.want {
+				if id := s.Radio(tt.serial).ClientID; id != legacyClientID {
+					t.Errorf("radio's client ID = %q, want the legacy one", id)
+				}
+				if s.ClientID != "" {
+					t.Errorf("legacy client ID = %q after handing it over", s.ClientID)
+				}
+				// Only once
+				if s.AdoptLegacyClientID("9999-0000-1111-2222", true) {
+					t.Error("handed the legacy client ID over twice")
+				}
+				return
+			}
+			if s.ClientID != legacyClientID {
+				t.Errorf("legacy client ID = %q, want it kept", s.ClientID)
+			}
+			if radio, ok := s.Radios[tt.serial]; ok && radio.ClientID != tt.own {
+				t.Errorf("radio's client ID = %q, want %q", radio.ClientID, tt.own)
+			} else if !ok && tt.hasSettings {
+				t.Error("radio's settings went away")
+			}
+		})
+	}
+}
+
+func TestUpgradeFromBaselineSettings(t *testing.T) {
+	// Connecting to a radio picked from the list, as the first run after
+	// upgrading does, then to another one
+	data, err := os.ReadFile("testdata/baseline-settings.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := newTestStore(t)
+	if err := os.WriteFile(ss.filepath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	connect := func(serial string) string {
+		t.Helper()
+		var id string
+		err := ss.Update(func(s *Settings) {
+			s.AdoptLegacyClientID(serial, false)
+			id = s.Radio(serial).ClientID
+			s.UI.LastRadio = serial
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	if id := connect("1111-2222-3333-4444"); id != legacyClientID {
+		t.Errorf("first radio's client ID = %q, want the legacy one", id)
+	}
+	if id := connect("5555-6666-7777-8888"); id != "" {
+		t.Errorf("second radio's client ID = %q, want none", id)
+	}
+	if id := connect("1111-2222-3333-4444"); id != legacyClientID {
+		t.Errorf("first radio's client ID = %q on reconnecting, want the legacy one", id)
+	}
+
+	settings, err := ss.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.ClientID != "" {
+		t.Errorf("legacy client ID = %q, want it handed over", settings.ClientID)
+	}
+	if len(settings.Radios) != 2 {
+		t.Errorf("settings for %d radios, want 2", len(settings.Radios))
+	}
+}
+
+func TestAdoptLegacyClientIDWithoutOne(t *testing.T) {
+	s := &Settings{UI: UISettings{LastRadio: "1111-2222-3333-4444"}}
+	if s.AdoptLegacyClientID("1111-2222-3333-4444", true) {
+		t.Error("handed over an empty client ID")
+	}
+}
+
+func TestMoveRadio(t *testing.T) {
+	s := &Settings{Radios: map[string]*RadioSettings{
+		"discovered": {ClientID: "a"},
+		"taken":      {ClientID: "b"},
+		"other":      {ClientID: "c"},
+	}}
+	s.MoveRadio("discovered", "reported")
+	if _, ok := s.Radios["discovered"]; ok || s.Radios["reported"].ClientID != "a" {
+		t.Errorf("radios = %v after moving", s.Radios)
+	}
+	s.MoveRadio("other", "taken")
+	if s.Radios["taken"].ClientID != "b" || s.Radios["other"].ClientID != "c" {
+		t.Error("moved over existing settings")
+	}
+	s.MoveRadio("missing", "new")
+	if _, ok := s.Radios["new"]; ok {
+		t.Error("moved settings that don't exist")
+	}
+	s.MoveRadio("other", "other")
+	if s.Radios["other"].ClientID != "c" {
+		t.Error("moving a radio onto itself lost it")
+	}
+}
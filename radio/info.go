@@ -0,0 +1,70 @@
+// Identifying the radio we're connected to
+
+package radio
+
+import (
+	"log"
+	"strings"
+
+	"github.com/kc2g-flex-tools/flexclient"
+)
+
+// parseInfo parses the reply to "info": comma separated key=value pairs,
+// where a value may be quoted (and then may contain commas)
+func parseInfo(msg string) map[string]string {
+	info := make(map[string]string)
+	for msg != "" {
+		key, rest, ok := strings.Cut(msg, "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+			_, rest, _ = strings.Cut(rest, ",")
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		info[strings.TrimSpace(key)] = value
+		msg = rest
+	}
+	return info
+}
+
+// identify asks the radio who it is, and keeps its settings under the serial
+// number it reports. Failing that they're kept under the serial number
+// discovery saw at its address, or as a last resort the address. It returns
+// the radio's serial number, nickname and IP address, named as discovery
+// names them, and the serial number discovery saw (if any).
+func (rs *RadioState) identify(fc *flexclient.FlexClient) (radio map[string]string, discovered string) {
+	var info map[string]string
+	res := fc.SendAndWait("info")
+	if res.Error != 0 {
+		log.Printf("radio info error: %v", res)
+	} else {
+		info = parseInfo(res.Message)
+	}
+	radio = map[string]string{
+		"serial":   info["chassis_serial"],
+		"nickname": info["name"],
+		"ip":       info["ip"],
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	discovered = rs.serial
+	switch {
+	case radio["serial"] != "":
+		if discovered != "" && discovered != radio["serial"] {
+			log.Printf("radio discovered as %s reports serial number %s", discovered, radio["serial"])
+		}
+	case discovered != "":
+		log.Printf("radio didn't report its serial number, using %s from discovery", discovered)
+		radio["serial"] = discovered
+	default:
+		log.Printf("radio didn't report its serial number, keeping its settings under %s", rs.address)
+		radio["serial"] = rs.address
+	}
+	rs.serial = radio["serial"]
+	return radio, discovered
+}
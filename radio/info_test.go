@@ -0,0 +1,69 @@
+package radio
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want map[string]string
+	}{
+		{
+			name: "6000 series",
+			msg:  `model="FLEX-6600",chassis_serial="1234-5678-9012-3456",name="Shack, upstairs",callsign="KC2G",ip="192.168.1.50",num_slice=4,region="USA"`,
+			want: map[string]string{
+				"model":          "FLEX-6600",
+				"chassis_serial": "1234-5678-9012-3456",
+				"name":           "Shack, upstairs",
+				"callsign":       "KC2G",
+				"ip":             "192.168.1.50",
+				"num_slice":      "4",
+				"region":         "USA",
+			},
+		},
+		{
+			name: "empty values",
+			msg:  `name="",location="",num_tx=1`,
+			want: map[string]string{"name": "", "location": "", "num_tx": "1"},
+		},
+		{
+			name: "unterminated quote",
+			msg:  `chassis_serial="1234,name`,
+			want: map[string]string{"chassis_serial": "1234,name"},
+		},
+		{
+			name: "empty",
+			want: map[string]string{},
+		},
+		{
+			name: "no pairs",
+			msg:  "garbage",
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseInfo(tt.msg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseInfo = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesRadio(t *testing.T) {
+	radio := map[string]string{"serial": "1234-5678-9012-3456", "nickname": "Shack", "ip": "192.168.1.50"}
+	for spec, want := range map[string]bool{
+		"1234-5678-9012-3456": true,
+		"shack":               true,
+		"192.168.1.50":        true,
+		"192.168.1.5":         false,
+		"Garage":              false,
+	} {
+		if got := matchesRadio(radio, spec); got != want {
+			t.Errorf("matchesRadio(%q) = %v, want %v", spec, got, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Transmit inhibit outside the band plan's privileges
+
+package radio
+
+import (
+	"context"
+	"log"
+
+	"github.com/kc2g-flex-tools/flexclient"
+
+	"github.com/kc2g-flex-tools/minstrel/bandplan"
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+// SetBandPlan limits transmitting to the plan's privileges. With no plan,
+// transmitting is allowed anywhere.
+func (rs *RadioState) SetBandPlan(plan *bandplan.Plan) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.bandPlan = plan
+}
+
+// checkTransmit returns why the TX slice in slices may not transmit, or nil if
+// it may (or if it isn't one of ours)
+func (rs *RadioState) checkTransmit(slices radioshim.SliceMap) error {
+	rs.mu.RLock()
+	plan := rs.bandPlan
+	rs.mu.RUnlock()
+	if plan == nil {
+		return nil
+	}
+	for _, slice := range slices {
+		if slice.Present && slice.TX {
+			return plan.CheckTransmit(slice.Freq, slice.FiltLow, slice.FiltHigh, slice.Mode)
+		}
+	}
+	return nil
+}
+
+// inhibit logs and publishes a refusal to transmit
+func (rs *RadioState) inhibit(action string, err error) {
+	log.Printf("%s inhibited: %v", action, err)
+	rs.EventBus.Publish(events.TransmitInhibited{
+		Action: action,
+		Reason: err.Error(),
+	})
+}
+
+// enforceTransmitInhibit unkeys and turns off TUNE and VOX if the TX slice has
+// moved outside the privileges while transmitting or with VOX on
+func (rs *RadioState) enforceTransmitInhibit(slices radioshim.SliceMap) {
+	if !rs.transmitting.Load() && !rs.voxEnabled.Load() {
+		return
+	}
+	err := rs.checkTransmit(slices)
+	if err == nil {
+		return
+	}
+	if rs.tuning.Swap(false) {
+		rs.FlexClient.SendCmd("transmit tune 0")
+		rs.inhibit("TUNE", err)
+	}
+	if rs.transmitting.Swap(false) {
+		rs.FlexClient.SendCmd("xmit 0")
+		rs.inhibit("PTT", err)
+	}
+	if rs.voxEnabled.Swap(false) {
+		rs.FlexClient.TransmitSet(context.Background(), flexclient.Object{"vox_enable": "0"})
+		rs.inhibit("VOX", err)
+	}
+}
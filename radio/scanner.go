@@ -0,0 +1,249 @@
+// Frequency scanner driving the active slice
+
+package radio
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+const (
+	// How often the scanner checks whether to move on
+	scanInterval = 50 * time.Millisecond
+	// Waterfall rows are ignored for this long after tuning, since they may
+	// still show the previous frequency
+	scanSettleTime = 300 * time.Millisecond
+	// The shortest dwell, which leaves time to see a signal after settling
+	scanMinDwell = 500 * time.Millisecond
+	// A level older than this is ignored, in case the waterfall stops
+	scanLevelMaxAge = time.Second
+	// The most frequencies a range scan can have
+	maxScanFreqs = 10000
+)
+
+// Scanner steps the active slice through a range or list of frequencies,
+// holding while the waterfall shows a signal in the slice's passband. It is
+// controlled through the event bus.
+type Scanner struct {
+	shim   radioshim.Shim
+	bus    *events.Bus
+	events chan events.Event
+
+	settings events.ScanSettings
+	freqs    []float64
+	index    int
+	running  bool
+	holding  bool
+
+	dwellUntil  time.Time
+	settleUntil time.Time
+	hangUntil   time.Time
+	// The most recent level in the passband, and when it was seen
+	level   int
+	levelAt time.Time
+
+	dataLow, dataHigh float64
+
+	// now is the clock, replaced in tests
+	now func() time.Time
+}
+
+// NewScanner creates a scanner that tunes through shim
+func NewScanner(shim radioshim.Shim, bus *events.Bus) *Scanner {
+	return &Scanner{
+		shim:   shim,
+		bus:    bus,
+		events: bus.Subscribe(100),
+		now:    time.Now,
+	}
+}
+
+// scanFrequencies lists the frequencies to scan, in MHz
+func scanFrequencies(s events.ScanSettings) []float64 {
+	if len(s.Freqs) > 0 {
+		return s.Freqs
+	}
+	if s.Step <= 0 || s.High < s.Low {
+		return nil
+	}
+	var freqs []float64
+	for i := 0; i < maxScanFreqs; i++ {
+		// Multiply rather than accumulate so rounding errors don't build up
+		freq := s.Low + float64(i)*s.Step
+		if freq > s.High+s.Step/1000 {
+			break
+		}
+		freqs = append(freqs, math.Round(freq*1e6)/1e6)
+	}
+	return freqs
+}
+
+// activeSlice returns the slice being scanned
+func (s *Scanner) activeSlice() *radioshim.SliceData {
+	for _, slice := range s.shim.GetSlices() {
+		if slice.Present && slice.Active {
+			return slice
+		}
+	}
+	return nil
+}
+
+// Run scans on request until ctx is done
+func (s *Scanner) Run(ctx context.Context) {
+	ticker := time.NewTicker(scanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.events:
+			s.handleEvent(event)
+		case <-ticker.C:
+			if s.running {
+				s.step(s.now())
+			}
+		}
+	}
+}
+
+func (s *Scanner) handleEvent(event events.Event) {
+	switch e := event.(type) {
+	case events.ScanRequested:
+		s.start(e.Settings, 0)
+	case events.ScanStopRequested:
+		s.stop()
+	case events.ScanToggleRequested:
+		if s.running {
+			s.stop()
+		} else if len(s.freqs) > 0 {
+			s.start(s.settings, s.index)
+		}
+	case events.WaterfallDataRangeChanged:
+		s.dataLow, s.dataHigh = e.Low, e.High
+	case events.WaterfallRowReceived:
+		if s.running {
+			s.measure(e.Bins, e.BlackLevel)
+		}
+	case events.TransmitStateChanged:
+		// Don't retune while transmitting
+		if e.Transmitting {
+			s.stop()
+		}
+	case events.RadioDisconnected:
+		s.stop()
+	}
+}
+
+// start scans settings from freqs[index]
+func (s *Scanner) start(settings events.ScanSettings, index int) {
+	freqs := scanFrequencies(settings)
+	if len(freqs) == 0 {
+		log.Println("scanner: nothing to scan")
+		return
+	}
+	s.settings, s.freqs = settings, freqs
+	s.running = true
+	s.tune(index%len(freqs), s.now())
+}
+
+func (s *Scanner) stop() {
+	if !s.running {
+		return
+	}
+	s.running, s.holding = false, false
+	s.publish()
+}
+
+// tune moves the active slice to freqs[index], centering the waterfall on it
+// if it's off screen so that signals there can be seen
+func (s *Scanner) tune(index int, now time.Time) {
+	slice := s.activeSlice()
+	if slice == nil {
+		log.Println("scanner: no active slice")
+		s.stop()
+		return
+	}
+	s.index = index
+	freq := s.freqs[index]
+	s.shim.TuneSlice(slice, freq, false)
+	if freq < s.dataLow || freq > s.dataHigh {
+		s.shim.CenterWaterfallAt(freq)
+	}
+	s.holding = false
+	s.dwellUntil = now.Add(max(s.settings.Dwell, scanMinDwell))
+	s.settleUntil = now.Add(scanSettleTime)
+	s.publish()
+}
+
+// measure finds the strongest waterfall bin in the active slice's passband
+func (s *Scanner) measure(bins []uint16, blackLevel uint32) {
+	now := s.now()
+	slice := s.activeSlice()
+	if slice == nil || now.Before(s.settleUntil) || len(bins) < 2 || s.dataHigh <= s.dataLow {
+		return
+	}
+	binAt := func(freq float64) int {
+		return int(math.Round((freq - s.dataLow) / (s.dataHigh - s.dataLow) * float64(len(bins)-1)))
+	}
+	first := max(binAt(slice.Freq+slice.FiltLow/1e6), 0)
+	last := min(binAt(slice.Freq+slice.FiltHigh/1e6), len(bins)-1)
+	level := 0
+	if first <= last {
+		for _, bin := range bins[first : last+1] {
+			// The same scale as the waterfall display
+			level = max(level, (int(bin)-int(blackLevel))/64)
+		}
+	}
+	s.level, s.levelAt = level, now
+}
+
+// step holds on a signal, or moves on once the dwell or hang time is up. If
+// the slice has been tuned away from the scan frequency, the scan stops.
+func (s *Scanner) step(now time.Time) {
+	if now.After(s.settleUntil) && s.tunedAway() {
+		log.Println("scanner: slice tuned away, stopping")
+		s.stop()
+		return
+	}
+	fresh := s.levelAt.After(s.settleUntil) && now.Sub(s.levelAt) < scanLevelMaxAge
+	signal := fresh && s.level >= s.settings.Threshold
+	switch {
+	case signal:
+		s.hangUntil = now.Add(s.settings.Hang)
+		if !s.holding {
+			s.holding = true
+			s.publish()
+		}
+	case s.holding && now.After(s.hangUntil):
+		s.tune((s.index+1)%len(s.freqs), now)
+	case !s.holding && now.After(s.dwellUntil):
+		s.tune((s.index+1)%len(s.freqs), now)
+	}
+}
+
+// tunedAway reports whether the active slice is no longer on the frequency
+// being scanned, because it was tuned by something else or another slice
+// was made active
+func (s *Scanner) tunedAway() bool {
+	slice := s.activeSlice()
+	return slice == nil || math.Abs(slice.Freq-s.freqs[s.index]) > 1e-6
+}
+
+func (s *Scanner) publish() {
+	var freq float64
+	if s.running {
+		freq = s.freqs[s.index]
+	}
+	s.bus.Publish(events.ScanStateChanged{
+		Running: s.running,
+		Holding: s.holding,
+		Freq:    freq,
+		Level:   s.level,
+	})
+}
@@ -0,0 +1,350 @@
+package radio
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+	"github.com/kc2g-flex-tools/minstrel/radioshim/shimtest"
+)
+
+func TestScanFrequencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings events.ScanSettings
+		want     []float64
+	}{
+		{
+			name:     "range including the upper end",
+			settings: events.ScanSettings{Low: 14.0, High: 14.01, Step: 0.0025},
+			want:     []float64{14.0, 14.0025, 14.005, 14.0075, 14.01},
+		},
+		{
+			name:     "range not a whole number of steps",
+			settings: events.ScanSettings{Low: 7.0, High: 7.012, Step: 0.005},
+			want:     []float64{7.0, 7.005, 7.01},
+		},
+		{
+			name:     "steps that don't add up exactly",
+			settings: events.ScanSettings{Low: 146.52, High: 146.58, Step: 0.02},
+			want:     []float64{146.52, 146.54, 146.56, 146.58},
+		},
+		{
+			name:     "single frequency range",
+			settings: events.ScanSettings{Low: 14.074, High: 14.074, Step: 0.001},
+			want:     []float64{14.074},
+		},
+		{
+			name:     "list",
+			settings: events.ScanSettings{Low: 1, High: 2, Step: 0.5, Freqs: []float64{7.074, 14.074, 21.074}},
+			want:     []float64{7.074, 14.074, 21.074},
+		},
+		{
+			name:     "no step",
+			settings: events.ScanSettings{Low: 14.0, High: 14.1},
+		},
+		{
+			name:     "high below low",
+			settings: events.ScanSettings{Low: 14.1, High: 14.0, Step: 0.001},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scanFrequencies(tt.settings); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanFrequencies = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if n := len(scanFrequencies(events.ScanSettings{Low: 1, High: 1000, Step: 0.000001})); n != maxScanFreqs {
+		t.Errorf("huge range has %d frequencies, want %d", n, maxScanFreqs)
+	}
+}
+
+// scanTest drives a Scanner with a fake radio and clock
+type scanTest struct {
+	*Scanner
+	t     *testing.T
+	clock time.Time
+	shim  *shimtest.Fake
+	state chan events.Event
+}
+
+func newScanTest(t *testing.T) *scanTest {
+	bus := events.NewBus()
+	shim := shimtest.New(radioshim.SliceMap{
+		"A": {Index: 0, Freq: 14.2, Mode: "USB", FiltLow: 100, FiltHigh: 2900, TuneStep: 1000, Active: true, TX: true},
+		"B": {Index: 1, Freq: 7.2, Mode: "LSB", FiltLow: -2900, FiltHigh: -100},
+	})
+	st := &scanTest{
+		Scanner: NewScanner(shim, bus),
+		t:       t,
+		clock:   time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC),
+		shim:    shim,
+		state:   bus.Subscribe(1000),
+	}
+	st.now = func() time.Time { return st.clock }
+	// The waterfall shows 14.0-14.1 MHz, 1 kHz per bin
+	st.handleEvent(events.WaterfallDataRangeChanged{Low: 14.0, High: 14.1})
+	return st
+}
+
+// runFor advances the clock by d in scanInterval steps, as Run would
+func (st *scanTest) runFor(d time.Duration) {
+	for end := st.clock.Add(d); st.clock.Before(end); {
+		st.clock = st.clock.Add(scanInterval)
+		if st.running {
+			st.step(st.clock)
+		}
+	}
+}
+
+// row sends a waterfall row with level at the bins covering 14.05-14.054
+// MHz, and 0 elsewhere
+func (st *scanTest) row(level int) {
+	const blackLevel = 1000
+	bins := make([]uint16, 101)
+	for i := range bins {
+		bins[i] = blackLevel
+	}
+	for i := 50; i <= 54; i++ {
+		bins[i] = uint16(blackLevel + level*64)
+	}
+	st.handleEvent(events.WaterfallRowReceived{Bins: bins, BlackLevel: blackLevel})
+}
+
+// tunes returns the frequencies tuned since the last call
+func (st *scanTest) tunes() []float64 {
+	var freqs []float64
+	for _, call := range st.shim.Calls() {
+		var index int
+		var freq float64
+		var snap bool
+		if _, err := fmt.Sscanf(call, "TuneSlice %d %g %t", &index, &freq, &snap); err == nil {
+			freqs = append(freqs, freq)
+		}
+	}
+	st.shim.ResetCalls()
+	return freqs
+}
+
+// last returns the last ScanStateChanged published since the last call
+func (st *scanTest) last() (events.ScanStateChanged, bool) {
+	var last events.ScanStateChanged
+	found := false
+	for {
+		select {
+		case e := <-st.state:
+			if e, ok := e.(events.ScanStateChanged); ok {
+				last, found = e, true
+			}
+		default:
+			return last, found
+		}
+	}
+}
+
+func (st *scanTest) wantTunes(want ...float64) {
+	st.t.Helper()
+	if got := st.tunes(); !reflect.DeepEqual(got, want) {
+		st.t.Errorf("tuned to %v, want %v", got, want)
+	}
+}
+
+func TestScanStepsAndWraps(t *testing.T) {
+	st := newScanTest(t)
+	st.handleEvent(events.ScanRequested{Settings: events.ScanSettings{
+		Low: 14.01, High: 14.03, Step: 0.01, Dwell: time.Second,
+	}})
+	st.wantTunes(14.01)
+
+	st.runFor(time.Second)
+	st.wantTunes()
+	st.runFor(scanInterval)
+	st.wantTunes(14.02)
+	st.runFor(time.Second + scanInterval)
+	st.wantTunes(14.03)
+	st.runFor(time.Second + scanInterval)
+	st.wantTunes(14.01)
+
+	state, ok := st.last()
+	if !ok || !state.Running || state.Holding || state.Freq != 14.01 {
+		t.Errorf("state = %+v, want running on 14.01", state)
+	}
+}
+
+func TestScanListWrapsAndResumes(t *testing.T) {
+	st := newScanTest(t)
+	st.handleEvent(events.ScanRequested{Settings: events.ScanSettings{
+		Freqs: []float64{14.074, 14.08}, Dwell: time.Second,
+	}})
+	st.wantTunes(14.074)
+	st.runFor(time.Second + scanInterval)
+	st.wantTunes(14.08)
+
+	// Stopping and resuming carries on from the same frequency
+	st.handleEvent(events.ScanToggleRequested{})
+	if state, _ := st.last(); state.Running {
+		t.Errorf("state = %+v after toggling, want stopped", state)
+	}
+	st.runFor(5 * time.Second)
+	st.wantTunes()
+	st.handleEvent(events.ScanToggleRequested{})
+	st.wantTunes(14.08)
+	st.runFor(time.Second + scanInterval)
+	st.wantTunes(14.074)
+}
+
+func TestScanMinimumDwell(t *testing.T) {
+	st := newScanTest(t)
+	st.handleEvent(events.ScanRequested{Settings: events.ScanSettings{
+		Freqs: []float64{14.074, 14.08},
+	}})
+	st.wantTunes(14.074)
+	st.runFor(scanMinDwell)
+	st.wantTunes()
+	st.runFor(scanInterval)
+	st.wantTunes(14.08)
+}
+
+func TestScanCentersWaterfall(t *testing.T) {
+	st := newScanTest(t)
+	st.handleEvent(events.ScanRequested{Settings: events.ScanSettings{
+		Freqs: []float64{14.05, 14.2},
+	}})
+	st.runFor(scanMinDwell + scanInterval)
+	want := []string{"TuneSlice 0 14.05 false", "TuneSlice 0 14.2 false", "CenterWaterfallAt 14.2"}
+	if calls := st.shim.Calls(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestScanHoldsOnSignal(t *testing.T) {
+	st := newScanTest(t)
+	st.handleEvent(events.ScanRequested{Settings: events.ScanSettings{
+		Freqs: []float64{14.05, 14.06}, Dwell: time.Second, Hang: 2 * time.Second, Threshold: 10,
+	}})
+	st.wantTunes(14.05)
+	st.last()
+
+	// Rows arriving before the slice settles are ignored
+	st.row(20)
+	st.runFor(scanInterval)
+	if state, ok := st.last(); ok && state.Holding {
+		t.Fatalf("held on a row from before settling: %+v", state)
+	}
+
+	// A signal above the squelch threshold holds the scan past the dwell
+	st.runFor(scanSettleTime)
+	for range 40 {
+		st.row(20)
+		st.runFor(scanInterval)
+	}
+	st.wantTunes()
+	state, ok := st.last()
+	if !ok || !state.Holding || state.Level != 20 || state.Freq != 14.05 {
+		t.Errorf("state = %+v, want holding on 14.05 at level 20", state)
+	}
+
+	// Once the signal drops below the threshold, the scan resumes after the
+	// hang time
+	for range 20 {
+		st.row(5)
+		st.runFor(scanInterval)
+	}
+	st.wantTunes()
+	for range 21 {
+		st.row(5)
+		st.runFor(scanInterval)
+	}
+	st.wantTunes(14.06)
+	state, _ = st.last()
+	if state.Holding || state.Freq != 14.06 {
+		t.Errorf("state = %+v, want scanning on 14.06", state)
+	}
+}
+
+func TestScanIgnoresStaleLevel(t *testing.T) {
+	st := newScanTest(t)
+	st.handleEvent(events.ScanRequested{Settings: events.ScanSettings{
+		Freqs: []float64{14.05, 14.06}, Dwell: 3 * time.Second, Threshold: 10,
+	}})
+	st.wantTunes(14.05)
+	st.runFor(scanSettleTime + scanInterval)
+	st.row(20)
+	st.runFor(scanInterval)
+	if state, _ := st.last(); !state.Holding {
+		t.Fatalf("state = %+v, want holding", state)
+	}
+
+	// The waterfall stops: the hold is released once the level is too old,
+	// well before the dwell time is up
+	st.runFor(scanLevelMaxAge + time.Second)
+	st.wantTunes(14.06)
+}
+
+func TestScanStops(t *testing.T) {
+	tests := []struct {
+		name  string
+		event events.Event
+		tune  func(*scanTest)
+	}{
+		{name: "stop requested", event: events.ScanStopRequested{}},
+		{name: "transmitting", event: events.TransmitStateChanged{Transmitting: true}},
+		{name: "radio disconnected", event: events.RadioDisconnected{}},
+		{name: "manual tune", tune: func(st *scanTest) {
+			st.shim.TuneSlice(st.shim.Slice("A"), 14.0123, false)
+		}},
+		{name: "manual step", tune: func(st *scanTest) {
+			st.shim.TuneSliceStep(st.shim.Slice("A"), 1)
+		}},
+		{name: "another slice made active", tune: func(st *scanTest) {
+			st.shim.ActivateSlice(1)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newScanTest(t)
+			st.handleEvent(events.ScanRequested{Settings: events.ScanSettings{
+				Freqs: []float64{14.05, 14.06}, Dwell: time.Second,
+			}})
+			st.runFor(scanSettleTime + scanInterval)
+			st.last()
+			st.shim.ResetCalls()
+
+			if tt.event != nil {
+				st.handleEvent(tt.event)
+			}
+			if tt.tune != nil {
+				tt.tune(st)
+				st.shim.ResetCalls()
+			}
+			st.runFor(scanInterval)
+
+			state, ok := st.last()
+			if !ok || state.Running {
+				t.Errorf("state = %+v, want stopped", state)
+			}
+			st.runFor(5 * time.Second)
+			st.wantTunes()
+		})
+	}
+}
+
+func TestScanKeepsGoingOnItsOwnTuning(t *testing.T) {
+	// The scanner's own tuning isn't mistaken for a manual tune
+	st := newScanTest(t)
+	st.handleEvent(events.ScanRequested{Settings: events.ScanSettings{
+		Low: 14.0, High: 14.01, Step: 0.0025, Dwell: time.Second,
+	}})
+	st.runFor(10 * time.Second)
+	if got := st.tunes(); len(got) < 9 {
+		t.Errorf("tuned to %v, want the scan to keep going", got)
+	}
+	if state, _ := st.last(); !state.Running {
+		t.Errorf("state = %+v, want running", state)
+	}
+}
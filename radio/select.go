@@ -0,0 +1,36 @@
+package radio
+
+import (
+	"net"
+	"strings"
+)
+
+// DirectAddress reports whether spec is an IP address (with optional port)
+// that can be connected to without discovery
+func DirectAddress(spec string) (string, bool) {
+	host := spec
+	if h, _, err := net.SplitHostPort(spec); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) == nil {
+		return "", false
+	}
+	return spec, true
+}
+
+// FindRadio looks for a discovered radio whose serial number, nickname
+// (ignoring case) or IP address is spec, and returns its address
+func FindRadio(radios []map[string]string, spec string) (string, bool) {
+	for _, radio := range radios {
+		if matchesRadio(radio, spec) {
+			return radio["ip"] + ":" + radio["port"], true
+		}
+	}
+	return "", false
+}
+
+// matchesRadio reports whether spec is the serial number, nickname (ignoring
+// case) or IP address of radio, described as discovery describes it
+func matchesRadio(radio map[string]string, spec string) bool {
+	return radio["serial"] == spec || radio["ip"] == spec || strings.EqualFold(radio["nickname"], spec)
+}
@@ -47,6 +47,9 @@ func (rs *RadioState) updateGUI() {
 		out.TuneStep = errutil.MustParseFloat(slice["step"], "slice step")
 		out.TuneStep /= 1e6
 		out.Volume = errutil.MustParseInt(slice["audio_level"], "slice audio_level")
+		out.Muted = slice["audio_mute"] == "1"
+		out.RIT = slice["rit_on"] == "1"
+		out.RITOffset = errutil.MustParseInt(slice["rit_freq"], "slice rit_freq")
 		slices[letter] = &out
 	}
 	rs.mu.Lock()
@@ -57,6 +60,8 @@ func (rs *RadioState) updateGUI() {
 	rs.EventBus.Publish(events.SlicesUpdated{
 		Slices: slices,
 	})
+
+	rs.enforceTransmitInhibit(slices)
 }
 
 func (rs *RadioState) SetSliceVolume(index int, volume int) {
@@ -67,6 +72,17 @@ func (rs *RadioState) SetSliceVolume(index int, volume int) {
 	}
 }
 
+func (rs *RadioState) SetSliceMute(index int, mute bool) {
+	value := "0"
+	if mute {
+		value = "1"
+	}
+	_, err := rs.FlexClient.SliceSet(context.Background(), fmt.Sprintf("%d", index), flexclient.Object{"audio_mute": value})
+	if err != nil {
+		log.Println("SliceSet error:", err)
+	}
+}
+
 func (rs *RadioState) ActivateSlice(index int) {
 	_, err := rs.FlexClient.SliceSet(context.Background(), fmt.Sprintf("%d", index), flexclient.Object{"active": "1"})
 	if err != nil {
@@ -100,6 +116,24 @@ func (rs *RadioState) SetSliceMode(index int, mode string) {
 	}
 }
 
+func (rs *RadioState) SetSliceFilter(index int, lo, hi int) {
+	_, err := rs.FlexClient.SliceSetFilter(context.Background(), fmt.Sprintf("%d", index), lo, hi)
+	if err != nil {
+		log.Println("SliceSetFilter error:", err)
+	}
+}
+
+func (rs *RadioState) SetSliceRIT(index int, enabled bool, offset int) {
+	on := "0"
+	if enabled {
+		on = "1"
+	}
+	_, err := rs.FlexClient.SliceSet(context.Background(), fmt.Sprintf("%d", index), flexclient.Object{"rit_on": on, "rit_freq": strconv.Itoa(offset)})
+	if err != nil {
+		log.Println("SliceSet error:", err)
+	}
+}
+
 func (rs *RadioState) SetSliceRXAnt(index int, rxant string) {
 	_, err := rs.FlexClient.SliceSet(context.Background(), fmt.Sprintf("%d", index), flexclient.Object{"rxant": rxant})
 	if err != nil {
@@ -0,0 +1,118 @@
+package radio
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kc2g-flex-tools/flexclient"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+// How long spots we add are kept by the radio
+const spotLifetime = 30 * time.Minute
+
+// spotFromState converts a radio spot object. Spots come from other programs,
+// so malformed fields are ignored rather than treated as fatal.
+func spotFromState(objName string, spot flexclient.Object) (radioshim.SpotData, bool) {
+	index, err := strconv.Atoi(strings.TrimPrefix(objName, "spot "))
+	if err != nil {
+		return radioshim.SpotData{}, false
+	}
+	freq, err := strconv.ParseFloat(spot["rx_freq"], 64)
+	if err != nil {
+		// Removed spots are left with no fields
+		return radioshim.SpotData{}, false
+	}
+	out := radioshim.SpotData{
+		Index:           index,
+		Callsign:        spot["callsign"],
+		Freq:            freq,
+		Mode:            spot["mode"],
+		Comment:         spot["comment"],
+		Source:          spot["source"],
+		Spotter:         spot["spotter_callsign"],
+		Color:           spot["color"],
+		BackgroundColor: spot["background_color"],
+		Priority:        5,
+	}
+	if priority, err := strconv.Atoi(spot["priority"]); err == nil {
+		out.Priority = priority
+	}
+	if timestamp, err := strconv.ParseInt(spot["timestamp"], 10, 64); err == nil {
+		out.Timestamp = time.Unix(timestamp, 0)
+	}
+	if lifetime, err := strconv.Atoi(spot["lifetime_seconds"]); err == nil {
+		out.Lifetime = time.Duration(lifetime) * time.Second
+	}
+	return out, true
+}
+
+// publishSpots sends all of the radio's spots, ordered by frequency
+func (rs *RadioState) publishSpots() {
+	spots := []radioshim.SpotData{}
+	for objName, spot := range rs.FlexClient.FindObjects("spot ") {
+		if out, ok := spotFromState(objName, spot); ok {
+			spots = append(spots, out)
+		}
+	}
+	slices.SortFunc(spots, func(a, b radioshim.SpotData) int {
+		switch {
+		case a.Freq < b.Freq:
+			return -1
+		case a.Freq > b.Freq:
+			return 1
+		}
+		return a.Index - b.Index
+	})
+	rs.EventBus.Publish(events.RadioSpotsUpdated{
+		Spots: spots,
+	})
+}
+
+// AddSpot creates a spot on the radio, which shares it with other clients
+func (rs *RadioState) AddSpot(freq float64, mode, callsign, comment string) {
+	cmd, ok := spotAddCommand(freq, mode, callsign, comment)
+	if !ok {
+		log.Printf("not spotting %q: no callsign left once cleaned up", callsign)
+		return
+	}
+	rs.FlexClient.SendCmd(cmd)
+}
+
+// spotAddCommand returns the command to add a spot, or false if the callsign
+// is empty once cleaned up
+func spotAddCommand(freq float64, mode, callsign, comment string) (string, bool) {
+	if callsign = spotValue(callsign); callsign == "" {
+		return "", false
+	}
+	cmd := fmt.Sprintf("spot add rx_freq=%.6f callsign=%s source=Minstrel lifetime_seconds=%d",
+		freq, callsign, int(spotLifetime.Seconds()))
+	if mode = spotValue(mode); mode != "" {
+		cmd += " mode=" + mode
+	}
+	if comment = spotValue(comment); comment != "" {
+		cmd += " comment=" + comment
+	}
+	return cmd, true
+}
+
+// spotValue makes s safe to send as the value of a spot command field. The
+// radio splits commands on spaces and '=' and ends them at a newline, and
+// shows 0x7f as a space, so spaces are sent as 0x7f and '=' and control
+// characters are dropped.
+func spotValue(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '=' || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.ReplaceAll(strings.TrimSpace(s), " ", "\x7f")
+}
@@ -0,0 +1,105 @@
+package radio
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kc2g-flex-tools/flexclient"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+// spotStatus is spot status in the form the radio sends it, with spaces in the
+// callsign and comment sent as 0x7f
+var spotStatus = []string{
+	"V1.4.0.0",
+	"H2B6A1C04",
+	"S0|spot 7 callsign=DL1ABC/P rx_freq=14.025000 tx_freq=14.025000 mode=CW color=#FFFF0000 " +
+		"background_color=#80000000 source=N1MM spotter_callsign=W1AW timestamp=1760358000 lifetime_seconds=1800 " +
+		"priority=2 comment=CQ\x7fTEST\x7fup\x7f1 trigger_action=tune",
+	"S0|spot 3 callsign=K1AB rx_freq=7.074000 tx_freq=7.074000 mode=FT8 color= background_color= source=WSJT-X " +
+		"spotter_callsign= timestamp=1760358060 lifetime_seconds=0 priority= comment= trigger_action=tune",
+	"S0|spot 12 callsign=W9XYZ rx_freq=14.074000 mode=FT8 source=WSJT-X",
+	"S0|spot 12 removed",
+	"S0|spot 4 callsign=VE3XY rx_freq= mode=CW",
+}
+
+// captureState runs a FlexClient against a radio that sends status and
+// hangs up
+func captureState(t *testing.T, status []string) *flexclient.FlexClient {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte(strings.Join(status, "\n") + "\n"))
+	}()
+	fc, err := flexclient.NewFlexClient(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.Run()
+	return fc
+}
+
+func TestSpotFromState(t *testing.T) {
+	fc := captureState(t, spotStatus)
+	bus := events.NewBus()
+	updates := bus.Subscribe(1)
+	rs := &RadioState{FlexClient: fc, EventBus: bus}
+	rs.publishSpots()
+
+	want := []radioshim.SpotData{
+		{
+			Index: 3, Callsign: "K1AB", Freq: 7.074, Mode: "FT8", Source: "WSJT-X",
+			Priority: 5, Timestamp: time.Unix(1760358060, 0),
+		},
+		{
+			Index: 7, Callsign: "DL1ABC/P", Freq: 14.025, Mode: "CW", Comment: "CQ TEST up 1", Source: "N1MM",
+			Spotter: "W1AW", Color: "#FFFF0000", BackgroundColor: "#80000000", Priority: 2,
+			Timestamp: time.Unix(1760358000, 0), Lifetime: 30 * time.Minute,
+		},
+	}
+	e := (<-updates).(events.RadioSpotsUpdated)
+	if !reflect.DeepEqual(e.Spots, want) {
+		t.Errorf("spots =\n%+v\nwant\n%+v", e.Spots, want)
+	}
+}
+
+func TestSpotAddCommand(t *testing.T) {
+	tests := []struct {
+		mode, callsign, comment string
+		want                    string
+	}{
+		{"CW", "K1AB", "", "spot add rx_freq=14.025000 callsign=K1AB source=Minstrel lifetime_seconds=1800 mode=CW"},
+		{"", "K1AB", "", "spot add rx_freq=14.025000 callsign=K1AB source=Minstrel lifetime_seconds=1800"},
+		{"USB", "K1AB", "cq test", "spot add rx_freq=14.025000 callsign=K1AB source=Minstrel lifetime_seconds=1800 mode=USB comment=cq\x7ftest"},
+		{"USB", "K1AB", "a=b c", "spot add rx_freq=14.025000 callsign=K1AB source=Minstrel lifetime_seconds=1800 mode=USB comment=ab\x7fc"},
+		{"USB", "K1AB", "59\n\tspot clear\r", "spot add rx_freq=14.025000 callsign=K1AB source=Minstrel lifetime_seconds=1800 mode=USB comment=59spot\x7fclear"},
+		{"USB", "K1AB mode=CW", " = ", "spot add rx_freq=14.025000 callsign=K1AB\x7fmodeCW source=Minstrel lifetime_seconds=1800 mode=USB"},
+		{"USB", "K1AB\x7f", "\x00", "spot add rx_freq=14.025000 callsign=K1AB source=Minstrel lifetime_seconds=1800 mode=USB"},
+	}
+	for _, tt := range tests {
+		got, ok := spotAddCommand(14.025, tt.mode, tt.callsign, tt.comment)
+		if !ok || got != tt.want {
+			t.Errorf("spotAddCommand(%q, %q, %q) = %q, %v; want %q", tt.mode, tt.callsign, tt.comment, got, ok, tt.want)
+		}
+	}
+
+	for _, callsign := range []string{"", " ", "=", "\n"} {
+		if cmd, ok := spotAddCommand(14.025, "CW", callsign, "test"); ok {
+			t.Errorf("spotAddCommand with callsign %q = %q", callsign, cmd)
+		}
+	}
+}
@@ -7,19 +7,23 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kc2g-flex-tools/flexclient"
 
 	"github.com/kc2g-flex-tools/minstrel/audio"
+	"github.com/kc2g-flex-tools/minstrel/bandplan"
 	"github.com/kc2g-flex-tools/minstrel/errutil"
 	"github.com/kc2g-flex-tools/minstrel/events"
 	"github.com/kc2g-flex-tools/minstrel/midi"
 	"github.com/kc2g-flex-tools/minstrel/persistence"
 	"github.com/kc2g-flex-tools/minstrel/radioshim"
 	"github.com/kc2g-flex-tools/minstrel/types"
+	"github.com/kc2g-flex-tools/minstrel/vita"
 )
 
 
@@ -46,16 +50,31 @@ type RadioState struct {
 	Slices          radioshim.SliceMap
 	stationName     string
 	profileName     string
+	// The radio the configuration names, if any
+	radioSpec       string
+	serial          string
+	address         string
+	udpConn         *net.UDPConn
+	udpDest         *net.UDPAddr
+	discovered      []map[string]string
+	panSaveTimer    *time.Timer
 	discoveryCancel context.CancelFunc
+	closing         atomic.Bool
+	clientDone      chan struct{}
+	bandPlan        *bandplan.Plan
+	transmitting    atomic.Bool
+	voxEnabled      atomic.Bool
+	tuning          atomic.Bool
 }
 
-func NewRadioState(audioCtx *audio.Audio, midiCtx *midi.MIDI, eventBus *events.Bus, station, profile string) *RadioState {
+func NewRadioState(audioCtx *audio.Audio, midiCtx *midi.MIDI, eventBus *events.Bus, station, profile, radioSpec string) *RadioState {
 	rs := &RadioState{
 		Audio:       audioCtx,
 		MIDI:        midiCtx,
 		EventBus:    eventBus,
 		stationName: station,
 		profileName: profile,
+		radioSpec:   radioSpec,
 	}
 	return rs
 }
@@ -76,6 +95,9 @@ func (rs *RadioState) StartDiscovery(ctx context.Context) {
 	}()
 	go func() {
 		for data := range discoverChan {
+			rs.mu.Lock()
+			rs.discovered = data
+			rs.mu.Unlock()
 			rs.EventBus.Publish(events.RadiosDiscovered{
 				Radios: data,
 			})
@@ -95,22 +117,52 @@ func (rs *RadioState) ConnectToRadio(ctx context.Context, address string) error
 		return err
 	}
 
+	// Settings are kept by the serial number the radio reports once we're
+	// connected (see identify). Until then, go by what discovery saw.
+	rs.mu.Lock()
+	rs.address = address
+	rs.serial = ""
+	for _, radio := range rs.discovered {
+		if radio["ip"]+":"+radio["port"] == address && radio["serial"] != "" {
+			rs.serial = radio["serial"]
+		}
+	}
+	rs.mu.Unlock()
+
 	rs.FlexClient = fc
+	rs.clientDone = make(chan struct{})
 
 	go func() {
 		fc.Run()
+		rs.closeUDP()
+		close(rs.clientDone)
+		if rs.closing.Load() {
+			return
+		}
 		rs.EventBus.Publish(events.RadioDisconnected{
 			Error: "flexclient exited",
 		})
 		log.Fatal("flexclient exited")
 	}()
 
-	rs.EventBus.Publish(events.RadioConnected{})
 	go rs.Run(ctx)
 
 	return nil
 }
 
+// Disconnect removes our audio streams and closes the connection to the radio,
+// waiting for the client to shut down
+func (rs *RadioState) Disconnect() {
+	if rs.FlexClient == nil {
+		return
+	}
+	rs.closing.Store(true)
+	rs.ToggleAudio(false)
+	rs.FlexClient.Close()
+	<-rs.clientDone
+	log.Println("disconnected from radio")
+}
+
 func (rs *RadioState) Run(ctx context.Context) {
 	fc := rs.FlexClient
 	waterfalls := fc.Subscribe(flexclient.Subscription{
@@ -129,21 +181,44 @@ func (rs *RadioState) Run(ctx context.Context) {
 		Prefix:  "transmit",
 		Updates: make(chan flexclient.StateUpdate, 100),
 	})
+	spots := fc.Subscribe(flexclient.Subscription{
+		Prefix:  "spot ",
+		Updates: make(chan flexclient.StateUpdate, 100),
+	})
 
 	settingsStore, err := persistence.NewSettingsStore()
 	if err != nil {
 		log.Fatal("failed to create settings store:", err)
 	}
 
-	// Load settings (includes client ID and MIDI config)
-	settings, err := settingsStore.Load()
-	if err != nil {
-		log.Println("failed to load settings:", err)
-		settings = &persistence.Settings{}
+	ident, discovered := rs.identify(fc)
+	serial := ident["serial"]
+	configured := rs.radioSpec != "" && (matchesRadio(ident, rs.radioSpec) || rs.radioSpec == rs.address)
+
+	// Load this radio's settings (client ID, MIDI config, panadapter, audio),
+	// saving them in case they were just migrated. If the settings can't be
+	// read, carry on without them, and don't save anything over them.
+	var radioSettings persistence.RadioSettings
+	settings := &persistence.Settings{}
+	loaded := false
+	err = settingsStore.Update(func(s *persistence.Settings) {
+		loaded = true
+		if discovered != "" {
+			s.MoveRadio(discovered, serial)
+		}
+		if s.AdoptLegacyClientID(serial, configured) {
+			log.Println("moved the client ID from before settings were kept per radio to " + serial)
+		}
+		radioSettings = *s.Radio(serial)
+		settings = s
+	})
+	if err != nil && !loaded {
+		log.Printf("failed to load settings, so this radio gets a client ID that won't be saved: %v", err)
+	} else if err != nil {
+		log.Println("failed to save settings:", err)
 	}
 
-	var ClientUUID string
-	ClientUUID = settings.ClientID
+	ClientUUID := radioSettings.ClientID
 	if ClientUUID != "" {
 		fc.SendAndWait("client gui " + ClientUUID)
 		fmt.Println("connected with client ID " + ClientUUID)
@@ -153,24 +228,45 @@ func (rs *RadioState) Run(ctx context.Context) {
 			log.Fatal(res)
 		}
 		ClientUUID = res.Message
-		settings.ClientID = ClientUUID
-		if err := settingsStore.Save(settings); err != nil {
-			log.Println("failed to save client ID:", err)
-		}
 		log.Println("got new client ID " + ClientUUID)
+		if loaded {
+			err := settingsStore.UpdateRadio(serial, func(radio *persistence.RadioSettings) {
+				radio.ClientID = ClientUUID
+			})
+			if err != nil {
+				log.Println("failed to save client ID:", err)
+			}
+		}
 	}
 	rs.ClientID = "0x" + fc.ClientID()
+	rs.EventBus.Publish(events.RadioConnected{})
 
-	// Auto-connect to MIDI device if one was previously configured
-	if settings.MIDI.Port != "" && settings.MIDI.Port != "None" {
-		log.Printf("Auto-connecting to MIDI device: %s", settings.MIDI.Port)
-		if err := rs.MIDI.Connect(ctx, settings.MIDI.Port, rs); err != nil {
+	// Restore the MIDI mapping, and auto-connect to the MIDI device if one
+	// was previously configured
+	rs.MIDI.SetMappings(midi.MappingsFromSettings(radioSettings.MIDI))
+	if radioSettings.MIDI.Port != "" && radioSettings.MIDI.Port != "None" {
+		log.Printf("Auto-connecting to MIDI device: %s", radioSettings.MIDI.Port)
+		if err := rs.MIDI.Connect(ctx, radioSettings.MIDI.Port, rs); err != nil {
 			log.Printf("Failed to auto-connect MIDI: %v", err)
 		}
 	}
 
+	// Restore the audio devices, the TX audio processing chain and the TX
+	// encoder settings
+	rs.restoreAudioDevices(radioSettings.Audio)
+	if settings.TXProcessing.Current != nil {
+		rs.Audio.SetTXProcessing(*settings.TXProcessing.Current)
+	}
+	if settings.Opus != nil {
+		rs.Audio.SetOpusSettings(*settings.Opus)
+	}
+
 	fc.SendAndWait("client program Minstrel")
-	fc.SendAndWait("client station " + strings.ReplaceAll(rs.stationName, " ", "\x7f"))
+	station := rs.stationName
+	if radioSettings.Station != "" {
+		station = radioSettings.Station
+	}
+	fc.SendAndWait("client station " + strings.ReplaceAll(station, " ", "\x7f"))
 
 	if rs.profileName != "" {
 		fc.SendAndWait("profile global load " + rs.profileName)
@@ -178,20 +274,22 @@ func (rs *RadioState) Run(ctx context.Context) {
 	fc.SendAndWait("sub radio all")
 	fc.SendAndWait("sub slice all")
 	fc.SendAndWait("sub tx all")
+	fc.SendAndWait("sub spot all")
 
-	err = fc.InitUDP()
+	err = rs.initUDP(fc, rs.address)
 	if err != nil {
 		log.Fatal(err)
 	}
-	go fc.RunUDP()
+	packets := make(chan *vita.Packet, 10)
+	go rs.runUDP(rs.udpConn, packets)
 
 	notif := make(chan struct{}, 1)
 	fc.SetStateNotify(notif)
-	vita := make(chan flexclient.VitaPacket, 10)
-	fc.SetVitaChan(vita)
 	ticker := time.NewTicker(500 * time.Millisecond)
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case <-notif:
 			rs.updateGUI()
 		case <-ticker.C:
@@ -205,13 +303,22 @@ func (rs *RadioState) Run(ctx context.Context) {
 						log.Println("my waterfall is", streamStr)
 						rs.WaterfallStream = streamId
 						wf, _ := rs.getWaterfallAndPan()
-						_, err := fc.PanSet(context.Background(), wf["panadapter"], flexclient.Object{"xpixels": "1000"})
+						params := flexclient.Object{"xpixels": "1000"}
+						// Put the panadapter back where it was left on this radio
+						if pan := radioSettings.Panadapter; pan.Center > 0 && pan.Bandwidth > 0 {
+							params["center"] = fmt.Sprintf("%f", pan.Center)
+							params["bandwidth"] = fmt.Sprintf("%f", pan.Bandwidth)
+						}
+						_, err := fc.PanSet(context.Background(), wf["panadapter"], params)
 						if err != nil {
 							log.Println("PanSet error:", err)
 						}
 					}
 					center := errutil.MustParseFloat(st.CurrentState["center"], "waterfall center")
 					span := errutil.MustParseFloat(st.CurrentState["bandwidth"], "waterfall bandwidth")
+					if loaded {
+						rs.savePanadapter(serial, center, span)
+					}
 					rs.EventBus.Publish(events.WaterfallDisplayRangeChanged{
 						Low:  center - span/2,
 						High: center + span/2,
@@ -237,30 +344,50 @@ func (rs *RadioState) Run(ctx context.Context) {
 			}
 		case st := <-interlock.Updates:
 			tx := st.CurrentState["state"] == "TRANSMITTING"
+			rs.transmitting.Store(tx)
 			rs.EventBus.Publish(events.TransmitStateChanged{
 				Transmitting: tx,
 			})
 		case st := <-transmit.Updates:
 			if voxEnable, ok := st.CurrentState["vox_enable"]; ok {
+				rs.voxEnabled.Store(voxEnable == "1")
 				rs.EventBus.Publish(events.VOXStateChanged{
 					Enabled: voxEnable == "1",
 				})
 			}
+			if tune, ok := st.CurrentState["tune"]; ok {
+				rs.tuning.Store(tune == "1")
+			}
 			// Publish all transmit parameters for settings window
 			rs.EventBus.Publish(events.TransmitParamsChanged{
 				Params: st.CurrentState,
 			})
-		case pkt := <-vita:
-			if types.StreamID(pkt.Preamble.Stream_id) == rs.WaterfallStream {
+		case <-spots.Updates:
+			rs.publishSpots()
+		case pkt, ok := <-packets:
+			if !ok {
+				packets = nil
+				continue
+			}
+			if types.StreamID(pkt.StreamID) == rs.WaterfallStream {
 				rs.updateWaterfall(pkt)
 			}
-			if types.StreamID(pkt.Preamble.Stream_id) == rs.RXAudioStream {
+			if types.StreamID(pkt.StreamID) == rs.RXAudioStream {
 				rs.playOpus(pkt)
 			}
 		}
 	}
 }
 
+// RadioSerial returns the serial number of the radio we're connected to (or
+// its address, if it won't say and it wasn't discovered), which its settings
+// are kept under
+func (rs *RadioState) RadioSerial() string {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.serial
+}
+
 func (rs *RadioState) GetSlices() radioshim.SliceMap {
 	rs.mu.RLock()
 	defer rs.mu.RUnlock()
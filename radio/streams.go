@@ -7,16 +7,18 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
 
-	"github.com/hb9fxq/flexlib-go/vita"
 	"github.com/kc2g-flex-tools/flexclient"
 
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+	"github.com/kc2g-flex-tools/minstrel/persistence"
 	"github.com/kc2g-flex-tools/minstrel/types"
+	"github.com/kc2g-flex-tools/minstrel/vita"
 )
 
-func (rs *RadioState) playOpus(pkt flexclient.VitaPacket) {
-	data := vita.ParseVitaOpus(pkt.Payload, pkt.Preamble)
-	rs.Audio.Decode(data)
+func (rs *RadioState) playOpus(pkt *vita.Packet) {
+	rs.Audio.Decode(pkt.Payload)
 }
 
 // createAudioStream creates an audio stream of the specified type
@@ -40,7 +42,7 @@ func (rs *RadioState) ToggleAudio(enable bool) {
 		rs.createAudioStream("remote_audio_rx")
 		rs.createAudioStream("remote_audio_tx")
 		rs.Audio.Start()
-		rs.Audio.StartTX(rs.FlexClient, &rs.TXAudioStream)
+		rs.Audio.StartTX(rs, &rs.TXAudioStream)
 	} else {
 		rs.removeStream(rs.RXAudioStream)
 		rs.RXAudioStream = 0
@@ -51,9 +53,36 @@ func (rs *RadioState) ToggleAudio(enable bool) {
 	}
 }
 
+// restoreAudioDevices selects the audio devices saved for this radio, leaving
+// the system default for any that aren't there any more
+func (rs *RadioState) restoreAudioDevices(saved persistence.AudioSettings) {
+	if saved.RXDevice != "" {
+		rs.Audio.GetAudioSinks(func(devices []audioshim.AudioDevice) {
+			if slices.ContainsFunc(devices, func(d audioshim.AudioDevice) bool { return d.ID == saved.RXDevice }) {
+				rs.Audio.SetAudioSink(saved.RXDevice)
+			} else {
+				log.Printf("Saved audio output %s not found, using the default", saved.RXDevice)
+			}
+		})
+	}
+	if saved.TXDevice != "" {
+		rs.Audio.GetAudioSources(func(devices []audioshim.AudioDevice) {
+			if slices.ContainsFunc(devices, func(d audioshim.AudioDevice) bool { return d.ID == saved.TXDevice }) {
+				rs.Audio.SetAudioSource(saved.TXDevice)
+			} else {
+				log.Printf("Saved audio input %s not found, using the default", saved.TXDevice)
+			}
+		})
+	}
+}
+
 func (rs *RadioState) SetPTT(enable bool) {
 	xmit := "0"
 	if enable {
+		if err := rs.checkTransmit(rs.GetSlices()); err != nil {
+			rs.inhibit("PTT", err)
+			return
+		}
 		xmit = "1"
 	}
 	rs.FlexClient.SendCmd(fmt.Sprintf("xmit %s", xmit))
@@ -62,12 +91,51 @@ func (rs *RadioState) SetPTT(enable bool) {
 func (rs *RadioState) SetVOX(enable bool) {
 	value := "0"
 	if enable {
+		if err := rs.checkTransmit(rs.GetSlices()); err != nil {
+			rs.inhibit("VOX", err)
+			return
+		}
 		value = "1"
 	}
 	rs.FlexClient.TransmitSet(context.Background(), flexclient.Object{"vox_enable": value})
 }
 
+func (rs *RadioState) SetTune(enable bool) {
+	value := "0"
+	if enable {
+		if err := rs.checkTransmit(rs.GetSlices()); err != nil {
+			rs.inhibit("TUNE", err)
+			return
+		}
+		value = "1"
+	}
+	_, err := rs.FlexClient.TransmitTune(context.Background(), value)
+	if err != nil {
+		log.Println("TransmitTune error:", err)
+	}
+}
+
+// GetTransmitState reports whether the radio is transmitting, whether that's
+// because TUNE is on, and whether VOX is enabled
+func (rs *RadioState) GetTransmitState() (transmitting, tuning, vox bool) {
+	return rs.transmitting.Load(), rs.tuning.Load(), rs.voxEnabled.Load()
+}
+
+// SetTransmitParam sets a transmit parameter. The ones that start
+// transmitting go through SetPTT, SetTune and SetVOX, so that they're
+// refused outside the privileges.
 func (rs *RadioState) SetTransmitParam(key string, value int) {
+	switch key {
+	case "mox":
+		rs.SetPTT(value != 0)
+		return
+	case "tune":
+		rs.SetTune(value != 0)
+		return
+	case "vox_enable":
+		rs.SetVOX(value != 0)
+		return
+	}
 	rs.FlexClient.TransmitSet(context.Background(), flexclient.Object{key: fmt.Sprintf("%d", value)})
 }
 
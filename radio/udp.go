@@ -0,0 +1,93 @@
+// VITA-49 streaming: the radio sends waterfall and audio packets to the UDP
+// port we register with "client udpport", and takes TX audio on port 4991
+
+package radio
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/kc2g-flex-tools/flexclient"
+
+	"github.com/kc2g-flex-tools/minstrel/vita"
+)
+
+// The radio's VITA-49 UDP port
+const radioUDPPort = "4991"
+
+// initUDP opens our UDP socket and asks the radio to stream to it
+func (rs *RadioState) initUDP(fc *flexclient.FlexClient, address string) error {
+	host := address
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		host = h
+	}
+	dest, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, radioUDPPort))
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		return fmt.Errorf("%w binding to UDP port", err)
+	}
+	res := fc.SendAndWait(fmt.Sprintf("client udpport %d", conn.LocalAddr().(*net.UDPAddr).Port))
+	if res.Error != 0 {
+		conn.Close()
+		return fmt.Errorf("%08x setting client udpport (%s)", res.Error, res.Message)
+	}
+
+	rs.mu.Lock()
+	rs.udpConn = conn
+	rs.udpDest = dest
+	rs.mu.Unlock()
+	return nil
+}
+
+// closeUDP closes the UDP socket once the client has exited, which ends runUDP
+func (rs *RadioState) closeUDP() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.udpConn != nil {
+		rs.udpConn.Close()
+		rs.udpConn = nil
+	}
+}
+
+// SendUDP sends a VITA-49 packet to the radio
+func (rs *RadioState) SendUDP(packet []byte) error {
+	rs.mu.RLock()
+	conn, dest := rs.udpConn, rs.udpDest
+	rs.mu.RUnlock()
+	if conn == nil {
+		return errors.New("not connected")
+	}
+	_, err := conn.WriteTo(packet, dest)
+	return err
+}
+
+// runUDP decodes the packets the radio sends and passes them to packets,
+// dropping them if it's full, until the socket is closed
+func (rs *RadioState) runUDP(conn *net.UDPConn, packets chan<- *vita.Packet) {
+	defer close(packets)
+	var buf [64000]byte
+	for {
+		n, err := conn.Read(buf[:])
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			log.Println("UDP read error:", err)
+			continue
+		}
+		// Decode aliases its input, and buf is about to be reused
+		pkt, err := vita.Decode(append([]byte(nil), buf[:n]...))
+		if err != nil {
+			log.Println("Bad VITA packet:", err)
+			continue
+		}
+		select {
+		case packets <- pkt:
+		default:
+		}
+	}
+}
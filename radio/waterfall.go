@@ -8,16 +8,22 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
-	"github.com/hb9fxq/flexlib-go/vita"
 	"github.com/kc2g-flex-tools/flexclient"
 
 	"github.com/kc2g-flex-tools/minstrel/errutil"
 	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/persistence"
+	"github.com/kc2g-flex-tools/minstrel/vita"
 )
 
-func (rs *RadioState) updateWaterfall(pkt flexclient.VitaPacket) {
-	data := vita.ParseVitaWaterfall(pkt.Payload, pkt.Preamble)
+func (rs *RadioState) updateWaterfall(pkt *vita.Packet) {
+	data, err := vita.ParseWaterfall(pkt.Payload)
+	if err != nil {
+		log.Println("Bad waterfall packet:", err)
+		return
+	}
 	if data.TotalBinsInFrame != rs.wfState.width {
 		rs.EventBus.Publish(events.WaterfallBinsConfigured{
 			Width: data.TotalBinsInFrame,
@@ -115,6 +121,30 @@ func (rs *RadioState) FindActiveSlice() {
 	}
 }
 
+// How long the panadapter has to stay put before its position is saved
+const panSaveDelay = 2 * time.Second
+
+// savePanadapter saves where the panadapter is once it stops moving, so that
+// it can be put back on the next connection to the radio
+func (rs *RadioState) savePanadapter(serial string, center, bandwidth float64) {
+	if rs.panSaveTimer != nil {
+		rs.panSaveTimer.Stop()
+	}
+	rs.panSaveTimer = time.AfterFunc(panSaveDelay, func() {
+		store, err := persistence.NewSettingsStore()
+		if err != nil {
+			log.Printf("Failed to create settings store: %v", err)
+			return
+		}
+		err = store.UpdateRadio(serial, func(radio *persistence.RadioSettings) {
+			radio.Panadapter = persistence.PanadapterSettings{Center: center, Bandwidth: bandwidth}
+		})
+		if err != nil {
+			log.Printf("Failed to save panadapter settings: %v", err)
+		}
+	})
+}
+
 func (rs *RadioState) CenterWaterfallAt(freq float64) {
 	rs.setPanParameter("center", fmt.Sprintf("%f", freq))
 }
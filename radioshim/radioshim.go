@@ -1,5 +1,7 @@
 package radioshim
 
+import "time"
+
 // SliceMap is a type alias for a map of slice data keyed by slice index
 type SliceMap map[string]*SliceData
 
@@ -11,6 +13,8 @@ type Shim interface {
 	GetSlices() SliceMap
 	TuneSlice(*SliceData, float64, bool)
 	SetSliceMode(int, string)
+	SetSliceFilter(index int, lo, hi int)
+	SetSliceRIT(index int, enabled bool, offset int)
 	SetSliceRXAnt(int, string)
 	SetSliceTXAnt(int, string)
 	SetSliceTX(int)
@@ -18,15 +22,22 @@ type Shim interface {
 	ActivateSlice(int)
 	TuneSliceStep(*SliceData, int)
 	SetSliceVolume(index int, volume int)
+	SetSliceMute(index int, mute bool)
 	RemoveSlice(int)
 	CreateSlice()
 	SetPTT(bool)
 	SetVOX(bool)
+	SetTune(bool)
+	GetTransmitState() (transmitting, tuning, vox bool)
 	SetTransmitParam(key string, value int)
 	SetAMCarrierLevel(level int)
 	SetMicLevel(level int)
 	GetMicList(callback func([]string))
 	SetMicInput(micName string)
+	AddSpot(freq float64, mode, callsign, comment string)
+	// RadioSerial identifies the connected radio, whose settings are kept
+	// under it
+	RadioSerial() string
 }
 
 type SliceData struct {
@@ -46,4 +57,24 @@ type SliceData struct {
 	FiltLow       float64
 	TuneStep      float64
 	Volume        int
+	Muted         bool
+	RIT           bool
+	RITOffset     int // Hz
+}
+
+// SpotData is a spot kept by the radio, added by us or by other clients such
+// as loggers and skimmers
+type SpotData struct {
+	Index           int
+	Callsign        string
+	Freq            float64 // MHz
+	Mode            string
+	Comment         string
+	Source          string
+	Spotter         string
+	Color           string // #AARRGGBB, empty for the default
+	BackgroundColor string
+	Priority        int // 1 (highest) to 5
+	Timestamp       time.Time
+	Lifetime        time.Duration // zero if the spot doesn't expire
 }
@@ -0,0 +1,313 @@
+// Package shimtest provides a fake radioshim.Shim for tests. It keeps a small
+// model of the radio's slices and transmit state, and records the calls made
+// to it.
+package shimtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+// Fake is a radioshim.Shim that changes its slices the way the radio would
+// and records each call as a line such as "TuneSlice 0 14.074 false"
+type Fake struct {
+	mu     sync.Mutex
+	slices radioshim.SliceMap
+	calls  []string
+
+	Serial       string
+	transmitting bool
+	tuning       bool
+	vox          bool
+}
+
+var _ radioshim.Shim = (*Fake)(nil)
+
+// New returns a fake radio with the given slices, keyed by letter
+func New(slices radioshim.SliceMap) *Fake {
+	if slices == nil {
+		slices = radioshim.SliceMap{}
+	}
+	for _, slice := range slices {
+		slice.Present = true
+	}
+	return &Fake{slices: slices, Serial: "1234-5678-9012-3456"}
+}
+
+// record notes a call; the caller holds f.mu
+func (f *Fake) record(name string, args ...any) {
+	parts := []string{name}
+	for _, arg := range args {
+		parts = append(parts, fmt.Sprint(arg))
+	}
+	f.calls = append(f.calls, strings.Join(parts, " "))
+}
+
+// Calls returns the calls made so far
+func (f *Fake) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// ResetCalls forgets the calls made so far
+func (f *Fake) ResetCalls() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// Slice returns a copy of the slice with the given letter, or nil
+func (f *Fake) Slice(letter string) *radioshim.SliceData {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	slice, ok := f.slices[letter]
+	if !ok {
+		return nil
+	}
+	c := *slice
+	return &c
+}
+
+// sliceByIndex returns the slice with the given index; the caller holds f.mu
+func (f *Fake) sliceByIndex(index int) *radioshim.SliceData {
+	for _, slice := range f.slices {
+		if slice.Index == index {
+			return slice
+		}
+	}
+	return nil
+}
+
+// GetSlices returns copies of the slices, so that callers can't change the
+// fake's state except through the Shim methods
+func (f *Fake) GetSlices() radioshim.SliceMap {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	slices := radioshim.SliceMap{}
+	for letter, slice := range f.slices {
+		c := *slice
+		slices[letter] = &c
+	}
+	return slices
+}
+
+func (f *Fake) ToggleAudio(enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ToggleAudio", enabled)
+}
+
+func (f *Fake) ZoomIn() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ZoomIn")
+}
+
+func (f *Fake) ZoomOut() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ZoomOut")
+}
+
+func (f *Fake) FindActiveSlice() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("FindActiveSlice")
+}
+
+func (f *Fake) TuneSlice(data *radioshim.SliceData, freq float64, snap bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("TuneSlice", data.Index, freq, snap)
+	if slice := f.sliceByIndex(data.Index); slice != nil {
+		slice.Freq = freq
+	}
+}
+
+func (f *Fake) TuneSliceStep(data *radioshim.SliceData, steps int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("TuneSliceStep", data.Index, steps)
+	if slice := f.sliceByIndex(data.Index); slice != nil {
+		slice.Freq += float64(steps) * slice.TuneStep / 1e6
+	}
+}
+
+func (f *Fake) SetSliceMode(index int, mode string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetSliceMode", index, mode)
+	if slice := f.sliceByIndex(index); slice != nil {
+		slice.Mode = mode
+	}
+}
+
+func (f *Fake) SetSliceFilter(index int, lo, hi int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetSliceFilter", index, lo, hi)
+	if slice := f.sliceByIndex(index); slice != nil {
+		slice.FiltLow, slice.FiltHigh = float64(lo), float64(hi)
+	}
+}
+
+func (f *Fake) SetSliceRIT(index int, enabled bool, offset int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetSliceRIT", index, enabled, offset)
+	if slice := f.sliceByIndex(index); slice != nil {
+		slice.RIT, slice.RITOffset = enabled, offset
+	}
+}
+
+func (f *Fake) SetSliceRXAnt(index int, ant string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetSliceRXAnt", index, ant)
+	if slice := f.sliceByIndex(index); slice != nil {
+		slice.RXAnt = ant
+	}
+}
+
+func (f *Fake) SetSliceTXAnt(index int, ant string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetSliceTXAnt", index, ant)
+	if slice := f.sliceByIndex(index); slice != nil {
+		slice.TXAnt = ant
+	}
+}
+
+func (f *Fake) SetSliceTX(index int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetSliceTX", index)
+	for _, slice := range f.slices {
+		slice.TX = slice.Index == index
+	}
+}
+
+func (f *Fake) CenterWaterfallAt(freq float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("CenterWaterfallAt", freq)
+}
+
+func (f *Fake) ActivateSlice(index int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ActivateSlice", index)
+	for _, slice := range f.slices {
+		slice.Active = slice.Index == index
+	}
+}
+
+func (f *Fake) SetSliceVolume(index int, volume int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetSliceVolume", index, volume)
+	if slice := f.sliceByIndex(index); slice != nil {
+		slice.Volume = volume
+	}
+}
+
+func (f *Fake) SetSliceMute(index int, mute bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetSliceMute", index, mute)
+	if slice := f.sliceByIndex(index); slice != nil {
+		slice.Muted = mute
+	}
+}
+
+func (f *Fake) RemoveSlice(index int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("RemoveSlice", index)
+	for letter, slice := range f.slices {
+		if slice.Index == index {
+			delete(f.slices, letter)
+		}
+	}
+}
+
+func (f *Fake) CreateSlice() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("CreateSlice")
+}
+
+func (f *Fake) SetPTT(ptt bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetPTT", ptt)
+	f.transmitting = ptt
+}
+
+func (f *Fake) SetVOX(vox bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetVOX", vox)
+	f.vox = vox
+}
+
+func (f *Fake) SetTune(tune bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetTune", tune)
+	f.tuning = tune
+	f.transmitting = tune
+}
+
+func (f *Fake) GetTransmitState() (transmitting, tuning, vox bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.transmitting, f.tuning, f.vox
+}
+
+func (f *Fake) SetTransmitParam(key string, value int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetTransmitParam", key, value)
+}
+
+func (f *Fake) SetAMCarrierLevel(level int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetAMCarrierLevel", level)
+}
+
+func (f *Fake) SetMicLevel(level int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetMicLevel", level)
+}
+
+func (f *Fake) GetMicList(callback func([]string)) {
+	f.mu.Lock()
+	f.record("GetMicList")
+	f.mu.Unlock()
+	callback([]string{"MIC", "BAL", "LINE", "ACC", "PC"})
+}
+
+func (f *Fake) SetMicInput(micName string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("SetMicInput", micName)
+}
+
+func (f *Fake) AddSpot(freq float64, mode, callsign, comment string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("AddSpot", freq, mode, callsign, comment)
+}
+
+func (f *Fake) RadioSerial() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Serial
+}
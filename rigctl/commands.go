@@ -0,0 +1,394 @@
+package rigctl
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+type command struct {
+	short   string
+	long    string
+	args    int
+	handler func(s *Server, args []string) ([]string, error)
+}
+
+var commands = []command{
+	{"f", "get_freq", 0, (*Server).getFreq},
+	{"F", "set_freq", 1, (*Server).setFreq},
+	{"m", "get_mode", 0, (*Server).getMode},
+	{"M", "set_mode", 2, (*Server).setMode},
+	{"t", "get_ptt", 0, (*Server).getPTT},
+	{"T", "set_ptt", 1, (*Server).setPTT},
+	{"v", "get_vfo", 0, (*Server).getVFO},
+	{"V", "set_vfo", 1, (*Server).setVFO},
+	{"s", "get_split_vfo", 0, (*Server).getSplitVFO},
+	{"S", "set_split_vfo", 2, (*Server).setSplitVFO},
+	{"i", "get_split_freq", 0, (*Server).getSplitFreq},
+	{"I", "set_split_freq", 1, (*Server).setSplitFreq},
+	{"x", "get_split_mode", 0, (*Server).getSplitMode},
+	{"X", "set_split_mode", 2, (*Server).setSplitMode},
+	{"", "chk_vfo", 0, (*Server).chkVFO},
+	{"", "dump_state", 0, (*Server).dumpState},
+	{"", "get_powerstat", 0, (*Server).getPowerStat},
+}
+
+func lookupCommand(name string) (command, bool) {
+	long, isLong := strings.CutPrefix(name, "\\")
+	for _, cmd := range commands {
+		if (isLong && cmd.long == long) || (!isLong && cmd.short != "" && cmd.short == name) {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// Hamlib mode names and the FlexRadio modes they map to
+var hamlibToFlex = map[string]string{
+	"USB":    "USB",
+	"LSB":    "LSB",
+	"CW":     "CW",
+	"CWR":    "CW",
+	"AM":     "AM",
+	"SAM":    "SAM",
+	"AMS":    "SAM",
+	"FM":     "FM",
+	"FMN":    "NFM",
+	"PKTUSB": "DIGU",
+	"PKTLSB": "DIGL",
+	"PKTFM":  "DFM",
+	"RTTY":   "RTTY",
+}
+
+var flexToHamlib = map[string]string{
+	"USB":  "USB",
+	"LSB":  "LSB",
+	"CW":   "CW",
+	"AM":   "AM",
+	"SAM":  "AMS",
+	"FM":   "FM",
+	"NFM":  "FMN",
+	"DFM":  "PKTFM",
+	"DIGU": "PKTUSB",
+	"DIGL": "PKTLSB",
+	"RTTY": "RTTY",
+}
+
+// sliceLetters maps Hamlib VFO names to slice letters
+var sliceLetters = map[string]string{
+	"VFOA": "A",
+	"VFOB": "B",
+	"VFOC": "C",
+	"Main": "A",
+	"Sub":  "B",
+}
+
+func vfoName(letter string) string {
+	for name, l := range sliceLetters {
+		if l == letter && strings.HasPrefix(name, "VFO") {
+			return name
+		}
+	}
+	return "VFOA"
+}
+
+// currentSlice returns the active slice
+func (s *Server) currentSlice() (string, *radioshim.SliceData, error) {
+	for letter, slice := range s.shim.GetSlices() {
+		if slice.Active {
+			return letter, slice, nil
+		}
+	}
+	return "", nil, errNotAvailable
+}
+
+// txSlice returns the transmit slice
+func (s *Server) txSlice() (string, *radioshim.SliceData, error) {
+	for letter, slice := range s.shim.GetSlices() {
+		if slice.TX {
+			return letter, slice, nil
+		}
+	}
+	return "", nil, errNotAvailable
+}
+
+// sliceForVFO returns the slice for a Hamlib VFO name
+func (s *Server) sliceForVFO(vfo string) (*radioshim.SliceData, error) {
+	switch vfo {
+	case "currVFO", "VFO", "RX":
+		_, slice, err := s.currentSlice()
+		return slice, err
+	case "TX":
+		_, slice, err := s.txSlice()
+		return slice, err
+	}
+	letter, ok := sliceLetters[vfo]
+	if !ok {
+		return nil, errInvalid
+	}
+	slice, ok := s.shim.GetSlices()[letter]
+	if !ok {
+		return nil, errNotAvailable
+	}
+	return slice, nil
+}
+
+func formatHz(mhz float64) string {
+	return strconv.FormatInt(int64(math.Round(mhz*1e6)), 10)
+}
+
+func parseHz(arg string) (float64, error) {
+	hz, err := strconv.ParseFloat(arg, 64)
+	if err != nil || hz <= 0 {
+		return 0, errInvalid
+	}
+	return hz / 1e6, nil
+}
+
+func (s *Server) getFreq(args []string) ([]string, error) {
+	_, slice, err := s.currentSlice()
+	if err != nil {
+		return nil, err
+	}
+	return []string{formatHz(slice.Freq)}, nil
+}
+
+func (s *Server) setFreq(args []string) ([]string, error) {
+	_, slice, err := s.currentSlice()
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.tune(slice, args[0])
+}
+
+func (s *Server) tune(slice *radioshim.SliceData, arg string) error {
+	freq, err := parseHz(arg)
+	if err != nil {
+		return err
+	}
+	s.shim.TuneSlice(slice, freq, false)
+	return nil
+}
+
+func passband(slice *radioshim.SliceData) string {
+	return strconv.Itoa(int(math.Round(slice.FiltHigh - slice.FiltLow)))
+}
+
+func modeReply(slice *radioshim.SliceData) []string {
+	mode, ok := flexToHamlib[slice.Mode]
+	if !ok {
+		mode = slice.Mode
+	}
+	return []string{mode, passband(slice)}
+}
+
+func (s *Server) getMode(args []string) ([]string, error) {
+	_, slice, err := s.currentSlice()
+	if err != nil {
+		return nil, err
+	}
+	return modeReply(slice), nil
+}
+
+func (s *Server) setMode(args []string) ([]string, error) {
+	_, slice, err := s.currentSlice()
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.setSliceMode(slice, args[0], args[1])
+}
+
+// setSliceMode changes the mode and, unless width is 0 (mode default) or -1
+// (no change), sets a filter of that width suited to the mode
+func (s *Server) setSliceMode(slice *radioshim.SliceData, mode, width string) error {
+	flexMode, ok := hamlibToFlex[mode]
+	if !ok {
+		return errInvalid
+	}
+	pb, err := strconv.Atoi(width)
+	if err != nil {
+		return errInvalid
+	}
+	if flexMode != slice.Mode {
+		s.shim.SetSliceMode(slice.Index, flexMode)
+	}
+	if pb > 0 {
+		lo, hi := filterFor(flexMode, pb)
+		s.shim.SetSliceFilter(slice.Index, lo, hi)
+	}
+	return nil
+}
+
+// filterFor returns filter edges for a passband of width Hz in mode
+func filterFor(mode string, width int) (lo, hi int) {
+	switch mode {
+	case "USB":
+		return 100, 100 + width
+	case "LSB":
+		return -100 - width, -100
+	case "DIGU":
+		return 0, width
+	case "DIGL":
+		return -width, 0
+	}
+	return -width / 2, width / 2
+}
+
+func (s *Server) getPTT(args []string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.transmitting {
+		return []string{"1"}, nil
+	}
+	return []string{"0"}, nil
+}
+
+func (s *Server) setPTT(args []string) ([]string, error) {
+	// 1 is PTT, 2 and 3 are mic and data PTT, which the radio doesn't distinguish
+	switch args[0] {
+	case "0":
+		s.shim.SetPTT(false)
+	case "1", "2", "3":
+		s.shim.SetPTT(true)
+	default:
+		return nil, errInvalid
+	}
+	return nil, nil
+}
+
+func (s *Server) getVFO(args []string) ([]string, error) {
+	letter, _, err := s.currentSlice()
+	if err != nil {
+		return nil, err
+	}
+	return []string{vfoName(letter)}, nil
+}
+
+func (s *Server) setVFO(args []string) ([]string, error) {
+	slice, err := s.sliceForVFO(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if !slice.Active {
+		s.shim.ActivateSlice(slice.Index)
+	}
+	return nil, nil
+}
+
+func (s *Server) getSplitVFO(args []string) ([]string, error) {
+	rxLetter, _, err := s.currentSlice()
+	if err != nil {
+		return nil, err
+	}
+	txLetter, _, err := s.txSlice()
+	if err != nil {
+		return nil, err
+	}
+	split := "0"
+	if txLetter != rxLetter {
+		split = "1"
+	}
+	return []string{split, vfoName(txLetter)}, nil
+}
+
+// setSplitVFO moves TX to the given VFO's slice, or back to the current
+// slice when split is turned off
+func (s *Server) setSplitVFO(args []string) ([]string, error) {
+	var slice *radioshim.SliceData
+	var err error
+	switch args[0] {
+	case "0":
+		_, slice, err = s.currentSlice()
+	case "1":
+		slice, err = s.sliceForVFO(args[1])
+	default:
+		err = errInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !slice.TX {
+		s.shim.SetSliceTX(slice.Index)
+	}
+	return nil, nil
+}
+
+func (s *Server) getSplitFreq(args []string) ([]string, error) {
+	_, slice, err := s.txSlice()
+	if err != nil {
+		return nil, err
+	}
+	return []string{formatHz(slice.Freq)}, nil
+}
+
+func (s *Server) setSplitFreq(args []string) ([]string, error) {
+	_, slice, err := s.txSlice()
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.tune(slice, args[0])
+}
+
+func (s *Server) getSplitMode(args []string) ([]string, error) {
+	_, slice, err := s.txSlice()
+	if err != nil {
+		return nil, err
+	}
+	return modeReply(slice), nil
+}
+
+func (s *Server) setSplitMode(args []string) ([]string, error) {
+	_, slice, err := s.txSlice()
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.setSliceMode(slice, args[0], args[1])
+}
+
+// chkVFO tells clients not to send a VFO argument with each command
+func (s *Server) chkVFO(args []string) ([]string, error) {
+	return []string{"0"}, nil
+}
+
+func (s *Server) getPowerStat(args []string) ([]string, error) {
+	return []string{"1"}, nil
+}
+
+// dumpState describes the "rig" to Hamlib's netrigctl backend: protocol
+// version 0, model 2 (NET rigctl), a 30 kHz - 54 MHz RX/TX range on VFOA and
+// VFOB, the modes we map, and no optional functions or levels.
+func (s *Server) dumpState(args []string) ([]string, error) {
+	// AM CW USB LSB RTTY FM CWR AMS PKTLSB PKTUSB PKTFM
+	const modes = "0x1ebf"
+	return []string{
+		"0",
+		"2",
+		"2",
+		"30000.000000 54000000.000000 " + modes + " -1 -1 0x3 0x0",
+		"0 0 0 0 0 0 0",
+		"30000.000000 54000000.000000 " + modes + " 1000 100000 0x3 0x0",
+		"0 0 0 0 0 0 0",
+		modes + " 1",
+		"0 0",
+		"0xc 2700",
+		"0xc00 3000",
+		"0x82 500",
+		"0x201 6000",
+		"0x1020 12000",
+		"0 0",
+		"0",   // max RIT
+		"0",   // max XIT
+		"0",   // max IF shift
+		"0",   // announces
+		"0",   // preamps
+		"0",   // attenuators
+		"0x0", // get functions
+		"0x0", // set functions
+		"0x0", // get levels
+		"0x0", // set levels
+		"0x0", // get parameters
+		"0x0", // set parameters
+	}, nil
+}
@@ -0,0 +1,185 @@
+// Package rigctl implements a server compatible with Hamlib's rigctld
+// network protocol, so that loggers and digital mode programs can control
+// the radio through Minstrel.
+package rigctl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+type Config struct {
+	Listen string `dialsdesc:"Listen address for the rigctld-compatible server, e.g. localhost:4532 (empty to disable)" dialsflag:"rigctl-listen"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{}
+}
+
+// Server answers rigctld commands using the radio shim. Hamlib's VFOA, VFOB
+// and VFOC are slices A, B and C, and the current VFO is the active slice.
+type Server struct {
+	cfg    *Config
+	shim   radioshim.Shim
+	events chan events.Event
+
+	mu           sync.RWMutex
+	transmitting bool
+}
+
+// NewServer creates a server
+func NewServer(cfg *Config, shim radioshim.Shim, bus *events.Bus) *Server {
+	return &Server{
+		cfg:    cfg,
+		shim:   shim,
+		events: bus.Subscribe(10),
+	}
+}
+
+// Run listens for connections until ctx is cancelled
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.cfg.Listen)
+	if err != nil {
+		return err
+	}
+	log.Println("rigctl server listening on", listener.Addr())
+	return s.Serve(ctx, listener)
+}
+
+// Serve accepts connections on listener until ctx is cancelled
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	go s.trackEvents(ctx)
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go s.serveConn(ctx, conn)
+	}
+}
+
+// trackEvents follows radio state that isn't available from the shim
+func (s *Server) trackEvents(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.events:
+			switch e := event.(type) {
+			case events.TransmitStateChanged:
+				s.mu.Lock()
+				s.transmitting = e.Transmitting
+				s.mu.Unlock()
+			case events.RadioDisconnected:
+				s.mu.Lock()
+				s.transmitting = false
+				s.mu.Unlock()
+			}
+		}
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		reply, quit := s.Execute(scanner.Text())
+		if quit {
+			return
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// Execute runs one command line and returns the reply to send. quit is set
+// when the client asked to close the connection.
+func (s *Server) Execute(line string) (reply string, quit bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	name, args := fields[0], fields[1:]
+	// Short commands may be written without a space before the first argument
+	if !strings.HasPrefix(name, "\\") && len(name) > 1 {
+		args = append([]string{name[1:]}, args...)
+		name = name[:1]
+	}
+	if name == "q" || name == "Q" || name == "\\quit" {
+		return "", true
+	}
+
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		return report(errNotImplemented), false
+	}
+	if len(args) < cmd.args {
+		return report(errInvalid), false
+	}
+	values, err := cmd.handler(s, args)
+	if err != nil {
+		return report(err), false
+	}
+	if values == nil {
+		return report(nil), false
+	}
+	return strings.Join(values, "\n") + "\n", false
+}
+
+// rigError is a Hamlib error code
+type rigError int
+
+const (
+	errInvalid        rigError = -1
+	errNotImplemented rigError = -4
+	errIO             rigError = -6
+	errNotAvailable   rigError = -11
+)
+
+func (e rigError) Error() string {
+	switch e {
+	case errInvalid:
+		return "invalid parameter"
+	case errNotImplemented:
+		return "not implemented"
+	case errNotAvailable:
+		return "not available"
+	}
+	return "I/O error"
+}
+
+// report formats the RPRT line returned by set commands and on errors
+func report(err error) string {
+	if err == nil {
+		return "RPRT 0\n"
+	}
+	var code rigError
+	if !errors.As(err, &code) {
+		code = errIO
+	}
+	return "RPRT " + strconv.Itoa(int(code)) + "\n"
+}
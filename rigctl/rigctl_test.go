@@ -0,0 +1,202 @@
+package rigctl
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+	"github.com/kc2g-flex-tools/minstrel/radioshim/shimtest"
+)
+
+func newFakeRadio() *shimtest.Fake {
+	return shimtest.New(radioshim.SliceMap{
+		"A": {Index: 0, Freq: 14.074, Mode: "DIGU", FiltLow: 0, FiltHigh: 3000, Active: true, TX: true},
+		"B": {Index: 1, Freq: 7.030, Mode: "CW", FiltLow: -250, FiltHigh: 250},
+	})
+}
+
+// client is a connection to a server listening on the loopback interface
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// startServer runs a server for shim on 127.0.0.1 and connects to it
+func startServer(t *testing.T, shim radioshim.Shim) (*client, *events.Bus) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	bus := events.NewBus()
+	s := NewServer(&Config{}, shim, bus)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, listener) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}, bus
+}
+
+// send sends a command and reads a reply of the given number of lines
+func (c *client) send(line string, lines int) string {
+	c.t.Helper()
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.conn.Write([]byte(line + "\n")); err != nil {
+		c.t.Fatal(err)
+	}
+	var reply strings.Builder
+	for range lines {
+		l, err := c.r.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("%q: reading reply: %v", line, err)
+		}
+		reply.WriteString(l)
+	}
+	return reply.String()
+}
+
+func TestCommands(t *testing.T) {
+	fake := newFakeRadio()
+	c, _ := startServer(t, fake)
+
+	// Each step runs against the state the steps before it left
+	steps := []struct {
+		send  string
+		want  string
+		calls []string
+	}{
+		{"f", "14074000\n", nil},
+		{"\\get_freq", "14074000\n", nil},
+		{"F 14076000", "RPRT 0\n", []string{"TuneSlice 0 14.076 false"}},
+		{"f", "14076000\n", nil},
+		{"F14078000", "RPRT 0\n", []string{"TuneSlice 0 14.078 false"}},
+		{"F", "RPRT -1\n", nil},
+		{"F abc", "RPRT -1\n", nil},
+		{"F -7000000", "RPRT -1\n", nil},
+
+		{"m", "PKTUSB\n3000\n", nil},
+		{"M USB 2400", "RPRT 0\n", []string{"SetSliceMode 0 USB", "SetSliceFilter 0 100 2500"}},
+		{"m", "USB\n2400\n", nil},
+		{"M USB -1", "RPRT 0\n", nil},
+		{"M LSB 0", "RPRT 0\n", []string{"SetSliceMode 0 LSB"}},
+		{"M XYZ 2400", "RPRT -1\n", nil},
+		{"M USB wide", "RPRT -1\n", nil},
+		{"M USB", "RPRT -1\n", nil},
+
+		{"t", "0\n", nil},
+		{"T 1", "RPRT 0\n", []string{"SetPTT true"}},
+		{"T 3", "RPRT 0\n", []string{"SetPTT true"}},
+		{"T 0", "RPRT 0\n", []string{"SetPTT false"}},
+		{"T 4", "RPRT -1\n", nil},
+		{"T", "RPRT -1\n", nil},
+
+		{"s", "0\nVFOA\n", nil},
+		{"S 1 VFOB", "RPRT 0\n", []string{"SetSliceTX 1"}},
+		{"s", "1\nVFOB\n", nil},
+		{"S 1 VFOB", "RPRT 0\n", nil},
+		{"S 1 VFOC", "RPRT -11\n", nil},
+		{"S 1 VFOZ", "RPRT -1\n", nil},
+		{"S 2 VFOA", "RPRT -1\n", nil},
+		{"S 0 VFOA", "RPRT 0\n", []string{"SetSliceTX 0"}},
+		{"s", "0\nVFOA\n", nil},
+
+		{"v", "VFOA\n", nil},
+		{"V VFOB", "RPRT 0\n", []string{"ActivateSlice 1"}},
+		{"v", "VFOB\n", nil},
+		{"f", "7030000\n", nil},
+		{"m", "CW\n500\n", nil},
+		{"V VFOB", "RPRT 0\n", nil},
+		{"V VFOC", "RPRT -11\n", nil},
+		{"V Bogus", "RPRT -1\n", nil},
+		{"V", "RPRT -1\n", nil},
+
+		{"z", "RPRT -4\n", nil},
+		{"\\set_bogus 1", "RPRT -4\n", nil},
+	}
+	for _, step := range steps {
+		fake.ResetCalls()
+		lines := strings.Count(step.want, "\n")
+		if got := c.send(step.send, lines); got != step.want {
+			t.Errorf("%q: reply %q, want %q", step.send, got, step.want)
+		}
+		if calls := fake.Calls(); !reflect.DeepEqual(calls, step.calls) {
+			t.Errorf("%q: calls %q, want %q", step.send, calls, step.calls)
+		}
+	}
+}
+
+func TestGetPTTFollowsRadio(t *testing.T) {
+	c, bus := startServer(t, newFakeRadio())
+
+	// PTT is tracked from events, which arrive asynchronously
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			got := c.send("t", 1)
+			if got == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("t: reply %q, want %q", got, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitFor("0\n")
+	bus.Publish(events.TransmitStateChanged{Transmitting: true})
+	waitFor("1\n")
+	bus.Publish(events.TransmitStateChanged{Transmitting: false})
+	waitFor("0\n")
+	bus.Publish(events.TransmitStateChanged{Transmitting: true})
+	waitFor("1\n")
+	bus.Publish(events.RadioDisconnected{})
+	waitFor("0\n")
+}
+
+func TestNoSlices(t *testing.T) {
+	c, _ := startServer(t, shimtest.New(nil))
+
+	for _, send := range []string{"f", "F 14074000", "m", "M USB 2400", "v", "s", "i", "x"} {
+		if got := c.send(send, 1); got != "RPRT -11\n" {
+			t.Errorf("%q: reply %q, want %q", send, got, "RPRT -11\n")
+		}
+	}
+	// Commands that don't need a slice still work
+	if got := c.send("t", 1); got != "0\n" {
+		t.Errorf("t: reply %q, want %q", got, "0\n")
+	}
+}
+
+func TestQuit(t *testing.T) {
+	c, _ := startServer(t, newFakeRadio())
+
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.conn.Write([]byte("q\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.r.ReadByte(); err == nil {
+		t.Error("connection still open after q")
+	}
+}
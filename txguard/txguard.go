@@ -0,0 +1,191 @@
+// Package txguard unkeys the radio when it has been transmitting for too long,
+// or when something that could be holding PTT stops responding.
+package txguard
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+type Config struct {
+	Timeout  time.Duration `dialsdesc:"Stop transmitting after this long (0 for the 10 minute maximum)" dialsflag:"tx-timeout"`
+	Warning  time.Duration `dialsdesc:"Count down for this long before the TX timeout" dialsflag:"tx-timeout-warning"`
+	Watchdog time.Duration `dialsdesc:"Stop transmitting if the UI stops responding for this long (0 to disable)" dialsflag:"tx-watchdog"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Timeout:  3 * time.Minute,
+		Warning:  15 * time.Second,
+		Watchdog: 2 * time.Second,
+	}
+}
+
+// How often the timeout and watchdog are checked
+const checkInterval = 250 * time.Millisecond
+
+// maxKeyed is the longest the radio may transmit, whatever keyed it and
+// whatever the configured timeout, so that a stuck key (e.g. a MIDI note
+// that never gets its note off) can't transmit indefinitely
+const maxKeyed = 10 * time.Minute
+
+// Guard watches the transmit state and unkeys the radio when the timeout
+// expires or a heartbeat source goes quiet while transmitting
+type Guard struct {
+	cfg    *Config
+	shim   radioshim.Shim
+	bus    *events.Bus
+	events chan events.Event
+
+	mu    sync.Mutex
+	beats map[string]time.Time
+
+	// now is the clock, replaced in tests
+	now func() time.Time
+}
+
+// txState is what Run tracks about the current transmission
+type txState struct {
+	transmitting bool
+	voxEnabled   bool
+	txStart      time.Time
+	// Set once we've unkeyed, so we only do it once per transmission
+	tripped bool
+	// The last countdown published, in whole seconds
+	warned int
+}
+
+// NewGuard creates a guard that unkeys through shim
+func NewGuard(cfg *Config, shim radioshim.Shim, bus *events.Bus) *Guard {
+	return &Guard{
+		cfg:    cfg,
+		shim:   shim,
+		bus:    bus,
+		events: bus.Subscribe(10),
+		beats:  make(map[string]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Beat records that source is still running. Once a source has sent a
+// heartbeat, the watchdog expects one at least every cfg.Watchdog while
+// transmitting.
+func (g *Guard) Beat(source string) {
+	g.mu.Lock()
+	g.beats[source] = g.now()
+	g.mu.Unlock()
+}
+
+// stale returns a heartbeat source that has gone quiet, or ""
+func (g *Guard) stale(now time.Time) string {
+	if g.cfg.Watchdog <= 0 {
+		return ""
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for source, t := range g.beats {
+		if now.Sub(t) > g.cfg.Watchdog {
+			return source
+		}
+	}
+	return ""
+}
+
+// timeout returns how long the radio may transmit: the configured timeout,
+// but never more than maxKeyed
+func (g *Guard) timeout() time.Duration {
+	if g.cfg.Timeout <= 0 || g.cfg.Timeout > maxKeyed {
+		return maxKeyed
+	}
+	return g.cfg.Timeout
+}
+
+// Run watches the transmit state until ctx is done
+func (g *Guard) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	var st txState
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-g.events:
+			g.handleEvent(&st, event)
+		case <-ticker.C:
+			g.check(&st)
+		}
+	}
+}
+
+// handleEvent follows the transmit and VOX state
+func (g *Guard) handleEvent(st *txState, event events.Event) {
+	switch e := event.(type) {
+	case events.TransmitStateChanged:
+		if e.Transmitting == st.transmitting {
+			return
+		}
+		st.transmitting = e.Transmitting
+		st.txStart, st.tripped = g.now(), false
+		if !st.transmitting && st.warned > 0 {
+			st.warned = 0
+			g.bus.Publish(events.TXTimeoutWarning{})
+		}
+	case events.VOXStateChanged:
+		st.voxEnabled = e.Enabled
+	}
+}
+
+// check unkeys if a heartbeat source has gone quiet or the timeout has
+// expired, and counts down to the timeout
+func (g *Guard) check(st *txState) {
+	if !st.transmitting || st.tripped {
+		return
+	}
+	now := g.now()
+	if source := g.stale(now); source != "" {
+		g.unkey(st.voxEnabled, fmt.Sprintf("%s stopped responding while transmitting", source))
+		st.tripped = true
+		return
+	}
+	timeout := g.timeout()
+	remaining := timeout - now.Sub(st.txStart)
+	if remaining <= 0 {
+		g.unkey(st.voxEnabled, fmt.Sprintf("Transmitted for longer than the %v timeout", timeout))
+		st.tripped = true
+		return
+	}
+	if remaining <= g.cfg.Warning {
+		seconds := int((remaining + time.Second - 1) / time.Second)
+		if seconds != st.warned {
+			st.warned = seconds
+			g.bus.Publish(events.TXTimeoutWarning{Remaining: time.Duration(seconds) * time.Second})
+		}
+	}
+}
+
+// unkey drops PTT and TUNE, and turns off VOX so that it can't key the radio
+// again
+func (g *Guard) unkey(voxEnabled bool, reason string) {
+	log.Println("TX guard:", reason)
+	g.shim.SetPTT(false)
+	action := "PTT"
+	if _, tuning, _ := g.shim.GetTransmitState(); tuning {
+		g.shim.SetTune(false)
+		action = "TUNE"
+	}
+	if voxEnabled {
+		g.shim.SetVOX(false)
+		action = "VOX"
+	}
+	g.bus.Publish(events.TransmitInhibited{
+		Action: action,
+		Reason: reason,
+	})
+}
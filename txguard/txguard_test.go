@@ -0,0 +1,262 @@
+package txguard
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim/shimtest"
+)
+
+// fakeClock is a clock that only moves when told to
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+type testGuard struct {
+	*Guard
+	clock   *fakeClock
+	shim    *shimtest.Fake
+	watched chan events.Event
+	st      txState
+}
+
+func newTestGuard(cfg *Config) *testGuard {
+	bus := events.NewBus()
+	shim := shimtest.New(nil)
+	clock := &fakeClock{t: time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)}
+	g := NewGuard(cfg, shim, bus)
+	g.now = clock.now
+	return &testGuard{Guard: g, clock: clock, shim: shim, watched: bus.Subscribe(1000)}
+}
+
+func (tg *testGuard) key(transmitting bool) {
+	tg.handleEvent(&tg.st, events.TransmitStateChanged{Transmitting: transmitting})
+}
+
+// runFor advances the clock by d in checkInterval steps, checking at each
+func (tg *testGuard) runFor(d time.Duration) {
+	for end := tg.clock.t.Add(d); tg.clock.t.Before(end); {
+		tg.clock.advance(checkInterval)
+		tg.check(&tg.st)
+	}
+}
+
+// published returns the events published since the last call
+func (tg *testGuard) published() []events.Event {
+	var published []events.Event
+	for {
+		select {
+		case e := <-tg.watched:
+			published = append(published, e)
+		default:
+			return published
+		}
+	}
+}
+
+// inhibited returns the TransmitInhibited events published since the last
+// call
+func (tg *testGuard) inhibited() []events.TransmitInhibited {
+	var inhibited []events.TransmitInhibited
+	for _, e := range tg.published() {
+		if e, ok := e.(events.TransmitInhibited); ok {
+			inhibited = append(inhibited, e)
+		}
+	}
+	return inhibited
+}
+
+func TestTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		trip    time.Duration
+		reason  string
+	}{
+		{"configured timeout", 3 * time.Minute, 3 * time.Minute, "3m0s timeout"},
+		{"short timeout", 30 * time.Second, 30 * time.Second, "30s timeout"},
+		{"stuck key with the timeout off", 0, maxKeyed, "10m0s timeout"},
+		{"timeout above the maximum", time.Hour, maxKeyed, "10m0s timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newTestGuard(&Config{Timeout: tt.timeout, Watchdog: 2 * time.Second})
+			tg.key(true)
+
+			tg.runFor(tt.trip - checkInterval)
+			if calls := tg.shim.Calls(); len(calls) != 0 {
+				t.Fatalf("unkeyed early: %q", calls)
+			}
+			if inhibited := tg.inhibited(); len(inhibited) != 0 {
+				t.Fatalf("inhibited early: %+v", inhibited)
+			}
+
+			tg.runFor(checkInterval)
+			if calls, want := tg.shim.Calls(), []string{"SetPTT false"}; !reflect.DeepEqual(calls, want) {
+				t.Errorf("calls = %q, want %q", calls, want)
+			}
+			inhibited := tg.inhibited()
+			if len(inhibited) != 1 || inhibited[0].Action != "PTT" || !strings.Contains(inhibited[0].Reason, tt.reason) {
+				t.Errorf("inhibited = %+v, want one for PTT mentioning %q", inhibited, tt.reason)
+			}
+
+			// Only once per transmission
+			tg.shim.ResetCalls()
+			tg.runFor(time.Minute)
+			if calls := tg.shim.Calls(); len(calls) != 0 {
+				t.Errorf("unkeyed again: %q", calls)
+			}
+		})
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	tg := newTestGuard(&Config{Timeout: 3 * time.Minute, Watchdog: 2 * time.Second})
+	tg.Beat("UI")
+	tg.key(true)
+
+	// Keeping up the heartbeat keeps transmitting
+	for range 20 {
+		tg.runFor(time.Second)
+		tg.Beat("UI")
+	}
+	if calls := tg.shim.Calls(); len(calls) != 0 {
+		t.Fatalf("unkeyed with the heartbeat going: %q", calls)
+	}
+
+	tg.runFor(2 * time.Second)
+	if calls := tg.shim.Calls(); len(calls) != 0 {
+		t.Fatalf("unkeyed at the watchdog limit: %q", calls)
+	}
+	tg.runFor(checkInterval)
+	if calls, want := tg.shim.Calls(), []string{"SetPTT false"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+	inhibited := tg.inhibited()
+	if len(inhibited) != 1 || !strings.Contains(inhibited[0].Reason, "UI stopped responding") {
+		t.Errorf("inhibited = %+v, want one for the UI", inhibited)
+	}
+}
+
+func TestWatchdogDisabled(t *testing.T) {
+	tg := newTestGuard(&Config{Timeout: 3 * time.Minute})
+	tg.Beat("UI")
+	tg.key(true)
+	tg.runFor(time.Minute)
+	if calls := tg.shim.Calls(); len(calls) != 0 {
+		t.Errorf("unkeyed: %q", calls)
+	}
+}
+
+func TestWatchdogOnlyWhileTransmitting(t *testing.T) {
+	tg := newTestGuard(&Config{Timeout: 3 * time.Minute, Watchdog: 2 * time.Second})
+	tg.Beat("UI")
+	tg.runFor(time.Minute)
+	if calls := tg.shim.Calls(); len(calls) != 0 {
+		t.Errorf("unkeyed while receiving: %q", calls)
+	}
+}
+
+func TestUnkeyActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		tune   bool
+		vox    bool
+		calls  []string
+		action string
+	}{
+		{"PTT", false, false, []string{"SetPTT false"}, "PTT"},
+		{"TUNE", true, false, []string{"SetPTT false", "SetTune false"}, "TUNE"},
+		{"VOX", false, true, []string{"SetPTT false", "SetVOX false"}, "VOX"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newTestGuard(&Config{Timeout: time.Minute})
+			if tt.tune {
+				tg.shim.SetTune(true)
+				tg.shim.ResetCalls()
+			}
+			tg.handleEvent(&tg.st, events.VOXStateChanged{Enabled: tt.vox})
+			tg.key(true)
+			tg.runFor(time.Minute)
+
+			if calls := tg.shim.Calls(); !reflect.DeepEqual(calls, tt.calls) {
+				t.Errorf("calls = %q, want %q", calls, tt.calls)
+			}
+			inhibited := tg.inhibited()
+			if len(inhibited) != 1 || inhibited[0].Action != tt.action {
+				t.Errorf("inhibited = %+v, want one for %s", inhibited, tt.action)
+			}
+		})
+	}
+}
+
+func TestRekeyRestartsTimeout(t *testing.T) {
+	tg := newTestGuard(&Config{Timeout: time.Minute})
+	tg.key(true)
+	tg.runFor(50 * time.Second)
+	tg.key(false)
+	tg.runFor(time.Minute)
+	tg.key(true)
+	tg.runFor(50 * time.Second)
+	if calls := tg.shim.Calls(); len(calls) != 0 {
+		t.Fatalf("unkeyed before the timeout: %q", calls)
+	}
+	tg.runFor(10 * time.Second)
+	if calls, want := tg.shim.Calls(), []string{"SetPTT false"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestWarningCountdown(t *testing.T) {
+	tg := newTestGuard(&Config{Timeout: time.Minute, Warning: 3 * time.Second})
+	tg.key(true)
+
+	warnings := func() []time.Duration {
+		var remaining []time.Duration
+		for _, e := range tg.published() {
+			if e, ok := e.(events.TXTimeoutWarning); ok {
+				remaining = append(remaining, e.Remaining)
+			}
+		}
+		return remaining
+	}
+
+	tg.runFor(57*time.Second - checkInterval)
+	if got := warnings(); len(got) != 0 {
+		t.Fatalf("warned early: %v", got)
+	}
+	tg.runFor(3 * time.Second)
+	if got, want := warnings(), []time.Duration{3 * time.Second, 2 * time.Second, time.Second}; !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings = %v, want %v", got, want)
+	}
+
+	// Unkeying clears the countdown
+	tg.key(false)
+	if got, want := warnings(), []time.Duration{0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings after unkeying = %v, want %v", got, want)
+	}
+}
+
+func TestMaxKeyedWarning(t *testing.T) {
+	// The countdown also runs to the maximum when the timeout is off
+	tg := newTestGuard(&Config{Warning: 15 * time.Second})
+	tg.key(true)
+	tg.runFor(maxKeyed - 15*time.Second)
+	found := false
+	for _, e := range tg.published() {
+		if e, ok := e.(events.TXTimeoutWarning); ok && e.Remaining == 15*time.Second {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("no warning 15s before the maximum")
+	}
+}
@@ -0,0 +1,147 @@
+package ui
+
+import (
+	"fmt"
+
+	ebimage "github.com/ebitenui/ebitenui/image"
+	"github.com/ebitenui/ebitenui/widget"
+	"golang.org/x/image/colornames"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+)
+
+// meterRangeDB is the range shown by the level meters, from -meterRangeDB to 0 dBFS
+const meterRangeDB = 60
+
+type meterRow struct {
+	container *widget.Container
+	bar       *widget.ProgressBar
+	label     *widget.Text
+	clip      *widget.Text
+}
+
+// addAudioMeterRows adds the mic and RX level meters and the mic test toggle
+// to the Audio tab
+func (u *UI) addAudioMeterRows(ts *TransmitSettings, container *widget.TabBookTab) {
+	container.AddChild(widget.NewText(
+		widget.TextOpts.Text("Levels (peak, dBFS)", u.Font("Roboto-16"), colornames.White),
+	))
+
+	micRow := u.makeMeterRow("Mic")
+	ts.MicMeter = micRow.bar
+	ts.MicMeterLabel = micRow.label
+	ts.MicClip = micRow.clip
+	container.AddChild(micRow.container)
+
+	rxRow := u.makeMeterRow("RX")
+	ts.RXMeter = rxRow.bar
+	ts.RXMeterLabel = rxRow.label
+	ts.RXClip = rxRow.clip
+	container.AddChild(rxRow.container)
+
+	ts.MicTestToggle = u.MakeToggleButton("Roboto-16", "Test mic", func(args *widget.ButtonChangedEventArgs) {
+		enabled := args.State == widget.WidgetChecked
+		go u.AudioShim.SetMicTest(enabled)
+	})
+	container.AddChild(ts.MicTestToggle)
+	container.AddChild(widget.NewText(
+		widget.TextOpts.Text("Plays the mic through the RX device; RX audio is muted while testing", u.Font("Roboto-16"), colornames.Gray),
+	))
+
+	if u.AudioShim.MicTest() {
+		ts.MicTestToggle.SetState(widget.WidgetChecked)
+	}
+}
+
+func (u *UI) makeMeterRow(name string) meterRow {
+	row := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionHorizontal),
+			widget.RowLayoutOpts.Spacing(8),
+		)),
+	)
+
+	nameLabel := widget.NewText(
+		widget.TextOpts.Text(name, u.Font("Roboto-16"), colornames.White),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+			Position: widget.RowLayoutPositionCenter,
+		})),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.MinSize(60, 0)),
+	)
+
+	bar := widget.NewProgressBar(
+		widget.ProgressBarOpts.Values(0, meterRangeDB, 0),
+		widget.ProgressBarOpts.Images(
+			&widget.ProgressBarImage{
+				Idle:     ebimage.NewNineSliceColor(colornames.Dimgray),
+				Disabled: ebimage.NewNineSliceColor(colornames.Dimgray),
+			},
+			&widget.ProgressBarImage{
+				Idle:     ebimage.NewNineSliceColor(colornames.Limegreen),
+				Disabled: ebimage.NewNineSliceColor(colornames.Limegreen),
+			},
+		),
+		widget.ProgressBarOpts.TrackPadding(widget.NewInsetsSimple(2)),
+		widget.ProgressBarOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+				Stretch:  true,
+				Position: widget.RowLayoutPositionCenter,
+			}),
+			widget.WidgetOpts.MinSize(200, 16),
+		),
+	)
+
+	valueLabel := widget.NewText(
+		widget.TextOpts.Text(formatMeterDB(audioshim.Levels{PeakDB: -meterRangeDB}), u.Font("Roboto-16"), colornames.White),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+			Position: widget.RowLayoutPositionCenter,
+		})),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.MinSize(60, 0)),
+	)
+
+	clip := widget.NewText(
+		widget.TextOpts.Text("CLIP", u.Font("Roboto-16"), colornames.Dimgray),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+			Position: widget.RowLayoutPositionCenter,
+		})),
+	)
+
+	row.AddChild(nameLabel)
+	row.AddChild(bar)
+	row.AddChild(valueLabel)
+	row.AddChild(clip)
+
+	return meterRow{
+		container: row,
+		bar:       bar,
+		label:     valueLabel,
+		clip:      clip,
+	}
+}
+
+// updateAudioMeters refreshes the Audio tab meters from the audio engine
+func (ts *TransmitSettings) updateAudioMeters(u *UI) {
+	if ts.MicMeter == nil {
+		return
+	}
+	mic, rx := u.AudioShim.GetAudioLevels()
+	setMeter(ts.MicMeter, ts.MicMeterLabel, ts.MicClip, mic)
+	setMeter(ts.RXMeter, ts.RXMeterLabel, ts.RXClip, rx)
+}
+
+func setMeter(bar *widget.ProgressBar, label, clip *widget.Text, levels audioshim.Levels) {
+	bar.SetCurrent(roundInt(min(max(levels.PeakDB+meterRangeDB, 0), meterRangeDB)))
+	label.Label = formatMeterDB(levels)
+	if levels.Clipped {
+		clip.SetColor(colornames.Red)
+	} else {
+		clip.SetColor(colornames.Dimgray)
+	}
+}
+
+func formatMeterDB(levels audioshim.Levels) string {
+	if levels.PeakDB <= -meterRangeDB {
+		return "-inf"
+	}
+	return fmt.Sprintf("%.0f", levels.PeakDB)
+}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/colornames"
+
+	"github.com/kc2g-flex-tools/minstrel/bandplan"
+)
+
+// The band plan is a strip along the bottom of the ruler
+const bandPlanHeight = 6
+
+var (
+	// Ruler background while the active slice is outside our privileges
+	outOfPrivilegesBackground = color.NRGBA{0x80, 0x10, 0x10, 0xff}
+	// Shade over the parts of the band plan we can't transmit in
+	disallowedShade = color.NRGBA{0x00, 0x00, 0x00, 0xc0}
+)
+
+func segmentColor(mode string) color.Color {
+	if mode == bandplan.ModeBeacon {
+		return colornames.Orange
+	}
+	return spotColor(mode)
+}
+
+// outOfPrivileges reports whether the active slice's passband is outside the
+// license privileges for its mode
+func (wf *Waterfall) outOfPrivileges(u *UI) bool {
+	slice := u.Widgets.WaterfallPage.GetActiveSlice()
+	if u.BandPlan == nil || slice == nil {
+		return false
+	}
+	data := slice.Data
+	return u.BandPlan.CheckTransmit(data.Freq, data.FiltLow, data.FiltHigh, data.Mode) != nil
+}
+
+// drawBandPlan draws the band plan's mode segments along the bottom of the
+// ruler, shading the parts where the active slice's mode isn't allowed
+func (wf *Waterfall) drawBandPlan(u *UI) {
+	if u.BandPlan == nil {
+		return
+	}
+	low, high := wf.DispLowLatch, wf.DispHighLatch
+	y := float32(wf.Ruler.Height - bandPlanHeight)
+	fill := func(from, to float64, c color.Color) {
+		from, to = max(from, low), min(to, high)
+		if to <= from {
+			return
+		}
+		x0 := float32(float64(wf.Width) * (from - low) / (high - low))
+		x1 := float32(float64(wf.Width) * (to - low) / (high - low))
+		vector.DrawFilledRect(wf.Ruler.Img, x0, y, x1-x0, bandPlanHeight, c, false)
+	}
+
+	for _, seg := range u.BandPlan.Segments {
+		fill(seg.Low, seg.High, segmentColor(seg.Mode))
+	}
+
+	mode := bandplan.ModePhone
+	if slice := u.Widgets.WaterfallPage.GetActiveSlice(); slice != nil {
+		mode = bandplan.SliceMode(slice.Data.Mode)
+	}
+	from := low
+	for _, r := range u.BandPlan.AllowedRanges(mode) {
+		fill(from, r[0], disallowedShade)
+		from = max(from, r[1])
+	}
+	fill(from, high, disallowedShade)
+}
@@ -0,0 +1,361 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ebimage "github.com/ebitenui/ebitenui/image"
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"golang.org/x/image/colornames"
+
+	"github.com/kc2g-flex-tools/minstrel/bandplan"
+	"github.com/kc2g-flex-tools/minstrel/contest"
+	"github.com/kc2g-flex-tools/minstrel/logbook"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+// How often the contest window's rates and slice readout are refreshed
+const contestRefreshInterval = time.Second
+
+// ContestWindow is the contest entry window. It is kept after closing so the
+// contest can be picked up where it was left.
+type ContestWindow struct {
+	Window         *Window
+	TemplateButton *widget.Button
+	ExchangeInput  *widget.TextInput
+	CallInput      *widget.TextInput
+	RcvdInput      *widget.TextInput
+	SliceLabel     *widget.Text
+	SendLabel      *widget.Text
+	DupeLabel      *widget.Text
+	RateLabel      *widget.Text
+	StatusLabel    *widget.Text
+
+	session      *contest.Session
+	templates    []contest.Template
+	transmitting bool
+	lastRefresh  time.Time
+}
+
+// activeSlice finds the active slice in the radio's slices
+func activeSlice(slices radioshim.SliceMap) *radioshim.SliceData {
+	for _, slice := range slices {
+		if slice.Present && slice.Active {
+			return slice
+		}
+	}
+	return nil
+}
+
+// defaultCabrilloPath is where the Cabrillo log is written when no path is
+// given
+func defaultCabrilloPath(contestID string) string {
+	name := strings.ToLower(contestID)
+	if name == "" {
+		name = "contest"
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return name + ".log"
+	}
+	return filepath.Join(home, name+".log")
+}
+
+// ShowContest opens the contest entry window
+func (u *UI) ShowContest() {
+	if u.Logbook == nil {
+		log.Println("logbook is not available")
+		return
+	}
+	if u.Widgets.WaterfallPage.Contest == nil {
+		u.Widgets.WaterfallPage.Contest = u.MakeContestWindow()
+	}
+	cw := u.Widgets.WaterfallPage.Contest
+	cw.refresh(u)
+	u.ShowWindow(cw.Window)
+	u.Defer(func() {
+		u.eui.SetFocusedWidget(cw.CallInput)
+	})
+}
+
+func (u *UI) MakeContestWindow() *ContestWindow {
+	cw := &ContestWindow{}
+	templates, err := contest.LoadTemplates()
+	if err != nil {
+		log.Println("loading contest templates:", err)
+	}
+	cw.templates = templates
+	cw.session = contest.NewSession(u.Logbook, templates[0])
+
+	face := u.Font("Roboto-16")
+	bigFace := u.Font("Roboto-24")
+	textColor := color.NRGBA{0xee, 0xee, 0xee, 0xff}
+	makeInput := func(face *text.Face, extra ...widget.TextInputOpt) *widget.TextInput {
+		opts := []widget.TextInputOpt{
+			widget.TextInputOpts.Face(face),
+			widget.TextInputOpts.Color(&widget.TextInputColor{
+				Idle:          textColor,
+				Disabled:      textColor,
+				Caret:         textColor,
+				DisabledCaret: textColor,
+			}),
+			widget.TextInputOpts.Image(&widget.TextInputImage{
+				Idle:     ebimage.NewNineSliceColor(color.NRGBA{0x44, 0x44, 0x44, 0xff}),
+				Disabled: ebimage.NewNineSliceColor(color.NRGBA{0x44, 0x44, 0x44, 0xff}),
+			}),
+			widget.TextInputOpts.Padding(widget.NewInsetsSimple(4)),
+		}
+		return widget.NewTextInput(append(opts, extra...)...)
+	}
+	label := func(face *text.Face, s string) *widget.Text {
+		return widget.NewText(widget.TextOpts.Text(s, face, textColor))
+	}
+
+	// Contest selection and the exchange we send
+	setup := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(2),
+			widget.GridLayoutOpts.Spacing(8, 6),
+			widget.GridLayoutOpts.Stretch([]bool{false, true}, nil),
+		)),
+	)
+	cw.TemplateButton = u.makeDeviceSelectionButton("Roboto-16", cw.session.Template.Name, func(args *widget.ButtonClickedEventArgs) {
+		items := make([]any, len(cw.templates))
+		for i, t := range cw.templates {
+			items[i] = t.Name
+		}
+		window := u.MakeDropdownWindow(
+			cw.TemplateButton,
+			items,
+			cw.session.Template.Name,
+			func(item any) string { return item.(string) },
+			func(item any, ok bool) {
+				if !ok || item == nil {
+					return
+				}
+				for _, t := range cw.templates {
+					if t.Name == item.(string) {
+						cw.session = contest.NewSession(u.Logbook, t)
+						cw.TemplateButton.Text().Label = t.Name
+						cw.ExchangeInput.SetText(t.Exchange)
+						cw.refresh(u)
+					}
+				}
+			},
+		)
+		u.ShowDropdownWindow(window, cw.TemplateButton)
+	})
+	cw.ExchangeInput = makeInput(face,
+		widget.TextInputOpts.ChangedHandler(func(args *widget.TextInputChangedEventArgs) {
+			cw.session.Exchange = args.InputText
+			cw.refresh(u)
+		}),
+	)
+	cw.ExchangeInput.SetText(cw.session.Exchange)
+	setup.AddChild(
+		label(face, "Contest"), cw.TemplateButton,
+		label(face, "Sent exchange"), cw.ExchangeInput,
+	)
+
+	// QSO entry
+	entry := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(3),
+			widget.GridLayoutOpts.Spacing(8, 4),
+			widget.GridLayoutOpts.Stretch([]bool{true, true, false}, nil),
+		)),
+	)
+	submit := widget.TextInputOpts.SubmitHandler(func(*widget.TextInputChangedEventArgs) {
+		cw.logQSO(u)
+	})
+	cw.CallInput = makeInput(bigFace, submit,
+		widget.TextInputOpts.ChangedHandler(func(*widget.TextInputChangedEventArgs) {
+			cw.checkDupe(u)
+		}),
+		widget.TextInputOpts.WidgetOpts(widget.WidgetOpts.MinSize(200, 0)),
+	)
+	cw.RcvdInput = makeInput(bigFace, submit,
+		widget.TextInputOpts.WidgetOpts(widget.WidgetOpts.MinSize(200, 0)),
+	)
+	entry.AddChild(
+		label(face, "Call"), label(face, "Received exchange"), widget.NewContainer(),
+		cw.CallInput, cw.RcvdInput,
+		u.MakeButton("Roboto-24", "Log", func(_ *widget.ButtonClickedEventArgs) {
+			cw.logQSO(u)
+		}),
+	)
+
+	cw.SliceLabel = label(face, "")
+	cw.SendLabel = label(bigFace, "")
+	cw.DupeLabel = label(bigFace, "")
+	cw.RateLabel = label(face, "")
+	cw.StatusLabel = label(face, "")
+
+	buttons := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(2),
+			widget.GridLayoutOpts.Spacing(8, 8),
+			widget.GridLayoutOpts.Stretch([]bool{true, true}, nil),
+		)),
+	)
+	buttons.AddChild(
+		u.MakeButton("Roboto-16", "Export Cabrillo", func(_ *widget.ButtonClickedEventArgs) {
+			cw.exportCabrillo(u)
+		}),
+		u.MakeButton("Roboto-16", "Close", func(_ *widget.ButtonClickedEventArgs) {
+			cw.Window.widget.Close()
+		}),
+	)
+
+	contents := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(1),
+			widget.GridLayoutOpts.Spacing(0, 8),
+			widget.GridLayoutOpts.Stretch([]bool{true}, nil),
+		)),
+	)
+	contents.AddChild(setup, entry, cw.SliceLabel, cw.SendLabel, cw.DupeLabel, cw.RateLabel, cw.StatusLabel, buttons)
+	cw.Window = u.MakeWindow("Contest", "Roboto-24", contents)
+	return cw
+}
+
+// current returns the active slice's frequency, band and ADIF mode
+func (cw *ContestWindow) current(u *UI) (freq float64, band, mode, submode string, ok bool) {
+	slice := activeSlice(u.RadioShim.GetSlices())
+	if slice == nil {
+		return 0, "", "", "", false
+	}
+	mode, submode = logbook.ModeFromSlice(slice.Mode)
+	return slice.Freq, bandplan.BandName(slice.Freq), mode, submode, true
+}
+
+// checkDupe shows whether the call being entered has already been worked on
+// this band and mode
+func (cw *ContestWindow) checkDupe(u *UI) {
+	call := strings.TrimSpace(cw.CallInput.GetText())
+	_, band, mode, _, ok := cw.current(u)
+	if call == "" || !ok {
+		cw.DupeLabel.Label = ""
+		return
+	}
+	if q, dupe := cw.session.Dupe(call, band, mode); dupe {
+		cw.DupeLabel.Label = fmt.Sprintf("DUPE: %s worked at %s", q.Call, q.Time.UTC().Format("15:04"))
+		cw.DupeLabel.SetColor(colornames.Red)
+	} else {
+		cw.DupeLabel.Label = fmt.Sprintf("New: %s on %s %s", strings.ToUpper(call), band, mode)
+		cw.DupeLabel.SetColor(colornames.Lightgreen)
+	}
+}
+
+// SetTransmitting highlights the exchange to send while transmitting
+func (cw *ContestWindow) SetTransmitting(transmitting bool) {
+	cw.transmitting = transmitting
+	cw.lastRefresh = time.Time{}
+}
+
+// update refreshes the window periodically while it's open
+func (cw *ContestWindow) update(u *UI) {
+	if !u.eui.IsWindowOpen(cw.Window.widget) || time.Since(cw.lastRefresh) < contestRefreshInterval {
+		return
+	}
+	cw.refresh(u)
+}
+
+// refresh updates the slice readout, the exchange to send, the dupe check and
+// the rates
+func (cw *ContestWindow) refresh(u *UI) {
+	cw.lastRefresh = time.Now()
+	freq, band, mode, _, ok := cw.current(u)
+	if ok {
+		cw.SliceLabel.Label = fmt.Sprintf("%.3f kHz  %s  %s", freq*1000, band, mode)
+	} else {
+		cw.SliceLabel.Label = "No active slice"
+	}
+
+	qsos := cw.session.QSOs()
+	send := contest.Render(cw.session.Exchange, mode, cw.session.NextSerial())
+	if cw.transmitting {
+		cw.SendLabel.Label = "TX  " + send
+		cw.SendLabel.SetColor(colornames.Red)
+	} else {
+		cw.SendLabel.Label = "Send  " + send
+		cw.SendLabel.SetColor(colornames.White)
+	}
+	cw.checkDupe(u)
+
+	now := time.Now()
+	cw.RateLabel.Label = fmt.Sprintf("Rate: %.0f/h (10 min)  %.0f/h (60 min)  QSOs: %d",
+		contest.Rate(qsos, now, 10*time.Minute), contest.Rate(qsos, now, time.Hour), len(qsos))
+}
+
+// logQSO logs the entered QSO at the active slice's frequency and mode, and
+// clears the entry for the next one
+func (cw *ContestWindow) logQSO(u *UI) {
+	call := strings.ToUpper(strings.TrimSpace(cw.CallInput.GetText()))
+	rcvd := strings.ToUpper(strings.TrimSpace(cw.RcvdInput.GetText()))
+	if call == "" {
+		return
+	}
+	if rcvd == "" {
+		// Enter in the call field moves on to the exchange
+		u.eui.SetFocusedWidget(cw.RcvdInput)
+		return
+	}
+	freq, band, mode, submode, ok := cw.current(u)
+	if !ok {
+		cw.StatusLabel.Label = "No active slice"
+		return
+	}
+	_, dupe := cw.session.Dupe(call, band, mode)
+	q, err := cw.session.Add(logbook.QSO{
+		Time:     time.Now().UTC(),
+		Call:     call,
+		Freq:     freq,
+		Mode:     mode,
+		Submode:  submode,
+		ExchRcvd: rcvd,
+	})
+	if err != nil {
+		log.Println("saving contest QSO:", err)
+		cw.StatusLabel.Label = "Failed to save the log: " + err.Error()
+		return
+	}
+	cw.StatusLabel.Label = fmt.Sprintf("Logged %s #%d", q.Call, q.SerialSent)
+	if dupe {
+		cw.StatusLabel.Label += " (dupe)"
+	}
+	cw.CallInput.SetText("")
+	cw.RcvdInput.SetText("")
+	u.eui.SetFocusedWidget(cw.CallInput)
+	cw.refresh(u)
+}
+
+func (cw *ContestWindow) exportCabrillo(u *UI) {
+	if u.cfg.Callsign == "" {
+		cw.StatusLabel.Label = "Set your callsign with --callsign to export"
+		return
+	}
+	defaultPath := defaultCabrilloPath(cw.session.Template.ID)
+	u.ShowWindow(u.MakeEntryWindow("Export Cabrillo", "Roboto-24", "File (empty for "+defaultPath+")", "Roboto-16", func(path string, ok bool) {
+		if !ok {
+			return
+		}
+		path = strings.TrimSpace(path)
+		if path == "" {
+			path = defaultPath
+		}
+		n, err := cw.session.ExportCabrillo(path, u.cfg.Callsign)
+		if err != nil {
+			log.Println("exporting Cabrillo:", err)
+			cw.StatusLabel.Label = "Export failed: " + err.Error()
+			return
+		}
+		cw.StatusLabel.Label = fmt.Sprintf("Exported %d QSOs to %s", n, path)
+	}))
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/colornames"
+
+	"github.com/kc2g-flex-tools/minstrel/dxcluster"
+	"github.com/kc2g-flex-tools/minstrel/events"
+)
+
+// The ruler grows a row for DX spot labels above the frequencies once the DX
+// cluster has sent anything. The band plan is below the frequencies.
+const (
+	rulerHeight          = 18 + bandPlanHeight
+	rulerHeightWithSpots = 34 + bandPlanHeight
+	spotRowHeight        = 16.0
+)
+
+// spotHit is the clickable area of a spot label on the ruler
+type spotHit struct {
+	Rect image.Rectangle
+	Freq float64
+}
+
+func spotColor(mode string) color.Color {
+	switch mode {
+	case dxcluster.ModeCW:
+		return colornames.Lightskyblue
+	case dxcluster.ModePhone:
+		return colornames.Lightgreen
+	case dxcluster.ModeDigi:
+		return colornames.Plum
+	}
+	return colornames.Lightgray
+}
+
+// SetDXSpots replaces the DX cluster spots shown on the ruler
+func (wfw *WaterfallWidgets) SetDXSpots(spots []events.DXSpot) {
+	wfw.Waterfall.DXSpots = spots
+	wfw.RulerArea.GetWidget().MinHeight = rulerHeightWithSpots
+}
+
+// drawDXSpots draws a callsign label above each spot's frequency, skipping
+// labels that would overlap the one before
+func (wf *Waterfall) drawDXSpots(u *UI) {
+	wf.Ruler.SpotHits = wf.Ruler.SpotHits[:0]
+	face := u.Font("Roboto-Condensed-12")
+	lastRight := -1.0
+
+	for _, spot := range wf.DXSpots {
+		if spot.Freq < wf.DispLowLatch || spot.Freq > wf.DispHighLatch {
+			continue
+		}
+		freqX := float64(wf.Width) * (spot.Freq - wf.DispLowLatch) / (wf.DispHighLatch - wf.DispLowLatch)
+		labelWidth, _ := text.Measure(spot.Call, *face, 0)
+		labelX := min(max(freqX-labelWidth/2, 0), float64(wf.Ruler.Width)-labelWidth)
+		if labelX < lastRight+4 {
+			continue
+		}
+		lastRight = labelX + labelWidth
+
+		clr := spotColor(spot.Mode)
+		vector.StrokeLine(wf.Ruler.Img, float32(freqX), spotRowHeight-3, float32(freqX), spotRowHeight, 1, clr, false)
+		textOpts := &text.DrawOptions{}
+		textOpts.GeoM.Translate(labelX, 1)
+		textOpts.ColorScale.ScaleWithColor(clr)
+		text.Draw(wf.Ruler.Img, spot.Call, *face, textOpts)
+
+		wf.Ruler.SpotHits = append(wf.Ruler.SpotHits, spotHit{
+			Rect: image.Rect(int(labelX), 0, int(labelX+labelWidth), int(spotRowHeight)),
+			Freq: spot.Freq,
+		})
+	}
+}
+
+// clickDXSpot tunes the active slice to the spot under a click on the ruler,
+// reporting whether there was one
+func (wf *Waterfall) clickDXSpot(u *UI, wfw *WaterfallWidgets, pt image.Point) bool {
+	for _, hit := range wf.Ruler.SpotHits {
+		if pt.In(hit.Rect) {
+			if slice := wfw.GetActiveSlice(); slice != nil {
+				go u.RadioShim.TuneSlice(slice.Data, hit.Freq, false)
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Tuning steps taken by the coarse tuning keys
+const coarseTuneSteps = 10
+
+// Modes selected by the mode keys
+var modeActions = map[string]string{
+	"mode_usb":  "USB",
+	"mode_lsb":  "LSB",
+	"mode_cw":   "CW",
+	"mode_am":   "AM",
+	"mode_fm":   "FM",
+	"mode_digu": "DIGU",
+	"mode_digl": "DIGL",
+	"mode_rtty": "RTTY",
+}
+
+// Slices selected by the slice keys
+var sliceActions = map[string]string{
+	"slice_a": "A",
+	"slice_b": "B",
+}
+
+// handleKeys handles the keyboard shortcuts. It isn't called while a text
+// input has the keyboard.
+func (u *UI) handleKeys() {
+	km := u.keymap
+	wf := u.Widgets.WaterfallPage
+	if u.state == MainState && wf.handleFreqEntry(u) {
+		return
+	}
+	if km.justPressed("quit") {
+		u.exit = true
+	}
+	if km.justPressed("fullscreen") {
+		ebiten.SetFullscreen(!ebiten.IsFullscreen())
+	}
+	if km.justPressed("help") {
+		u.ToggleKeyboardHelp()
+	}
+	if u.state == MainState {
+		wf.handleKeys(u)
+	}
+}
+
+// handleKeys handles the keyboard shortcuts for the main screen
+func (wf *WaterfallWidgets) handleKeys(u *UI) {
+	km := u.keymap
+	slice := wf.GetActiveSlice()
+	tune := func(steps int) {
+		if slice != nil {
+			go u.RadioShim.TuneSliceStep(slice.Data, steps)
+		}
+	}
+	if km.justPressed("tune_down") {
+		tune(-1)
+	}
+	if km.justPressed("tune_up") {
+		tune(1)
+	}
+	if km.justPressed("tune_down_coarse") {
+		tune(-coarseTuneSteps)
+	}
+	if km.justPressed("tune_up_coarse") {
+		tune(coarseTuneSteps)
+	}
+
+	if km.justPressed("ptt") {
+		wf.pttHeld = true
+		u.RadioShim.SetPTT(true)
+	}
+	if km.justPressed("mox") {
+		u.RadioShim.SetPTT(wf.Controls.MOX.State() != widget.WidgetChecked)
+	}
+
+	if km.justPressed("zoom_in") {
+		u.RadioShim.ZoomIn()
+	}
+	if km.justPressed("zoom_out") {
+		u.RadioShim.ZoomOut()
+	}
+	if km.justPressed("find") {
+		u.RadioShim.FindActiveSlice()
+	}
+
+	for action, letter := range sliceActions {
+		if km.justPressed(action) {
+			if s, ok := wf.Slices[letter]; ok && s.Data.Present {
+				u.RadioShim.ActivateSlice(s.Data.Index)
+			}
+		}
+	}
+	for action, mode := range modeActions {
+		if km.justPressed(action) && slice != nil && slices.Contains(slice.Data.Modes, mode) {
+			u.RadioShim.SetSliceMode(slice.Data.Index, mode)
+		}
+	}
+}
+
+// releasePTT unkeys when the PTT key is let go of. It's called whether or not
+// the other shortcuts are, so that typing can't leave PTT held. PTT is only
+// released by the key that pressed it, so releasing the space bar after
+// toggling MOX with Shift+Space doesn't unkey.
+func (wf *WaterfallWidgets) releasePTT(u *UI) {
+	if wf.pttHeld && u.keymap.justReleased("ptt") {
+		wf.pttHeld = false
+		u.RadioShim.SetPTT(false)
+	}
+}
+
+// handleFreqEntry lets a frequency be typed straight in: digits (with a point
+// for MHz, or without for kHz), Enter to tune and Escape to cancel. It
+// reports whether an entry is in progress, in which case other shortcuts are
+// ignored.
+func (wf *WaterfallWidgets) handleFreqEntry(u *UI) bool {
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if (r >= '0' && r <= '9') || (r == '.' && !strings.Contains(wf.freqEntry, ".")) {
+			wf.freqEntry += string(r)
+		}
+	}
+	if wf.freqEntry == "" {
+		return false
+	}
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		wf.freqEntry = ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace):
+		wf.freqEntry = wf.freqEntry[:len(wf.freqEntry)-1]
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter), inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter):
+		if freq, ok := parseFreqEntry(wf.freqEntry); ok {
+			if slice := wf.GetActiveSlice(); slice != nil {
+				go u.RadioShim.TuneSlice(slice.Data, freq, false)
+			}
+		}
+		wf.freqEntry = ""
+	}
+	return true
+}
+
+// parseFreqEntry converts a typed frequency to MHz. With a decimal point it's
+// in MHz, and without one in kHz.
+func parseFreqEntry(entry string) (float64, bool) {
+	freq, err := strconv.ParseFloat(entry, 64)
+	if err != nil || freq <= 0 {
+		return 0, false
+	}
+	if !strings.Contains(entry, ".") {
+		freq /= 1000
+	}
+	return freq, true
+}
+
+// drawFreqEntry shows the frequency being typed in the top left of the
+// waterfall
+func (wf *Waterfall) drawFreqEntry(u *UI) {
+	entry := u.Widgets.WaterfallPage.freqEntry
+	if entry == "" {
+		return
+	}
+	unit := "kHz"
+	if strings.Contains(entry, ".") {
+		unit = "MHz"
+	}
+	label := fmt.Sprintf("%s_ %s", entry, unit)
+	face := u.Font("Roboto-24")
+	width, height := text.Measure(label, *face, 0)
+	const padding = 6
+	vector.DrawFilledRect(wf.Widget.Image, 8, 8, float32(width+2*padding), float32(height+2*padding), color.NRGBA{0x30, 0x30, 0x30, 0xd0}, false)
+	textOpts := &text.DrawOptions{}
+	textOpts.GeoM.Translate(8+padding, 8+padding)
+	textOpts.ColorScale.ScaleWithColor(color.NRGBA{0xee, 0xee, 0xee, 0xff})
+	text.Draw(wf.Widget.Image, label, *face, textOpts)
+}
+
+// ToggleKeyboardHelp shows or hides the list of keyboard shortcuts
+func (u *UI) ToggleKeyboardHelp() {
+	if u.helpWindow != nil && u.eui.IsWindowOpen(u.helpWindow.widget) {
+		u.helpWindow.widget.Close()
+		return
+	}
+
+	face := u.Font("Roboto-16")
+	textColor := color.NRGBA{0xee, 0xee, 0xee, 0xff}
+	keyColor := color.NRGBA{0xff, 0xd0, 0x60, 0xff}
+	grid := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(2),
+			widget.GridLayoutOpts.Spacing(16, 4),
+		)),
+	)
+	addRow := func(keys, description string) {
+		grid.AddChild(
+			widget.NewText(widget.TextOpts.Text(keys, face, keyColor)),
+			widget.NewText(widget.TextOpts.Text(description, face, textColor)),
+		)
+	}
+	for _, action := range keyActions {
+		if keys := u.keymap.keys(action.Name); keys != "" {
+			addRow(keys, action.Description)
+		}
+	}
+	addRow("0-9 .", "Type a frequency (kHz, or MHz with a point), then Enter")
+
+	u.helpWindow = u.MakeWindow("Keyboard", "Roboto-24", grid)
+	u.ShowWindow(u.helpWindow)
+}
@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// keyAction is something that can be bound to keys
+type keyAction struct {
+	Name        string
+	Description string
+	Keys        []string // default bindings
+}
+
+// keyActions are the keyboard actions, in the order the help lists them
+var keyActions = []keyAction{
+	{"tune_down", "Tune down one step", []string{"ArrowLeft"}},
+	{"tune_up", "Tune up one step", []string{"ArrowRight"}},
+	{"tune_down_coarse", "Tune down ten steps", []string{"PageDown"}},
+	{"tune_up_coarse", "Tune up ten steps", []string{"PageUp"}},
+	{"ptt", "Transmit while held", []string{"Space"}},
+	{"mox", "Toggle transmit", []string{"Shift+Space"}},
+	{"zoom_in", "Zoom in", []string{"Equal", "NumpadAdd"}},
+	{"zoom_out", "Zoom out", []string{"Minus", "NumpadSubtract"}},
+	{"find", "Center the waterfall on the active slice", []string{"Home"}},
+	{"slice_a", "Select slice A", []string{"Shift+A"}},
+	{"slice_b", "Select slice B", []string{"Shift+B"}},
+	{"mode_usb", "USB", []string{"U"}},
+	{"mode_lsb", "LSB", []string{"L"}},
+	{"mode_cw", "CW", []string{"C"}},
+	{"mode_am", "AM", []string{"A"}},
+	{"mode_fm", "FM", []string{"M"}},
+	{"mode_digu", "DIGU", []string{"D"}},
+	{"mode_digl", "DIGL", []string{"Shift+D"}},
+	{"mode_rtty", "RTTY", []string{"R"}},
+	{"fullscreen", "Toggle fullscreen", []string{"F"}},
+	{"help", "Show the keyboard help", []string{"F1", "Shift+Slash"}},
+	{"quit", "Quit", []string{"Q"}},
+}
+
+// keyBinding is a key and the modifiers that must be held with it
+type keyBinding struct {
+	Key   ebiten.Key
+	Shift bool
+	Ctrl  bool
+	Alt   bool
+}
+
+// parseKeyBinding parses a binding like "Shift+A", using Ebiten's key names
+func parseKeyBinding(s string) (keyBinding, error) {
+	var b keyBinding
+	parts := strings.Split(s, "+")
+	for _, mod := range parts[:len(parts)-1] {
+		switch strings.ToLower(mod) {
+		case "shift":
+			b.Shift = true
+		case "ctrl", "control":
+			b.Ctrl = true
+		case "alt":
+			b.Alt = true
+		default:
+			return b, fmt.Errorf("unknown modifier %q in %q", mod, s)
+		}
+	}
+	if err := b.Key.UnmarshalText([]byte(parts[len(parts)-1])); err != nil {
+		return b, fmt.Errorf("unknown key in %q", s)
+	}
+	return b, nil
+}
+
+func (b keyBinding) String() string {
+	var s strings.Builder
+	if b.Ctrl {
+		s.WriteString("Ctrl+")
+	}
+	if b.Alt {
+		s.WriteString("Alt+")
+	}
+	if b.Shift {
+		s.WriteString("Shift+")
+	}
+	s.WriteString(b.Key.String())
+	return s.String()
+}
+
+// modifiersHeld reports whether exactly the binding's modifiers are held
+func (b keyBinding) modifiersHeld() bool {
+	return b.Shift == ebiten.IsKeyPressed(ebiten.KeyShift) &&
+		b.Ctrl == ebiten.IsKeyPressed(ebiten.KeyControl) &&
+		b.Alt == ebiten.IsKeyPressed(ebiten.KeyAlt)
+}
+
+// Keymap maps action names to their key bindings
+type Keymap map[string][]keyBinding
+
+// LoadKeymap returns the default keymap, overridden by minstrel/keymap.json
+// in the XDG config directory if there is one. That file maps action names
+// to lists of keys, e.g. {"mode_cw": ["W"], "find": []}; an empty list
+// unbinds the action.
+func LoadKeymap() (Keymap, error) {
+	bindings := make(map[string][]string, len(keyActions))
+	for _, action := range keyActions {
+		bindings[action.Name] = action.Keys
+	}
+
+	var loadErr error
+	if path, err := xdg.SearchConfigFile("minstrel/keymap.json"); err == nil {
+		var user map[string][]string
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, &user)
+		}
+		if err != nil {
+			loadErr = fmt.Errorf("failed to load %s: %w", path, err)
+		}
+		for name, keys := range user {
+			if _, ok := bindings[name]; !ok {
+				loadErr = fmt.Errorf("unknown action %q in %s", name, path)
+				continue
+			}
+			bindings[name] = keys
+		}
+	}
+
+	km := make(Keymap, len(bindings))
+	for name, keys := range bindings {
+		for _, key := range keys {
+			b, err := parseKeyBinding(key)
+			if err != nil {
+				loadErr = err
+				continue
+			}
+			km[name] = append(km[name], b)
+		}
+	}
+	return km, loadErr
+}
+
+// justPressed reports whether a key bound to action was pressed this frame
+func (km Keymap) justPressed(action string) bool {
+	for _, b := range km[action] {
+		if inpututil.IsKeyJustPressed(b.Key) && b.modifiersHeld() {
+			return true
+		}
+	}
+	return false
+}
+
+// justReleased reports whether a key bound to action was released this
+// frame, whatever modifiers are held
+func (km Keymap) justReleased(action string) bool {
+	for _, b := range km[action] {
+		if inpututil.IsKeyJustReleased(b.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// keys describes the keys bound to action
+func (km Keymap) keys(action string) string {
+	names := make([]string, len(km[action]))
+	for i, b := range km[action] {
+		names[i] = b.String()
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,316 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	ebimage "github.com/ebitenui/ebitenui/image"
+	"github.com/ebitenui/ebitenui/widget"
+
+	"github.com/kc2g-flex-tools/minstrel/logbook"
+)
+
+const logTimeFormat = "2006-01-02 15:04"
+
+// defaultADIFPath is where ADIF files are exported to and imported from when
+// no path is given
+func defaultADIFPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "minstrel.adi"
+	}
+	return filepath.Join(home, "minstrel.adi")
+}
+
+// ShowLogbook opens the logging window with a new QSO prefilled from the
+// active slice, alongside a searchable list of logged QSOs
+func (u *UI) ShowLogbook() {
+	if u.Logbook == nil {
+		log.Println("logbook is not available")
+		return
+	}
+
+	var window *Window
+	face := u.Font("Roboto-16")
+	textColor := color.NRGBA{0xee, 0xee, 0xee, 0xff}
+
+	// The QSO being edited, or nil for a new one
+	var editing *logbook.QSO
+	// The submode only survives as long as the mode it belongs to
+	var submode, submodeFor string
+
+	makeInput := func(extra ...widget.TextInputOpt) *widget.TextInput {
+		opts := []widget.TextInputOpt{
+			widget.TextInputOpts.Face(face),
+			widget.TextInputOpts.Color(&widget.TextInputColor{
+				Idle:          textColor,
+				Disabled:      textColor,
+				Caret:         textColor,
+				DisabledCaret: textColor,
+			}),
+			widget.TextInputOpts.Image(&widget.TextInputImage{
+				Idle:     ebimage.NewNineSliceColor(color.NRGBA{0x44, 0x44, 0x44, 0xff}),
+				Disabled: ebimage.NewNineSliceColor(color.NRGBA{0x44, 0x44, 0x44, 0xff}),
+			}),
+			widget.TextInputOpts.Padding(widget.NewInsetsSimple(4)),
+			widget.TextInputOpts.WidgetOpts(widget.WidgetOpts.MinSize(240, 0)),
+		}
+		return widget.NewTextInput(append(opts, extra...)...)
+	}
+
+	form := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(2),
+			widget.GridLayoutOpts.Spacing(8, 6),
+			widget.GridLayoutOpts.Stretch([]bool{false, true}, nil),
+		)),
+	)
+	var save func()
+	addField := func(label string) *widget.TextInput {
+		input := makeInput(widget.TextInputOpts.SubmitHandler(func(*widget.TextInputChangedEventArgs) {
+			save()
+		}))
+		form.AddChild(widget.NewText(widget.TextOpts.Text(label, face, textColor)), input)
+		return input
+	}
+	callInput := addField("Call")
+	freqInput := addField("Freq (MHz)")
+	modeInput := addField("Mode")
+	timeInput := addField("Time (UTC)")
+	rstSentInput := addField("RST sent")
+	rstRcvdInput := addField("RST rcvd")
+	nameInput := addField("Name")
+	notesInput := addField("Notes")
+
+	status := widget.NewText(widget.TextOpts.Text("", face, textColor))
+
+	load := func(q logbook.QSO) {
+		callInput.SetText(q.Call)
+		freqInput.SetText("")
+		if q.Freq > 0 {
+			freqInput.SetText(strconv.FormatFloat(q.Freq, 'f', -1, 64))
+		}
+		modeInput.SetText(q.Mode)
+		timeInput.SetText(q.Time.UTC().Format(logTimeFormat))
+		rstSentInput.SetText(q.RSTSent)
+		rstRcvdInput.SetText(q.RSTRcvd)
+		nameInput.SetText(q.Name)
+		notesInput.SetText(q.Notes)
+		submode, submodeFor = q.Submode, q.Mode
+	}
+	newQSO := func() {
+		editing = nil
+		q := logbook.QSO{Time: time.Now().UTC()}
+		if slice := u.Widgets.WaterfallPage.GetActiveSlice(); slice != nil {
+			q.Freq = slice.Data.Freq
+			q.Mode, q.Submode = logbook.ModeFromSlice(slice.Data.Mode)
+			q.RSTSent = logbook.DefaultRST(q.Mode)
+			q.RSTRcvd = q.RSTSent
+		}
+		load(q)
+		status.Label = ""
+	}
+
+	qsoList := u.MakeList("Roboto-16", func(e any) string {
+		q := e.(logbook.QSO)
+		return fmt.Sprintf("%s  %-10s %5s %s", q.Time.UTC().Format(logTimeFormat), q.Call, q.Band, q.Mode)
+	})
+	qsoList.GetWidget().MinWidth = 360
+	qsoList.GetWidget().MinHeight = 240
+	var searchInput *widget.TextInput
+	refresh := func() {
+		qsos := u.Logbook.Search(searchInput.GetText())
+		entries := make([]any, len(qsos))
+		for i, q := range qsos {
+			entries[i] = q
+		}
+		qsoList.SetEntries(entries)
+	}
+	searchInput = makeInput(
+		widget.TextInputOpts.Placeholder("Search"),
+		widget.TextInputOpts.ChangedHandler(func(*widget.TextInputChangedEventArgs) {
+			refresh()
+		}),
+	)
+	qsoList.EntrySelectedEvent.AddHandler(func(args any) {
+		q, ok := args.(*widget.ListEntrySelectedEventArgs).Entry.(logbook.QSO)
+		if !ok {
+			return
+		}
+		editing = &q
+		load(q)
+		status.Label = fmt.Sprintf("Editing QSO with %s", q.Call)
+	})
+
+	save = func() {
+		// Start from the QSO being edited to keep fields the form doesn't show
+		var q logbook.QSO
+		if editing != nil {
+			q = *editing
+		}
+		q.Call = strings.ToUpper(strings.TrimSpace(callInput.GetText()))
+		q.Mode = strings.ToUpper(strings.TrimSpace(modeInput.GetText()))
+		q.Submode = ""
+		q.RSTSent = strings.TrimSpace(rstSentInput.GetText())
+		q.RSTRcvd = strings.TrimSpace(rstRcvdInput.GetText())
+		q.Name = strings.TrimSpace(nameInput.GetText())
+		q.Notes = strings.TrimSpace(notesInput.GetText())
+		if q.Call == "" {
+			status.Label = "A callsign is required"
+			return
+		}
+		// Imported QSOs may only have a band
+		if freqText := strings.TrimSpace(freqInput.GetText()); freqText != "" || q.Band == "" {
+			freq, err := strconv.ParseFloat(freqText, 64)
+			if err != nil || freq <= 0 {
+				status.Label = "Invalid frequency"
+				return
+			}
+			// The band follows the frequency
+			q.Freq, q.Band = freq, ""
+		}
+		var err error
+		q.Time, err = time.Parse(logTimeFormat, strings.TrimSpace(timeInput.GetText()))
+		if err != nil {
+			status.Label = "Time must look like " + logTimeFormat
+			return
+		}
+		if q.Mode == submodeFor {
+			q.Submode = submode
+		}
+
+		if editing != nil {
+			// Keep the seconds when the minute hasn't been changed
+			if q.Time.Equal(editing.Time.Truncate(time.Minute)) {
+				q.Time = editing.Time
+			}
+			err = u.Logbook.Update(q)
+		} else {
+			_, err = u.Logbook.Add(q)
+		}
+		if err != nil {
+			log.Println("saving QSO:", err)
+			status.Label = "Failed to save the log: " + err.Error()
+			return
+		}
+		newQSO()
+		status.Label = "Saved QSO with " + q.Call
+		refresh()
+	}
+
+	adifFile := func(title string, cb func(path string)) {
+		u.ShowWindow(u.MakeEntryWindow(title, "Roboto-24", "File (empty for "+defaultADIFPath()+")", "Roboto-16", func(path string, ok bool) {
+			if !ok {
+				return
+			}
+			path = strings.TrimSpace(path)
+			if path == "" {
+				path = defaultADIFPath()
+			}
+			cb(path)
+		}))
+	}
+	exportADIF := func(_ *widget.ButtonClickedEventArgs) {
+		adifFile("Export ADIF", func(path string) {
+			n, err := u.Logbook.ExportADIF(path)
+			if err != nil {
+				log.Println("exporting ADIF:", err)
+				status.Label = "Export failed: " + err.Error()
+				return
+			}
+			status.Label = fmt.Sprintf("Exported %d QSOs to %s", n, path)
+		})
+	}
+	importADIF := func(_ *widget.ButtonClickedEventArgs) {
+		adifFile("Import ADIF", func(path string) {
+			n, err := u.Logbook.ImportADIF(path)
+			if err != nil {
+				log.Println("importing ADIF:", err)
+				status.Label = "Import failed: " + err.Error()
+				return
+			}
+			status.Label = fmt.Sprintf("Imported %d new QSOs from %s", n, path)
+			refresh()
+		})
+	}
+
+	formButtons := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(4),
+			widget.GridLayoutOpts.Spacing(8, 8),
+			widget.GridLayoutOpts.Stretch([]bool{true, true, true, true}, nil),
+		)),
+	)
+	formButtons.AddChild(
+		u.MakeButton("Roboto-16", "Save", func(_ *widget.ButtonClickedEventArgs) {
+			save()
+		}),
+		u.MakeButton("Roboto-16", "New", func(_ *widget.ButtonClickedEventArgs) {
+			newQSO()
+		}),
+		u.MakeButton("Roboto-16", "Delete", func(_ *widget.ButtonClickedEventArgs) {
+			if editing == nil {
+				return
+			}
+			if err := u.Logbook.Delete(editing.ID); err != nil {
+				log.Println("deleting QSO:", err)
+				status.Label = "Failed to delete: " + err.Error()
+				return
+			}
+			call := editing.Call
+			newQSO()
+			status.Label = "Deleted QSO with " + call
+			refresh()
+		}),
+		u.MakeButton("Roboto-16", "Close", func(_ *widget.ButtonClickedEventArgs) {
+			window.widget.Close()
+		}),
+	)
+
+	listButtons := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(2),
+			widget.GridLayoutOpts.Spacing(8, 8),
+			widget.GridLayoutOpts.Stretch([]bool{true, true}, nil),
+		)),
+	)
+	listButtons.AddChild(
+		u.MakeButton("Roboto-16", "Export ADIF", exportADIF),
+		u.MakeButton("Roboto-16", "Import ADIF", importADIF),
+	)
+
+	column := func(children ...widget.PreferredSizeLocateableWidget) *widget.Container {
+		c := widget.NewContainer(
+			widget.ContainerOpts.Layout(widget.NewGridLayout(
+				widget.GridLayoutOpts.Columns(1),
+				widget.GridLayoutOpts.Spacing(0, 8),
+				widget.GridLayoutOpts.Stretch([]bool{true}, nil),
+			)),
+		)
+		c.AddChild(children...)
+		return c
+	}
+
+	contents := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(2),
+			widget.GridLayoutOpts.Spacing(16, 8),
+			widget.GridLayoutOpts.Stretch([]bool{true, true}, nil),
+		)),
+	)
+	contents.AddChild(
+		column(form, formButtons, status),
+		column(searchInput, qsoList, listButtons),
+	)
+
+	newQSO()
+	refresh()
+	window = u.MakeWindow("Log", "Roboto-24", contents)
+	u.ShowWindow(window)
+}
@@ -0,0 +1,155 @@
+package ui
+
+import (
+	"slices"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"golang.org/x/image/colornames"
+
+	"github.com/kc2g-flex-tools/minstrel/controls"
+	"github.com/kc2g-flex-tools/minstrel/midi"
+	"github.com/kc2g-flex-tools/minstrel/persistence"
+)
+
+// addMIDIMappingRows adds the mapping table and the learn controls to the MIDI
+// tab
+func (u *UI) addMIDIMappingRows(ts *TransmitSettings, container *widget.TabBookTab) {
+	container.AddChild(widget.NewText(
+		widget.TextOpts.Text("Controls", u.Font("Roboto-16"), colornames.White),
+	))
+
+	ts.MIDIMappingList = widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(3),
+			widget.GridLayoutOpts.Spacing(12, 4),
+			widget.GridLayoutOpts.Stretch([]bool{false, true, false}, nil),
+		)),
+	)
+	container.AddChild(ts.MIDIMappingList)
+
+	// Learn: pick an action, then press the key or move the control for it
+	ts.midiLearnAction = controls.Actions[0]
+	learnRow := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionHorizontal),
+			widget.RowLayoutOpts.Spacing(8),
+		)),
+	)
+	ts.MIDILearnActionButton = u.makeDeviceSelectionButton("Roboto-16", ts.midiLearnAction.Description, func(args *widget.ButtonClickedEventArgs) {
+		actions := make([]any, len(controls.Actions))
+		for i, action := range controls.Actions {
+			actions[i] = action
+		}
+		window := u.MakeDropdownWindow(
+			ts.MIDILearnActionButton,
+			actions,
+			ts.midiLearnAction,
+			func(item any) string {
+				return item.(controls.ActionInfo).Description
+			},
+			func(item any, ok bool) {
+				if ok && item != nil {
+					ts.midiLearnAction = item.(controls.ActionInfo)
+					ts.MIDILearnActionButton.Text().Label = ts.midiLearnAction.Description
+				}
+			},
+		)
+		u.ShowDropdownWindow(window, ts.MIDILearnActionButton)
+	})
+	ts.MIDILearnButton = u.MakeButton("Roboto-16", "Learn", func(args *widget.ButtonClickedEventArgs) {
+		u.toggleMIDILearn(ts)
+	})
+	learnRow.AddChild(ts.MIDILearnActionButton, ts.MIDILearnButton)
+	container.AddChild(learnRow)
+
+	ts.MIDILearnLabel = widget.NewText(
+		widget.TextOpts.Text("", u.Font("Roboto-16"), colornames.Gray),
+	)
+	container.AddChild(ts.MIDILearnLabel)
+
+	u.refreshMIDIMappings(ts)
+}
+
+// refreshMIDIMappings rebuilds the mapping table
+func (u *UI) refreshMIDIMappings(ts *TransmitSettings) {
+	ts.MIDIMappingList.RemoveChildren()
+	if u.MIDIShim == nil {
+		return
+	}
+	face := u.Font("Roboto-16")
+	for _, mapping := range u.MIDIShim.Mappings() {
+		description := mapping.Action
+		if action, ok := controls.LookupAction(mapping.Action); ok {
+			description = action.Description
+		}
+		ts.MIDIMappingList.AddChild(
+			widget.NewText(widget.TextOpts.Text(midi.DescribeMessage(mapping), face, colornames.Lightskyblue)),
+			widget.NewText(widget.TextOpts.Text(description, face, colornames.White)),
+			u.MakeButton("Roboto-16", "Remove", func(args *widget.ButtonClickedEventArgs) {
+				u.setMIDIMappings(ts, slices.DeleteFunc(u.MIDIShim.Mappings(), func(m midi.Mapping) bool {
+					return m == mapping
+				}))
+			}),
+		)
+	}
+}
+
+// toggleMIDILearn starts waiting for a MIDI message to map to the selected
+// action, or stops waiting
+func (u *UI) toggleMIDILearn(ts *TransmitSettings) {
+	if u.MIDIShim == nil {
+		ts.MIDILearnLabel.Label = "MIDI not available"
+		return
+	}
+	if ts.midiLearning {
+		u.MIDIShim.CancelLearn()
+		ts.midiLearning = false
+		ts.MIDILearnButton.Text().Label = "Learn"
+		ts.MIDILearnLabel.Label = ""
+		return
+	}
+	if connected, _, _ := u.MIDIShim.Status(); !connected {
+		ts.MIDILearnLabel.Label = "Connect a MIDI device first"
+		return
+	}
+
+	action := ts.midiLearnAction
+	ts.midiLearning = true
+	ts.MIDILearnButton.Text().Label = "Cancel"
+	if action.Relative {
+		ts.MIDILearnLabel.Label = "Turn the knob or wheel for " + action.Description
+	} else {
+		ts.MIDILearnLabel.Label = "Press the key or button for " + action.Description
+	}
+	u.MIDIShim.Learn(func(learned midi.Mapping) {
+		u.Defer(func() {
+			ts.midiLearning = false
+			ts.MIDILearnButton.Text().Label = "Learn"
+			if action.Relative && learned.Type != midi.MessageCC {
+				ts.MIDILearnLabel.Label = action.Description + " needs a knob or wheel, not a key"
+				return
+			}
+			learned.Action = action.Name
+			// A message can only do one thing, so replace anything it was
+			// mapped to before
+			mappings := slices.DeleteFunc(u.MIDIShim.Mappings(), func(m midi.Mapping) bool {
+				return m.Type == learned.Type && m.Number == learned.Number &&
+					(m.Channel == 0 || m.Channel == learned.Channel)
+			})
+			u.setMIDIMappings(ts, append(mappings, learned))
+			ts.MIDILearnLabel.Label = midi.DescribeMessage(learned) + " mapped to " + action.Description
+		})
+	})
+}
+
+// setMIDIMappings applies and saves a new mapping
+func (u *UI) setMIDIMappings(ts *TransmitSettings, mappings []midi.Mapping) {
+	u.MIDIShim.SetMappings(mappings)
+	u.refreshMIDIMappings(ts)
+
+	// Never save nil, which would bring the defaults back
+	saved := append([]midi.Mapping{}, mappings...)
+	u.saveRadioSettings("MIDI mappings", func(radio *persistence.RadioSettings) {
+		radio.MIDI.Mappings = saved
+	})
+}
@@ -56,22 +56,30 @@ func (u *UI) ConnectMIDIDevice(deviceName string, ts *TransmitSettings) {
 	}
 
 	// Save the MIDI device selection to persistent storage
+	u.saveRadioSettings("MIDI settings", func(radio *persistence.RadioSettings) {
+		radio.MIDI.Port = deviceName
+	})
+}
+
+// saveRadioSettings applies fn to the connected radio's saved settings in the
+// background
+func (u *UI) saveRadioSettings(what string, fn func(*persistence.RadioSettings)) {
+	if u.RadioShim == nil {
+		return
+	}
+	serial := u.RadioShim.RadioSerial()
+	if serial == "" {
+		log.Printf("Not saving %s: no radio connected", what)
+		return
+	}
 	go func() {
 		store, err := persistence.NewSettingsStore()
 		if err != nil {
 			log.Printf("Failed to create settings store: %v", err)
 			return
 		}
-
-		settings, err := store.Load()
-		if err != nil {
-			log.Printf("Failed to load settings: %v", err)
-			settings = &persistence.Settings{}
-		}
-
-		settings.MIDI.Port = deviceName
-		if err := store.Save(settings); err != nil {
-			log.Printf("Failed to save MIDI settings: %v", err)
+		if err := store.UpdateRadio(serial, fn); err != nil {
+			log.Printf("Failed to save %s: %v", what, err)
 		}
 	}()
 }
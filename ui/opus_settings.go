@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"golang.org/x/image/colornames"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+	"github.com/kc2g-flex-tools/minstrel/persistence"
+)
+
+// opusApplicationLabels are the names shown for each Opus application
+var opusApplicationLabels = map[string]string{
+	"audio":    "Audio",
+	"voip":     "VoIP",
+	"lowdelay": "Low delay",
+}
+
+// addOpusSettingsRows adds the TX Opus encoder controls to the Audio tab.
+// Changes apply immediately and are saved; the command line sets the values
+// used until the first change.
+func (u *UI) addOpusSettingsRows(ts *TransmitSettings, container *widget.TabBookTab) {
+	ts.opus = u.AudioShim.GetOpusSettings()
+
+	container.AddChild(widget.NewText(
+		widget.TextOpts.Text("TX encoder (Opus)", u.Font("Roboto-16"), colornames.White),
+	))
+
+	bitrateRow := u.makeSliderRow("Bitrate", audioshim.OpusMinBitrate/1000, audioshim.OpusMaxBitrate/1000, ts.opus.Bitrate/1000, formatKbps, func(value int) {
+		u.updateOpusSettings(ts, func(s *audioshim.OpusSettings) {
+			s.Bitrate = value * 1000
+		})
+	})
+	ts.OpusBitrateSlider = bitrateRow.slider
+	ts.OpusBitrateLabel = bitrateRow.label
+	container.AddChild(bitrateRow.container)
+
+	complexityRow := u.makeSliderRow("Complexity", 0, audioshim.OpusMaxComplexity, ts.opus.Complexity, func(value int) string {
+		return fmt.Sprintf("%d", value)
+	}, func(value int) {
+		u.updateOpusSettings(ts, func(s *audioshim.OpusSettings) {
+			s.Complexity = value
+		})
+	})
+	ts.OpusComplexitySlider = complexityRow.slider
+	ts.OpusComplexityLabel = complexityRow.label
+	container.AddChild(complexityRow.container)
+
+	appRow := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionHorizontal),
+			widget.RowLayoutOpts.Spacing(8),
+		)),
+	)
+	appLabel := widget.NewText(
+		widget.TextOpts.Text("Application", u.Font("Roboto-16"), colornames.White),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.RowLayoutData{
+			Position: widget.RowLayoutPositionCenter,
+		})),
+	)
+	ts.OpusAppButton = u.makeDeviceSelectionButton("Roboto-16", opusApplicationLabels[ts.opus.Application], func(args *widget.ButtonClickedEventArgs) {
+		window := u.MakeDropdownWindow(
+			ts.OpusAppButton,
+			stringSliceToAny(audioshim.OpusApplications),
+			ts.opus.Application,
+			func(item any) string {
+				return opusApplicationLabels[item.(string)]
+			},
+			func(item any, ok bool) {
+				if !ok || item == nil {
+					return
+				}
+				application := item.(string)
+				ts.OpusAppButton.Text().Label = opusApplicationLabels[application]
+				u.updateOpusSettings(ts, func(s *audioshim.OpusSettings) {
+					s.Application = application
+				})
+			},
+		)
+		u.ShowDropdownWindow(window, ts.OpusAppButton)
+	})
+	appRow.AddChild(appLabel)
+	appRow.AddChild(ts.OpusAppButton)
+	container.AddChild(appRow)
+
+	lossRow := u.makeToggleSliderRow("FEC", 0, 30, ts.opus.PacketLossPerc, func(enabled bool) {
+		u.updateOpusSettings(ts, func(s *audioshim.OpusSettings) {
+			s.FEC = enabled
+		})
+	}, formatPercent, func(value int) {
+		u.updateOpusSettings(ts, func(s *audioshim.OpusSettings) {
+			s.PacketLossPerc = value
+		})
+	})
+	ts.OpusFECToggle = lossRow.toggle
+	ts.OpusLossSlider = lossRow.slider
+	ts.OpusLossLabel = lossRow.label
+	container.AddChild(lossRow.container)
+	container.AddChild(widget.NewText(
+		widget.TextOpts.Text("FEC slider sets the expected packet loss", u.Font("Roboto-16"), colornames.Gray),
+	))
+
+	ts.OpusDTXToggle = u.MakeToggleButton("Roboto-16", "DTX", func(args *widget.ButtonChangedEventArgs) {
+		enabled := args.State == widget.WidgetChecked
+		u.updateOpusSettings(ts, func(s *audioshim.OpusSettings) {
+			s.DTX = enabled
+		})
+	})
+	container.AddChild(ts.OpusDTXToggle)
+
+	if ts.opus.FEC {
+		ts.OpusFECToggle.SetState(widget.WidgetChecked)
+	}
+	if ts.opus.DTX {
+		ts.OpusDTXToggle.SetState(widget.WidgetChecked)
+	}
+}
+
+// updateOpusSettings applies a change to the encoder settings and saves it
+func (u *UI) updateOpusSettings(ts *TransmitSettings, fn func(*audioshim.OpusSettings)) {
+	before := ts.opus
+	fn(&ts.opus)
+	if ts.opus != before {
+		u.AudioShim.SetOpusSettings(ts.opus)
+		u.saveOpusSettings(ts)
+	}
+}
+
+// saveOpusSettings persists the encoder settings after a short delay, so that
+// dragging a slider doesn't rewrite the settings file on every step
+func (u *UI) saveOpusSettings(ts *TransmitSettings) {
+	opus := ts.opus
+
+	if ts.opusSaveTimer != nil {
+		ts.opusSaveTimer.Stop()
+	}
+	ts.opusSaveTimer = time.AfterFunc(processingSaveDelay, func() {
+		store, err := persistence.NewSettingsStore()
+		if err != nil {
+			log.Printf("Failed to create settings store: %v", err)
+			return
+		}
+		err = store.Update(func(settings *persistence.Settings) {
+			settings.Opus = &opus
+		})
+		if err != nil {
+			log.Printf("Failed to save Opus settings: %v", err)
+		}
+	})
+}
+
+func formatKbps(value int) string {
+	return fmt.Sprintf("%d kbps", value)
+}
@@ -0,0 +1,220 @@
+package ui
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	ebimage "github.com/ebitenui/ebitenui/image"
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
+)
+
+// Radio spots are drawn in rows below the slice flags, higher priority spots
+// getting the first choice of position
+const (
+	radioSpotTop     = 30.0
+	radioSpotRows    = 4
+	radioSpotPadding = 3.0
+	// Spots fade out over the last part of their lifetime
+	radioSpotFadeFraction = 0.2
+)
+
+var (
+	radioSpotDefaultColor      = color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	radioSpotDefaultBackground = color.NRGBA{0x00, 0x00, 0x00, 0xa0}
+)
+
+// labelRows are the occupied horizontal ranges in each row of labels drawn on
+// the waterfall
+type labelRows [][][2]float64
+
+// place reserves room for a label from x0 to x1 in the first row it fits in,
+// returning the row or -1 if it doesn't fit anywhere
+func (rows labelRows) place(x0, x1 float64) int {
+	row := slices.IndexFunc(rows, func(ranges [][2]float64) bool {
+		return !slices.ContainsFunc(ranges, func(r [2]float64) bool {
+			return x0 < r[1]+2 && x1 > r[0]-2
+		})
+	})
+	if row >= 0 {
+		rows[row] = append(rows[row], [2]float64{x0, x1})
+	}
+	return row
+}
+
+// parseSpotColor parses a radio spot color (#AARRGGBB or #RRGGBB)
+func parseSpotColor(s string, fallback color.NRGBA) color.NRGBA {
+	s = strings.TrimPrefix(s, "#")
+	value, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return fallback
+	}
+	switch len(s) {
+	case 8:
+		return color.NRGBA{uint8(value >> 16), uint8(value >> 8), uint8(value), uint8(value >> 24)}
+	case 6:
+		return color.NRGBA{uint8(value >> 16), uint8(value >> 8), uint8(value), 0xff}
+	}
+	return fallback
+}
+
+// spotAlpha is the opacity of a spot given its age, or 0 once it has expired
+func spotAlpha(spot radioshim.SpotData, now time.Time) float32 {
+	if spot.Lifetime <= 0 || spot.Timestamp.IsZero() {
+		return 1
+	}
+	remaining := spot.Lifetime - now.Sub(spot.Timestamp)
+	if remaining <= 0 {
+		return 0
+	}
+	fade := time.Duration(float64(spot.Lifetime) * radioSpotFadeFraction)
+	if remaining >= fade {
+		return 1
+	}
+	return 0.3 + 0.7*float32(remaining)/float32(fade)
+}
+
+// drawRadioSpots draws each of the radio's spots as a labelled marker at its
+// frequency. Spots that don't fit in any row are left out.
+func (wf *Waterfall) drawRadioSpots(u *UI) {
+	wf.RadioSpotHits = wf.RadioSpotHits[:0]
+	if len(wf.RadioSpots) == 0 {
+		return
+	}
+	face := u.Font("Roboto-Condensed-14")
+	_, labelHeight := text.Measure("M", *face, 0)
+	boxHeight := labelHeight + 2*radioSpotPadding
+	now := time.Now()
+
+	ordered := slices.Clone(wf.RadioSpots)
+	slices.SortStableFunc(ordered, func(a, b radioshim.SpotData) int {
+		return a.Priority - b.Priority
+	})
+
+	rows := make(labelRows, radioSpotRows)
+	for _, spot := range ordered {
+		if spot.Freq < wf.DispLowLatch || spot.Freq > wf.DispHighLatch {
+			continue
+		}
+		alpha := spotAlpha(spot, now)
+		if alpha == 0 {
+			continue
+		}
+		freqX := float64(wf.Width) * (spot.Freq - wf.DispLowLatch) / (wf.DispHighLatch - wf.DispLowLatch)
+		labelWidth, _ := text.Measure(spot.Callsign, *face, 0)
+		boxWidth := labelWidth + 2*radioSpotPadding
+		boxX := min(max(freqX-boxWidth/2, 0), float64(wf.Width)-boxWidth)
+
+		row := rows.place(boxX, boxX+boxWidth)
+		if row < 0 {
+			continue
+		}
+		boxY := radioSpotTop + float64(row)*(boxHeight+2)
+
+		fg := parseSpotColor(spot.Color, radioSpotDefaultColor)
+		bg := parseSpotColor(spot.BackgroundColor, radioSpotDefaultBackground)
+		if bg.A == 0 {
+			bg = radioSpotDefaultBackground
+		}
+		fg.A = uint8(float32(fg.A) * alpha)
+		bg.A = uint8(float32(bg.A) * alpha)
+
+		vector.StrokeLine(wf.Widget.Image, float32(freqX), 0, float32(freqX), float32(boxY), 1, color.NRGBA{fg.R, fg.G, fg.B, fg.A / 2}, false)
+		vector.DrawFilledRect(wf.Widget.Image, float32(boxX), float32(boxY), float32(boxWidth), float32(boxHeight), bg, false)
+		textOpts := &text.DrawOptions{}
+		textOpts.GeoM.Translate(boxX+radioSpotPadding, boxY+radioSpotPadding)
+		textOpts.ColorScale.ScaleWithColor(fg)
+		text.Draw(wf.Widget.Image, spot.Callsign, *face, textOpts)
+
+		wf.RadioSpotHits = append(wf.RadioSpotHits, spotHit{
+			Rect: image.Rect(int(boxX), int(boxY), int(boxX+boxWidth), int(boxY+boxHeight)),
+			Freq: spot.Freq,
+		})
+	}
+}
+
+// clickRadioSpot tunes the active slice to the radio spot under a click on the
+// waterfall, reporting whether there was one
+func (wf *Waterfall) clickRadioSpot(u *UI, wfw *WaterfallWidgets, pt image.Point) bool {
+	for _, hit := range wf.RadioSpotHits {
+		if pt.In(hit.Rect) {
+			if slice := wfw.GetActiveSlice(); slice != nil {
+				go u.RadioShim.TuneSlice(slice.Data, hit.Freq, false)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// ShowAddSpotWindow asks for a callsign and comment and spots them at the
+// active slice's frequency and mode
+func (u *UI) ShowAddSpotWindow() {
+	slice := u.Widgets.WaterfallPage.GetActiveSlice()
+	if slice == nil {
+		return
+	}
+	freq, mode := slice.Data.Freq, slice.Data.Mode
+
+	var window *Window
+	mainFace := u.Font("Roboto-24")
+	textColor := color.NRGBA{0xee, 0xee, 0xee, 0xff}
+
+	contents := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(2),
+			widget.GridLayoutOpts.Spacing(8, 16),
+			widget.GridLayoutOpts.Stretch([]bool{false, true}, nil),
+		)),
+	)
+	var callInput, commentInput *widget.TextInput
+	submit := func() {
+		call := strings.ToUpper(strings.TrimSpace(callInput.GetText()))
+		if call != "" {
+			go u.RadioShim.AddSpot(freq, mode, call, strings.TrimSpace(commentInput.GetText()))
+		}
+		window.widget.Close()
+	}
+	makeInput := func(label string) *widget.TextInput {
+		input := widget.NewTextInput(
+			widget.TextInputOpts.Face(mainFace),
+			widget.TextInputOpts.Color(&widget.TextInputColor{
+				Idle:          textColor,
+				Disabled:      textColor,
+				Caret:         textColor,
+				DisabledCaret: textColor,
+			}),
+			widget.TextInputOpts.Image(&widget.TextInputImage{
+				Idle:     ebimage.NewNineSliceColor(color.NRGBA{0x44, 0x44, 0x44, 0xff}),
+				Disabled: ebimage.NewNineSliceColor(color.NRGBA{0x44, 0x44, 0x44, 0xff}),
+			}),
+			widget.TextInputOpts.SubmitHandler(func(*widget.TextInputChangedEventArgs) {
+				submit()
+			}),
+			widget.TextInputOpts.WidgetOpts(widget.WidgetOpts.MinSize(300, 0)),
+		)
+		contents.AddChild(widget.NewText(widget.TextOpts.Text(label, mainFace, textColor)), input)
+		return input
+	}
+	callInput = makeInput("Call")
+	commentInput = makeInput("Comment")
+
+	contents.AddChild(
+		u.MakeButton("Roboto-24", "Spot", func(_ *widget.ButtonClickedEventArgs) {
+			submit()
+		}),
+		u.MakeButton("Roboto-24", "Cancel", func(_ *widget.ButtonClickedEventArgs) {
+			window.widget.Close()
+		}),
+	)
+
+	window = u.MakeWindow("Spot at "+slice.Data.FreqFormatted, "Roboto-24", contents)
+	u.ShowWindow(window)
+}
@@ -36,10 +36,7 @@ func (u *UI) MakeRadiosPage() *RadiosPage {
 			event := e.(*widget.ListEntrySelectedEventArgs)
 			switch entry := event.Entry.(type) {
 			case *radioProps:
-				radio := *entry
-				u.eventBus.Publish(events.RadioSelected{
-					Address: radio["ip"] + ":" + radio["port"],
-				})
+				u.selectRadio(*entry)
 			case string:
 				switch entry {
 				case "Exit":
@@ -50,6 +47,7 @@ func (u *UI) MakeRadiosPage() *RadiosPage {
 					u.ShowWindow(
 						u.MakeNumericEntryWindow("Enter IP", "Roboto-24", "Enter an IP[:port] to connect to a radio", "Roboto", 24, func(ip string, ok bool) {
 							if ok {
+								u.uiState.connecting = true
 								u.eventBus.Publish(events.RadioSelected{
 									Address: ip,
 								})
@@ -67,6 +65,14 @@ func (u *UI) MakeRadiosPage() *RadiosPage {
 	return radios
 }
 
+// selectRadio connects to a discovered radio
+func (u *UI) selectRadio(radio radioProps) {
+	u.uiState.connecting = true
+	u.eventBus.Publish(events.RadioSelected{
+		Address: radio["ip"] + ":" + radio["port"],
+	})
+}
+
 // SetRadios updates the radio list in the UI.
 // NOTE: Must be called from deferred queue (via u.Defer) to ensure thread safety.
 func (u *UI) SetRadios(radios []radioProps) {
@@ -0,0 +1,196 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+
+	ebimage "github.com/ebitenui/ebitenui/image"
+	"github.com/ebitenui/ebitenui/widget"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/format"
+)
+
+// Scanner defaults
+const (
+	defaultScanDwell     = 2 * time.Second
+	defaultScanHang      = 3 * time.Second
+	defaultScanThreshold = 32
+)
+
+// ScannerWindow sets up and controls the scanner. It is kept after closing so
+// the settings are remembered.
+type ScannerWindow struct {
+	Window         *Window
+	LowInput       *widget.TextInput
+	HighInput      *widget.TextInput
+	StepInput      *widget.TextInput
+	FreqsInput     *widget.TextInput
+	DwellInput     *widget.TextInput
+	HangInput      *widget.TextInput
+	ThresholdInput *widget.TextInput
+	StatusLabel    *widget.Text
+}
+
+// ShowScanner opens the scanner window
+func (u *UI) ShowScanner() {
+	if u.Widgets.WaterfallPage.Scanner == nil {
+		u.Widgets.WaterfallPage.Scanner = u.MakeScannerWindow()
+	}
+	u.ShowWindow(u.Widgets.WaterfallPage.Scanner.Window)
+}
+
+func (u *UI) MakeScannerWindow() *ScannerWindow {
+	sw := &ScannerWindow{}
+	face := u.Font("Roboto-16")
+	textColor := color.NRGBA{0xee, 0xee, 0xee, 0xff}
+
+	form := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(2),
+			widget.GridLayoutOpts.Spacing(8, 6),
+			widget.GridLayoutOpts.Stretch([]bool{false, true}, nil),
+		)),
+	)
+	addField := func(label, value string) *widget.TextInput {
+		input := widget.NewTextInput(
+			widget.TextInputOpts.Face(face),
+			widget.TextInputOpts.Color(&widget.TextInputColor{
+				Idle:          textColor,
+				Disabled:      textColor,
+				Caret:         textColor,
+				DisabledCaret: textColor,
+			}),
+			widget.TextInputOpts.Image(&widget.TextInputImage{
+				Idle:     ebimage.NewNineSliceColor(color.NRGBA{0x44, 0x44, 0x44, 0xff}),
+				Disabled: ebimage.NewNineSliceColor(color.NRGBA{0x44, 0x44, 0x44, 0xff}),
+			}),
+			widget.TextInputOpts.Padding(widget.NewInsetsSimple(4)),
+			widget.TextInputOpts.WidgetOpts(widget.WidgetOpts.MinSize(240, 0)),
+		)
+		input.SetText(value)
+		form.AddChild(widget.NewText(widget.TextOpts.Text(label, face, textColor)), input)
+		return input
+	}
+
+	// Start with the visible part of the band and the active slice's step
+	wf := u.Widgets.WaterfallPage.Waterfall
+	step := 5.0
+	if slice := u.Widgets.WaterfallPage.GetActiveSlice(); slice != nil && slice.Data.TuneStep > 0 {
+		step = slice.Data.TuneStep * 1000
+	}
+	sw.LowInput = addField("Low (MHz)", fmt.Sprintf("%.3f", wf.DispLow))
+	sw.HighInput = addField("High (MHz)", fmt.Sprintf("%.3f", wf.DispHigh))
+	sw.StepInput = addField("Step (kHz)", strconv.FormatFloat(step, 'f', -1, 64))
+	sw.FreqsInput = addField("Or frequencies (MHz)", "")
+	sw.DwellInput = addField("Dwell (s)", strconv.FormatFloat(defaultScanDwell.Seconds(), 'f', -1, 64))
+	sw.HangInput = addField("Hang (s)", strconv.FormatFloat(defaultScanHang.Seconds(), 'f', -1, 64))
+	sw.ThresholdInput = addField("Threshold (0-255)", strconv.Itoa(defaultScanThreshold))
+
+	sw.StatusLabel = widget.NewText(widget.TextOpts.Text("Stopped", face, textColor))
+
+	buttons := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(3),
+			widget.GridLayoutOpts.Spacing(8, 8),
+			widget.GridLayoutOpts.Stretch([]bool{true, true, true}, nil),
+		)),
+	)
+	buttons.AddChild(
+		u.MakeButton("Roboto-16", "Start", func(_ *widget.ButtonClickedEventArgs) {
+			settings, err := sw.settings()
+			if err != nil {
+				sw.StatusLabel.Label = "Can't scan: " + err.Error()
+				return
+			}
+			u.eventBus.Publish(events.ScanRequested{Settings: settings})
+		}),
+		u.MakeButton("Roboto-16", "Stop", func(_ *widget.ButtonClickedEventArgs) {
+			u.eventBus.Publish(events.ScanStopRequested{})
+		}),
+		u.MakeButton("Roboto-16", "Close", func(_ *widget.ButtonClickedEventArgs) {
+			sw.Window.widget.Close()
+		}),
+	)
+
+	contents := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(1),
+			widget.GridLayoutOpts.Spacing(0, 8),
+			widget.GridLayoutOpts.Stretch([]bool{true}, nil),
+		)),
+	)
+	contents.AddChild(form, buttons, sw.StatusLabel)
+	sw.Window = u.MakeWindow("Scanner", "Roboto-24", contents)
+	return sw
+}
+
+// settings reads the scan settings from the form
+func (sw *ScannerWindow) settings() (events.ScanSettings, error) {
+	var s events.ScanSettings
+	number := func(input *widget.TextInput, name string) (float64, error) {
+		value, err := strconv.ParseFloat(strings.TrimSpace(input.GetText()), 64)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("invalid %s", name)
+		}
+		return value, nil
+	}
+
+	if freqs := strings.TrimSpace(sw.FreqsInput.GetText()); freqs != "" {
+		for _, field := range strings.FieldsFunc(freqs, func(r rune) bool { return r == ',' || r == ' ' }) {
+			freq, err := strconv.ParseFloat(field, 64)
+			if err != nil || freq <= 0 {
+				return s, fmt.Errorf("invalid frequency %q", field)
+			}
+			s.Freqs = append(s.Freqs, freq)
+		}
+	} else {
+		var err error
+		if s.Low, err = number(sw.LowInput, "low frequency"); err != nil {
+			return s, err
+		}
+		if s.High, err = number(sw.HighInput, "high frequency"); err != nil {
+			return s, err
+		}
+		step, err := number(sw.StepInput, "step")
+		if err != nil || step == 0 {
+			return s, fmt.Errorf("invalid step")
+		}
+		s.Step = step / 1000
+		if s.High <= s.Low {
+			return s, fmt.Errorf("high must be above low")
+		}
+	}
+
+	dwell, err := number(sw.DwellInput, "dwell time")
+	if err != nil {
+		return s, err
+	}
+	hang, err := number(sw.HangInput, "hang time")
+	if err != nil {
+		return s, err
+	}
+	threshold, err := number(sw.ThresholdInput, "threshold")
+	if err != nil {
+		return s, err
+	}
+	s.Dwell = time.Duration(dwell * float64(time.Second))
+	s.Hang = time.Duration(hang * float64(time.Second))
+	s.Threshold = int(threshold)
+	return s, nil
+}
+
+// SetScanState shows what the scanner is doing
+func (sw *ScannerWindow) SetScanState(e events.ScanStateChanged) {
+	switch {
+	case !e.Running:
+		sw.StatusLabel.Label = "Stopped"
+	case e.Holding:
+		sw.StatusLabel.Label = fmt.Sprintf("Holding on %s (level %d)", format.FrequencyMHz(e.Freq), e.Level)
+	default:
+		sw.StatusLabel.Label = fmt.Sprintf("Scanning %s", format.FrequencyMHz(e.Freq))
+	}
+}
@@ -3,10 +3,13 @@ package ui
 import (
 	"fmt"
 	"image"
+	"time"
 
 	ebimage "github.com/ebitenui/ebitenui/image"
 	"github.com/ebitenui/ebitenui/widget"
 	"github.com/kc2g-flex-tools/minstrel/audioshim"
+	"github.com/kc2g-flex-tools/minstrel/controls"
+	"github.com/kc2g-flex-tools/minstrel/persistence"
 	"golang.org/x/image/colornames"
 )
 
@@ -34,25 +37,93 @@ type TransmitSettings struct {
 	RFPowerSlider   *widget.Slider
 	RFPowerLabel    *widget.Text
 
+	// Client-side TX processing widgets (Phone tab)
+	ProcessingToggle *widget.Button
+	ProcessingPreset *widget.Button
+	ProcessingLevels *widget.Text
+
+	// DSP tab widgets
+	HighPassToggle  *widget.Button
+	HighPassSlider  *widget.Slider
+	HighPassLabel   *widget.Text
+	GateToggle      *widget.Button
+	GateSlider      *widget.Slider
+	GateLabel       *widget.Text
+	CompToggle      *widget.Button
+	CompSlider      *widget.Slider
+	CompLabel       *widget.Text
+	CompRatioSlider *widget.Slider
+	CompRatioLabel  *widget.Text
+	CompMakeup      *widget.Slider
+	CompMakeupLabel *widget.Text
+	EQToggle        *widget.Button
+	EQSliders       []*widget.Slider
+	EQLabels        []*widget.Text
+
 	// Audio tab widgets
 	RXDeviceButton *widget.Button
 	TXDeviceButton *widget.Button
+	MicMeter       *widget.ProgressBar
+	MicMeterLabel  *widget.Text
+	MicClip        *widget.Text
+	RXMeter        *widget.ProgressBar
+	RXMeterLabel   *widget.Text
+	RXClip         *widget.Text
+	MicTestToggle  *widget.Button
+
+	// Opus encoder widgets (Audio tab)
+	OpusBitrateSlider    *widget.Slider
+	OpusBitrateLabel     *widget.Text
+	OpusComplexitySlider *widget.Slider
+	OpusComplexityLabel  *widget.Text
+	OpusAppButton        *widget.Button
+	OpusFECToggle        *widget.Button
+	OpusLossSlider       *widget.Slider
+	OpusLossLabel        *widget.Text
+	OpusDTXToggle        *widget.Button
 
 	// MIDI tab widgets
 	MIDIDeviceButton *widget.Button
 	MIDIStatusLabel  *widget.Text
 	MIDIConnectBtn   *widget.Button
 
+	// MIDI mapping widgets
+	MIDIMappingList       *widget.Container
+	MIDILearnActionButton *widget.Button
+	MIDILearnButton       *widget.Button
+	MIDILearnLabel        *widget.Text
+
 	// Audio device state
 	selectedRXDevice string
 	selectedTXDevice string
 
 	// MIDI device state
 	selectedMIDIDevice string
+
+	// MIDI learn state
+	midiLearnAction controls.ActionInfo
+	midiLearning    bool
+
+	// TX processing state
+	processing       audioshim.TXProcessing
+	processingPreset string
+	userPresets      map[string]audioshim.TXProcessing
+	refreshingDSP    bool
+	saveTimer        *time.Timer
+	levelsUpdated    time.Time
+
+	// Opus encoder state
+	opus          audioshim.OpusSettings
+	opusSaveTimer *time.Timer
 }
 
 func (u *UI) MakeTransmitSettingsWindow() *TransmitSettings {
-	ts := &TransmitSettings{}
+	// Start from the devices in use, which may have been restored for this
+	// radio
+	ts := &TransmitSettings{
+		selectedRXDevice: u.AudioShim.GetSinkDevice(),
+		selectedTXDevice: u.AudioShim.GetSourceDevice(),
+	}
 
 	// Create tabs
 	phoneTab := widget.NewTabBookTab(
@@ -71,6 +142,14 @@ func (u *UI) MakeTransmitSettingsWindow() *TransmitSettings {
 		))),
 	)
 
+	dspTab := widget.NewTabBookTab(
+		widget.TabBookTabOpts.Label("DSP"),
+		widget.TabBookTabOpts.ContainerOpts(widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(12),
+		))),
+	)
+
 	midiTab := widget.NewTabBookTab(
 		widget.TabBookTabOpts.Label("MIDI"),
 		widget.TabBookTabOpts.ContainerOpts(widget.ContainerOpts.Layout(widget.NewRowLayout(
@@ -81,7 +160,7 @@ func (u *UI) MakeTransmitSettingsWindow() *TransmitSettings {
 
 	// Create TabBook with proper styling
 	tabBook := widget.NewTabBook(
-		widget.TabBookOpts.Tabs(phoneTab, audioTab, midiTab),
+		widget.TabBookOpts.Tabs(phoneTab, dspTab, audioTab, midiTab),
 		widget.TabBookOpts.TabButtonImage(u.makeTabButtonImage()),
 		widget.TabBookOpts.TabButtonText(u.Font("Roboto-16"), &widget.ButtonTextColor{
 			Idle:     colornames.White,
@@ -96,6 +175,9 @@ func (u *UI) MakeTransmitSettingsWindow() *TransmitSettings {
 	// Populate Phone tab
 	u.populatePhoneTab(ts, phoneTab)
 
+	// Populate DSP tab
+	u.populateDSPTab(ts, dspTab)
+
 	// Populate Audio tab
 	u.populateAudioTab(ts, audioTab)
 
@@ -127,6 +209,8 @@ func (u *UI) MakeTransmitSettingsWindow() *TransmitSettings {
 	)
 	buttonRow.AddChild(
 		u.MakeButton("Roboto-16", "Close", func(_ *widget.ButtonClickedEventArgs) {
+			// Don't leave RX audio muted by a forgotten mic test
+			ts.MicTestToggle.SetState(widget.WidgetUnchecked)
 			ts.Window.widget.Close()
 		}, widget.WidgetOpts.LayoutData(
 			widget.RowLayoutData{Stretch: true},
@@ -300,10 +384,17 @@ func (u *UI) populatePhoneTab(ts *TransmitSettings, container *widget.TabBookTab
 	ts.RFPowerSlider = rfPowerRow.slider
 	ts.RFPowerLabel = rfPowerRow.label
 	container.AddChild(rfPowerRow.container)
+
+	// Client-side TX processing
+	u.addTXProcessingRows(ts, container)
 }
 
 // populateAudioTab populates the Audio tab with device selection controls
 func (u *UI) populateAudioTab(ts *TransmitSettings, container *widget.TabBookTab) {
+	container.AddChild(widget.NewText(
+		widget.TextOpts.Text("Audio backend: "+u.AudioShim.GetAudioBackend(), u.Font("Roboto-16"), colornames.Gray),
+	))
+
 	// RX Device (Speakers/Headphones) Selection
 	rxRow := widget.NewContainer(
 		widget.ContainerOpts.Layout(widget.NewRowLayout(
@@ -362,6 +453,9 @@ func (u *UI) populateAudioTab(ts *TransmitSettings, container *widget.TabBookTab
 						ts.selectedRXDevice = device.ID
 						// Run audio device change in background to avoid blocking UI
 						go u.AudioShim.SetAudioSink(device.ID)
+						u.saveRadioSettings("audio settings", func(radio *persistence.RadioSettings) {
+							radio.Audio.RXDevice = device.ID
+						})
 					}
 				},
 			)
@@ -430,6 +524,9 @@ func (u *UI) populateAudioTab(ts *TransmitSettings, container *widget.TabBookTab
 						ts.selectedTXDevice = device.ID
 						// Run audio device change in background to avoid blocking UI
 						go u.AudioShim.SetAudioSource(device.ID)
+						u.saveRadioSettings("audio settings", func(radio *persistence.RadioSettings) {
+							radio.Audio.TXDevice = device.ID
+						})
 					}
 				},
 			)
@@ -440,6 +537,12 @@ func (u *UI) populateAudioTab(ts *TransmitSettings, container *widget.TabBookTab
 	txRow.AddChild(ts.TXDeviceButton)
 	container.AddChild(txRow)
 
+	// Level meters and mic test
+	u.addAudioMeterRows(ts, container)
+
+	// TX encoder settings
+	u.addOpusSettingsRows(ts, container)
+
 	// Initialize device button labels asynchronously
 	go func() {
 		// Initialize RX device button
@@ -910,6 +1013,9 @@ func (u *UI) populateMIDITab(ts *TransmitSettings, container *widget.TabBookTab)
 	buttonRow.AddChild(ts.MIDIConnectBtn)
 	container.AddChild(buttonRow)
 
+	// Mapping table and MIDI learn
+	u.addMIDIMappingRows(ts, container)
+
 	// Initialize status asynchronously
 	go u.UpdateMIDIStatus(ts)
 }
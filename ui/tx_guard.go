@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ebitenui/ebitenui/widget"
+)
+
+// ShowTransmitInhibited tells the user why PTT or VOX was refused or turned
+// off, and resets its button
+func (u *UI) ShowTransmitInhibited(action, reason string) {
+	controls := u.Widgets.WaterfallPage.Controls
+	switch action {
+	case "PTT":
+		controls.MOX.SetState(widget.WidgetUnchecked)
+	case "VOX":
+		controls.VOX.SetState(widget.WidgetUnchecked)
+	}
+	// Don't stack up windows when PTT is pressed repeatedly
+	if u.inhibitWindow != nil && u.eui.IsWindowOpen(u.inhibitWindow.widget) {
+		return
+	}
+	u.inhibitWindow = u.MakeMessageWindow("Transmit inhibited", "Roboto-24", reason, "Roboto-16")
+	u.ShowWindow(u.inhibitWindow)
+}
+
+// ShowTXTimeoutWarning counts down to the TX timeout on the MOX button
+func (u *UI) ShowTXTimeoutWarning(remaining time.Duration) {
+	label := "MOX"
+	if remaining > 0 {
+		label = fmt.Sprintf("MOX %d", int(remaining/time.Second))
+	}
+	u.Widgets.WaterfallPage.Controls.MOX.Text().Label = label
+}
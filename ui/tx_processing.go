@@ -0,0 +1,426 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"golang.org/x/image/colornames"
+
+	"github.com/kc2g-flex-tools/minstrel/audioshim"
+	"github.com/kc2g-flex-tools/minstrel/persistence"
+)
+
+// builtinPresetOrder is the order built-in presets appear in the dropdown
+var builtinPresetOrder = []string{"Flat", "Ragchew", "DX", "Contest"}
+
+const (
+	customPresetLabel   = "Custom"
+	processingSaveDelay = 500 * time.Millisecond
+	levelsUpdateRate    = 100 * time.Millisecond
+)
+
+// initialTXProcessing returns the current processing configuration, filling in
+// defaults from the Flat preset if nothing has been configured yet
+func (u *UI) initialTXProcessing() audioshim.TXProcessing {
+	cfg := u.AudioShim.GetTXProcessing()
+	if len(cfg.EQ.Bands) == 0 {
+		enabled := cfg.Enabled
+		cfg = audioshim.DefaultTXPresets()["Flat"]
+		cfg.Enabled = enabled
+	}
+	return cfg
+}
+
+// addTXProcessingRows adds the client DSP toggle, preset selector and level
+// readout to the Phone tab
+func (u *UI) addTXProcessingRows(ts *TransmitSettings, container *widget.TabBookTab) {
+	ts.processing = u.initialTXProcessing()
+	ts.processingPreset = customPresetLabel
+
+	row := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionHorizontal),
+			widget.RowLayoutOpts.Spacing(8),
+		)),
+	)
+	ts.ProcessingToggle = u.MakeToggleButton("Roboto-16", "Client DSP", func(args *widget.ButtonChangedEventArgs) {
+		enabled := args.State == widget.WidgetChecked
+		u.updateTXProcessing(ts, false, func(cfg *audioshim.TXProcessing) bool {
+			changed := cfg.Enabled != enabled
+			cfg.Enabled = enabled
+			return changed
+		})
+	})
+	ts.ProcessingToggle.GetWidget().LayoutData = widget.RowLayoutData{
+		Position: widget.RowLayoutPositionCenter,
+	}
+	ts.ProcessingPreset = u.makeDeviceSelectionButton("Roboto-16", ts.processingPreset, func(args *widget.ButtonClickedEventArgs) {
+		presets := ts.allPresets()
+		names := presetNames(presets)
+		var selected any
+		for _, name := range names {
+			if name == ts.processingPreset {
+				selected = name
+			}
+		}
+		window := u.MakeDropdownWindow(
+			ts.ProcessingPreset,
+			stringSliceToAny(names),
+			selected,
+			func(item any) string {
+				return item.(string)
+			},
+			func(item any, ok bool) {
+				if !ok || item == nil {
+					return
+				}
+				name := item.(string)
+				if preset, found := presets[name]; found {
+					u.applyTXPreset(ts, name, preset)
+				}
+			},
+		)
+		u.ShowDropdownWindow(window, ts.ProcessingPreset)
+	})
+	row.AddChild(ts.ProcessingToggle)
+	row.AddChild(ts.ProcessingPreset)
+	container.AddChild(row)
+
+	ts.ProcessingLevels = widget.NewText(
+		widget.TextOpts.Text(formatProcessingLevels(audioshim.Levels{PeakDB: -100, RMSDB: -100}, audioshim.Levels{PeakDB: -100, RMSDB: -100}), u.Font("Roboto-16"), colornames.Gray),
+	)
+	container.AddChild(ts.ProcessingLevels)
+
+	ts.refreshingDSP = true
+	if ts.processing.Enabled {
+		ts.ProcessingToggle.SetState(widget.WidgetChecked)
+	}
+	ts.refreshingDSP = false
+
+	// Load the saved preset name and user presets
+	go func() {
+		store, err := persistence.NewSettingsStore()
+		if err != nil {
+			log.Printf("Failed to create settings store: %v", err)
+			return
+		}
+		settings, err := store.Load()
+		if err != nil {
+			log.Printf("Failed to load settings: %v", err)
+			return
+		}
+		u.Defer(func() {
+			ts.userPresets = settings.TXProcessing.Presets
+			if settings.TXProcessing.Preset != "" && ts.processingPreset == customPresetLabel {
+				ts.processingPreset = settings.TXProcessing.Preset
+				ts.ProcessingPreset.Text().Label = ts.processingPreset
+			}
+		})
+	}()
+}
+
+// populateDSPTab populates the DSP tab with controls for each processing stage
+func (u *UI) populateDSPTab(ts *TransmitSettings, container *widget.TabBookTab) {
+	cfg := ts.processing
+
+	// High-pass filter
+	hpfRow := u.makeToggleSliderRow("HPF", 20, 400, roundInt(cfg.HighPass.CutoffHz), func(enabled bool) {
+		u.updateTXProcessing(ts, true, func(cfg *audioshim.TXProcessing) bool {
+			changed := cfg.HighPass.Enabled != enabled
+			cfg.HighPass.Enabled = enabled
+			return changed
+		})
+	}, formatHz, func(value int) {
+		u.updateTXProcessing(ts, true, func(cfg *audioshim.TXProcessing) bool {
+			return setRounded(&cfg.HighPass.CutoffHz, value)
+		})
+	})
+	ts.HighPassToggle = hpfRow.toggle
+	ts.HighPassSlider = hpfRow.slider
+	ts.HighPassLabel = hpfRow.label
+	container.AddChild(hpfRow.container)
+
+	// Noise gate
+	gateRow := u.makeToggleSliderRow("Gate", -80, -20, roundInt(cfg.Gate.ThresholdDB), func(enabled bool) {
+		u.updateTXProcessing(ts, true, func(cfg *audioshim.TXProcessing) bool {
+			changed := cfg.Gate.Enabled != enabled
+			cfg.Gate.Enabled = enabled
+			return changed
+		})
+	}, formatDB, func(value int) {
+		u.updateTXProcessing(ts, true, func(cfg *audioshim.TXProcessing) bool {
+			return setRounded(&cfg.Gate.ThresholdDB, value)
+		})
+	})
+	ts.GateToggle = gateRow.toggle
+	ts.GateSlider = gateRow.slider
+	ts.GateLabel = gateRow.label
+	container.AddChild(gateRow.container)
+
+	// Compressor
+	compRow := u.makeToggleSliderRow("Comp", -50, 0, roundInt(cfg.Compressor.ThresholdDB), func(enabled bool) {
+		u.updateTXProcessing(ts, true, func(cfg *audioshim.TXProcessing) bool {
+			changed := cfg.Compressor.Enabled != enabled
+			cfg.Compressor.Enabled = enabled
+			return changed
+		})
+	}, formatDB, func(value int) {
+		u.updateTXProcessing(ts, true, func(cfg *audioshim.TXProcessing) bool {
+			return setRounded(&cfg.Compressor.ThresholdDB, value)
+		})
+	})
+	ts.CompToggle = compRow.toggle
+	ts.CompSlider = compRow.slider
+	ts.CompLabel = compRow.label
+	container.AddChild(compRow.container)
+
+	ratioRow := u.makeSliderRow("Ratio", 1, 10, roundInt(cfg.Compressor.Ratio), formatRatio, func(value int) {
+		u.updateTXProcessing(ts, true, func(cfg *audioshim.TXProcessing) bool {
+			return setRounded(&cfg.Compressor.Ratio, value)
+		})
+	})
+	ts.CompRatioSlider = ratioRow.slider
+	ts.CompRatioLabel = ratioRow.label
+	container.AddChild(ratioRow.container)
+
+	makeupRow := u.makeSliderRow("Makeup", 0, 18, roundInt(cfg.Compressor.MakeupDB), formatGainDB, func(value int) {
+		u.updateTXProcessing(ts, true, func(cfg *audioshim.TXProcessing) bool {
+			return setRounded(&cfg.Compressor.MakeupDB, value)
+		})
+	})
+	ts.CompMakeup = makeupRow.slider
+	ts.CompMakeupLabel = makeupRow.label
+	container.AddChild(makeupRow.container)
+
+	// Mic EQ
+	ts.EQToggle = u.MakeToggleButton("Roboto-16", "EQ", func(args *widget.ButtonChangedEventArgs) {
+		enabled := args.State == widget.WidgetChecked
+		u.updateTXProcessing(ts, true, func(cfg *audioshim.TXProcessing) bool {
+			changed := cfg.EQ.Enabled != enabled
+			cfg.EQ.Enabled = enabled
+			return changed
+		})
+	})
+	container.AddChild(ts.EQToggle)
+
+	ts.EQSliders = nil
+	ts.EQLabels = nil
+	for i, band := range cfg.EQ.Bands {
+		bandRow := u.makeSliderRow(formatHz(roundInt(band.FreqHz)), -12, 12, roundInt(band.GainDB), formatGainDB, func(value int) {
+			u.updateTXProcessing(ts, true, func(cfg *audioshim.TXProcessing) bool {
+				if i >= len(cfg.EQ.Bands) {
+					return false
+				}
+				return setRounded(&cfg.EQ.Bands[i].GainDB, value)
+			})
+		})
+		ts.EQSliders = append(ts.EQSliders, bandRow.slider)
+		ts.EQLabels = append(ts.EQLabels, bandRow.label)
+		container.AddChild(bandRow.container)
+	}
+
+	// Save the current settings as a named preset
+	buttonRow := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionHorizontal),
+			widget.RowLayoutOpts.Spacing(8),
+		)),
+	)
+	buttonRow.AddChild(u.MakeButton("Roboto-16", "Save preset", func(args *widget.ButtonClickedEventArgs) {
+		window := u.MakeEntryWindow("Save Preset", "Roboto-24", "Preset name", "Roboto-16", func(name string, ok bool) {
+			if !ok || name == "" || name == customPresetLabel {
+				return
+			}
+			if ts.userPresets == nil {
+				ts.userPresets = make(map[string]audioshim.TXProcessing)
+			}
+			ts.userPresets[name] = ts.processing
+			ts.processingPreset = name
+			ts.ProcessingPreset.Text().Label = name
+			u.saveTXProcessing(ts)
+		})
+		u.ShowWindow(window)
+	}))
+	container.AddChild(buttonRow)
+
+	u.refreshDSPWidgets(ts)
+}
+
+// updateTXProcessing applies a change to the processing configuration. If fn
+// reports a change, the config is pushed to the audio engine and saved. Changes
+// to individual stages (custom is true) detach the config from its preset.
+func (u *UI) updateTXProcessing(ts *TransmitSettings, custom bool, fn func(*audioshim.TXProcessing) bool) {
+	if ts.refreshingDSP || !fn(&ts.processing) {
+		return
+	}
+	if custom {
+		ts.processingPreset = customPresetLabel
+		ts.ProcessingPreset.Text().Label = customPresetLabel
+	}
+	u.AudioShim.SetTXProcessing(ts.processing)
+	u.saveTXProcessing(ts)
+}
+
+// applyTXPreset switches the processing chain to a preset
+func (u *UI) applyTXPreset(ts *TransmitSettings, name string, preset audioshim.TXProcessing) {
+	preset.EQ.Bands = slices.Clone(preset.EQ.Bands)
+	ts.processing = preset
+	ts.processingPreset = name
+	ts.ProcessingPreset.Text().Label = name
+	u.AudioShim.SetTXProcessing(preset)
+	u.refreshDSPWidgets(ts)
+	u.saveTXProcessing(ts)
+}
+
+// refreshDSPWidgets updates all processing widgets to match the current config
+func (u *UI) refreshDSPWidgets(ts *TransmitSettings) {
+	ts.refreshingDSP = true
+	defer func() { ts.refreshingDSP = false }()
+
+	cfg := ts.processing
+	setToggle(ts.ProcessingToggle, cfg.Enabled)
+	setToggle(ts.HighPassToggle, cfg.HighPass.Enabled)
+	setToggle(ts.GateToggle, cfg.Gate.Enabled)
+	setToggle(ts.CompToggle, cfg.Compressor.Enabled)
+	setToggle(ts.EQToggle, cfg.EQ.Enabled)
+
+	// Slider changes fire their handlers on the next render; those see that the
+	// value matches the config and do nothing.
+	setSlider(ts.HighPassSlider, ts.HighPassLabel, roundInt(cfg.HighPass.CutoffHz), formatHz)
+	setSlider(ts.GateSlider, ts.GateLabel, roundInt(cfg.Gate.ThresholdDB), formatDB)
+	setSlider(ts.CompSlider, ts.CompLabel, roundInt(cfg.Compressor.ThresholdDB), formatDB)
+	setSlider(ts.CompRatioSlider, ts.CompRatioLabel, roundInt(cfg.Compressor.Ratio), formatRatio)
+	setSlider(ts.CompMakeup, ts.CompMakeupLabel, roundInt(cfg.Compressor.MakeupDB), formatGainDB)
+	for i, band := range cfg.EQ.Bands {
+		if i < len(ts.EQSliders) {
+			setSlider(ts.EQSliders[i], ts.EQLabels[i], roundInt(band.GainDB), formatGainDB)
+		}
+	}
+}
+
+// saveTXProcessing persists the processing config after a short delay, so that
+// dragging a slider doesn't rewrite the settings file on every step
+func (u *UI) saveTXProcessing(ts *TransmitSettings) {
+	cfg := ts.processing
+	cfg.EQ.Bands = slices.Clone(cfg.EQ.Bands)
+	preset := ts.processingPreset
+	if preset == customPresetLabel {
+		preset = ""
+	}
+	presets := maps.Clone(ts.userPresets)
+
+	if ts.saveTimer != nil {
+		ts.saveTimer.Stop()
+	}
+	ts.saveTimer = time.AfterFunc(processingSaveDelay, func() {
+		store, err := persistence.NewSettingsStore()
+		if err != nil {
+			log.Printf("Failed to create settings store: %v", err)
+			return
+		}
+		err = store.Update(func(settings *persistence.Settings) {
+			settings.TXProcessing.Current = &cfg
+			settings.TXProcessing.Preset = preset
+			settings.TXProcessing.Presets = presets
+		})
+		if err != nil {
+			log.Printf("Failed to save TX processing settings: %v", err)
+		}
+	})
+}
+
+// updateLevels refreshes the before/after level readout and the Audio tab meters
+func (ts *TransmitSettings) updateLevels(u *UI) {
+	if time.Since(ts.levelsUpdated) < levelsUpdateRate {
+		return
+	}
+	ts.levelsUpdated = time.Now()
+	if ts.ProcessingLevels != nil {
+		before, after := u.AudioShim.GetTXProcessingLevels()
+		ts.ProcessingLevels.Label = formatProcessingLevels(before, after)
+	}
+	ts.updateAudioMeters(u)
+}
+
+// allPresets returns the built-in presets merged with the user's saved presets
+func (ts *TransmitSettings) allPresets() map[string]audioshim.TXProcessing {
+	presets := audioshim.DefaultTXPresets()
+	maps.Copy(presets, ts.userPresets)
+	return presets
+}
+
+// presetNames lists built-in presets first, followed by user presets in
+// alphabetical order
+func presetNames(presets map[string]audioshim.TXProcessing) []string {
+	var names []string
+	for _, name := range builtinPresetOrder {
+		if _, ok := presets[name]; ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(presets)) {
+		if !slices.Contains(builtinPresetOrder, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func setToggle(button *widget.Button, checked bool) {
+	if button == nil {
+		return
+	}
+	state := widget.WidgetUnchecked
+	if checked {
+		state = widget.WidgetChecked
+	}
+	button.SetState(state)
+}
+
+func setSlider(slider *widget.Slider, label *widget.Text, value int, formatter func(int) string) {
+	if slider == nil {
+		return
+	}
+	slider.Current = value
+	label.Label = formatter(value)
+}
+
+// setRounded stores value in *field, reporting whether it differs from the
+// rounded value already there
+func setRounded(field *float64, value int) bool {
+	if roundInt(*field) == value {
+		return false
+	}
+	*field = float64(value)
+	return true
+}
+
+func roundInt(v float64) int {
+	return int(math.Round(v))
+}
+
+func formatHz(value int) string {
+	return fmt.Sprintf("%d Hz", value)
+}
+
+func formatDB(value int) string {
+	return fmt.Sprintf("%d dB", value)
+}
+
+func formatGainDB(value int) string {
+	return fmt.Sprintf("%+d dB", value)
+}
+
+func formatRatio(value int) string {
+	return fmt.Sprintf("%d:1", value)
+}
+
+func formatProcessingLevels(before, after audioshim.Levels) string {
+	return fmt.Sprintf("Mic in %4.0f / %4.0f dB   out %4.0f / %4.0f dB (peak / RMS)",
+		before.PeakDB, before.RMSDB, after.PeakDB, after.RMSDB)
+}
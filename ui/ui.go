@@ -11,11 +11,14 @@ import (
 	"github.com/ebitenui/ebitenui/image"
 	"github.com/ebitenui/ebitenui/widget"
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
 
+	"github.com/kc2g-flex-tools/minstrel/accel"
 	"github.com/kc2g-flex-tools/minstrel/audioshim"
+	"github.com/kc2g-flex-tools/minstrel/bandplan"
 	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/logbook"
+	"github.com/kc2g-flex-tools/minstrel/midi"
 	"github.com/kc2g-flex-tools/minstrel/radioshim"
 )
 
@@ -39,6 +42,9 @@ type Config struct {
 	Kiosk      bool    `dialsdesc:"Kiosk mode (I own the machine)" dialsflag:"kiosk"`
 	Fullscreen bool    `dialsdesc:"Start in fullscreen mode" dialsflag:"fullscreen"`
 	Scale      float64 `dialsdesc:"UI Scale factor" dialsflag:"scale"`
+	Callsign   string  `dialsdesc:"Your callsign, for contest logs" dialsflag:"callsign"`
+	// AutoConnect connects to the radio used last time when discovery finds it
+	AutoConnect bool `dialsdesc:"Connect to the last radio used once it is discovered" dialsflag:"auto-connect"`
 }
 
 func DefaultConfig() *Config {
@@ -65,7 +71,24 @@ type UI struct {
 		Connect(ctx context.Context, portName string, rs radioshim.Shim) error
 		Disconnect()
 		Status() (connected bool, port string, errorMsg string)
+		Mappings() []midi.Mapping
+		SetMappings([]midi.Mapping)
+		Learn(cb func(midi.Mapping))
+		CancelLearn()
 	}
+	// TXGuard gets a heartbeat every frame, so that it can stop transmitting
+	// if the UI hangs
+	TXGuard interface {
+		Beat(source string)
+	}
+	// VFOAccel speeds up mouse wheel tuning
+	VFOAccel       *accel.Accelerator
+	Logbook        *logbook.Logbook
+	BandPlan       *bandplan.Plan
+	inhibitWindow  *Window
+	helpWindow     *Window
+	keymap         Keymap
+	uiState        uiState
 	deferred       []func()
 	cfg            *Config
 	eventBus       *events.Bus
@@ -89,10 +112,16 @@ func NewUI(cfg *Config, eventBus *events.Bus) *UI {
 		Widgets: widgets{
 			Root: rootContainer,
 		},
+		VFOAccel:       accel.NewAccelerator(accel.Curve{}),
 		cfg:            cfg,
 		eventBus:       eventBus,
 		transmitParams: make(map[string]string),
 	}
+	keymap, err := LoadKeymap()
+	if err != nil {
+		log.Println("keymap:", err)
+	}
+	u.keymap = keymap
 	u.MakeLayout()
 	u.Widgets.Radios = u.MakeRadiosPage()
 	u.MakeWaterfallPage()
@@ -107,6 +136,7 @@ func NewUI(cfg *Config, eventBus *events.Bus) *UI {
 	if cfg.Touch {
 		ebiten.SetCursorMode(ebiten.CursorModeHidden)
 	}
+	u.loadUIState()
 	if cfg.Fullscreen {
 		ebiten.SetFullscreen(true)
 	}
@@ -119,21 +149,37 @@ func (u *UI) MakeLayout() {
 }
 
 func (u *UI) Update() error {
-	if u.exit || inpututil.IsKeyJustPressed(ebiten.KeyQ) {
-		return ebiten.Termination
+	// A held PTT key is let go of even if a text input has taken the
+	// keyboard since it was pressed
+	if wf := u.Widgets.WaterfallPage; wf != nil {
+		wf.releasePTT(u)
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
-		ebiten.SetFullscreen(!ebiten.IsFullscreen())
+	if !u.typing() {
+		u.handleKeys()
+	}
+	if u.exit {
+		return ebiten.Termination
 	}
 	if u.state == MainState {
 		u.Widgets.WaterfallPage.Update(u)
 	}
+	u.trackWindow()
 	u.runDeferred()
 	u.eui.Update()
 	u.update = true
+	if u.TXGuard != nil {
+		u.TXGuard.Beat("UI")
+	}
 	return nil
 }
 
+// typing reports whether a text input has the keyboard, in which case
+// keyboard shortcuts are ignored
+func (u *UI) typing() bool {
+	_, ok := u.eui.GetFocusedWidget().(*widget.TextInput)
+	return ok
+}
+
 func (u *UI) runDeferred() {
 	u.mu.Lock()
 	defer u.mu.Unlock()
@@ -195,12 +241,15 @@ func (u *UI) HandleEvents(eventChan chan events.Event) {
 		case events.RadiosDiscovered:
 			u.Defer(func() {
 				u.SetRadios(e.Radios)
+				u.autoConnect(e.Radios)
 			})
 
 		case events.RadioConnected:
 			u.Defer(func() {
+				u.uiState.connecting = true
 				u.ShowWaterfall()
 			})
+			u.radioConnected()
 
 		case events.TransmitStateChanged:
 			u.Defer(func() {
@@ -209,6 +258,9 @@ func (u *UI) HandleEvents(eventChan chan events.Event) {
 					state = widget.WidgetChecked
 				}
 				u.Widgets.WaterfallPage.Controls.MOX.SetState(state)
+				if cw := u.Widgets.WaterfallPage.Contest; cw != nil {
+					cw.SetTransmitting(e.Transmitting)
+				}
 			})
 
 		case events.VOXStateChanged:
@@ -220,6 +272,23 @@ func (u *UI) HandleEvents(eventChan chan events.Event) {
 				u.Widgets.WaterfallPage.Controls.VOX.SetState(state)
 			})
 
+		case events.TransmitInhibited:
+			u.Defer(func() {
+				u.ShowTransmitInhibited(e.Action, e.Reason)
+			})
+
+		case events.ScanStateChanged:
+			u.Defer(func() {
+				if sw := u.Widgets.WaterfallPage.Scanner; sw != nil {
+					sw.SetScanState(e)
+				}
+			})
+
+		case events.TXTimeoutWarning:
+			u.Defer(func() {
+				u.ShowTXTimeoutWarning(e.Remaining)
+			})
+
 		case events.TransmitParamsChanged:
 			u.Defer(func() {
 				// Cache the parameters (already under u.mu)
@@ -233,6 +302,9 @@ func (u *UI) HandleEvents(eventChan chan events.Event) {
 		case events.SlicesUpdated:
 			u.Defer(func() {
 				u.Widgets.WaterfallPage.UpdateSlices(e.Slices)
+				// The first update means the client is set up and can
+				// create audio streams
+				u.restoreAudio()
 			})
 
 		case events.WaterfallDisplayRangeChanged:
@@ -258,6 +330,21 @@ func (u *UI) HandleEvents(eventChan chan events.Event) {
 			u.Defer(func() {
 				u.Widgets.WaterfallPage.Waterfall.AddRow(e.Bins, e.BlackLevel)
 			})
+
+		case events.DXSpotsUpdated:
+			u.Defer(func() {
+				u.Widgets.WaterfallPage.SetDXSpots(e.Spots)
+			})
+
+		case events.RadioSpotsUpdated:
+			u.Defer(func() {
+				u.Widgets.WaterfallPage.Waterfall.RadioSpots = e.Spots
+			})
+
+		case events.WSJTXDecodesUpdated:
+			u.Defer(func() {
+				u.Widgets.WaterfallPage.Waterfall.Decodes = e.Decodes
+			})
 		}
 	}
 }
@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"log"
+	"time"
+
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/kc2g-flex-tools/minstrel/persistence"
+)
+
+// How long the window has to stay the same size before it's saved
+const windowSaveDelay = 2 * time.Second
+
+// uiState is the state of the UI that's kept between runs
+type uiState struct {
+	window      persistence.UISettings
+	saveTimer   *time.Timer
+	lastRadio   string
+	connecting  bool
+	enableAudio bool
+}
+
+// loadUIState restores the window from the last run
+func (u *UI) loadUIState() {
+	store, err := persistence.NewSettingsStore()
+	if err != nil {
+		log.Printf("Failed to create settings store: %v", err)
+		return
+	}
+	settings, err := store.Load()
+	if err != nil {
+		log.Printf("Failed to load settings: %v", err)
+		return
+	}
+	saved := settings.UI
+	u.uiState.window = saved
+	u.uiState.lastRadio = saved.LastRadio
+	if saved.WindowWidth > 0 && saved.WindowHeight > 0 {
+		ebiten.SetWindowSize(saved.WindowWidth, saved.WindowHeight)
+	}
+	if saved.Fullscreen {
+		ebiten.SetFullscreen(true)
+	}
+}
+
+// trackWindow saves the window size and fullscreen state when they change
+func (u *UI) trackWindow() {
+	window := u.uiState.window
+	window.Fullscreen = ebiten.IsFullscreen()
+	if !window.Fullscreen {
+		window.WindowWidth, window.WindowHeight = ebiten.WindowSize()
+	}
+	if window == u.uiState.window {
+		return
+	}
+	u.uiState.window = window
+
+	if u.uiState.saveTimer != nil {
+		u.uiState.saveTimer.Stop()
+	}
+	u.uiState.saveTimer = time.AfterFunc(windowSaveDelay, func() {
+		store, err := persistence.NewSettingsStore()
+		if err != nil {
+			log.Printf("Failed to create settings store: %v", err)
+			return
+		}
+		err = store.Update(func(settings *persistence.Settings) {
+			settings.UI.WindowWidth = window.WindowWidth
+			settings.UI.WindowHeight = window.WindowHeight
+			settings.UI.Fullscreen = window.Fullscreen
+		})
+		if err != nil {
+			log.Printf("Failed to save window settings: %v", err)
+		}
+	})
+}
+
+// autoConnect connects to the radio used last time once discovery finds it,
+// if enabled.
+// NOTE: Must be called from deferred queue (via u.Defer) to ensure thread safety.
+func (u *UI) autoConnect(radios []radioProps) {
+	if !u.cfg.AutoConnect || u.uiState.lastRadio == "" || u.uiState.connecting || u.state != DiscoveryState {
+		return
+	}
+	for _, radio := range radios {
+		if radio["serial"] == u.uiState.lastRadio {
+			log.Printf("Auto-connecting to %s (%s)", radio["nickname"], radio["serial"])
+			u.selectRadio(radio)
+			return
+		}
+	}
+}
+
+// radioConnected remembers the radio as the last one used, and turns remote
+// audio on if it was on last time
+func (u *UI) radioConnected() {
+	serial := u.RadioShim.RadioSerial()
+	go func() {
+		store, err := persistence.NewSettingsStore()
+		if err != nil {
+			log.Printf("Failed to create settings store: %v", err)
+			return
+		}
+		var audio persistence.AudioSettings
+		err = store.Update(func(settings *persistence.Settings) {
+			settings.UI.LastRadio = serial
+			audio = settings.Radio(serial).Audio
+		})
+		if err != nil {
+			log.Printf("Failed to save last radio: %v", err)
+		}
+		if audio.Enabled {
+			u.Defer(func() {
+				u.uiState.enableAudio = true
+			})
+		}
+	}()
+}
+
+// restoreAudio turns remote audio on once the radio is ready for it, if it
+// was on last time.
+// NOTE: Must be called from deferred queue (via u.Defer) to ensure thread safety.
+func (u *UI) restoreAudio() {
+	if !u.uiState.enableAudio {
+		return
+	}
+	u.uiState.enableAudio = false
+	u.Widgets.WaterfallPage.Controls.Audio.SetState(widget.WidgetChecked)
+}
@@ -13,10 +13,12 @@ import (
 	ebimage "github.com/ebitenui/ebitenui/image"
 	"github.com/ebitenui/ebitenui/widget"
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
 	"golang.org/x/image/colornames"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/radioshim"
 )
 
 // =============================================================================
@@ -31,6 +33,12 @@ type WaterfallWidgets struct {
 	Waterfall        *Waterfall
 	Controls         *WaterfallControls
 	TransmitSettings *TransmitSettings
+	Contest          *ContestWindow
+	Scanner          *ScannerWindow
+
+	// Keyboard state
+	freqEntry string
+	pttHeld   bool
 }
 
 const sliceFlagPadding = 4.0
@@ -44,10 +52,11 @@ type DragData struct {
 
 // WaterfallRuler handles the frequency ruler display
 type WaterfallRuler struct {
-	Widget *widget.Graphic
-	Img    *ebiten.Image
-	Width  int
-	Height int
+	Widget   *widget.Graphic
+	Img      *ebiten.Image
+	Width    int
+	Height   int
+	SpotHits []spotHit
 }
 
 // WaterfallSliceGraphics stores pre-rendered graphics for slice markers
@@ -64,6 +73,9 @@ type WaterfallSliceGraphics struct {
 type WaterfallInteraction struct {
 	Drag      DragData
 	ClickTime time.Time
+	// Wheel movement not yet tuned, from trackpads scrolling by fractions
+	// of a step
+	WheelAccumulator float64
 }
 
 // WaterfallData tracks incoming data frequency range
@@ -143,6 +155,17 @@ type Waterfall struct{
 
 	// Data frequency range (incoming VITA packets)
 	Data *WaterfallData
+
+	// DX cluster spots, ordered by frequency
+	DXSpots []events.DXSpot
+
+	// Spots kept by the radio, and where they were drawn
+	RadioSpots    []radioshim.SpotData
+	RadioSpotHits []spotHit
+
+	// WSJT-X decodes, and where they were drawn
+	Decodes    []events.WSJTXDecode
+	DecodeHits []decodeHit
 }
 
 // =============================================================================
@@ -196,9 +219,9 @@ func (u *UI) MakeWaterfallPage() {
 		widget.ContainerOpts.BackgroundImage(ebimage.NewNineSliceColor(colornames.Black)),
 		widget.ContainerOpts.WidgetOpts(
 			widget.WidgetOpts.LayoutData(widget.RowLayoutData{
-				MaxHeight: 18,
+				MaxHeight: rulerHeightWithSpots,
 			}),
-			widget.WidgetOpts.MinSize(0, 18),
+			widget.WidgetOpts.MinSize(0, rulerHeight),
 		),
 	)
 	wf.RulerArea.AddChild(wf.Waterfall.Ruler.Widget)
@@ -230,18 +253,11 @@ func (wfw *WaterfallWidgets) GetActiveSlice() *Slice {
 
 func (wf *WaterfallWidgets) Update(u *UI) {
 	wf.Waterfall.Update(u)
-	if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
-		if slice := wf.GetActiveSlice(); slice != nil {
-			go u.RadioShim.TuneSliceStep(slice.Data, -1)
-		}
-	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyRight) {
-		if slice := wf.GetActiveSlice(); slice != nil {
-			go u.RadioShim.TuneSliceStep(slice.Data, 1)
-		}
+	if wf.Contest != nil {
+		wf.Contest.update(u)
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-		u.RadioShim.SetPTT(wf.Controls.MOX.State() != widget.WidgetChecked)
+	if wf.TransmitSettings != nil {
+		wf.TransmitSettings.updateLevels(u)
 	}
 
 	wf.Container.RequestRelayout()
@@ -265,6 +281,10 @@ func (u *UI) MakeWaterfall(wfw *WaterfallWidgets) *Waterfall {
 			widget.WidgetOpts.MouseButtonPressedHandler(func(args *widget.WidgetMouseButtonPressedEventArgs) {
 				clickPt := image.Pt(args.OffsetX, args.OffsetY)
 
+				if wf.clickRadioSpot(u, wfw, clickPt) || wf.clickDecode(u, clickPt) {
+					return
+				}
+
 				// Build ordered slice list: active slice first, then inactive slices alphabetically
 				var orderedSlices []struct {
 					key   string
@@ -337,9 +357,13 @@ func (u *UI) MakeWaterfall(wfw *WaterfallWidgets) *Waterfall {
 			}),
 			widget.WidgetOpts.ScrolledHandler(func(args *widget.WidgetScrolledEventArgs) {
 				// Scroll up (positive Y) tunes up, scroll down (negative Y) tunes down
-				if args.Y != 0 {
+				wf.Interaction.WheelAccumulator += args.Y
+				steps := int(wf.Interaction.WheelAccumulator)
+				wf.Interaction.WheelAccumulator -= float64(steps)
+				if steps != 0 {
+					steps = u.VFOAccel.Apply(steps, time.Now())
 					if slice := wfw.GetActiveSlice(); slice != nil {
-						go u.RadioShim.TuneSliceStep(slice.Data, int(args.Y))
+						go u.RadioShim.TuneSliceStep(slice.Data, steps)
 					}
 				}
 			}),
@@ -355,6 +379,11 @@ func (u *UI) MakeWaterfall(wfw *WaterfallWidgets) *Waterfall {
 					StretchHorizontal: true,
 					StretchVertical:   true,
 				}),
+				widget.WidgetOpts.MouseButtonPressedHandler(func(args *widget.WidgetMouseButtonPressedEventArgs) {
+					if !wf.clickDXSpot(u, wfw, image.Pt(args.OffsetX, args.OffsetY)) {
+						u.ShowAddSpotWindow()
+					}
+				}),
 			),
 		),
 	}
@@ -548,8 +577,14 @@ func (wf *Waterfall) drawFrequencyRuler(u *UI) {
 		return
 	}
 
-	// Clear ruler background to black
-	wf.Ruler.Img.Fill(colornames.Black)
+	// Clear ruler background to black, or red if we can't transmit where the
+	// active slice is
+	if wf.outOfPrivileges(u) {
+		wf.Ruler.Img.Fill(outOfPrivilegesBackground)
+	} else {
+		wf.Ruler.Img.Fill(colornames.Black)
+	}
+	wf.drawBandPlan(u)
 
 	// Use a small readable font
 	rulerFace := u.Font("Roboto-Semibold-12")
@@ -562,8 +597,12 @@ func (wf *Waterfall) drawFrequencyRuler(u *UI) {
 	// Find the first label position (round down to nearest step)
 	firstFreq := math.Floor(wf.DispLowLatch/step) * step
 
-	// Draw labels
+	// Draw labels, below the DX spots if there are any
 	rulerY := 2.0
+	if wf.DXSpots != nil {
+		wf.drawDXSpots(u)
+		rulerY += spotRowHeight
+	}
 	labelColor := colornames.Lightgray
 
 	for freq := firstFreq; freq <= wf.DispHighLatch; freq += step {
@@ -791,6 +830,8 @@ func (wf *Waterfall) Update(u *UI) {
 	wf.Ruler.updateSize()
 	wf.handleFreqScroll()
 	wf.drawWaterfall()
+	wf.drawRadioSpots(u)
+	wf.drawDecodes(u)
 
 	// Draw frequency ruler
 	wf.drawFrequencyRuler(u)
@@ -804,4 +845,5 @@ func (wf *Waterfall) Update(u *UI) {
 			wf.drawSliceMarker(slice, letter, u)
 		}
 	}
+	wf.drawFreqEntry(u)
 }
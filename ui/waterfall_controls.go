@@ -4,6 +4,8 @@ import (
 	"os/exec"
 
 	"github.com/ebitenui/ebitenui/widget"
+
+	"github.com/kc2g-flex-tools/minstrel/persistence"
 )
 
 type WaterfallControls struct {
@@ -16,20 +18,23 @@ type WaterfallControls struct {
 	MOX       *widget.Button
 	VOX       *widget.Button
 	Settings  *widget.Button
+	Log       *widget.Button
+	Contest   *widget.Button
+	Scan      *widget.Button
 }
 
 func (u *UI) MakeWaterfallControls() *WaterfallControls {
 	wfc := &WaterfallControls{}
 	wfc.Container = widget.NewContainer(
 		widget.ContainerOpts.Layout(widget.NewGridLayout(
-			widget.GridLayoutOpts.Columns(4),
+			widget.GridLayoutOpts.Columns(6),
 			widget.GridLayoutOpts.Spacing(8, 8),
-			widget.GridLayoutOpts.Stretch([]bool{false, false, false, false}, []bool{false}),
+			widget.GridLayoutOpts.Stretch([]bool{false, false, false, false, false, false}, []bool{false}),
 		)),
 		widget.ContainerOpts.WidgetOpts(
 			widget.WidgetOpts.LayoutData(
 				widget.GridLayoutData{
-					MaxWidth:           192,
+					MaxWidth:           288,
 					HorizontalPosition: widget.GridLayoutPositionCenter,
 					VerticalPosition:   widget.GridLayoutPositionEnd,
 				},
@@ -46,7 +51,11 @@ func (u *UI) MakeWaterfallControls() *WaterfallControls {
 		})
 	}
 	wfc.Audio = u.MakeToggleButton("Icons-32", "\ue050", func(args *widget.ButtonChangedEventArgs) {
-		u.RadioShim.ToggleAudio(args.State == widget.WidgetChecked)
+		enabled := args.State == widget.WidgetChecked
+		u.RadioShim.ToggleAudio(enabled)
+		u.saveRadioSettings("audio settings", func(radio *persistence.RadioSettings) {
+			radio.Audio.Enabled = enabled
+		})
 	})
 	wfc.ZoomOut = u.MakeButton("Icons-32", "\ue900", func(args *widget.ButtonClickedEventArgs) {
 		u.RadioShim.ZoomOut()
@@ -72,15 +81,27 @@ func (u *UI) MakeWaterfallControls() *WaterfallControls {
 	wfc.Settings = u.MakeButton("Icons-32", "\ue8b8", func(args *widget.ButtonClickedEventArgs) {
 		u.ShowTransmitSettings()
 	})
+	wfc.Log = u.MakeButton("Roboto-16", "LOG", func(args *widget.ButtonClickedEventArgs) {
+		u.ShowLogbook()
+	})
+	wfc.Scan = u.MakeButton("Roboto-16", "SCAN", func(args *widget.ButtonClickedEventArgs) {
+		u.ShowScanner()
+	})
+	wfc.Contest = u.MakeButton("Icons-32", "\uea65", func(args *widget.ButtonClickedEventArgs) {
+		u.ShowContest()
+	})
 
 	wfc.Container.AddChild(
 		wfc.Exit,
 		wfc.Audio,
 		wfc.MOX,
 		wfc.VOX,
+		wfc.Log,
+		wfc.Scan,
 		wfc.ZoomOut,
 		wfc.ZoomIn,
 		wfc.Find,
+		wfc.Contest,
 		wfc.Settings,
 	)
 
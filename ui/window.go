@@ -68,6 +68,30 @@ func (u *UI) MakeNumericEntryWindow(title, titleFont, prompt, mainFont string, f
 	return u.MakeEntryWindow(title, titleFont, prompt, fmt.Sprintf("%s-%d", mainFont, fontSize), cb)
 }
 
+// MakeMessageWindow creates a window showing a message with an OK button
+func (u *UI) MakeMessageWindow(title, titleFont, message, mainFont string) *Window {
+	var window *Window
+	contents := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(16),
+		)),
+	)
+	contents.AddChild(
+		widget.NewText(
+			widget.TextOpts.Text(message, u.Font(mainFont), color.NRGBA{0xee, 0xee, 0xee, 0xff}),
+			widget.TextOpts.MaxWidth(600),
+		),
+		u.MakeButton(mainFont, "OK", func(_ *widget.ButtonClickedEventArgs) {
+			window.widget.Close()
+		}, widget.WidgetOpts.LayoutData(
+			widget.RowLayoutData{Stretch: true},
+		)),
+	)
+	window = u.MakeWindow(title, titleFont, contents)
+	return window
+}
+
 func (u *UI) MakeEntryWindow(title, titleFont, prompt, mainFont string, cb func(string, bool)) *Window {
 	var window *Window
 	mainFace := u.Font(mainFont)
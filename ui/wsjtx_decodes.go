@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+)
+
+// WSJT-X decodes are drawn in rows up from the bottom of the waterfall
+const (
+	decodeRows    = 3
+	decodePadding = 2.0
+)
+
+var (
+	decodeColor          = color.NRGBA{0xee, 0xee, 0xee, 0xff}
+	decodeBackground     = color.NRGBA{0x30, 0x30, 0x30, 0xb0}
+	decodeCQBackground   = color.NRGBA{0x20, 0x80, 0x20, 0xd0}
+	decodeToMeBackground = color.NRGBA{0xb0, 0x20, 0x20, 0xd0}
+)
+
+// decodeHit is the clickable area of a decode label
+type decodeHit struct {
+	Rect   image.Rectangle
+	Decode events.WSJTXDecode
+}
+
+// decodeFreq is where a decode's signal is: its audio offset from the digital
+// slice nearest to WSJT-X's dial frequency, or from the dial frequency if
+// there is no digital slice
+func decodeFreq(d events.WSJTXDecode, slices map[string]*Slice) float64 {
+	freq := d.DialFreq + float64(d.DF)/1e6
+	best := math.Inf(1)
+	for _, slice := range slices {
+		if !slice.Data.Present {
+			continue
+		}
+		offset := float64(d.DF) / 1e6
+		switch slice.Data.Mode {
+		case "DIGU":
+		case "DIGL":
+			offset = -offset
+		default:
+			continue
+		}
+		if dist := math.Abs(slice.Data.Freq - d.DialFreq); dist < best {
+			best = dist
+			freq = slice.Data.Freq + offset
+		}
+	}
+	return freq
+}
+
+// decodePriority orders decodes for placement: stations calling us, then CQs,
+// then the rest
+func decodePriority(d events.WSJTXDecode) int {
+	switch {
+	case d.ToMe:
+		return 0
+	case d.CQ:
+		return 1
+	}
+	return 2
+}
+
+// drawDecodes labels the stations WSJT-X has decoded at their signals,
+// highlighting CQs and stations calling us
+func (wf *Waterfall) drawDecodes(u *UI) {
+	wf.DecodeHits = wf.DecodeHits[:0]
+	if len(wf.Decodes) == 0 {
+		return
+	}
+	face := u.Font("Roboto-Condensed-12")
+	_, labelHeight := text.Measure("M", *face, 0)
+	boxHeight := labelHeight + 2*decodePadding
+
+	ordered := slices.Clone(wf.Decodes)
+	slices.SortStableFunc(ordered, func(a, b events.WSJTXDecode) int {
+		return cmp.Or(cmp.Compare(decodePriority(a), decodePriority(b)), cmp.Compare(b.SNR, a.SNR))
+	})
+
+	rows := make(labelRows, decodeRows)
+	for _, d := range ordered {
+		if d.Call == "" {
+			continue
+		}
+		freq := decodeFreq(d, u.Widgets.WaterfallPage.Slices)
+		if freq < wf.DispLowLatch || freq > wf.DispHighLatch {
+			continue
+		}
+		freqX := float64(wf.Width) * (freq - wf.DispLowLatch) / (wf.DispHighLatch - wf.DispLowLatch)
+		labelWidth, _ := text.Measure(d.Call, *face, 0)
+		boxWidth := labelWidth + 2*decodePadding
+		boxX := min(freqX, float64(wf.Width)-boxWidth)
+		row := rows.place(boxX, boxX+boxWidth)
+		if row < 0 {
+			continue
+		}
+		boxY := float64(wf.Height) - float64(row+1)*(boxHeight+2)
+
+		bg := decodeBackground
+		switch {
+		case d.ToMe:
+			bg = decodeToMeBackground
+		case d.CQ:
+			bg = decodeCQBackground
+		}
+		vector.DrawFilledRect(wf.Widget.Image, float32(boxX), float32(boxY), float32(boxWidth), float32(boxHeight), bg, false)
+		textOpts := &text.DrawOptions{}
+		textOpts.GeoM.Translate(boxX+decodePadding, boxY+decodePadding)
+		textOpts.ColorScale.ScaleWithColor(decodeColor)
+		text.Draw(wf.Widget.Image, d.Call, *face, textOpts)
+
+		wf.DecodeHits = append(wf.DecodeHits, decodeHit{
+			Rect:   image.Rect(int(boxX), int(boxY), int(boxX+boxWidth), int(boxY+boxHeight)),
+			Decode: d,
+		})
+	}
+}
+
+// clickDecode asks WSJT-X to answer the decode under a click on the
+// waterfall, reporting whether there was one
+func (wf *Waterfall) clickDecode(u *UI, pt image.Point) bool {
+	for _, hit := range wf.DecodeHits {
+		if pt.In(hit.Rect) {
+			u.eventBus.Publish(events.WSJTXReplyRequested{Decode: hit.Decode})
+			return true
+		}
+	}
+	return false
+}
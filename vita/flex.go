@@ -0,0 +1,183 @@
+package vita
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// FlexRadio class identifiers
+const (
+	FlexOUI                  = 0x001C2D
+	FlexInformationClassCode = 0x534C
+
+	ClassMeter     = 0x8002
+	ClassFFT       = 0x8003
+	ClassWaterfall = 0x8004
+	ClassOpus      = 0x8005
+	// ClassDAXReduced is reduced-bandwidth DAX audio: mono int16
+	ClassDAXReduced = 0x0123
+	// ClassDAX is DAX audio: stereo float32
+	ClassDAX = 0x03E3
+)
+
+// FlexClassID returns the class ID of a FlexRadio packet class
+func FlexClassID(packetClass uint16) *ClassID {
+	return &ClassID{
+		OUI:                  FlexOUI,
+		InformationClassCode: FlexInformationClassCode,
+		PacketClassCode:      packetClass,
+	}
+}
+
+// IsFlexClass reports whether p is a FlexRadio packet of the given class
+func (p *Packet) IsFlexClass(packetClass uint16) bool {
+	return p.ClassID != nil && p.ClassID.OUI == FlexOUI &&
+		p.ClassID.InformationClassCode == FlexInformationClassCode &&
+		p.ClassID.PacketClassCode == packetClass
+}
+
+// NewOpusPacket returns a packet carrying one Opus frame on streamID, time
+// stamped with UTC seconds and the number of samples sent before it
+func NewOpusPacket(streamID uint32, count uint8, seconds uint32, samples uint64, frame []byte) *Packet {
+	return &Packet{
+		Type:                ExtDataWithStream,
+		Count:               count & 0xF,
+		TSI:                 TSIUTC,
+		TSF:                 TSFSampleCount,
+		StreamID:            streamID,
+		ClassID:             FlexClassID(ClassOpus),
+		IntegerTimestamp:    seconds,
+		FractionalTimestamp: samples,
+		Payload:             frame,
+	}
+}
+
+// Meter is one meter reading from a meter packet
+type Meter struct {
+	ID    uint16
+	Value int16
+}
+
+// ParseMeters decodes a meter packet payload
+func ParseMeters(payload []byte) []Meter {
+	meters := make([]Meter, len(payload)/4)
+	for i := range meters {
+		meters[i].ID = binary.BigEndian.Uint16(payload[4*i:])
+		meters[i].Value = int16(binary.BigEndian.Uint16(payload[4*i+2:]))
+	}
+	return meters
+}
+
+// EncodeMeters encodes meter readings as a meter packet payload
+func EncodeMeters(meters []Meter) []byte {
+	payload := make([]byte, 4*len(meters))
+	for i, m := range meters {
+		binary.BigEndian.PutUint16(payload[4*i:], m.ID)
+		binary.BigEndian.PutUint16(payload[4*i+2:], uint16(m.Value))
+	}
+	return payload
+}
+
+// WaterfallTile is a block of waterfall bins. Frequencies are in Hz.
+type WaterfallTile struct {
+	FrameLowFreq     uint64
+	BinBandwidth     uint64
+	LineDurationMS   uint32
+	Width            uint16
+	Height           uint16
+	Timecode         uint32
+	AutoBlackLevel   uint32
+	TotalBinsInFrame uint16
+	FirstBinIndex    uint16
+	Data             []uint16
+}
+
+const waterfallHeaderLen = 36
+
+// Frequencies are 64-bit fixed point with 20 fractional bits
+const frequencyShift = 20
+
+// ParseWaterfall decodes a waterfall packet payload
+func ParseWaterfall(payload []byte) (*WaterfallTile, error) {
+	if len(payload) < waterfallHeaderLen {
+		return nil, errors.New("vita: short waterfall packet")
+	}
+	tile := &WaterfallTile{
+		FrameLowFreq:     binary.BigEndian.Uint64(payload[0:]) >> frequencyShift,
+		BinBandwidth:     binary.BigEndian.Uint64(payload[8:]) >> frequencyShift,
+		LineDurationMS:   binary.BigEndian.Uint32(payload[16:]),
+		Width:            binary.BigEndian.Uint16(payload[20:]),
+		Height:           binary.BigEndian.Uint16(payload[22:]),
+		Timecode:         binary.BigEndian.Uint32(payload[24:]),
+		AutoBlackLevel:   binary.BigEndian.Uint32(payload[28:]),
+		TotalBinsInFrame: binary.BigEndian.Uint16(payload[32:]),
+		FirstBinIndex:    binary.BigEndian.Uint16(payload[34:]),
+	}
+	// Tolerate a short final tile rather than dropping the whole line
+	bins := min(int(tile.Width)*int(tile.Height), (len(payload)-waterfallHeaderLen)/2)
+	tile.Data = make([]uint16, bins)
+	for i := range tile.Data {
+		tile.Data[i] = binary.BigEndian.Uint16(payload[waterfallHeaderLen+2*i:])
+	}
+	return tile, nil
+}
+
+// EncodeWaterfall encodes a waterfall tile as a packet payload
+func EncodeWaterfall(tile *WaterfallTile) []byte {
+	payload := make([]byte, waterfallHeaderLen+2*len(tile.Data))
+	binary.BigEndian.PutUint64(payload[0:], tile.FrameLowFreq<<frequencyShift)
+	binary.BigEndian.PutUint64(payload[8:], tile.BinBandwidth<<frequencyShift)
+	binary.BigEndian.PutUint32(payload[16:], tile.LineDurationMS)
+	binary.BigEndian.PutUint16(payload[20:], tile.Width)
+	binary.BigEndian.PutUint16(payload[22:], tile.Height)
+	binary.BigEndian.PutUint32(payload[24:], tile.Timecode)
+	binary.BigEndian.PutUint32(payload[28:], tile.AutoBlackLevel)
+	binary.BigEndian.PutUint16(payload[32:], tile.TotalBinsInFrame)
+	binary.BigEndian.PutUint16(payload[34:], tile.FirstBinIndex)
+	for i, bin := range tile.Data {
+		binary.BigEndian.PutUint16(payload[waterfallHeaderLen+2*i:], bin)
+	}
+	return payload
+}
+
+// ParseDAX decodes a DAX audio payload to interleaved float32 samples.
+// ClassDAX payloads are stereo float32 and ClassDAXReduced payloads are mono
+// int16, both big-endian.
+func ParseDAX(packetClass uint16, payload []byte) ([]float32, error) {
+	switch packetClass {
+	case ClassDAX:
+		samples := make([]float32, len(payload)/4)
+		for i := range samples {
+			samples[i] = math.Float32frombits(binary.BigEndian.Uint32(payload[4*i:]))
+		}
+		return samples, nil
+	case ClassDAXReduced:
+		samples := make([]float32, len(payload)/2)
+		for i := range samples {
+			samples[i] = float32(int16(binary.BigEndian.Uint16(payload[2*i:]))) / (1 << 15)
+		}
+		return samples, nil
+	}
+	return nil, errors.New("vita: not a DAX packet class")
+}
+
+// EncodeDAX encodes samples as a DAX audio payload of the given class
+func EncodeDAX(packetClass uint16, samples []float32) ([]byte, error) {
+	switch packetClass {
+	case ClassDAX:
+		payload := make([]byte, 4*len(samples))
+		for i, s := range samples {
+			binary.BigEndian.PutUint32(payload[4*i:], math.Float32bits(s))
+		}
+		return payload, nil
+	case ClassDAXReduced:
+		payload := make([]byte, 2*len(samples))
+		for i, s := range samples {
+			v := min(max(s*(1<<15), math.MinInt16), math.MaxInt16)
+			binary.BigEndian.PutUint16(payload[2*i:], uint16(int16(v)))
+		}
+		return payload, nil
+	}
+	return nil, errors.New("vita: not a DAX packet class")
+}
@@ -0,0 +1,239 @@
+// Package vita encodes and decodes the VITA-49 packets FlexRadio radios use
+// for audio, waterfall, meter and other streaming data.
+package vita
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// PacketType is the 4-bit VITA-49 packet type
+type PacketType uint8
+
+const (
+	IFData            PacketType = 0
+	IFDataWithStream  PacketType = 1
+	ExtData           PacketType = 2
+	ExtDataWithStream PacketType = 3
+	IFContext         PacketType = 4
+	ExtContext        PacketType = 5
+)
+
+// HasStreamID reports whether packets of this type carry a stream ID
+func (t PacketType) HasStreamID() bool {
+	return t != IFData && t != ExtData
+}
+
+// TSI is the integer timestamp type
+type TSI uint8
+
+const (
+	TSINone  TSI = 0
+	TSIUTC   TSI = 1
+	TSIGPS   TSI = 2
+	TSIOther TSI = 3
+)
+
+// TSF is the fractional timestamp type
+type TSF uint8
+
+const (
+	TSFNone        TSF = 0
+	TSFSampleCount TSF = 1
+	TSFRealTime    TSF = 2
+	TSFFreeRunning TSF = 3
+)
+
+// ClassID identifies the organization and kind of a packet
+type ClassID struct {
+	OUI                  uint32
+	InformationClassCode uint16
+	PacketClassCode      uint16
+}
+
+// Trailer is the optional last word of a data packet. Enables and Indicators
+// are the 12-bit fields; bit 11 is the first (calibrated time) flag.
+type Trailer struct {
+	Enables            uint16
+	Indicators         uint16
+	ContextCountEnable bool
+	ContextCount       uint8
+}
+
+// Trailer state and event flags, for use in Trailer.Enables and Trailer.Indicators
+const (
+	TrailerCalibratedTime uint16 = 1 << (11 - iota)
+	TrailerValidData
+	TrailerReferenceLock
+	TrailerAGCMGC
+	TrailerDetectedSignal
+	TrailerSpectralInversion
+	TrailerOverRange
+	TrailerSampleLoss
+)
+
+func (t Trailer) word() uint32 {
+	w := uint32(t.Enables&0xFFF)<<20 | uint32(t.Indicators&0xFFF)<<8 | uint32(t.ContextCount&0x7F)
+	if t.ContextCountEnable {
+		w |= 1 << 7
+	}
+	return w
+}
+
+func parseTrailer(w uint32) Trailer {
+	return Trailer{
+		Enables:            uint16(w>>20) & 0xFFF,
+		Indicators:         uint16(w>>8) & 0xFFF,
+		ContextCountEnable: w&(1<<7) != 0,
+		ContextCount:       uint8(w & 0x7F),
+	}
+}
+
+// Packet is a decoded VITA-49 packet. ClassID and Trailer are nil when absent.
+type Packet struct {
+	Type                PacketType
+	Count               uint8 // 4-bit sequence number
+	TSI                 TSI
+	TSF                 TSF
+	StreamID            uint32
+	ClassID             *ClassID
+	IntegerTimestamp    uint32
+	FractionalTimestamp uint64
+	Payload             []byte
+	Trailer             *Trailer
+}
+
+// headerLen returns the length in bytes of everything before the payload
+func (p *Packet) headerLen() int {
+	n := 4
+	if p.Type.HasStreamID() {
+		n += 4
+	}
+	if p.ClassID != nil {
+		n += 8
+	}
+	if p.TSI != TSINone {
+		n += 4
+	}
+	if p.TSF != TSFNone {
+		n += 8
+	}
+	return n
+}
+
+// Size returns the encoded packet size in 32-bit words. The payload is
+// padded with zeros to a whole number of words.
+func (p *Packet) Size() int {
+	n := p.headerLen() + (len(p.Payload)+3)/4*4
+	if p.Trailer != nil {
+		n += 4
+	}
+	return n / 4
+}
+
+// Encode serializes the packet
+func (p *Packet) Encode() ([]byte, error) {
+	size := p.Size()
+	if size > 0xFFFF {
+		return nil, fmt.Errorf("vita: packet too large (%d words)", size)
+	}
+	buf := make([]byte, size*4)
+
+	header := uint32(p.Type&0xF)<<28 |
+		uint32(p.TSI&0x3)<<22 |
+		uint32(p.TSF&0x3)<<20 |
+		uint32(p.Count&0xF)<<16 |
+		uint32(size)
+	if p.ClassID != nil {
+		header |= 1 << 27
+	}
+	if p.Trailer != nil {
+		header |= 1 << 26
+	}
+	binary.BigEndian.PutUint32(buf[0:], header)
+
+	pos := 4
+	if p.Type.HasStreamID() {
+		binary.BigEndian.PutUint32(buf[pos:], p.StreamID)
+		pos += 4
+	}
+	if p.ClassID != nil {
+		binary.BigEndian.PutUint32(buf[pos:], p.ClassID.OUI&0x00FFFFFF)
+		binary.BigEndian.PutUint32(buf[pos+4:], uint32(p.ClassID.InformationClassCode)<<16|uint32(p.ClassID.PacketClassCode))
+		pos += 8
+	}
+	if p.TSI != TSINone {
+		binary.BigEndian.PutUint32(buf[pos:], p.IntegerTimestamp)
+		pos += 4
+	}
+	if p.TSF != TSFNone {
+		binary.BigEndian.PutUint64(buf[pos:], p.FractionalTimestamp)
+		pos += 8
+	}
+	copy(buf[pos:], p.Payload)
+	if p.Trailer != nil {
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], p.Trailer.word())
+	}
+	return buf, nil
+}
+
+// Decode parses a packet. The payload aliases data.
+func Decode(data []byte) (*Packet, error) {
+	if len(data) < 4 {
+		return nil, errors.New("vita: packet too short")
+	}
+	header := binary.BigEndian.Uint32(data[0:])
+	p := &Packet{
+		Type:  PacketType(header >> 28),
+		TSI:   TSI(header>>22) & 0x3,
+		TSF:   TSF(header>>20) & 0x3,
+		Count: uint8(header>>16) & 0xF,
+	}
+	size := int(header&0xFFFF) * 4
+	if size > len(data) {
+		return nil, fmt.Errorf("vita: packet size %d exceeds %d bytes received", size, len(data))
+	}
+	data = data[:size]
+
+	hasClassID := header&(1<<27) != 0
+	hasTrailer := header&(1<<26) != 0
+	if hasClassID {
+		p.ClassID = &ClassID{}
+	}
+	pos := p.headerLen()
+	end := len(data)
+	if hasTrailer {
+		end -= 4
+	}
+	if pos > end {
+		return nil, errors.New("vita: packet too short for its header")
+	}
+
+	pos = 4
+	if p.Type.HasStreamID() {
+		p.StreamID = binary.BigEndian.Uint32(data[pos:])
+		pos += 4
+	}
+	if hasClassID {
+		p.ClassID.OUI = binary.BigEndian.Uint32(data[pos:]) & 0x00FFFFFF
+		codes := binary.BigEndian.Uint32(data[pos+4:])
+		p.ClassID.InformationClassCode = uint16(codes >> 16)
+		p.ClassID.PacketClassCode = uint16(codes)
+		pos += 8
+	}
+	if p.TSI != TSINone {
+		p.IntegerTimestamp = binary.BigEndian.Uint32(data[pos:])
+		pos += 4
+	}
+	if p.TSF != TSFNone {
+		p.FractionalTimestamp = binary.BigEndian.Uint64(data[pos:])
+		pos += 8
+	}
+	p.Payload = data[pos:end]
+	if hasTrailer {
+		trailer := parseTrailer(binary.BigEndian.Uint32(data[end:]))
+		p.Trailer = &trailer
+	}
+	return p, nil
+}
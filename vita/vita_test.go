@@ -0,0 +1,352 @@
+package vita
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// unhex decodes a hex string, ignoring whitespace
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Packets as a FlexRadio sends them, one of each class minstrel handles
+var goldenPackets = []struct {
+	name   string
+	class  uint16
+	packet string
+	want   Packet // Payload isn't compared here
+	check  func(t *testing.T, payload []byte)
+}{
+	{
+		name:  "opus",
+		class: ClassOpus,
+		packet: `38550009 84000001 00001c2d 534c8005
+			6543a1b0 00000000 00000f00
+			78010203 04050607`,
+		want: Packet{
+			Type:                ExtDataWithStream,
+			Count:               5,
+			TSI:                 TSIUTC,
+			TSF:                 TSFSampleCount,
+			StreamID:            0x84000001,
+			ClassID:             FlexClassID(ClassOpus),
+			IntegerTimestamp:    0x6543a1b0,
+			FractionalTimestamp: 3840,
+		},
+		check: func(t *testing.T, payload []byte) {
+			want := []byte{0x78, 1, 2, 3, 4, 5, 6, 7}
+			if !bytes.Equal(payload, want) {
+				t.Errorf("payload = % x, want % x", payload, want)
+			}
+		},
+	},
+	{
+		name:  "dax",
+		class: ClassDAX,
+		packet: `3c5f000c 04000008 00001c2d 534c03e3
+			6543a1b0 00000000 00000100
+			3f000000 bf000000 3e800000 bf800000
+			c0040000`,
+		want: Packet{
+			Type:                ExtDataWithStream,
+			Count:               15,
+			TSI:                 TSIUTC,
+			TSF:                 TSFSampleCount,
+			StreamID:            0x04000008,
+			ClassID:             FlexClassID(ClassDAX),
+			IntegerTimestamp:    0x6543a1b0,
+			FractionalTimestamp: 256,
+			Trailer: &Trailer{
+				Enables:    TrailerCalibratedTime | TrailerValidData,
+				Indicators: TrailerValidData,
+			},
+		},
+		check: func(t *testing.T, payload []byte) {
+			samples, err := ParseDAX(ClassDAX, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []float32{0.5, -0.5, 0.25, -1}
+			if !reflect.DeepEqual(samples, want) {
+				t.Errorf("samples = %v, want %v", samples, want)
+			}
+		},
+	},
+	{
+		name:  "dax reduced",
+		class: ClassDAXReduced,
+		packet: `38500009 04000009 00001c2d 534c0123
+			6543a1b0 00000000 00000200
+			4000c000 7fff8000`,
+		want: Packet{
+			Type:                ExtDataWithStream,
+			TSI:                 TSIUTC,
+			TSF:                 TSFSampleCount,
+			StreamID:            0x04000009,
+			ClassID:             FlexClassID(ClassDAXReduced),
+			IntegerTimestamp:    0x6543a1b0,
+			FractionalTimestamp: 512,
+		},
+		check: func(t *testing.T, payload []byte) {
+			samples, err := ParseDAX(ClassDAXReduced, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []float32{0.5, -0.5, 32767.0 / 32768, -1}
+			if !reflect.DeepEqual(samples, want) {
+				t.Errorf("samples = %v, want %v", samples, want)
+			}
+		},
+	},
+	{
+		name:  "waterfall",
+		class: ClassWaterfall,
+		packet: `3802000f 42000000 00001c2d 534c8004
+			00000d59 f8000000 00000000 06400000
+			00000064 00040001 00001234 00000bb8
+			04000010
+			10002000 3000ffff`,
+		want: Packet{
+			Type:     ExtDataWithStream,
+			Count:    2,
+			StreamID: 0x42000000,
+			ClassID:  FlexClassID(ClassWaterfall),
+		},
+		check: func(t *testing.T, payload []byte) {
+			tile, err := ParseWaterfall(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &WaterfallTile{
+				FrameLowFreq:     14000000,
+				BinBandwidth:     100,
+				LineDurationMS:   100,
+				Width:            4,
+				Height:           1,
+				Timecode:         0x1234,
+				AutoBlackLevel:   3000,
+				TotalBinsInFrame: 1024,
+				FirstBinIndex:    16,
+				Data:             []uint16{0x1000, 0x2000, 0x3000, 0xffff},
+			}
+			if !reflect.DeepEqual(tile, want) {
+				t.Errorf("tile = %+v, want %+v", tile, want)
+			}
+		},
+	},
+	{
+		name:  "meter",
+		class: ClassMeter,
+		packet: `38010006 00000700 00001c2d 534c8002
+			0001f800 00110100`,
+		want: Packet{
+			Type:     ExtDataWithStream,
+			Count:    1,
+			StreamID: 0x00000700,
+			ClassID:  FlexClassID(ClassMeter),
+		},
+		check: func(t *testing.T, payload []byte) {
+			meters := ParseMeters(payload)
+			want := []Meter{{ID: 1, Value: -2048}, {ID: 0x11, Value: 0x100}}
+			if !reflect.DeepEqual(meters, want) {
+				t.Errorf("meters = %v, want %v", meters, want)
+			}
+		},
+	},
+}
+
+func TestGoldenPackets(t *testing.T) {
+	for _, tt := range goldenPackets {
+		t.Run(tt.name, func(t *testing.T) {
+			data := unhex(t, tt.packet)
+			p, err := Decode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !p.IsFlexClass(tt.class) {
+				t.Errorf("class ID = %+v, want class %04x", p.ClassID, tt.class)
+			}
+			tt.check(t, p.Payload)
+
+			got := *p
+			got.Payload = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode = %+v, want %+v", got, tt.want)
+			}
+
+			// Re-encoding gives back the same bytes
+			encoded, err := p.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, data) {
+				t.Errorf("Encode = %x, want %x", encoded, data)
+			}
+		})
+	}
+}
+
+func TestGoldenPayloadEncoding(t *testing.T) {
+	// The payload encoders produce the golden payloads
+	opus := NewOpusPacket(0x84000001, 0x15, 0x6543a1b0, 3840, []byte{0x78, 1, 2, 3, 4, 5, 6, 7})
+	dax, _ := EncodeDAX(ClassDAX, []float32{0.5, -0.5, 0.25, -1})
+	daxReduced, _ := EncodeDAX(ClassDAXReduced, []float32{0.5, -0.5, 1, -1})
+	waterfall := EncodeWaterfall(&WaterfallTile{
+		FrameLowFreq:     14000000,
+		BinBandwidth:     100,
+		LineDurationMS:   100,
+		Width:            4,
+		Height:           1,
+		Timecode:         0x1234,
+		AutoBlackLevel:   3000,
+		TotalBinsInFrame: 1024,
+		FirstBinIndex:    16,
+		Data:             []uint16{0x1000, 0x2000, 0x3000, 0xffff},
+	})
+	meters := EncodeMeters([]Meter{{ID: 1, Value: -2048}, {ID: 0x11, Value: 0x100}})
+
+	for i, payload := range [][]byte{opus.Payload, dax, daxReduced, waterfall, meters} {
+		tt := goldenPackets[i]
+		p, err := Decode(unhex(t, tt.packet))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(payload, p.Payload) {
+			t.Errorf("%s: payload = %x, want %x", tt.name, payload, p.Payload)
+		}
+	}
+
+	encoded, err := opus.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := unhex(t, goldenPackets[0].packet); !bytes.Equal(encoded, want) {
+		t.Errorf("NewOpusPacket encodes to %x, want %x", encoded, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Packet
+	}{
+		{"no stream ID", Packet{Type: ExtData, Payload: []byte{1, 2, 3, 4}}},
+		{"stream ID only", Packet{Type: IFDataWithStream, StreamID: 0x12345678, Payload: []byte{1, 2, 3, 4}}},
+		{"empty payload", Packet{Type: ExtDataWithStream, StreamID: 1}},
+		{"class ID", Packet{Type: ExtDataWithStream, StreamID: 2, ClassID: FlexClassID(ClassFFT), Payload: []byte{9, 8, 7, 6}}},
+		{"integer timestamp", Packet{Type: ExtDataWithStream, TSI: TSIGPS, IntegerTimestamp: 0xdeadbeef}},
+		{"fractional timestamp", Packet{Type: ExtDataWithStream, TSF: TSFRealTime, FractionalTimestamp: 0x0123456789abcdef}},
+		{"count", Packet{Type: ExtDataWithStream, Count: 9}},
+		{"trailer", Packet{Type: ExtDataWithStream, Payload: []byte{1, 2, 3, 4}, Trailer: &Trailer{
+			Enables:            TrailerOverRange | TrailerSampleLoss,
+			Indicators:         TrailerSampleLoss,
+			ContextCountEnable: true,
+			ContextCount:       0x55,
+		}}},
+		{"everything", Packet{
+			Type:                IFDataWithStream,
+			Count:               3,
+			TSI:                 TSIOther,
+			TSF:                 TSFFreeRunning,
+			StreamID:            0xffffffff,
+			ClassID:             &ClassID{OUI: 0xabcdef, InformationClassCode: 1, PacketClassCode: 2},
+			IntegerTimestamp:    7,
+			FractionalTimestamp: 8,
+			Payload:             bytes.Repeat([]byte{0x5a}, 64),
+			Trailer:             &Trailer{Enables: 0xfff, Indicators: 0xfff},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.p.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != tt.p.Size()*4 {
+				t.Errorf("encoded %d bytes, want %d", len(data), tt.p.Size()*4)
+			}
+			got, err := Decode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Payload) == 0 {
+				got.Payload = nil
+			}
+			if !reflect.DeepEqual(*got, tt.p) {
+				t.Errorf("Decode(Encode) = %+v, want %+v", *got, tt.p)
+			}
+		})
+	}
+}
+
+func TestPayloadPadding(t *testing.T) {
+	// Payloads are padded with zeros to a whole number of words
+	p := Packet{Type: ExtDataWithStream, Payload: []byte{1, 2, 3, 4, 5}, Trailer: &Trailer{}}
+	data, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Size() != 5 || len(data) != 20 {
+		t.Fatalf("size = %d words, %d bytes; want 5 words, 20 bytes", p.Size(), len(data))
+	}
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{1, 2, 3, 4, 5, 0, 0, 0}; !bytes.Equal(got.Payload, want) {
+		t.Errorf("payload = % x, want % x", got.Payload, want)
+	}
+	if got.Trailer == nil {
+		t.Error("trailer missing")
+	}
+}
+
+func TestDecodeIgnoresTrailingBytes(t *testing.T) {
+	// The size in the header, not the datagram length, ends the packet
+	data := append(unhex(t, goldenPackets[4].packet), 0xff, 0xff, 0xff, 0xff)
+	p, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Payload) != 8 {
+		t.Errorf("payload length = %d, want 8", len(p.Payload))
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet string
+	}{
+		{"empty", ""},
+		{"short header", "380100"},
+		{"size larger than packet", "38010006 00000700 00001c2d 534c8002"},
+		{"stream ID missing", "38000001"},
+		{"class ID missing", "38000003 00000700 00001c2d"},
+		{"timestamps missing", "38500004 00000700 00001c2d 534c8005"},
+		{"trailer missing", "34000002 00000700"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if p, err := Decode(unhex(t, tt.packet)); err == nil {
+				t.Errorf("Decode succeeded: %+v", p)
+			}
+		})
+	}
+}
+
+func TestEncodeTooLarge(t *testing.T) {
+	p := Packet{Type: ExtDataWithStream, Payload: make([]byte, 4*0x10000)}
+	if _, err := p.Encode(); err == nil {
+		t.Error("Encode succeeded")
+	}
+}
@@ -0,0 +1,276 @@
+package wsjtx
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// The WSJT-X UDP protocol, documented in NetworkMessage.hpp in the WSJT-X
+// sources. Messages are Qt QDataStream encoded: big endian, with strings as
+// a length followed by UTF-8.
+const (
+	magic  = 0xadbccbda
+	schema = 2
+
+	typeHeartbeat = 0
+	typeStatus    = 1
+	typeDecode    = 2
+	typeClear     = 3
+	typeReply     = 4
+	typeQSOLogged = 5
+	typeClose     = 6
+)
+
+// Julian day of the Unix epoch, for QDate
+const unixEpochJulianDay = 2440588
+
+var errShort = errors.New("message too short")
+
+// reader decodes QDataStream fields, remembering the first error
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = errShort
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) uint8() uint8 {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) bool() bool {
+	return r.uint8() != 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) uint64() uint64 {
+	if b := r.take(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *reader) float64() float64 {
+	return math.Float64frombits(r.uint64())
+}
+
+// string reads a QByteArray holding UTF-8, where a length of 0xffffffff means
+// a null string
+func (r *reader) string() string {
+	n := r.uint32()
+	if n == 0xffffffff {
+		return ""
+	}
+	return string(r.take(int(n)))
+}
+
+// time reads a QTime as the time since midnight
+func (r *reader) time() time.Duration {
+	ms := r.uint32()
+	if ms == 0xffffffff {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// dateTime reads a QDateTime
+func (r *reader) dateTime() time.Time {
+	day := int64(r.uint64())
+	sinceMidnight := r.time()
+	loc := time.UTC
+	switch r.uint8() {
+	case 0:
+		loc = time.Local
+	case 2:
+		loc = time.FixedZone("", int(int32(r.uint32())))
+	case 3:
+		// Time zone by name, which we don't handle
+		r.string()
+	}
+	if r.err != nil {
+		return time.Time{}
+	}
+	y, m, d := time.Unix((day-unixEpochJulianDay)*86400, 0).UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc).Add(sinceMidnight).UTC()
+}
+
+// writer encodes QDataStream fields
+type writer struct {
+	data []byte
+}
+
+func (w *writer) uint8(v uint8) {
+	w.data = append(w.data, v)
+}
+
+func (w *writer) bool(v bool) {
+	if v {
+		w.uint8(1)
+	} else {
+		w.uint8(0)
+	}
+}
+
+func (w *writer) uint32(v uint32) {
+	w.data = binary.BigEndian.AppendUint32(w.data, v)
+}
+
+func (w *writer) float64(v float64) {
+	w.data = binary.BigEndian.AppendUint64(w.data, math.Float64bits(v))
+}
+
+func (w *writer) string(s string) {
+	w.uint32(uint32(len(s)))
+	w.data = append(w.data, s...)
+}
+
+func (w *writer) header(msgType uint32, id string) {
+	w.uint32(magic)
+	w.uint32(schema)
+	w.uint32(msgType)
+	w.string(id)
+}
+
+// status is the part of a Status message we use
+type status struct {
+	DialFreq float64 // MHz
+	Mode     string
+	DECall   string
+	Period   time.Duration // zero for older versions that don't send it
+}
+
+func parseStatus(r *reader) (status, error) {
+	var s status
+	s.DialFreq = float64(r.uint64()) / 1e6
+	s.Mode = r.string()
+	r.string() // DX call
+	r.string() // report
+	r.string() // TX mode
+	r.bool()   // TX enabled
+	r.bool()   // transmitting
+	r.bool()   // decoding
+	r.uint32() // RX DF
+	r.uint32() // TX DF
+	s.DECall = r.string()
+	if r.err != nil {
+		return s, r.err
+	}
+	// Later fields were added over time
+	r.string() // DE grid
+	r.string() // DX grid
+	r.bool()   // TX watchdog
+	r.string() // submode
+	r.bool()   // fast mode
+	r.uint8()  // special operation mode
+	r.uint32() // frequency tolerance
+	period := r.uint32()
+	if r.err == nil {
+		s.Period = time.Duration(period) * time.Second
+	}
+	return s, nil
+}
+
+// decode is a Decode message
+type decode struct {
+	New           bool
+	Time          time.Duration
+	SNR           int
+	DT            float64
+	DF            int
+	Mode          string
+	Message       string
+	LowConfidence bool
+}
+
+func parseDecode(r *reader) (decode, error) {
+	var d decode
+	d.New = r.bool()
+	d.Time = r.time()
+	d.SNR = int(int32(r.uint32()))
+	d.DT = r.float64()
+	d.DF = int(r.uint32())
+	d.Mode = r.string()
+	d.Message = r.string()
+	d.LowConfidence = r.bool()
+	return d, r.err
+}
+
+// qsoLogged is a QSOLogged message
+type qsoLogged struct {
+	TimeOff  time.Time
+	Call     string
+	Grid     string
+	Freq     float64 // MHz
+	Mode     string
+	RSTSent  string
+	RSTRcvd  string
+	Power    string
+	Comments string
+	Name     string
+	TimeOn   time.Time
+	ExchSent string
+	ExchRcvd string
+}
+
+func parseQSOLogged(r *reader) (qsoLogged, error) {
+	var q qsoLogged
+	q.TimeOff = r.dateTime()
+	q.Call = r.string()
+	q.Grid = r.string()
+	q.Freq = float64(r.uint64()) / 1e6
+	q.Mode = r.string()
+	q.RSTSent = r.string()
+	q.RSTRcvd = r.string()
+	q.Power = r.string()
+	q.Comments = r.string()
+	q.Name = r.string()
+	q.TimeOn = r.dateTime()
+	if r.err != nil {
+		return q, r.err
+	}
+	r.string() // operator call
+	r.string() // my call
+	r.string() // my grid
+	q.ExchSent = r.string()
+	q.ExchRcvd = r.string()
+	// Older versions end before the exchange
+	return q, nil
+}
+
+// encodeReply builds a Reply message asking WSJT-X instance id to answer a
+// decode, as if it had been double-clicked
+func encodeReply(id string, d decode) []byte {
+	w := &writer{}
+	w.header(typeReply, id)
+	w.uint32(uint32(d.Time / time.Millisecond))
+	w.uint32(uint32(int32(d.SNR)))
+	w.float64(d.DT)
+	w.uint32(uint32(d.DF))
+	w.string(d.Mode)
+	w.string(d.Message)
+	w.bool(d.LowConfidence)
+	w.uint8(0) // no modifier keys
+	return w.data
+}
@@ -0,0 +1,278 @@
+package wsjtx
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+// packet decodes a message written out a field at a time in hex
+func packet(t *testing.T, fields ...string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(strings.ReplaceAll(strings.Join(fields, ""), " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// body reads a message's header, checking it, and returns a reader for the rest
+func body(t *testing.T, data []byte, msgType uint32) *reader {
+	t.Helper()
+	r := &reader{data: data}
+	if m := r.uint32(); m != magic {
+		t.Fatalf("magic = %x", m)
+	}
+	if s := r.uint32(); s != schema {
+		t.Errorf("schema = %d, want %d", s, schema)
+	}
+	if typ := r.uint32(); typ != msgType {
+		t.Errorf("type = %d, want %d", typ, msgType)
+	}
+	if id := r.string(); id != "WSJT-X" {
+		t.Errorf("id = %q, want WSJT-X", id)
+	}
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	return r
+}
+
+const (
+	statusHeader   = "adbccbda 00000002 00000001 00000006 57534a542d58"
+	decodeHeader   = "adbccbda 00000002 00000002 00000006 57534a542d58"
+	qsoHeader      = "adbccbda 00000002 00000005 00000006 57534a542d58"
+	replyHeader    = "adbccbda 00000002 00000004 00000006 57534a542d58"
+	statusUpToCall = "" +
+		"0000000000d6c090" + // dial frequency 14074000 Hz
+		"00000003 465438" + // mode FT8
+		"00000005 573958595a" + // DX call W9XYZ
+		"00000003 2d3130" + // report -10
+		"00000003 465438" + // TX mode FT8
+		"01" + // TX enabled
+		"00" + // transmitting
+		"01" + // decoding
+		"000005dc" + // RX DF 1500
+		"000005dc" + // TX DF 1500
+		"00000004 4b433247" // DE call KC2G
+	// As sent by WSJT-X 2.0 to 2.2
+	statusV20 = statusUpToCall +
+		"00000004 464e3432" + // DE grid FN42
+		"00000004 454d3733" + // DX grid EM73
+		"00" + // TX watchdog
+		"ffffffff" + // submode, null
+		"00" + // fast mode
+		"00" // special operation mode
+	// As sent by WSJT-X 2.3 and later
+	statusV26 = statusV20 +
+		"00000032" + // frequency tolerance 50
+		"0000000f" + // T/R period 15 s
+		"00000007 44656661756c74" + // configuration name Default
+		"00000010 4b3141424320573958595a20454d3733" // TX message K1ABC W9XYZ EM73
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields string
+		period time.Duration
+	}{
+		{"WSJT-X 2.6", statusV26, 15 * time.Second},
+		{"WSJT-X 2.0", statusV20, 0},
+		{"ending at the DE call", statusUpToCall, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := body(t, packet(t, statusHeader, tt.fields), typeStatus)
+			s, err := parseStatus(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := status{DialFreq: 14.074, Mode: "FT8", DECall: "KC2G", Period: tt.period}
+			if s != want {
+				t.Errorf("parseStatus = %+v, want %+v", s, want)
+			}
+		})
+	}
+
+	// Without the DE call it's too short to use
+	r := body(t, packet(t, statusHeader, statusUpToCall[:len(statusUpToCall)-len("00000004 4b433247")]), typeStatus)
+	if _, err := parseStatus(r); err != errShort {
+		t.Errorf("parseStatus of a short status = %v, want %v", err, errShort)
+	}
+}
+
+func TestParseDecode(t *testing.T) {
+	data := packet(t, decodeHeader,
+		"01",                                  // new
+		"02b2fe88",                            // time 12:34:45
+		"fffffff4",                            // SNR -12
+		"3fd3333333333333",                    // DT 0.3
+		"000004d2",                            // DF 1234
+		"00000001 7e",                         // mode ~ (FT8)
+		"0000000d 4351204b3141424320464e3432", // message CQ K1ABC FN42
+		"00",                                  // low confidence
+		"00",                                  // off air
+	)
+	d, err := parseDecode(body(t, data, typeDecode))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := decode{
+		New:     true,
+		Time:    12*time.Hour + 34*time.Minute + 45*time.Second,
+		SNR:     -12,
+		DT:      0.3,
+		DF:      1234,
+		Mode:    "~",
+		Message: "CQ K1ABC FN42",
+	}
+	if d != want {
+		t.Errorf("parseDecode = %+v, want %+v", d, want)
+	}
+
+	// Cut off in the message
+	if _, err := parseDecode(body(t, data[:len(data)-8], typeDecode)); err != errShort {
+		t.Errorf("parseDecode of a short decode = %v, want %v", err, errShort)
+	}
+}
+
+func TestParseQSOLogged(t *testing.T) {
+	const upToTimeOn = "" +
+		"0000000000258d22 02aea540 01" + // time off 2025-10-13 12:30:00 UTC
+		"00000005 573958595a" + // DX call W9XYZ
+		"00000004 454d3733" + // DX grid EM73
+		"0000000000d6c562" + // TX frequency 14075234 Hz
+		"00000003 465438" + // mode FT8
+		"00000003 2d3035" + // report sent -05
+		"00000003 2b3032" + // report received +02
+		"00000002 3557" + // TX power 5W
+		"00000006 746e78206662" + // comments tnx fb
+		"00000003 426f62" + // name Bob
+		"0000000000258d22 02acd080 01" // time on 2025-10-13 12:28:00 UTC
+	const upToMyGrid = upToTimeOn +
+		"ffffffff" + // operator call, null
+		"00000004 4b433247" + // my call KC2G
+		"00000004 464e3432" // my grid FN42
+	const withExchange = upToMyGrid +
+		"00000004 31204e59" + // exchange sent 1 NY
+		"00000005 3241205749" + // exchange received 2A WI
+		"00000000" // propagation mode, empty
+
+	base := qsoLogged{
+		TimeOff:  time.Date(2025, 10, 13, 12, 30, 0, 0, time.UTC),
+		Call:     "W9XYZ",
+		Grid:     "EM73",
+		Freq:     14.075234,
+		Mode:     "FT8",
+		RSTSent:  "-05",
+		RSTRcvd:  "+02",
+		Power:    "5W",
+		Comments: "tnx fb",
+		Name:     "Bob",
+		TimeOn:   time.Date(2025, 10, 13, 12, 28, 0, 0, time.UTC),
+	}
+	exchange := base
+	exchange.ExchSent, exchange.ExchRcvd = "1 NY", "2A WI"
+
+	tests := []struct {
+		name   string
+		fields string
+		want   qsoLogged
+	}{
+		{"with the exchange", withExchange, exchange},
+		{"without the exchange", upToMyGrid, base},
+		{"ending at the time on", upToTimeOn, base},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parseQSOLogged(body(t, packet(t, qsoHeader, tt.fields), typeQSOLogged))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q != tt.want {
+				t.Errorf("parseQSOLogged = %+v, want %+v", q, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateTime(t *testing.T) {
+	const date = "0000000000258d22" // 2025-10-13
+	const tenAM = "02255100"        // 10:00:00
+	tests := []struct {
+		name   string
+		fields string
+		want   time.Time
+	}{
+		{"local time", date + tenAM + "00", time.Date(2025, 10, 13, 10, 0, 0, 0, time.Local).UTC()},
+		{"UTC", date + tenAM + "01", time.Date(2025, 10, 13, 10, 0, 0, 0, time.UTC)},
+		{"offset from UTC", date + tenAM + "02 ffffb9b0", time.Date(2025, 10, 13, 15, 0, 0, 0, time.UTC)},
+		{"null time", date + "ffffffff 01", time.Date(2025, 10, 13, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &reader{data: packet(t, tt.fields)}
+			got := r.dateTime()
+			if r.err != nil {
+				t.Fatal(r.err)
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("dateTime = %v, want %v", got, tt.want)
+			}
+			if len(r.data) != 0 {
+				t.Errorf("%d bytes left over", len(r.data))
+			}
+		})
+	}
+
+	// The offset is missing
+	r := &reader{data: packet(t, date+tenAM+"02")}
+	if got := r.dateTime(); r.err != errShort || !got.IsZero() {
+		t.Errorf("dateTime without its offset = %v, %v; want %v", got, r.err, errShort)
+	}
+}
+
+func TestEncodeReply(t *testing.T) {
+	d := decode{
+		Time:    12*time.Hour + 34*time.Minute + 45*time.Second,
+		SNR:     -12,
+		DT:      0.3,
+		DF:      1234,
+		Mode:    "~",
+		Message: "CQ K1ABC FN42",
+	}
+	got := encodeReply("WSJT-X", d)
+	want := packet(t, replyHeader,
+		"02b2fe88",                            // time 12:34:45
+		"fffffff4",                            // SNR -12
+		"3fd3333333333333",                    // DT 0.3
+		"000004d2",                            // DF 1234
+		"00000001 7e",                         // mode ~
+		"0000000d 4351204b3141424320464e3432", // message CQ K1ABC FN42
+		"00",                                  // low confidence
+		"00",                                  // no modifiers
+	)
+	if hex.EncodeToString(got) != hex.EncodeToString(want) {
+		t.Errorf("encodeReply =\n%x, want\n%x", got, want)
+	}
+
+	// It reads back as the decode it answers
+	r := body(t, got, typeReply)
+	back := decode{
+		Time:          r.time(),
+		SNR:           int(int32(r.uint32())),
+		DT:            r.float64(),
+		DF:            int(r.uint32()),
+		Mode:          r.string(),
+		Message:       r.string(),
+		LowConfidence: r.bool(),
+	}
+	if modifiers := r.uint8(); r.err != nil || modifiers != 0 || len(r.data) != 0 {
+		t.Errorf("reply ends with modifiers %d, %d bytes left, error %v", modifiers, len(r.data), r.err)
+	}
+	if back != d {
+		t.Errorf("reply reads back as %+v, want %+v", back, d)
+	}
+}
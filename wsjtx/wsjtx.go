@@ -0,0 +1,336 @@
+// Package wsjtx listens for WSJT-X UDP messages, publishing its decodes on the
+// event bus, logging its QSOs and answering decodes on request.
+package wsjtx
+
+import (
+	"context"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+	"github.com/kc2g-flex-tools/minstrel/logbook"
+)
+
+type Config struct {
+	Listen string `dialsdesc:"Address to receive WSJT-X messages on, e.g. 127.0.0.1:2237 or a multicast group such as 224.0.0.1:2237 (empty to disable)" dialsflag:"wsjtx-listen"`
+	Log    bool   `dialsdesc:"Add QSOs logged in WSJT-X to the logbook" dialsflag:"wsjtx-log"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Log: true,
+	}
+}
+
+const (
+	// Decodes are shown for this many periods
+	decodePeriods = 2
+	// Period assumed until WSJT-X says otherwise (FT8)
+	defaultPeriod = 15 * time.Second
+	// How often to check for expired decodes
+	expiryInterval = time.Second
+)
+
+// client is a running instance of WSJT-X
+type client struct {
+	addr    *net.UDPAddr
+	status  status
+	decodes []events.WSJTXDecode
+}
+
+// Listener receives messages from any number of WSJT-X instances
+type Listener struct {
+	cfg    *Config
+	bus    *events.Bus
+	lb     *logbook.Logbook
+	events chan events.Event
+
+	mu      sync.Mutex
+	clients map[string]*client
+}
+
+// NewListener creates a listener. QSOs logged in WSJT-X are added to lb if it
+// isn't nil.
+func NewListener(cfg *Config, bus *events.Bus, lb *logbook.Logbook) *Listener {
+	return &Listener{
+		cfg:     cfg,
+		bus:     bus,
+		lb:      lb,
+		events:  bus.Subscribe(10),
+		clients: map[string]*client{},
+	}
+}
+
+// Run receives messages until ctx is cancelled
+func (l *Listener) Run(ctx context.Context) error {
+	addr, err := net.ResolveUDPAddr("udp", l.cfg.Listen)
+	if err != nil {
+		return err
+	}
+	var conn *net.UDPConn
+	if addr.IP.IsMulticast() {
+		conn, err = net.ListenMulticastUDP("udp", nil, addr)
+	} else {
+		conn, err = net.ListenUDP("udp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	log.Println("listening for WSJT-X on", conn.LocalAddr())
+
+	go l.handleEvents(ctx, conn)
+	go l.expireDecodes(ctx)
+
+	buf := make([]byte, 65536)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := l.handleMessage(buf[:n], from); err != nil {
+			log.Println("WSJT-X message:", err)
+		}
+	}
+}
+
+func (l *Listener) handleMessage(data []byte, from *net.UDPAddr) error {
+	r := &reader{data: data}
+	if r.uint32() != magic {
+		// Not a WSJT-X message
+		return nil
+	}
+	r.uint32() // schema; later schemas only add fields
+	msgType := r.uint32()
+	id := r.string()
+	if r.err != nil {
+		return r.err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := l.clients[id]
+	if c == nil {
+		c = &client{}
+		l.clients[id] = c
+	}
+	c.addr = from
+
+	switch msgType {
+	case typeStatus:
+		s, err := parseStatus(r)
+		if err != nil {
+			return err
+		}
+		// Decodes from another band or mode no longer apply
+		if s.DialFreq != c.status.DialFreq || s.Mode != c.status.Mode {
+			c.decodes = nil
+			defer l.publish()
+		}
+		c.status = s
+
+	case typeDecode:
+		d, err := parseDecode(r)
+		if err != nil {
+			return err
+		}
+		if c.status.DialFreq == 0 {
+			// Wait for a status to know where the decode is
+			return nil
+		}
+		duplicate := slices.ContainsFunc(c.decodes, func(e events.WSJTXDecode) bool {
+			return e.Time == d.Time && e.DF == d.DF && e.Message == d.Message
+		})
+		if duplicate {
+			return nil
+		}
+		call, cq, to := parseMessage(d.Message)
+		c.decodes = append(c.decodes, events.WSJTXDecode{
+			Client:        id,
+			Time:          d.Time,
+			SNR:           d.SNR,
+			DT:            d.DT,
+			DF:            d.DF,
+			DialFreq:      c.status.DialFreq,
+			Mode:          d.Mode,
+			Message:       d.Message,
+			LowConfidence: d.LowConfidence,
+			Call:          call,
+			CQ:            cq,
+			ToMe:          to != "" && strings.EqualFold(to, c.status.DECall),
+			Received:      time.Now(),
+		})
+		defer l.publish()
+
+	case typeClear:
+		c.decodes = nil
+		defer l.publish()
+
+	case typeClose:
+		delete(l.clients, id)
+		defer l.publish()
+
+	case typeQSOLogged:
+		q, err := parseQSOLogged(r)
+		if err != nil {
+			return err
+		}
+		if l.cfg.Log && l.lb != nil {
+			go l.logQSO(q)
+		}
+	}
+	return nil
+}
+
+// looksLikeCall tells callsigns from CQ modifiers such as "DX" or "POTA"
+func looksLikeCall(s string) bool {
+	s = strings.Trim(s, "<>")
+	return len(s) >= 3 && strings.ContainsAny(s, "0123456789") &&
+		strings.ContainsAny(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+}
+
+// parseMessage finds the transmitting station in a standard message, whether
+// it is calling CQ, and who it is calling otherwise
+func parseMessage(msg string) (call string, cq bool, to string) {
+	fields := strings.Fields(strings.ToUpper(msg))
+	switch {
+	case len(fields) >= 2 && fields[0] == "CQ":
+		cq = true
+		rest := fields[1:]
+		if len(rest) >= 2 && !looksLikeCall(rest[0]) {
+			rest = rest[1:]
+		}
+		call = rest[0]
+	case len(fields) >= 2:
+		to, call = fields[0], fields[1]
+	default:
+		return "", false, ""
+	}
+	// Hashed callsigns are shown in angle brackets, or as <...> if unknown
+	call = strings.Trim(call, "<>")
+	if call == "..." {
+		call = ""
+	}
+	return call, cq, strings.Trim(to, "<>")
+}
+
+// publish sends the decodes from every instance. Called with mu held.
+func (l *Listener) publish() {
+	var decodes []events.WSJTXDecode
+	for _, c := range l.clients {
+		decodes = append(decodes, c.decodes...)
+	}
+	l.bus.Publish(events.WSJTXDecodesUpdated{Decodes: decodes})
+}
+
+func (l *Listener) expireDecodes(ctx context.Context) {
+	ticker := time.NewTicker(expiryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			expired := false
+			for _, c := range l.clients {
+				period := c.status.Period
+				if period == 0 {
+					period = defaultPeriod
+				}
+				n := len(c.decodes)
+				c.decodes = slices.DeleteFunc(c.decodes, func(d events.WSJTXDecode) bool {
+					return time.Since(d.Received) > decodePeriods*period
+				})
+				expired = expired || len(c.decodes) != n
+			}
+			if expired {
+				l.publish()
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// handleEvents sends replies to WSJT-X when asked
+func (l *Listener) handleEvents(ctx context.Context, conn *net.UDPConn) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-l.events:
+			e, ok := event.(events.WSJTXReplyRequested)
+			if !ok {
+				continue
+			}
+			// The address changes as messages arrive, so copy it
+			var addr *net.UDPAddr
+			l.mu.Lock()
+			if c := l.clients[e.Decode.Client]; c != nil {
+				addr = c.addr
+			}
+			l.mu.Unlock()
+			if addr == nil {
+				continue
+			}
+			msg := encodeReply(e.Decode.Client, decode{
+				Time:          e.Decode.Time,
+				SNR:           e.Decode.SNR,
+				DT:            e.Decode.DT,
+				DF:            e.Decode.DF,
+				Mode:          e.Decode.Mode,
+				Message:       e.Decode.Message,
+				LowConfidence: e.Decode.LowConfidence,
+			})
+			if _, err := conn.WriteToUDP(msg, addr); err != nil {
+				log.Println("sending reply to WSJT-X:", err)
+			}
+		}
+	}
+}
+
+// adifMode converts a WSJT-X mode name to an ADIF mode and submode
+func adifMode(mode string) (string, string) {
+	switch mode {
+	case "FT4", "FST4", "FST4W", "Q65", "JS8":
+		return "MFSK", mode
+	}
+	return mode, ""
+}
+
+func (l *Listener) logQSO(q qsoLogged) {
+	mode, submode := adifMode(q.Mode)
+	qso := logbook.QSO{
+		Time:     q.TimeOn,
+		Call:     q.Call,
+		Freq:     q.Freq,
+		Mode:     mode,
+		Submode:  submode,
+		RSTSent:  q.RSTSent,
+		RSTRcvd:  q.RSTRcvd,
+		Name:     q.Name,
+		Grid:     q.Grid,
+		Notes:    q.Comments,
+		ExchSent: q.ExchSent,
+		ExchRcvd: q.ExchRcvd,
+	}
+	if qso.Time.IsZero() {
+		qso.Time = q.TimeOff
+	}
+	n, err := l.lb.Import([]logbook.QSO{qso})
+	if err != nil {
+		log.Println("logging WSJT-X QSO:", err)
+	} else if n > 0 {
+		log.Println("logged WSJT-X QSO with", qso.Call)
+	}
+}
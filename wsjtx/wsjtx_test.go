@@ -0,0 +1,80 @@
+package wsjtx
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kc2g-flex-tools/minstrel/events"
+)
+
+func TestReply(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	wsjtx, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wsjtx.Close()
+	from := wsjtx.LocalAddr().(*net.UDPAddr)
+
+	bus := events.NewBus()
+	l := NewListener(&Config{}, bus, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.handleEvents(ctx, conn)
+
+	status := packet(t, statusHeader, statusV26)
+	if err := l.handleMessage(status, from); err != nil {
+		t.Fatal(err)
+	}
+	// Messages keep arriving while the reply is sent
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				l.handleMessage(status, from)
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	d := decode{Time: 12*time.Hour + 34*time.Minute + 45*time.Second, SNR: -12, DT: 0.3, DF: 1234, Mode: "~", Message: "CQ K1ABC FN42"}
+	bus.Publish(events.WSJTXReplyRequested{Decode: events.WSJTXDecode{
+		Client:  "WSJT-X",
+		Time:    d.Time,
+		SNR:     d.SNR,
+		DT:      d.DT,
+		DF:      d.DF,
+		Mode:    d.Mode,
+		Message: d.Message,
+	}})
+	wsjtx.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	n, err := wsjtx.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := encodeReply("WSJT-X", d); !bytes.Equal(buf[:n], want) {
+		t.Errorf("reply = %x, want %x", buf[:n], want)
+	}
+
+	// Nothing is sent to an instance that hasn't been heard from
+	bus.Publish(events.WSJTXReplyRequested{Decode: events.WSJTXDecode{Client: "JTDX", Message: d.Message}})
+	wsjtx.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if n, err := wsjtx.Read(buf); err == nil {
+		t.Errorf("sent %x to WSJT-X for another instance", buf[:n])
+	}
+}